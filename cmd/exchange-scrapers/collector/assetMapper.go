@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/jackc/pgx/v4"
+)
+
+// assetMapStore is the subset of models.RelDB used for mapping bridged assets.
+type assetMapStore interface {
+	SetAsset(asset dia.Asset) error
+	GetAssetID(asset dia.Asset) (string, error)
+	GetAssetMap(assetID string) (string, error)
+	InsertAssetMap(groupID string, assetID string) error
+	InsertNewAssetMap(assetID string) error
+}
+
+// assetMapping is an asset newly assigned to an asset group.
+type assetMapping struct {
+	GroupID     string
+	Asset       dia.Asset
+	Counterpart dia.Asset
+	NewGroup    bool
+	Source      string
+	TradeID     string
+	Time        time.Time
+}
+
+// assetMapConflict occurs if the assets of a bridge transfer belong to different asset groups.
+type assetMapConflict struct {
+	QuoteToken   dia.Asset
+	QuoteGroupID string
+	BaseToken    dia.Asset
+	BaseGroupID  string
+	Source       string
+	TradeID      string
+	Time         time.Time
+}
+
+// assetMapper assigns the two assets of a bridge transfer, i.e. the same token on two
+// blockchains, to a common asset group in the assetIdent table.
+type assetMapper struct {
+	store assetMapStore
+
+	mu sync.Mutex
+	// Pairs of asset IDs which are already known to be in the same group.
+	mapped    map[string]struct{}
+	conflicts map[string]assetMapConflict
+	// Mappings and conflicts since the last report.
+	newMappings  []assetMapping
+	newConflicts []assetMapConflict
+	numTrades    int
+}
+
+func newAssetMapper(store assetMapStore) *assetMapper {
+	return &assetMapper{
+		store:     store,
+		mapped:    make(map[string]struct{}),
+		conflicts: make(map[string]assetMapConflict),
+	}
+}
+
+// mapTrade assigns quote and base token of the bridge transfer @t to the same asset group.
+// If only one of both assets is in a group, the other one is added to it. If none of both is,
+// a new group is created. If they belong to different groups, nothing is written and
+// the conflict is reported.
+func (am *assetMapper) mapTrade(t *dia.Trade) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.numTrades++
+
+	quoteID, err := am.assetID(t.QuoteToken)
+	if err != nil {
+		return fmt.Errorf("asset id of quote token %s on %s: %v", t.QuoteToken.Address, t.QuoteToken.Blockchain, err)
+	}
+	baseID, err := am.assetID(t.BaseToken)
+	if err != nil {
+		return fmt.Errorf("asset id of base token %s on %s: %v", t.BaseToken.Address, t.BaseToken.Blockchain, err)
+	}
+	if quoteID == baseID {
+		return nil
+	}
+	pairKey := assetPairKey(quoteID, baseID)
+	if _, ok := am.mapped[pairKey]; ok {
+		return nil
+	}
+	if _, ok := am.conflicts[pairKey]; ok {
+		return nil
+	}
+
+	quoteGroupID, err := am.groupID(quoteID)
+	if err != nil {
+		return err
+	}
+	baseGroupID, err := am.groupID(baseID)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case quoteGroupID != "" && quoteGroupID == baseGroupID:
+		// Both assets are already mapped.
+
+	case quoteGroupID != "" && baseGroupID != "":
+		conflict := assetMapConflict{
+			QuoteToken:   t.QuoteToken,
+			QuoteGroupID: quoteGroupID,
+			BaseToken:    t.BaseToken,
+			BaseGroupID:  baseGroupID,
+			Source:       t.Source,
+			TradeID:      t.ForeignTradeID,
+			Time:         t.Time,
+		}
+		am.conflicts[pairKey] = conflict
+		am.newConflicts = append(am.newConflicts, conflict)
+		log.Warnf("asset map conflict: %s on %s is in group %s, but %s on %s is in group %s.",
+			t.QuoteToken.Address, t.QuoteToken.Blockchain, quoteGroupID, t.BaseToken.Address, t.BaseToken.Blockchain, baseGroupID)
+		return nil
+
+	case quoteGroupID != "":
+		err = am.store.InsertAssetMap(quoteGroupID, baseID)
+		if err != nil {
+			return err
+		}
+		am.addMapping(t, quoteGroupID, t.BaseToken, t.QuoteToken, false)
+
+	case baseGroupID != "":
+		err = am.store.InsertAssetMap(baseGroupID, quoteID)
+		if err != nil {
+			return err
+		}
+		am.addMapping(t, baseGroupID, t.QuoteToken, t.BaseToken, false)
+
+	default:
+		err = am.store.InsertNewAssetMap(quoteID)
+		if err != nil {
+			return err
+		}
+		groupID, err := am.groupID(quoteID)
+		if err != nil {
+			return err
+		}
+		if groupID == "" {
+			return errors.New("no group id generated for asset " + quoteID)
+		}
+		err = am.store.InsertAssetMap(groupID, baseID)
+		if err != nil {
+			return err
+		}
+		am.addMapping(t, groupID, t.QuoteToken, t.BaseToken, true)
+		am.addMapping(t, groupID, t.BaseToken, t.QuoteToken, false)
+	}
+	am.mapped[pairKey] = struct{}{}
+	return nil
+}
+
+// report logs all assets mapped and all conflicts found since the last report.
+func (am *assetMapper) report() {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	log.Infof("asset map report: %d bridge transfers processed, %d assets newly mapped, %d new conflicts, %d conflicts in total.",
+		am.numTrades, len(am.newMappings), len(am.newConflicts), len(am.conflicts))
+	for _, m := range am.newMappings {
+		var newGroup string
+		if m.NewGroup {
+			newGroup = " (new group)"
+		}
+		log.Infof("mapped %s %s on %s to group %s%s together with %s on %s. source: %s, transfer: %s.",
+			m.Asset.Symbol, m.Asset.Address, m.Asset.Blockchain, m.GroupID, newGroup, m.Counterpart.Address, m.Counterpart.Blockchain, m.Source, m.TradeID)
+	}
+	for _, c := range am.newConflicts {
+		log.Warnf("conflict: %s %s on %s in group %s vs. %s %s on %s in group %s. source: %s, transfer: %s.",
+			c.QuoteToken.Symbol, c.QuoteToken.Address, c.QuoteToken.Blockchain, c.QuoteGroupID,
+			c.BaseToken.Symbol, c.BaseToken.Address, c.BaseToken.Blockchain, c.BaseGroupID, c.Source, c.TradeID)
+	}
+	am.newMappings = nil
+	am.newConflicts = nil
+}
+
+func (am *assetMapper) addMapping(t *dia.Trade, groupID string, asset dia.Asset, counterpart dia.Asset, newGroup bool) {
+	am.newMappings = append(am.newMappings, assetMapping{
+		GroupID:     groupID,
+		Asset:       asset,
+		Counterpart: counterpart,
+		NewGroup:    newGroup,
+		Source:      t.Source,
+		TradeID:     t.ForeignTradeID,
+		Time:        t.Time,
+	})
+}
+
+// assetID returns the ID of @asset in the asset table. Assets not yet in the table are added.
+func (am *assetMapper) assetID(asset dia.Asset) (string, error) {
+	if asset.Address == "" || asset.Blockchain == "" {
+		return "", errors.New("incomplete asset")
+	}
+	id, err := am.store.GetAssetID(asset)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", err
+	}
+	err = am.store.SetAsset(asset)
+	if err != nil {
+		return "", err
+	}
+	return am.store.GetAssetID(asset)
+}
+
+// groupID returns the asset group of @assetID or an empty string if the asset is not mapped.
+func (am *assetMapper) groupID(assetID string) (string, error) {
+	groupID, err := am.store.GetAssetMap(assetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return groupID, err
+}
+
+// assetPairKey returns the same key regardless of the order of the asset IDs.
+func assetPairKey(assetID1 string, assetID2 string) string {
+	if assetID1 > assetID2 {
+		assetID1, assetID2 = assetID2, assetID1
+	}
+	return strings.Join([]string{assetID1, assetID2}, "-")
+}
@@ -1,39 +1,27 @@
 package main
 
 import (
+	"context"
 	"flag"
-	"fmt"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia/helpers/configCollectors"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/tradeDump"
 	scrapers "github.com/diadata-org/diadata/pkg/dia/scraper/exchange-scrapers"
 	"github.com/diadata-org/diadata/pkg/utils"
 
 	"github.com/diadata-org/diadata/pkg/dia"
 	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
 	models "github.com/diadata-org/diadata/pkg/model"
-	"github.com/segmentio/kafka-go"
 	"github.com/sirupsen/logrus"
 )
 
 var (
-	log                  *logrus.Logger
-	swapTradesOnExchange = []string{
-		dia.CurveFIExchange,
-		dia.CurveFIExchangeFantom,
-		dia.CurveFIExchangeMoonbeam,
-		dia.CurveFIExchangePolygon,
-		dia.PlatypusExchange,
-		dia.WanswapExchange,
-		dia.OmniDexExchange,
-		dia.DiffusionExchange,
-		dia.SolarbeamExchange,
-		dia.AnyswapExchange,
-		dia.HermesExchange,
-		dia.HuckleberryExchange,
-		dia.NetswapExchange,
-	}
+	log *logrus.Logger
 
 	exchange = flag.String("exchange", "", "which exchange")
 	// mode==current:		default mode. Trades are forwarded to TBS and FBS.
@@ -41,10 +29,22 @@ var (
 	// mode==estimation:	trades are forwarded to tradesEstimationService, i.e. same as storeTrades mode
 	//						but estimatedUSDPrice is filled by tradesEstimationService.
 	// mode==historical:	trades are sent through kafka to TBS in tradesHistorical topic.
-	// mode==assetmap:   	Bridged Trades, asstes are mapped and trades are not saved.
-	mode = flag.String("mode", "current", "either storeTrades, current, historical or estimation.")
+	// mode==assetmap:   	Bridged Trades, assets are mapped into asset groups and trades are not saved.
+	// mode==record:		trades are written to rotating trade dump files in recordDir. See package tradeDump.
+	//						As in current mode, reversed trades of exchanges in SwapTradesOnExchange are included.
+	mode = flag.String("mode", "current", "either storeTrades, current, historical, estimation, assetmap or record.")
 
 	pairsfile = flag.Bool("pairsfile", false, "read pairs from json file in config folder.")
+
+	// Flags for record mode.
+	recordDir       = flag.String("recordDir", "tradedumps", "directory trade dumps are written to in record mode.")
+	recordFormat    = flag.String("recordFormat", tradeDump.FormatJSONL, "format of trade dumps, either jsonl or parquet.")
+	recordCompress  = flag.Bool("recordCompress", true, "compress trade dumps.")
+	recordMaxTrades = flag.Int("recordMaxTrades", 1000000, "maximal number of trades per trade dump. 0 for no limit.")
+	recordMaxAge    = flag.Duration("recordMaxAge", time.Hour, "maximal time span of a trade dump. 0 for no limit.")
+
+	// Flags for assetmap mode.
+	assetmapReportInterval = flag.Duration("assetmapReportInterval", time.Hour, "interval for reporting newly mapped assets and conflicts.")
 )
 
 func init() {
@@ -74,7 +74,7 @@ func main() {
 		log.Errorln("NewDataStore:", err)
 	}
 
-	ds, err := models.NewDataStore()
+	ds, err := models.NewTimeSeriesDataStore()
 	if err != nil {
 		log.Fatal("datastore: ", err)
 	}
@@ -100,30 +100,68 @@ func main() {
 	}
 	es := scrapers.NewAPIScraper(*exchange, true, configApi.ApiKey, configApi.SecretKey, relDB)
 
-	// Set up kafka writers for various modes.
+	// Set up message bus and topics for various modes.
 	var (
-		w     *kafka.Writer
-		wTest *kafka.Writer
+		bus   kafkaHelper.MessageBus
+		topic int
 	)
 
 	switch *mode {
 	case "current":
-		w = kafkaHelper.NewWriter(kafkaHelper.TopicTrades)
-		wTest = kafkaHelper.NewWriter(kafkaHelper.TopicTradesTest)
+		topic = kafkaHelper.TopicTrades
 	case "historical":
-		w = kafkaHelper.NewWriter(kafkaHelper.TopicTradesHistorical)
+		topic = kafkaHelper.TopicTradesHistorical
 	case "estimation":
-		w = kafkaHelper.NewWriter(kafkaHelper.TopicTradesEstimation)
-	case "assetmap":
-		w = kafkaHelper.NewWriter(kafkaHelper.TopicTradesEstimation)
+		topic = kafkaHelper.TopicTradesEstimation
+	}
+
+	if *mode == "current" || *mode == "historical" || *mode == "estimation" {
+		var err error
+		bus, err = kafkaHelper.NewMessageBusWithOptions(true)
+		if err != nil {
+			log.Fatal("NewMessageBusWithOptions: ", err)
+		}
+		defer func() {
+			err := bus.Close()
+			if err != nil {
+				log.Error(err)
+			}
+		}()
 	}
 
-	defer func() {
-		err := w.Close()
+	// Set up trade dump writer for record mode.
+	var rw *tradeDump.RotatingWriter
+	if *mode == "record" {
+		rw, err = tradeDump.NewRotatingWriter(*recordDir, *exchange, *recordFormat, *recordCompress, *recordMaxTrades, *recordMaxAge)
 		if err != nil {
-			log.Error(err)
+			log.Fatal("trade dump writer: ", err)
+		}
+		// Complete the current dump on shutdown. Parquet files are unreadable without their footer.
+		go func() {
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+			log.Info("received signal: ", <-sigs)
+			if err := rw.Close(); err != nil {
+				log.Error("close trade dump: ", err)
+			}
+			os.Exit(0)
+		}()
+	}
+
+	// Set up asset mapper for assetmap mode.
+	var am *assetMapper
+	if *mode == "assetmap" {
+		if relDB == nil {
+			log.Fatal("assetmap mode requires postgres.")
 		}
-	}()
+		am = newAssetMapper(relDB)
+		go func() {
+			ticker := time.NewTicker(*assetmapReportInterval)
+			for range ticker.C {
+				am.report()
+			}
+		}()
+	}
 
 	wg := sync.WaitGroup{}
 
@@ -150,10 +188,10 @@ func main() {
 		defer wg.Wait()
 
 	}
-	go handleTrades(es.Channel(), &wg, w, wTest, ds, *exchange, *mode)
+	go handleTrades(es.Channel(), &wg, bus, topic, rw, am, ds, *exchange, *mode)
 }
 
-func handleTrades(c chan *dia.Trade, wg *sync.WaitGroup, w *kafka.Writer, wTest *kafka.Writer, ds *models.DB, exchange string, mode string) {
+func handleTrades(c chan *dia.Trade, wg *sync.WaitGroup, bus kafkaHelper.MessageBus, topic int, rw *tradeDump.RotatingWriter, am *assetMapper, ds models.TradeStore, exchange string, mode string) {
 	lastTradeTime := time.Now()
 	watchdogDelay := scrapers.Exchanges[exchange].WatchdogDelay
 	t := time.NewTicker(time.Duration(watchdogDelay) * time.Second)
@@ -167,25 +205,33 @@ func handleTrades(c chan *dia.Trade, wg *sync.WaitGroup, w *kafka.Writer, wTest
 			}
 		case t, ok := <-c:
 			if !ok {
+				if am != nil {
+					am.report()
+				}
+				if rw != nil {
+					if err := rw.Close(); err != nil {
+						log.Error("close trade dump: ", err)
+					}
+				}
 				wg.Done()
 				log.Error("handleTrades")
 				return
 			}
 			lastTradeTime = time.Now()
-			// Trades are sent to the tradesblockservice through a kafka channel - either
+			// Trades are sent to the tradesblockservice through the message bus - either
 			// through trades topic or historical trades topic.
 			if mode == "current" || mode == "historical" || mode == "estimation" {
 
-				// Write trade to productive Kafka.
-				err := writeTradeToKafka(w, t)
+				// Publish trade to productive topic.
+				err := publishTrade(bus, topic, t)
 				if err != nil {
 					log.Error(err)
 				}
 
 				if scrapers.Exchanges[t.Source].Centralized {
-					// Write CEX trades to test Kafka.
+					// Publish CEX trades to test topic.
 					if mode == "current" {
-						err = writeTradeToKafka(wTest, t)
+						err = publishTrade(bus, kafkaHelper.TopicTradesTest, t)
 						if err != nil {
 							log.Error(err)
 						}
@@ -203,35 +249,51 @@ func handleTrades(c chan *dia.Trade, wg *sync.WaitGroup, w *kafka.Writer, wTest
 				}
 			}
 
+			// Assets of bridge transfers are mapped into asset groups. Trades are not saved.
 			if mode == "assetmap" {
+				err := am.mapTrade(t)
+				if err != nil {
+					log.Errorf("map assets of transfer %s: %v", t.ForeignTradeID, err)
+				}
+			}
 
-				fmt.Println("recieved trade", t)
-
+			// Trades are written to trade dump files only. Reversed trades are recorded as well,
+			// so that a dump holds the same trades as the trades topic in current mode.
+			if mode == "record" {
+				for _, trade := range withSwappedTrade(t) {
+					err := rw.Write(trade)
+					if err != nil {
+						log.Error("record trade: ", err)
+					}
+				}
 			}
 		}
 	}
 }
 
-func writeTradeToKafka(w *kafka.Writer, t *dia.Trade) error {
-	// Write trade to Kafka.
-	err := kafkaHelper.WriteMessage(w, t)
-	if err != nil {
-		return err
+// publishTrade publishes @t on @topic of @bus.
+func publishTrade(bus kafkaHelper.MessageBus, topic int, t *dia.Trade) error {
+	for _, trade := range withSwappedTrade(t) {
+		err := bus.Publish(context.Background(), topic, trade)
+		if err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Write reversed trade to Kafka as well for some exchanges.
-	if utils.Contains(&swapTradesOnExchange, t.Source) {
+// withSwappedTrade returns @t followed by its reversed trade for exchanges in scrapers.SwapTradesOnExchange.
+func withSwappedTrade(t *dia.Trade) []*dia.Trade {
+	trades := []*dia.Trade{t}
+	if utils.Contains(&scrapers.SwapTradesOnExchange, t.Source) {
 		tSwapped, err := dia.SwapTrade(*t)
 		if err != nil {
 			log.Error("swap trade: ", err)
 		} else {
-			err = kafkaHelper.WriteMessage(w, &tSwapped)
-			if err != nil {
-				return err
-			}
+			trades = append(trades, &tSwapped)
 		}
 	}
-	return nil
+	return trades
 }
 
 func isValidExchange(estring string) bool {
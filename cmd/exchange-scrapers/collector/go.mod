@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/diadata-org/diadata v1.4.27
+	github.com/jackc/pgx/v4 v4.11.0
 	github.com/segmentio/kafka-go v0.4.35
 	github.com/sirupsen/logrus v1.8.1
 )
\ No newline at end of file
@@ -24,9 +24,9 @@ func main() {
 		panic(err)
 	}
 
-	datastore, err := models.NewDataStore()
+	datastore, err := models.NewTimeSeriesDataStore()
 	if err != nil {
-		log.Errorln("NewDataStore", err)
+		log.Errorln("NewTimeSeriesDataStore", err)
 	}
 
 	relStore, err := models.NewRelDataStore()
@@ -40,7 +40,7 @@ func main() {
 		log.Fatal("parse batch duration ", err)
 	}
 
-	diaSchema := graphql.MustParseSchema(ds, &resolver.DiaResolver{DS: *datastore, RelDB: *relStore, InfluxBatchSize: influxBatchSize}, graphql.UseFieldResolvers())
+	diaSchema := graphql.MustParseSchema(ds, &resolver.DiaResolver{DS: datastore, RelDB: *relStore, InfluxBatchSize: influxBatchSize}, graphql.UseFieldResolvers())
 
 	mux := http.NewServeMux()
 	urlFolderPrefix := utils.Getenv("URL_FOLDER_PREFIX", "/graphql")
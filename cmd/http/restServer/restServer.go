@@ -174,6 +174,17 @@ func main() {
 		auth.GET("/refresh_token", authMiddleware.RefreshHandler)
 	}
 
+	bus, err := kafkaHelper.NewMessageBus()
+	if err != nil {
+		log.Fatal("NewMessageBus: ", err)
+	}
+	defer func() {
+		if err := bus.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+	kafkaApi.InitApis(bus)
+
 	kafka := r.Group("/kafka")
 	{
 		kafka.GET("/tradesBlock", GetTradesBlock)
@@ -183,14 +194,18 @@ func main() {
 
 	memoryStore := persistence.NewInMemoryStore(time.Second)
 
-	store, err := models.NewDataStore()
+	store, err := models.NewTimeSeriesDataStore()
 	if err != nil {
-		log.Errorln("NewDataStore", err)
+		log.Fatal("NewTimeSeriesDataStore: ", err)
 	}
 	relStore, err := models.NewRelDataStore()
 	if err != nil {
 		log.Errorln("NewRelDataStore", err)
 	}
+	err = diaApi.LoadBlockchains(relStore)
+	if err != nil {
+		log.Fatal("get all chains: ", err)
+	}
 	diaApiEnv := &diaApi.Env{
 		DataStore: store,
 		RelDB:     *relStore,
@@ -302,6 +317,8 @@ func main() {
 		diaGroup.GET("/NFTVolume/:blockchain/:address", cache.CachePageAtomic(memoryStore, cachingTimeLong, diaApiEnv.GetNFTVolume))
 		diaGroup.GET("/assetmap/:blockchain/:address", cache.CachePageAtomic(memoryStore, cachingTimeLong, diaApiEnv.GetAssetMap))
 		diaGroup.GET("/assetUpdates/:blockchain/:address/:deviation/:frequencySeconds", cache.CachePageAtomic(memoryStore, cachingTimeShort, diaApiEnv.GetAssetUpdates))
+		diaGroup.GET("/exchangeExclusions/:blockchain/:address", cache.CachePageAtomic(memoryStore, cachingTimeShort, diaApiEnv.GetExchangeExclusions))
+		diaGroup.GET("/candles/:blockchain/:address", cache.CachePageAtomic(memoryStore, cachingTimeShort, diaApiEnv.GetCandles))
 
 		// Endpoints for Synthassets
 
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/diadata-org/diadata/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Migrates the measurement INFLUX_TABLE_ORIGIN from influx 1.x into the measurement INFLUX_TABLE_DESTINATION
+// of the bucket of influx 2.x. Points are copied in windows of length INFLUX_MIGRATION_STEP, starting at
+// TIME_INIT until TIME_FINAL (unix seconds). In test mode only the first window is copied.
+func main() {
+	readURL := utils.Getenv("INFLUX_READ_URL", "http://influxdb:8086")
+	writeURL := utils.Getenv("INFLUX_WRITE_URL", "http://influxdb2:8086")
+	dbOrigin := utils.Getenv("INFLUX_DB_ORIGIN", "dia")
+	tableOrigin := utils.Getenv("INFLUX_TABLE_ORIGIN", "trades")
+	tableDestination := utils.Getenv("INFLUX_TABLE_DESTINATION", tableOrigin)
+	testmode, err := strconv.ParseBool(utils.Getenv("INFLUX_MIGRATION_TESTMODE", "false"))
+	if err != nil {
+		log.Fatal("parse INFLUX_MIGRATION_TESTMODE: ", err)
+	}
+	step, err := time.ParseDuration(utils.Getenv("INFLUX_MIGRATION_STEP", "1h"))
+	if err != nil {
+		log.Fatal("parse INFLUX_MIGRATION_STEP: ", err)
+	}
+	timeInit, err := parseUnixTime(utils.Getenv("TIME_INIT", "0"))
+	if err != nil {
+		log.Fatal("parse TIME_INIT: ", err)
+	}
+	timeFinal, err := parseUnixTime(utils.Getenv("TIME_FINAL", strconv.FormatInt(time.Now().Unix(), 10)))
+	if err != nil {
+		log.Fatal("parse TIME_FINAL: ", err)
+	}
+
+	datastore, err := models.NewInfluxV2DataStore()
+	if err != nil {
+		log.Fatal("datastore: ", err)
+	}
+	datastore.DB.SetInfluxClient(readURL)
+	datastore.SetInfluxClient(writeURL)
+
+	var total int64
+	for starttime := timeInit; starttime.Before(timeFinal); starttime = starttime.Add(step) {
+		endtime := starttime.Add(step)
+		if endtime.After(timeFinal) {
+			endtime = timeFinal
+		}
+		numCopiedRows, err := datastore.MigrateInfluxMeasurements(dbOrigin, tableOrigin, tableDestination, starttime, endtime)
+		if err != nil {
+			log.Fatalf("migrate %s in (%v, %v]: %v", tableOrigin, starttime, endtime, err)
+		}
+		total += numCopiedRows
+		log.Infof("migrated %d rows of %s in (%v, %v], %d in total", numCopiedRows, tableOrigin, starttime, endtime, total)
+		if testmode {
+			break
+		}
+	}
+	log.Infof("migration of %s into %s done: %d rows", tableOrigin, tableDestination, total)
+}
+
+func parseUnixTime(s string) (time.Time, error) {
+	t, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(t, 0), nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	filters "github.com/diadata-org/diadata/internal/pkg/filtersBlockService"
+	"github.com/diadata-org/diadata/internal/pkg/tradesBlockService"
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/configCollectors"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
+	scrapers "github.com/diadata-org/diadata/pkg/dia/scraper/exchange-scrapers"
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/diadata-org/diadata/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// The pipeline runs the complete price discovery path in a single process:
+// scrapers -> tradesBlockService -> filtersBlockService.
+// The services are connected by an in-memory message bus using the same topics
+// as the kafka deployment.
+
+var (
+	log *logrus.Logger
+
+	exchanges = flag.String("exchanges", "", "comma separated list of exchanges.")
+	pairsfile = flag.Bool("pairsfile", false, "read pairs from json file in config folder.")
+	blockSize = flag.Int64("blockSize", dia.BlockSizeSeconds, "size of a tradesBlock in seconds.")
+	retention = flag.Int("retention", kafkaHelper.DefaultMemoryBusRetention, "number of messages kept per topic on the in-memory bus.")
+)
+
+func init() {
+	log = logrus.New()
+	flag.Parse()
+	if *exchanges == "" {
+		flag.Usage()
+		log.Fatal("no exchange given.")
+	}
+	for _, exchange := range strings.Split(*exchanges, ",") {
+		if _, ok := scrapers.Exchanges[exchange]; !ok {
+			log.Fatal("Invalid exchange string: ", exchange)
+		}
+	}
+}
+
+func main() {
+	relDB, err := models.NewRelDataStore()
+	if err != nil {
+		log.Fatal("NewRelDataStore: ", err)
+	}
+	ds, err := models.NewTimeSeriesDataStore()
+	if err != nil {
+		log.Fatal("NewTimeSeriesDataStore: ", err)
+	}
+
+	bus := kafkaHelper.NewMemoryBus(*retention)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sanity, err := tradesBlockService.NewSanityEngine(ds, relDB, *blockSize, false)
+	if err != nil {
+		log.Fatal("load sanity rules: ", err)
+	}
+	tbs := tradesBlockService.NewTradesBlockService(ds, *blockSize, false, sanity, tradesBlockService.NewPriceResolver(ds, relDB, false))
+	chanFiltersBlock := make(chan *dia.FiltersBlock)
+	filterConfigs, err := filters.LoadFilterConfigs(relDB)
+	if err != nil {
+		log.Fatal("load filter configurations: ", err)
+	}
+	filterSets, err := filters.NewFilterSets(filterConfigs)
+	if err != nil {
+		log.Fatal("filter configurations: ", err)
+	}
+	filterSets.SetLiquiditySource(filters.NewPoolLiquidity(relDB, nil))
+	aggregationConfigs, err := filters.LoadAggregationConfigs()
+	if err != nil {
+		log.Fatal("load aggregation configurations: ", err)
+	}
+	if len(aggregationConfigs) > 0 {
+		aggregator, err := filters.NewAggregator(aggregationConfigs, relDB)
+		if err != nil {
+			log.Fatal("aggregation configurations: ", err)
+		}
+		filterSets.SetAggregator(aggregator)
+	}
+	fbs := filters.NewFiltersBlockService(nil, ds, chanFiltersBlock, filterSets)
+
+	// Consumers stop reading from the bus as soon as @ctx is cancelled.
+	consumers := sync.WaitGroup{}
+	consumers.Add(2)
+	go consumeTrades(ctx, bus, tbs, &consumers)
+	go consumeTradesBlocks(ctx, bus, fbs, &consumers)
+
+	// Producers forward the services' output until @servicesDone is closed.
+	servicesDone := make(chan struct{})
+	go publishTradesBlocks(bus, tbs, servicesDone)
+	go publishFiltersBlocks(bus, chanFiltersBlock, servicesDone)
+
+	var apiScrapers []scrapers.APIScraper
+	for _, exchange := range strings.Split(*exchanges, ",") {
+		es, err := startScraper(exchange, relDB)
+		if err != nil {
+			log.Errorf("start scraper for %s: %v", exchange, err)
+			continue
+		}
+		apiScrapers = append(apiScrapers, es)
+		go publishTrades(ctx, bus, es)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	log.Infof("pipeline running for %s. received signal: %v", *exchanges, <-sigs)
+
+	// Shut down in the order of the data flow, so that no service blocks on a closed successor.
+	for _, es := range apiScrapers {
+		if err := es.Close(); err != nil {
+			log.Error("close scraper: ", err)
+		}
+	}
+	cancel()
+	consumers.Wait()
+	if err := tbs.Close(); err != nil {
+		log.Error("close tradesBlockService: ", err)
+	}
+	if err := fbs.Close(); err != nil {
+		log.Error("close filtersBlockService: ", err)
+	}
+	close(servicesDone)
+	if err := bus.Close(); err != nil {
+		log.Error("close message bus: ", err)
+	}
+	if err := ds.Flush(); err != nil {
+		log.Error("flush influx batch: ", err)
+	}
+	log.Info("pipeline shut down.")
+}
+
+// startScraper returns a running APIScraper for @exchange.
+func startScraper(exchange string, relDB *models.RelDB) (scrapers.APIScraper, error) {
+	configApi, err := dia.GetConfig(exchange)
+	if err != nil {
+		log.Warning("no config for exchange's api ", err)
+	}
+	es := scrapers.NewAPIScraper(exchange, true, configApi.ApiKey, configApi.SecretKey, relDB)
+
+	// Subscription to pool events is managed inside scraper for DEX and Bridge scrapers.
+	if !scrapers.Exchanges[exchange].Centralized {
+		return es, nil
+	}
+
+	var pairsExchange []dia.ExchangePair
+	if !*pairsfile {
+		pairsExchange, err = relDB.GetExchangePairSymbols(exchange)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		cc := configCollectors.NewConfigCollectors(exchange, ".json")
+		pairsExchange = cc.AllPairs()
+	}
+	for _, pair := range pairsExchange {
+		log.Println("Adding pair:", pair.Symbol, pair.ForeignName, "on exchange", exchange)
+		_, err := es.ScrapePair(dia.ExchangePair{
+			Symbol:      pair.Symbol,
+			ForeignName: pair.ForeignName,
+		})
+		if err != nil {
+			log.Error(err)
+		}
+	}
+	return es, nil
+}
+
+// publishTrades writes all trades from @es to the trades topic, including swapped trades
+// just as the collector does.
+func publishTrades(ctx context.Context, bus kafkaHelper.MessageBus, es scrapers.APIScraper) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case t, ok := <-es.Channel():
+			if !ok {
+				return
+			}
+			err := bus.Publish(ctx, kafkaHelper.TopicTrades, t)
+			if err != nil {
+				log.Error("publish trade: ", err)
+				continue
+			}
+			if utils.Contains(&scrapers.SwapTradesOnExchange, t.Source) {
+				tSwapped, err := dia.SwapTrade(*t)
+				if err != nil {
+					log.Error("swap trade: ", err)
+					continue
+				}
+				err = bus.Publish(ctx, kafkaHelper.TopicTrades, &tSwapped)
+				if err != nil {
+					log.Error("publish swapped trade: ", err)
+				}
+			}
+		}
+	}
+}
+
+func consumeTrades(ctx context.Context, bus kafkaHelper.MessageBus, tbs *tradesBlockService.TradesBlockService, wg *sync.WaitGroup) {
+	defer wg.Done()
+	sub, err := bus.Subscribe(kafkaHelper.TopicTrades, kafkaHelper.OffsetFirst)
+	if err != nil {
+		log.Error("subscribe to trades: ", err)
+		return
+	}
+	defer func() {
+		if err := sub.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+	for {
+		m, err := sub.ReadMessage(ctx)
+		if err != nil {
+			log.Info("stop consuming trades: ", err)
+			return
+		}
+		var t dia.Trade
+		if err := t.UnmarshalBinary(m.Value); err != nil {
+			log.Errorf("ignored message at offset %d: %v", m.Offset, err)
+			continue
+		}
+		tbs.ProcessTrade(&t)
+	}
+}
+
+func consumeTradesBlocks(ctx context.Context, bus kafkaHelper.MessageBus, fbs *filters.FiltersBlockService, wg *sync.WaitGroup) {
+	defer wg.Done()
+	sub, err := bus.Subscribe(kafkaHelper.TopicTradesBlock, kafkaHelper.OffsetFirst)
+	if err != nil {
+		log.Error("subscribe to tradesBlocks: ", err)
+		return
+	}
+	defer func() {
+		if err := sub.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+	for {
+		m, err := sub.ReadMessage(ctx)
+		if err != nil {
+			log.Info("stop consuming tradesBlocks: ", err)
+			return
+		}
+		var tb dia.TradesBlock
+		if err := tb.UnmarshalBinary(m.Value); err != nil {
+			log.Errorf("ignored message at offset %d: %v", m.Offset, err)
+			continue
+		}
+		log.Info("number of trades in received tradesblock: ", len(tb.TradesBlockData.Trades))
+		fbs.ProcessTradesBlock(&tb)
+	}
+}
+
+func publishTradesBlocks(bus kafkaHelper.MessageBus, tbs *tradesBlockService.TradesBlockService, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case tb := <-tbs.Channel():
+			err := bus.Publish(context.Background(), kafkaHelper.TopicTradesBlock, tb)
+			if err != nil {
+				log.Error("publish tradesBlock: ", err)
+			}
+		}
+	}
+}
+
+func publishFiltersBlocks(bus kafkaHelper.MessageBus, chanFiltersBlock chan *dia.FiltersBlock, done chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		case fb := <-chanFiltersBlock:
+			log.Infof("generated filtersBlock %s with %d filter points", fb.BlockHash, fb.FiltersBlockData.FiltersNumber)
+			err := bus.Publish(context.Background(), kafkaHelper.TopicFiltersBlock, fb)
+			if err != nil {
+				log.Error("publish filtersBlock: ", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"time"
+
+	candles "github.com/diadata-org/diadata/internal/pkg/candleService"
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
+	models "github.com/diadata-org/diadata/pkg/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// candleService builds OHLCV candles of all assets on each exchange and on all exchanges from the
+// tradesBlock stream and writes them to influx. On start, candles are backfilled from the trades
+// stored in influx since the latest saved candle. Closed candles are periodically rebuilt from the
+// stored trades, so that they include late trades.
+
+// offsetCommitted keeps the committed offset of the consumer group. A new group starts at the first retained offset.
+const offsetCommitted = kafkaHelper.OffsetFirst - 1
+
+var (
+	groupID        = flag.String("group", "candleService", "kafka consumer group whose committed offset is used for resuming")
+	resumeOffset   = flag.Int64("offset", offsetCommitted, "resume reading tradesBlocks from this offset instead of the group's committed offset. -1 for the last, -2 for the first retained offset")
+	resolutions    = flag.String("resolutions", strings.Join(dia.CandleResolutions, ","), "comma separated list of candle resolutions")
+	maxBackfill    = flag.Duration("maxBackfill", 24*time.Hour, "maximal time range backfilled from stored trades on start")
+	repairInterval = flag.Duration("repairInterval", time.Hour, "interval in which closed candles are rebuilt from stored trades. 0 disables repairs")
+	repairLag      = flag.Duration("repairLag", 10*time.Minute, "time after the end of a candle until it is rebuilt from stored trades")
+)
+
+func init() {
+	flag.Parse()
+}
+
+func main() {
+	s, err := models.NewTimeSeriesDataStore()
+	if err != nil {
+		log.Fatal("NewTimeSeriesDataStore: ", err)
+	}
+	candleResolutions := strings.Split(*resolutions, ",")
+	builder := candles.NewBuilder(s, candleResolutions)
+
+	bus, err := kafkaHelper.NewMessageBus()
+	if err != nil {
+		log.Fatal("NewMessageBus: ", err)
+	}
+	defer func() {
+		if err := bus.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+	if *resumeOffset != offsetCommitted {
+		err = bus.SetGroupOffset(kafkaHelper.TopicTradesBlock, *groupID, *resumeOffset)
+		if err != nil {
+			log.Fatal("set offset of consumer group: ", err)
+		}
+	}
+	r, err := bus.SubscribeGroup(kafkaHelper.TopicTradesBlock, *groupID)
+	if err != nil {
+		log.Fatal("subscribe to tradesBlock topic: ", err)
+	}
+	defer func() {
+		if err := r.Close(); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	backfilled := false
+	for {
+		m, err := r.ReadMessage(context.Background())
+		if err != nil {
+			log.Error("read tradesBlock: ", err)
+			continue
+		}
+		var tb dia.TradesBlock
+		err = tb.UnmarshalBinary(m.Value)
+		if err != nil {
+			log.Error("unmarshal tradesBlock: ", err)
+		} else {
+			// Candles before the first tradesBlock are built from stored trades.
+			if !backfilled {
+				backfill(s, builder, tb.TradesBlockData.BeginTime)
+				go repair(s, candleResolutions, tb.TradesBlockData.BeginTime)
+				backfilled = true
+			}
+			err = builder.ProcessTradesBlock(&tb)
+			if err != nil {
+				log.Fatalf("save candles of tradesBlock %s: %v", tb.BlockHash, err)
+			}
+		}
+		// Unreadable messages are committed as well, as they would fail again after a restart.
+		err = r.CommitMessages(context.Background(), m)
+		if err != nil {
+			log.Fatal("commit offset: ", err)
+		}
+	}
+}
+
+// backfill builds the candles until @endtime from stored trades, beginning with the latest saved candle.
+func backfill(s models.Datastore, builder *candles.Builder, endtime time.Time) {
+	starttime := endtime.Add(-*maxBackfill)
+	latest, err := s.GetLatestCandleTime(dia.CandleResolutions[0])
+	if err != nil {
+		log.Error("get latest candle: ", err)
+	} else if latest.After(starttime) {
+		starttime = latest
+	}
+	log.Infof("backfill candles in [%v,%v)", starttime, endtime)
+	err = builder.Backfill(starttime, endtime)
+	if err != nil {
+		log.Fatal("backfill candles: ", err)
+	}
+}
+
+// repair periodically rebuilds the candles closed since @starttime from stored trades.
+func repair(s models.Datastore, resolutions []string, starttime time.Time) {
+	if *repairInterval <= 0 {
+		return
+	}
+	for range time.Tick(*repairInterval) {
+		endtime := time.Now().Add(-*repairLag)
+		if !endtime.After(starttime) {
+			continue
+		}
+		log.Infof("repair candles ending in (%v,%v]", starttime, endtime)
+		err := candles.Repair(s, resolutions, starttime, endtime)
+		if err != nil {
+			log.Error("repair candles: ", err)
+			continue
+		}
+		starttime = endtime
+	}
+}
@@ -10,18 +10,22 @@ import (
 	"github.com/diadata-org/diadata/pkg/dia"
 	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
 	models "github.com/diadata-org/diadata/pkg/model"
-	"github.com/segmentio/kafka-go"
+	"github.com/diadata-org/diadata/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
 
+// offsetCommitted keeps the committed offset of the consumer group. A new group starts at the first retained offset.
+const offsetCommitted = kafkaHelper.OffsetFirst - 1
+
 var (
 	replayInflux          = flag.Bool("replayInflux", false, "replayInflux ?")
 	historical            = flag.Bool("historical", false, "digest historical or current trades")
 	testing               = flag.Bool("testing", false, "set true for testing environment")
+	groupID               = flag.String("group", "filtersBlockService", "kafka consumer group whose committed offset is used for resuming")
+	resumeOffset          = flag.Int64("offset", offsetCommitted, "resume reading tradesBlocks from this offset instead of the group's committed offset. -1 for the last, -2 for the first retained offset")
 	filtersBlockTopic     int
 	tradesBlockTopic      int
 	filtersblockDoneTopic int
-	fbsDoneWriter         *kafka.Writer
 )
 
 func init() {
@@ -49,21 +53,23 @@ func main() {
 		if err != nil {
 			log.Errorln("NewDataStore", err)
 		}
-		f := filters.NewFiltersBlockService(nil, s, nil)
+		f := filters.NewFiltersBlockService(nil, s, nil, loadFilterSets())
 		createTradeBlockFromInflux(s, f)
 	} else {
-		s, err := models.NewDataStore()
+		s, err := models.NewTimeSeriesDataStore()
 		if err != nil {
-			log.Errorln("NewDataStore", err)
+			log.Fatal("NewTimeSeriesDataStore: ", err)
 		}
 		channel := make(chan *dia.FiltersBlock)
 
-		f := filters.NewFiltersBlockService(loadFilterPointsFromPreviousBlock(), s, channel)
-
-		w := kafkaHelper.NewSyncWriterWithCompression(filtersBlockTopic)
+		f := filters.NewFiltersBlockService(loadFilterPointsFromPreviousBlock(), s, channel, loadFilterSets())
 
+		bus, err := kafkaHelper.NewMessageBus()
+		if err != nil {
+			log.Fatal("NewMessageBus: ", err)
+		}
 		defer func() {
-			err := w.Close()
+			err := bus.Close()
 			if err != nil {
 				log.Error(err)
 			}
@@ -71,9 +77,20 @@ func main() {
 
 		wg := sync.WaitGroup{}
 
-		go handler(channel, &wg, w)
+		go handler(channel, &wg, bus)
 
-		r := kafkaHelper.NewReaderNextMessage(tradesBlockTopic)
+		// The committed offset of the consumer group is only advanced once the filter points
+		// of a tradesBlock are flushed, so that a restart neither skips nor repeats blocks.
+		if *resumeOffset != offsetCommitted {
+			err = bus.SetGroupOffset(tradesBlockTopic, *groupID, *resumeOffset)
+			if err != nil {
+				log.Fatal("set offset of consumer group: ", err)
+			}
+		}
+		r, err := bus.SubscribeGroup(tradesBlockTopic, *groupID)
+		if err != nil {
+			log.Fatal("subscribe to tradesBlock topic: ", err)
+		}
 		defer func() {
 			err := r.Close()
 			if err != nil {
@@ -81,16 +98,6 @@ func main() {
 			}
 		}()
 
-		if *historical {
-			fbsDoneWriter = kafkaHelper.NewSyncWriter(filtersblockDoneTopic)
-			defer func() {
-				err := w.Close()
-				if err != nil {
-					log.Error(err)
-				}
-			}()
-		}
-
 		for {
 			m, err := r.ReadMessage(context.Background())
 			if err != nil {
@@ -103,25 +110,51 @@ func main() {
 					log.Error("error unmarshalling trades block")
 				}
 				if err == nil {
-					t0 := time.Now()
-					log.Info("number of trades in received tradesblock: ", len(tb.TradesBlockData.Trades))
-					f.ProcessTradesBlock(&tb)
-					log.Info("time spent by filtersblockservice for processing tradesblock: ", time.Since(t0))
+					processTradesBlock(s, f, &tb)
 					// In historical mode, send timestamp of last trade as soon as fbs is done.
 					if *historical {
 						lastTimestamp := tb.TradesBlockData.EndTime
-						err := kafkaHelper.WriteMessage(fbsDoneWriter, &lastTimestamp)
+						err := bus.Publish(context.Background(), filtersblockDoneTopic, &lastTimestamp)
 						if err != nil {
 							log.Error("kafka: fbs-done feedback: ", err)
 						}
 					}
 				}
+				// Unreadable messages are committed as well, as they would fail again after a restart.
+				err = r.CommitMessages(context.Background(), m)
+				if err != nil {
+					log.Fatal("commit offset: ", err)
+				}
 			}
 		}
 	}
 }
 
-func handler(channel chan *dia.FiltersBlock, wg *sync.WaitGroup, w *kafka.Writer) {
+// processTradesBlock computes the filters for @tb unless they were already written before a restart.
+// The service exits if filter points cannot be flushed, so that the tradesBlock is read again on restart.
+func processTradesBlock(s models.Datastore, f *filters.FiltersBlockService, tb *dia.TradesBlock) {
+	processed, err := s.IsTradesBlockProcessed(tb.BlockHash, tb.Revision)
+	if err != nil {
+		log.Error("check whether tradesBlock is processed: ", err)
+	}
+	if processed {
+		log.Infof("tradesBlock %s revision %d already processed. skip.", tb.BlockHash, tb.Revision)
+		return
+	}
+	t0 := time.Now()
+	log.Info("number of trades in received tradesblock: ", len(tb.TradesBlockData.Trades))
+	err = f.ProcessTradesBlockSync(tb)
+	if err != nil {
+		log.Fatalf("flush filter points of tradesBlock %s: %v", tb.BlockHash, err)
+	}
+	log.Info("time spent by filtersblockservice for processing tradesblock: ", time.Since(t0))
+	err = s.SetTradesBlockProcessed(tb.BlockHash, tb.Revision)
+	if err != nil {
+		log.Error("mark tradesBlock as processed: ", err)
+	}
+}
+
+func handler(channel chan *dia.FiltersBlock, wg *sync.WaitGroup, bus kafkaHelper.MessageBus) {
 	var block int
 	for {
 		filtersblock, ok := <-channel
@@ -132,13 +165,63 @@ func handler(channel chan *dia.FiltersBlock, wg *sync.WaitGroup, w *kafka.Writer
 		}
 		block++
 		log.Infoln("kafka: generated ", block, " blocks")
-		err := kafkaHelper.WriteMessage(w, filtersblock)
+		err := bus.Publish(context.Background(), filtersBlockTopic, filtersblock)
 		if err != nil {
 			log.Errorln("kafka: handleBlocks", err)
 		}
 	}
 }
 
+// loadFilterSets returns the filters to compute for each asset as configured by FILTER_CONFIG_SOURCE.
+// Postgres is only connected if it holds the configurations, liquidity weighted filters need pools
+// or exclusions from aggregated prices are recorded.
+func loadFilterSets() *filters.FilterSets {
+	var relDB *models.RelDB
+	connectRelDB := func() {
+		if relDB != nil {
+			return
+		}
+		rdb, err := models.NewRelDataStore()
+		if err != nil {
+			log.Fatal("NewRelDataStore: ", err)
+		}
+		relDB = rdb
+	}
+	var configDB models.RelDatastore
+	if utils.Getenv("FILTER_CONFIG_SOURCE", filters.ConfigSourceDefault) == filters.ConfigSourcePostgres {
+		connectRelDB()
+		configDB = relDB
+	}
+	filterConfigs, err := filters.LoadFilterConfigs(configDB)
+	if err != nil {
+		log.Fatal("load filter configurations: ", err)
+	}
+	filterSets, err := filters.NewFilterSets(filterConfigs)
+	if err != nil {
+		log.Fatal("filter configurations: ", err)
+	}
+	for _, c := range filterConfigs {
+		if c.Filter == "LWVWAP" {
+			connectRelDB()
+			filterSets.SetLiquiditySource(filters.NewPoolLiquidity(relDB, nil))
+			break
+		}
+	}
+	aggregationConfigs, err := filters.LoadAggregationConfigs()
+	if err != nil {
+		log.Fatal("load aggregation configurations: ", err)
+	}
+	if len(aggregationConfigs) > 0 {
+		connectRelDB()
+		aggregator, err := filters.NewAggregator(aggregationConfigs, relDB)
+		if err != nil {
+			log.Fatal("aggregation configurations: ", err)
+		}
+		filterSets.SetAggregator(aggregator)
+	}
+	return filterSets
+}
+
 func loadFilterPointsFromPreviousBlock() []dia.FilterPoint {
 	// load the previous block points so that we have a value even if
 	// there is no trades
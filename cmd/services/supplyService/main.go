@@ -16,7 +16,7 @@ const (
 
 func main() {
 
-	ds, err := models.NewDataStore()
+	ds, err := models.NewTimeSeriesDataStore()
 	if err != nil {
 		log.Fatal("datastore error: ", err)
 	}
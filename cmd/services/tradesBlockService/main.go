@@ -9,11 +9,10 @@ import (
 	"github.com/diadata-org/diadata/pkg/dia"
 	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
 	models "github.com/diadata-org/diadata/pkg/model"
-	"github.com/segmentio/kafka-go"
 	log "github.com/sirupsen/logrus"
 )
 
-func handleBlocks(blockMaker *tradesBlockService.TradesBlockService, wg *sync.WaitGroup, w *kafka.Writer) {
+func handleBlocks(blockMaker *tradesBlockService.TradesBlockService, wg *sync.WaitGroup, bus kafkaHelper.MessageBus) {
 	for {
 		t, ok := <-blockMaker.Channel()
 		if !ok {
@@ -21,7 +20,7 @@ func handleBlocks(blockMaker *tradesBlockService.TradesBlockService, wg *sync.Wa
 			wg.Done()
 			return
 		}
-		err := kafkaHelper.WriteMessage(w, t)
+		err := bus.Publish(context.Background(), tradesBlockTopic, t)
 		if err != nil {
 			log.Errorln("handleBlocks", err)
 		}
@@ -56,15 +55,21 @@ func main() {
 		log.Info("run tradesblock service in historical mode")
 	}
 
-	kafkaWriter := kafkaHelper.NewSyncWriterWithCompression(tradesBlockTopic)
+	bus, err := kafkaHelper.NewMessageBus()
+	if err != nil {
+		log.Fatal("NewMessageBus: ", err)
+	}
 	defer func() {
-		err := kafkaWriter.Close()
+		err := bus.Close()
 		if err != nil {
 			log.Error(err)
 		}
 	}()
 
-	kafkaReader := kafkaHelper.NewReaderNextMessage(tradesTopic)
+	kafkaReader, err := bus.Subscribe(tradesTopic, kafkaHelper.OffsetLast)
+	if err != nil {
+		log.Fatal("subscribe to trades topic: ", err)
+	}
 	defer func() {
 		err := kafkaReader.Close()
 		if err != nil {
@@ -72,15 +77,30 @@ func main() {
 		}
 	}()
 
-	s, err := models.NewDataStore()
+	s, err := models.NewTimeSeriesDataStore()
+	if err != nil {
+		log.Errorln("NewTimeSeriesDataStore", err)
+	}
+
+	// Rejected trades are reported in postgres, so that they can be inspected in the feed stats.
+	var relDB models.RelDatastore
+	rdb, err := models.NewRelDataStore()
 	if err != nil {
-		log.Errorln("NewDataStore", err)
+		log.Errorln("NewRelDataStore", err)
+	} else {
+		relDB = rdb
 	}
+	sanity, err := tradesBlockService.NewSanityEngine(s, relDB, dia.BlockSizeSeconds, *historical)
+	if err != nil {
+		log.Fatal("load sanity rules: ", err)
+	}
+
+	resolver := tradesBlockService.NewPriceResolver(s, relDB, *historical)
 
-	service := tradesBlockService.NewTradesBlockService(s, dia.BlockSizeSeconds, *historical)
+	service := tradesBlockService.NewTradesBlockService(s, dia.BlockSizeSeconds, *historical, sanity, resolver)
 
 	wg := sync.WaitGroup{}
-	go handleBlocks(service, &wg, kafkaWriter)
+	go handleBlocks(service, &wg, bus)
 
 	log.Printf("starting...")
 
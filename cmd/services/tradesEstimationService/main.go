@@ -3,6 +3,8 @@ package main
 import (
 	"context"
 
+	"github.com/diadata-org/diadata/internal/pkg/priceResolver"
+	"github.com/diadata-org/diadata/internal/pkg/sanityRules"
 	"github.com/diadata-org/diadata/internal/pkg/tradesEstimationService"
 	"github.com/diadata-org/diadata/pkg/dia"
 	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
@@ -20,12 +22,29 @@ func main() {
 		}
 	}()
 
-	s, err := models.NewDataStore()
+	s, err := models.NewTimeSeriesDataStore()
 	if err != nil {
-		log.Errorln("NewDataStore", err)
+		log.Errorln("NewTimeSeriesDataStore", err)
 	}
 
-	service := tradesEstimationService.NewTradesEstimationService(s)
+	var relDB models.RelDatastore
+	var store sanityRules.RejectionStore
+	rdb, err := models.NewRelDataStore()
+	if err != nil {
+		log.Errorln("NewRelDataStore", err)
+	} else {
+		relDB = rdb
+		store = rdb
+	}
+	rules, err := sanityRules.LoadRules(relDB, tradesEstimationService.DefaultSanityRules())
+	if err != nil {
+		log.Fatal("load sanity rules: ", err)
+	}
+	sanity := sanityRules.NewEngine(rules, dia.BlockSizeSeconds, sanityRules.HistoricalPrice(s), store)
+
+	resolver := priceResolver.NewResolver(priceResolver.HistoricalQuotation(s), relDB, priceResolver.DefaultBridges(), priceResolver.MaxHops, priceResolver.Staleness)
+
+	service := tradesEstimationService.NewTradesEstimationService(s, sanity, resolver)
 
 	log.Printf("starting...")
 
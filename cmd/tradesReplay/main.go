@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	filters "github.com/diadata-org/diadata/internal/pkg/filtersBlockService"
+	"github.com/diadata-org/diadata/internal/pkg/priceResolver"
+	"github.com/diadata-org/diadata/internal/pkg/sanityRules"
+	"github.com/diadata-org/diadata/internal/pkg/tradesBlockService"
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/tradeDump"
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/sirupsen/logrus"
+)
+
+// tradesReplay rebuilds tradesBlocks from recorded trades using the block-boundary logic of the
+// tradesBlockService, runs them through the filtersBlockService and writes the resulting filter
+// values as JSONL. Each value is compared with the value stored in influx for the same filter,
+// asset, exchange and timestamp, so that price incidents can be audited.
+//
+// Trades are read either from a trade dump (-file) or from influx (-from, -to).
+// Trades from a dump are replayed in the order of the file, which is the order in which the
+// collector received them. Trades from influx are replayed in chronological order.
+//
+// Trades from a dump carry no USD price. Their base tokens are priced as done by the tradesBlockService,
+// i.e. by a price resolver looking up historical quotations in influx. Trades from influx keep the
+// USD price estimated when they were saved.
+
+var (
+	log *logrus.Logger
+
+	file        = flag.String("file", "", "trade dump (.jsonl, .jsonl.gz or .parquet). Trades are read from influx if empty.")
+	table       = flag.String("table", "trades", "influx measurement trades are read from.")
+	exchange    = flag.String("exchange", "", "only replay trades from this exchange.")
+	from        = flag.String("from", "", "begin of the time range in RFC3339.")
+	to          = flag.String("to", "", "end of the time range in RFC3339.")
+	blockSize   = flag.Int64("blockSize", dia.BlockSizeSeconds, "size of a tradesBlock in seconds.")
+	graceBlocks = flag.Int("graceBlocks", tradesBlockService.GraceBlocks, "number of finished tradesBlocks amended by late trades. Defaults to LATE_TRADE_GRACE_BLOCKS.")
+	dedup       = flag.Duration("dedup", 10*time.Minute, "time window for dropping duplicate trades as done by the tradesBlockService. 0 disables deduplication, e.g. for periods before it was introduced.")
+	rules       = flag.String("sanityRules", "", "json file in the config folder holding the sanity rules for trades. Default rules of the tradesBlockService if empty.")
+	filterCfg   = flag.String("filterConfig", "", "json file in the config folder holding the filter configurations. Default filters of the filtersBlockService if empty.")
+	diff        = flag.Bool("diff", true, "compare replayed filter values with the ones stored in influx.")
+	filterSet   = flag.String("filters", "", "comma separated list of filter names to output, e.g. MAIR120,MEDIR120. All if empty.")
+	assetGroups = flag.Bool("assetGroups", false, "price base tokens of dump trades via asset groups from postgres, as done by the tradesBlockService.")
+	out         = flag.String("out", "", "output file. Stdout if empty.")
+)
+
+func init() {
+	log = logrus.New()
+	log.Out = os.Stderr
+}
+
+func main() {
+	flag.Parse()
+	timeInit, timeFinal := parseTimeRange()
+
+	// Influx is required for trades, quotations of base tokens and stored filter values.
+	influxDS, err := models.NewInfluxDataStore()
+	if err != nil {
+		log.Fatal("NewInfluxDataStore: ", err)
+	}
+	var ds models.Datastore = influxDS
+
+	trades, err := loadTrades(ds, timeInit, timeFinal)
+	if err != nil {
+		log.Fatal("load trades: ", err)
+	}
+	log.Infof("replaying %d trades.", len(trades))
+
+	sanity, err := newSanityEngine(ds)
+	if err != nil {
+		log.Fatal("load sanity rules: ", err)
+	}
+
+	var filterSets *filters.FilterSets
+	if *filterCfg != "" {
+		filterConfigs, err := filters.ReadFilterConfigsFromConfig(*filterCfg)
+		if err != nil {
+			log.Fatal("read filter configurations: ", err)
+		}
+		filterSets, err = filters.NewFilterSets(filterConfigs)
+		if err != nil {
+			log.Fatal("filter configurations: ", err)
+		}
+	}
+
+	var pricer *tradesBlockService.USDPricer
+	if *file != "" {
+		pricer = newPricer(ds)
+	}
+
+	points := replay(trades, sanity, pricer, filterSets)
+	log.Infof("replay resulted in %d filter points.", len(points))
+
+	if *diff {
+		compareWithStored(ds, points)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		w, err = os.Create(*out)
+		if err != nil {
+			log.Fatal("create output file: ", err)
+		}
+	}
+	err = writePoints(w, points)
+	if err != nil {
+		log.Fatal("write filter points: ", err)
+	}
+	if *out != "" {
+		err = w.Close()
+		if err != nil {
+			log.Error("close output file: ", err)
+		}
+	}
+}
+
+func parseTimeRange() (timeInit time.Time, timeFinal time.Time) {
+	var err error
+	timeInit = time.Unix(0, 0)
+	timeFinal = time.Now()
+	if *from != "" {
+		timeInit, err = time.Parse(time.RFC3339, *from)
+		if err != nil {
+			log.Fatal("parse from: ", err)
+		}
+	}
+	if *to != "" {
+		timeFinal, err = time.Parse(time.RFC3339, *to)
+		if err != nil {
+			log.Fatal("parse to: ", err)
+		}
+	}
+	if *file == "" && *from == "" {
+		log.Fatal("from is required when replaying trades from influx.")
+	}
+	return
+}
+
+// loadTrades returns all trades in [@timeInit, @timeFinal) from the trade dump or from influx.
+func loadTrades(ds models.Datastore, timeInit time.Time, timeFinal time.Time) ([]dia.Trade, error) {
+	var trades []dia.Trade
+	if *file != "" {
+		dumpTrades, err := tradeDump.ReadAll(*file)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range dumpTrades {
+			if t.Time.Before(timeInit) || !t.Time.Before(timeFinal) {
+				continue
+			}
+			if *exchange != "" && t.Source != *exchange {
+				continue
+			}
+			trades = append(trades, t)
+		}
+		return trades, nil
+	}
+
+	// Query influx in hourly batches in order to keep responses small.
+	for starttime := timeInit; starttime.Before(timeFinal); starttime = starttime.Add(time.Hour) {
+		endtime := starttime.Add(time.Hour)
+		if endtime.After(timeFinal) {
+			endtime = timeFinal
+		}
+		batch, err := ds.GetOldTradesFromInflux(*table, *exchange, true, starttime, endtime)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, batch...)
+	}
+	return trades, nil
+}
+
+// newSanityEngine returns the rules engine trades are checked with. Rules depending on quotations
+// are only checked if quotations can be read from influx.
+func newSanityEngine(ds models.Datastore) (*sanityRules.Engine, error) {
+	sanityRuleSet := tradesBlockService.DefaultSanityRules()
+	if *rules != "" {
+		var err error
+		sanityRuleSet, err = sanityRules.ReadRulesFromConfig(*rules)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var price sanityRules.PriceFunc
+	if ds != nil {
+		price = sanityRules.HistoricalPrice(ds)
+	}
+	return sanityRules.NewEngine(sanityRuleSet, *blockSize, price, nil), nil
+}
+
+// newPricer returns the USD pricer for trades from a dump. Base tokens are priced by their historical
+// quotations in @ds and, if enabled, by asset groups from postgres.
+func newPricer(ds models.QuotationStore) *tradesBlockService.USDPricer {
+	var assetMap priceResolver.AssetMapSource
+	if *assetGroups {
+		relDB, err := models.NewRelDataStore()
+		if err != nil {
+			log.Fatal("NewRelDataStore: ", err)
+		}
+		assetMap = relDB
+	}
+	resolver := tradesBlockService.NewPriceResolver(ds, assetMap, true)
+	return tradesBlockService.NewUSDPricer(resolver)
+}
+
+// replay feeds @trades through the tradesBlock and filtersBlock logic and returns
+// the filter values the filtersBlockService would have stored. If @pricer is not nil,
+// the USD price of each trade is estimated by @pricer before the trade is checked.
+func replay(trades []dia.Trade, sanity *sanityRules.Engine, pricer *tradesBlockService.USDPricer, filterSets *filters.FilterSets) []replayPoint {
+	store := newRecordingStore()
+	// The filtersBlockService has to keep as many blocks for recomputation as are amended.
+	filters.GraceBlocks = *graceBlocks
+	fbs := filters.NewFiltersBlockService(nil, store, nil, filterSets)
+	bb := tradesBlockService.NewBlockBuilder(*blockSize, *graceBlocks)
+
+	var dd *tradesBlockService.Deduplicator
+	if *dedup > 0 {
+		dd = tradesBlockService.NewDeduplicator(*dedup, 0)
+	}
+
+	var numBlocks, numAmended, numLate int
+	for _, t := range trades {
+		if dd != nil && dd.Check(t) != tradesBlockService.DedupUnique {
+			continue
+		}
+		if pricer != nil {
+			pricer.EstimateUSDPrice(&t)
+		}
+		if !tradesBlockService.IsBlockTrade(t, sanity) {
+			continue
+		}
+		if bb.IsLate(t) {
+			if !bb.Amend(t) {
+				numLate++
+			}
+			continue
+		}
+		if finished, _ := bb.Add(t); finished != nil {
+			for _, amended := range bb.TakeAmended() {
+				fbs.ProcessTradesBlock(amended)
+				numAmended++
+			}
+			fbs.ProcessTradesBlock(finished)
+			numBlocks++
+			if pricer != nil {
+				pricer.ResetCache()
+			}
+		}
+	}
+	// In production, a block is only finalised once a trade of a later block arrives.
+	// The last block is processed nonetheless in order to cover the whole time range.
+	for _, amended := range bb.TakeAmended() {
+		fbs.ProcessTradesBlock(amended)
+		numAmended++
+	}
+	if b := bb.Flush(); b != nil {
+		fbs.ProcessTradesBlock(b)
+		numBlocks++
+	}
+	// Close returns after the last tradesBlock is processed.
+	err := fbs.Close()
+	if err != nil {
+		log.Error("close filtersBlockService: ", err)
+	}
+	log.Infof("processed %d tradesBlocks and %d amended tradesBlocks. %d late trades ignored.", numBlocks, numAmended, numLate)
+	if dd != nil {
+		for exchange, counts := range dd.Counts() {
+			log.Infof("dropped trades on %s: %d duplicates, %d mirrored.", exchange, counts.Duplicates, counts.Mirrored)
+		}
+	}
+
+	var names map[string]bool
+	if *filterSet != "" {
+		names = make(map[string]bool)
+		for _, name := range strings.Split(*filterSet, ",") {
+			names[name] = true
+		}
+	}
+	var points []replayPoint
+	for _, p := range store.points {
+		if names == nil || names[p.Filter] {
+			points = append(points, *p)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].less(points[j])
+	})
+	return points
+}
+
+// compareWithStored sets the stored value and the difference to it for all @points.
+func compareWithStored(ds models.Datastore, points []replayPoint) {
+	type seriesKey struct {
+		filter     string
+		address    string
+		blockchain string
+		exchange   string
+	}
+	series := make(map[seriesKey][]int)
+	for i, p := range points {
+		key := seriesKey{p.Filter, p.Asset.Address, p.Asset.Blockchain, p.Exchange}
+		series[key] = append(series[key], i)
+	}
+
+	var matched, mismatched, missing int
+	for key, indices := range series {
+		starttime := points[indices[0]].Time.Add(-time.Nanosecond)
+		endtime := points[indices[len(indices)-1]].Time
+		stored, err := storedValues(ds, key.filter, key.exchange, key.address, key.blockchain, starttime, endtime)
+		if err != nil {
+			log.Errorf("get stored values for %s on %s-%s: %v", key.filter, key.blockchain, key.address, err)
+			continue
+		}
+		for _, i := range indices {
+			value, ok := stored[points[i].Time.UnixNano()]
+			if !ok {
+				missing++
+				continue
+			}
+			difference := points[i].Value - value
+			points[i].Stored = &value
+			points[i].Diff = &difference
+			if difference == 0 {
+				matched++
+			} else {
+				mismatched++
+			}
+		}
+	}
+	log.Infof("compared with stored values: %d identical, %d different, %d not stored.", matched, mismatched, missing)
+}
+
+// storedValues returns the values of a filter series in influx mapped by their timestamp in nanoseconds.
+func storedValues(ds models.Datastore, filter, exchange, address, blockchain string, starttime, endtime time.Time) (map[int64]float64, error) {
+	values := make(map[int64]float64)
+	points, err := ds.GetFilterPointsAsset(filter, exchange, address, blockchain, starttime, endtime, false)
+	if err != nil {
+		return values, err
+	}
+	if len(points.DataPoints) == 0 || len(points.DataPoints[0].Series) == 0 {
+		return values, nil
+	}
+	// Columns: time,address,blockchain,exchange,filter,symbol,value
+	for _, row := range points.DataPoints[0].Series[0].Values {
+		timestamp, err := time.Parse(time.RFC3339, row[0].(string))
+		if err != nil {
+			return values, err
+		}
+		value, err := row[6].(json.Number).Float64()
+		if err != nil {
+			return values, err
+		}
+		values[timestamp.UnixNano()] = value
+	}
+	return values, nil
+}
+
+func writePoints(w io.Writer, points []replayPoint) error {
+	bw := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bw)
+	for _, p := range points {
+		if err := encoder.Encode(p); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// replayPoint is a filter value as saved by a filter of the filtersBlockService.
+type replayPoint struct {
+	Filter   string
+	Asset    dia.Asset
+	Exchange string
+	Time     time.Time
+	Value    float64
+	Stored   *float64                `json:",omitempty"`
+	Diff     *float64                `json:",omitempty"`
+	Quality  *dia.FilterPointQuality `json:",omitempty"`
+}
+
+func (p replayPoint) less(q replayPoint) bool {
+	if !p.Time.Equal(q.Time) {
+		return p.Time.Before(q.Time)
+	}
+	if p.Filter != q.Filter {
+		return p.Filter < q.Filter
+	}
+	if p.Asset.Blockchain != q.Asset.Blockchain {
+		return p.Asset.Blockchain < q.Asset.Blockchain
+	}
+	if p.Asset.Address != q.Asset.Address {
+		return p.Asset.Address < q.Asset.Address
+	}
+	return p.Exchange < q.Exchange
+}
+
+type pointKey struct {
+	filter     string
+	address    string
+	blockchain string
+	exchange   string
+	time       int64
+}
+
+// recordingStore records all filter values the filtersBlockService saves instead of writing them.
+// Just as in influx, a value saved twice for the same series and time overwrites the first one.
+// It is safe for concurrent use, as the filtersBlockService saves the filters of different assets concurrently.
+type recordingStore struct {
+	mu     sync.Mutex
+	points map[pointKey]*replayPoint
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{points: make(map[pointKey]*replayPoint)}
+}
+
+func (rs *recordingStore) SetFilter(filterName string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.points[pointKey{filterName, asset.Address, asset.Blockchain, exchange, t.UnixNano()}] = &replayPoint{
+		Filter:   filterName,
+		Asset:    asset,
+		Exchange: exchange,
+		Time:     t,
+		Value:    value,
+	}
+	return nil
+}
+
+// SetFilterQuality attaches @quality to the value saved for the same point, just as influx merges both.
+func (rs *recordingStore) SetFilterQuality(filterName string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if point, ok := rs.points[pointKey{filterName, asset.Address, asset.Blockchain, exchange, t.UnixNano()}]; ok {
+		point.Quality = &quality
+	}
+	return nil
+}
+
+func (rs *recordingStore) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	for key := range rs.points {
+		if key.address == asset.Address && key.blockchain == asset.Blockchain && key.exchange == exchange &&
+			key.time >= starttime.UnixNano() && key.time <= endtime.UnixNano() {
+			delete(rs.points, key)
+		}
+	}
+	return nil
+}
+
+func (rs *recordingStore) SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error {
+	return nil
+}
+
+func (rs *recordingStore) SetLastTradeTimeForExchange(asset dia.Asset, exchange string, t time.Time) error {
+	return nil
+}
+
+func (rs *recordingStore) ExecuteRedisPipe() error {
+	return nil
+}
+
+func (rs *recordingStore) FlushRedisPipe() error {
+	return nil
+}
+
+func (rs *recordingStore) Flush() error {
+	return nil
+}
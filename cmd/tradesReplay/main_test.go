@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/internal/pkg/priceResolver"
+	"github.com/diadata-org/diadata/internal/pkg/tradesBlockService"
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/tradeDump"
+	models "github.com/diadata-org/diadata/pkg/model"
+)
+
+var (
+	testETH  = dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	testUSDT = dia.Asset{Symbol: "USDT", Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Blockchain: dia.ETHEREUM}
+	testXYZ  = dia.Asset{Symbol: "XYZ", Address: "0x1111111111111111111111111111111111111111", Blockchain: dia.ETHEREUM}
+)
+
+// testQuotation quotes USDT at 1 USD. There are no quotations for other assets.
+func testQuotation(asset dia.Asset, timestamp time.Time) (*models.AssetQuotation, error) {
+	if asset.Address == testUSDT.Address {
+		return &models.AssetQuotation{Asset: asset, Price: 1, Time: timestamp}, nil
+	}
+	return nil, errors.New("no quotation")
+}
+
+// writeDump records ETH-USDT trades at 2000 and ETH-XYZ trades at 1 over @duration the way the collector
+// does in record mode, i.e. without USD price, and returns the path of the dump.
+func writeDump(t *testing.T, duration time.Duration) string {
+	dir := t.TempDir()
+	rw, err := tradeDump.NewRotatingWriter(dir, dia.BinanceExchange, tradeDump.FormatJSONL, true, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t0 := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; time.Duration(i)*10*time.Second < duration; i++ {
+		for _, base := range []dia.Asset{testUSDT, testXYZ} {
+			price := 2000.0
+			if base == testXYZ {
+				price = 1
+			}
+			err := rw.Write(&dia.Trade{
+				Symbol:         "ETH",
+				Pair:           "ETH-" + base.Symbol,
+				QuoteToken:     testETH,
+				BaseToken:      base,
+				Price:          price,
+				Volume:         10,
+				Time:           t0.Add(time.Duration(i) * 10 * time.Second),
+				ForeignTradeID: base.Symbol + strconv.Itoa(i),
+				Source:         dia.BinanceExchange,
+				VerifiedPair:   true,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("expected one trade dump, got %v: %v", paths, err)
+	}
+	return paths[0]
+}
+
+func TestReplayDump(t *testing.T) {
+	*file = writeDump(t, 10*time.Minute)
+	defer func() { *file = "" }()
+
+	trades, err := loadTrades(nil, time.Unix(0, 0), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, trade := range trades {
+		if trade.EstimatedUSDPrice != 0 {
+			t.Fatalf("recorded trade carries USD price %v", trade.EstimatedUSDPrice)
+		}
+	}
+	sanity, err := newSanityEngine(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := priceResolver.NewResolver(testQuotation, nil, nil, 0, 0)
+	points := replay(trades, sanity, tradesBlockService.NewUSDPricer(resolver), nil)
+
+	var numMAIR int
+	for _, p := range points {
+		if p.Filter != "MAIR120" || p.Asset.Address != testETH.Address {
+			continue
+		}
+		numMAIR++
+		// ETH-XYZ trades can not be priced and must not enter the filters.
+		if p.Value != 2000 {
+			t.Errorf("MAIR120 at %v: got %v, want 2000", p.Time, p.Value)
+		}
+	}
+	// Each of the five blocks results in a value for all exchanges and for Binance.
+	if numMAIR < 5 {
+		t.Errorf("got %d MAIR120 points, want at least 5", numMAIR)
+	}
+}
@@ -249,6 +249,59 @@ CREATE TABLE tradesdistribution (
     compute_time timestamp
 );
 
+CREATE TABLE filterconfig (
+    filterconfig_id UUID DEFAULT gen_random_uuid(),
+    exchange text DEFAULT '',
+    blockchain text DEFAULT '',
+    address text DEFAULT '',
+    filter text NOT NULL,
+    window_size integer NOT NULL,
+    outlier_detector text DEFAULT '',
+    outlier_threshold numeric DEFAULT 0,
+    UNIQUE(filterconfig_id),
+    UNIQUE(exchange,blockchain,address,filter,window_size)
+);
+
+-- empty selectors (exchange, blockchain, address, symbol) match all trades, zero bounds are not checked.
+CREATE TABLE sanityrule (
+    sanityrule_id UUID DEFAULT gen_random_uuid(),
+    exchange text DEFAULT '',
+    blockchain text DEFAULT '',
+    address text DEFAULT '',
+    symbol text DEFAULT '',
+    min_volume numeric DEFAULT 0,
+    max_deviation numeric DEFAULT 0,
+    peg text DEFAULT '',
+    peg_tolerance numeric DEFAULT 0,
+    max_price_jump numeric DEFAULT 0,
+    UNIQUE(sanityrule_id),
+    UNIQUE(exchange,blockchain,address,symbol)
+);
+
+CREATE TABLE traderejection (
+    traderejection_id UUID DEFAULT gen_random_uuid(),
+    asset_id UUID REFERENCES asset(asset_id),
+    exchange text,
+    reason text,
+    -- number of rejected trades in [compute_time-time_range_seconds, compute_time]
+    num_trades numeric,
+    time_range_seconds numeric NOT NULL,
+    compute_time timestamp
+);
+
+CREATE TABLE exchangeexclusion (
+    exchangeexclusion_id UUID DEFAULT gen_random_uuid(),
+    asset_id UUID REFERENCES asset(asset_id),
+    exchange text,
+    -- filter whose value deviated from the median of all exchanges by deviation
+    filter text,
+    filter_value numeric,
+    median numeric,
+    deviation numeric,
+    begin_time timestamp,
+    end_time timestamp
+);
+
 CREATE TABLE synthassetdata (
     synthassetdata_id UUID DEFAULT gen_random_uuid(),
     synthasset_id UUID REFERENCES asset(asset_id),
@@ -0,0 +1,86 @@
+-- Time series of the postgres backend of the datastore, selected by TIMESERIES_BACKEND=postgres.
+-- It is applied after pginit.sql and requires the timescaledb extension.
+CREATE EXTENSION IF NOT EXISTS timescaledb;
+
+CREATE TABLE trades (
+    time timestamptz NOT NULL,
+    symbol text NOT NULL DEFAULT '',
+    pair text NOT NULL DEFAULT '',
+    exchange text NOT NULL DEFAULT '',
+    verified boolean NOT NULL DEFAULT false,
+    quotetoken_address text NOT NULL DEFAULT '',
+    quotetoken_blockchain text NOT NULL DEFAULT '',
+    basetoken_address text NOT NULL DEFAULT '',
+    basetoken_blockchain text NOT NULL DEFAULT '',
+    price double precision,
+    volume double precision,
+    estimated_usd_price double precision,
+    foreign_trade_id text NOT NULL DEFAULT ''
+);
+SELECT create_hypertable('trades', 'time', chunk_time_interval => INTERVAL '1 day');
+CREATE INDEX ON trades (quotetoken_address, quotetoken_blockchain, time DESC);
+CREATE INDEX ON trades (exchange, time DESC);
+
+-- tradesBlockService writes its trades to the table given by INFLUX_MEASUREMENT_WRITE.
+CREATE TABLE tradestmp (LIKE trades INCLUDING DEFAULTS INCLUDING INDEXES);
+SELECT create_hypertable('tradestmp', 'time', chunk_time_interval => INTERVAL '1 day');
+
+CREATE TABLE filters (
+    time timestamptz NOT NULL,
+    filter text NOT NULL,
+    symbol text NOT NULL DEFAULT '',
+    address text NOT NULL DEFAULT '',
+    blockchain text NOT NULL DEFAULT '',
+    -- empty for filters across all exchanges
+    exchange text NOT NULL DEFAULT '',
+    value double precision,
+    -- quality of the filter point, set by SetFilterQuality
+    num_trades bigint,
+    num_exchanges bigint,
+    volume_usd double precision,
+    std_dev double precision,
+    iqr double precision,
+    outlier_fraction double precision,
+    seconds_since_last_trade double precision,
+    UNIQUE (filter, address, blockchain, exchange, time)
+);
+SELECT create_hypertable('filters', 'time', chunk_time_interval => INTERVAL '1 day');
+
+-- Hourly sums of the volume filter, from which volumes over longer time ranges are computed.
+-- Buckets which are not materialized yet are aggregated from filters on read.
+CREATE MATERIALIZED VIEW volumes_hourly
+WITH (timescaledb.continuous, timescaledb.materialized_only = false) AS
+SELECT time_bucket(INTERVAL '1 hour', time) AS bucket,
+    address,
+    blockchain,
+    exchange,
+    sum(value) AS volume
+FROM filters
+WHERE filter = 'VOL120'
+GROUP BY bucket, address, blockchain, exchange
+WITH NO DATA;
+SELECT add_continuous_aggregate_policy('volumes_hourly',
+    start_offset => INTERVAL '3 days',
+    end_offset => INTERVAL '1 hour',
+    schedule_interval => INTERVAL '30 minutes');
+
+CREATE TABLE assetquotations (
+    time timestamptz NOT NULL,
+    symbol text NOT NULL DEFAULT '',
+    name text NOT NULL DEFAULT '',
+    address text NOT NULL,
+    blockchain text NOT NULL,
+    price double precision,
+    UNIQUE (address, blockchain, time)
+);
+SELECT create_hypertable('assetquotations', 'time', chunk_time_interval => INTERVAL '7 days');
+
+CREATE TABLE dexpools (
+    time timestamptz NOT NULL,
+    exchange text NOT NULL DEFAULT '',
+    blockchain text NOT NULL DEFAULT '',
+    address text NOT NULL,
+    volumes jsonb
+);
+SELECT create_hypertable('dexpools', 'time', chunk_time_interval => INTERVAL '7 days');
+CREATE INDEX ON dexpools (address, time DESC);
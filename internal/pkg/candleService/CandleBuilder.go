@@ -0,0 +1,242 @@
+package candles
+
+import (
+	"sort"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/sirupsen/logrus"
+)
+
+// tradesTable is the influx measurement trades are backfilled from.
+const tradesTable = "trades"
+
+var log = logrus.New()
+
+type candleKey struct {
+	blockchain string
+	address    string
+	exchange   string
+	resolution string
+	time       int64
+}
+
+// openCandle is a candle which may still receive trades.
+type openCandle struct {
+	candle     dia.Candle
+	end        time.Time
+	firstTrade time.Time
+	lastTrade  time.Time
+	modified   bool
+}
+
+// add adds @trade to the candle. Trades may arrive out of order, so open and close are
+// taken from the earliest and latest trade respectively.
+func (oc *openCandle) add(trade dia.Trade) {
+	price := trade.EstimatedUSDPrice
+	c := &oc.candle
+	if c.NumTrades == 0 {
+		c.Open, c.High, c.Low, c.Close = price, price, price, price
+		oc.firstTrade, oc.lastTrade = trade.Time, trade.Time
+	}
+	if trade.Time.Before(oc.firstTrade) {
+		c.Open = price
+		oc.firstTrade = trade.Time
+	}
+	if !trade.Time.Before(oc.lastTrade) {
+		c.Close = price
+		oc.lastTrade = trade.Time
+	}
+	if price > c.High {
+		c.High = price
+	}
+	if price < c.Low {
+		c.Low = price
+	}
+	volume := trade.Volume
+	if volume < 0 {
+		volume = -volume
+	}
+	c.Volume += volume
+	c.VolumeUSD += volume * price
+	c.NumTrades++
+	oc.modified = true
+}
+
+// Datastore is the part of models.Datastore used for building candles from trades. Trades are read
+// through models.TradeStore and candles written through models.CandleStore and models.BatchStore.
+type Datastore interface {
+	GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) ([]dia.Trade, error)
+	SaveCandleInflux(candle dia.Candle) error
+	Flush() error
+}
+
+// Builder builds the candles of all traded assets on each exchange and on all exchanges.
+// Candles are saved whenever they receive trades, so that the current candle can be queried as well.
+type Builder struct {
+	datastore   Datastore
+	resolutions []string
+	durations   []time.Duration
+	candles     map[candleKey]*openCandle
+	// closedUntil is the time until which candles are closed. Later trades for closed candles
+	// are ignored and only included when candles are rebuilt from stored trades.
+	closedUntil time.Time
+}
+
+// NewBuilder returns a Builder of candles with @resolutions, which are saved to @datastore.
+// Unknown resolutions are ignored.
+func NewBuilder(datastore Datastore, resolutions []string) *Builder {
+	b := &Builder{
+		datastore: datastore,
+		candles:   make(map[candleKey]*openCandle),
+	}
+	for _, resolution := range resolutions {
+		d, ok := dia.CandleResolution(resolution)
+		if !ok {
+			log.Errorf("unknown candle resolution %s", resolution)
+			continue
+		}
+		b.resolutions = append(b.resolutions, resolution)
+		b.durations = append(b.durations, d)
+	}
+	return b
+}
+
+// ProcessTradesBlock adds the trades of @tb to the candles and saves the modified candles.
+// Candles ending until the end of @tb are closed. Amended revisions of tradesBlocks are ignored,
+// as their trades were already added. Their late trades are included by Repair.
+func (b *Builder) ProcessTradesBlock(tb *dia.TradesBlock) error {
+	if tb.Revision > 0 {
+		log.Infof("ignore revision %d of tradesBlock %s", tb.Revision, tb.BlockHash)
+		return nil
+	}
+	b.addTrades(tb.TradesBlockData.Trades)
+	return b.flush(tb.TradesBlockData.EndTime, true)
+}
+
+// Backfill builds the candles from the trades stored in influx in [@starttime,@endtime). @starttime is
+// rounded down to the longest resolution, so that candles are complete. Candles ending until @endtime are
+// saved, later ones are kept open for the following tradesBlocks.
+func (b *Builder) Backfill(starttime time.Time, endtime time.Time) error {
+	if len(b.durations) == 0 {
+		return nil
+	}
+	starttime = starttime.Truncate(b.durations[len(b.durations)-1])
+	if b.closedUntil.After(starttime) {
+		starttime = b.closedUntil
+	}
+	b.closedUntil = starttime
+
+	// Query influx in hourly batches in order to keep responses small.
+	for batchStart := starttime; batchStart.Before(endtime); batchStart = batchStart.Add(time.Hour) {
+		batchEnd := batchStart.Add(time.Hour)
+		if batchEnd.After(endtime) {
+			batchEnd = endtime
+		}
+		trades, err := b.datastore.GetOldTradesFromInflux(tradesTable, "", true, batchStart, batchEnd)
+		if err != nil {
+			// An empty range is returned as error as well.
+			log.Warnf("backfill trades in [%v,%v): %v", batchStart, batchEnd, err)
+		}
+		var verified []dia.Trade
+		for _, trade := range trades {
+			if !trade.VerifiedPair {
+				continue
+			}
+			if trade.QuoteToken.Symbol == "" {
+				trade.QuoteToken.Symbol = trade.Symbol
+			}
+			verified = append(verified, trade)
+		}
+		b.addTrades(verified)
+		if err := b.flush(batchEnd, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Repair rebuilds the candles with @resolutions ending in (@starttime,@endtime] from the trades stored
+// in @datastore, so that they include late trades and trades missed while the service was down.
+func Repair(datastore Datastore, resolutions []string, starttime time.Time, endtime time.Time) error {
+	var ended []string
+	from := starttime
+	for _, resolution := range resolutions {
+		d, ok := dia.CandleResolution(resolution)
+		if !ok || !endtime.Truncate(d).After(starttime) {
+			continue
+		}
+		ended = append(ended, resolution)
+		if start := starttime.Truncate(d); start.Before(from) {
+			from = start
+		}
+	}
+	if len(ended) == 0 {
+		return nil
+	}
+	// Candles of the repairing Builder which are still open are not saved.
+	return NewBuilder(datastore, ended).Backfill(from, endtime)
+}
+
+// addTrades adds @trades to the open candles in ascending order of time.
+func (b *Builder) addTrades(trades []dia.Trade) {
+	sorted := append([]dia.Trade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	for _, trade := range sorted {
+		if trade.EstimatedUSDPrice <= 0 {
+			continue
+		}
+		for i, resolution := range b.resolutions {
+			start := trade.Time.Truncate(b.durations[i])
+			end := start.Add(b.durations[i])
+			if !end.After(b.closedUntil) {
+				continue
+			}
+			for _, exchange := range []string{trade.Source, ""} {
+				key := candleKey{
+					blockchain: trade.QuoteToken.Blockchain,
+					address:    trade.QuoteToken.Address,
+					exchange:   exchange,
+					resolution: resolution,
+					time:       start.UnixNano(),
+				}
+				oc, ok := b.candles[key]
+				if !ok {
+					oc = &openCandle{
+						candle: dia.Candle{Asset: trade.QuoteToken, Exchange: exchange, Resolution: resolution, Time: start},
+						end:    end,
+					}
+					b.candles[key] = oc
+				}
+				oc.add(trade)
+			}
+		}
+	}
+}
+
+// flush saves the modified candles and closes the candles ending until @until.
+// If @saveOpen is false, only closed candles are saved.
+func (b *Builder) flush(until time.Time, saveOpen bool) error {
+	var saved int
+	for key, oc := range b.candles {
+		closed := !oc.end.After(until)
+		if oc.modified && (closed || saveOpen) {
+			if err := b.datastore.SaveCandleInflux(oc.candle); err != nil {
+				return err
+			}
+			oc.modified = false
+			saved++
+		}
+		if closed {
+			delete(b.candles, key)
+		}
+	}
+	if until.After(b.closedUntil) {
+		b.closedUntil = until
+	}
+	if saved == 0 {
+		return nil
+	}
+	log.Infof("saved %d candles until %v", saved, until)
+	return b.datastore.Flush()
+}
@@ -0,0 +1,154 @@
+package candles
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+type savedCandle struct {
+	exchange   string
+	resolution string
+	time       time.Time
+}
+
+// candleRecorder keeps the saved candles instead of writing them and serves stored trades from memory.
+type candleRecorder struct {
+	candles map[savedCandle]dia.Candle
+	trades  []dia.Trade
+}
+
+func newCandleRecorder(trades []dia.Trade) *candleRecorder {
+	return &candleRecorder{candles: make(map[savedCandle]dia.Candle), trades: trades}
+}
+
+func (cr *candleRecorder) SaveCandleInflux(candle dia.Candle) error {
+	cr.candles[savedCandle{candle.Exchange, candle.Resolution, candle.Time}] = candle
+	return nil
+}
+
+func (cr *candleRecorder) Flush() error {
+	return nil
+}
+
+func (cr *candleRecorder) GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) (trades []dia.Trade, err error) {
+	for _, trade := range cr.trades {
+		if !trade.Time.Before(timeInit) && trade.Time.Before(timeFinal) {
+			trades = append(trades, trade)
+		}
+	}
+	if len(trades) == 0 {
+		return nil, errors.New("no trades in time range")
+	}
+	return
+}
+
+var (
+	testAsset = dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	testBegin = time.Unix(1654041600, 0)
+)
+
+func testTrade(exchange string, price float64, volume float64, seconds int) dia.Trade {
+	return dia.Trade{
+		QuoteToken:        testAsset,
+		Source:            exchange,
+		EstimatedUSDPrice: price,
+		Volume:            volume,
+		Time:              testBegin.Add(time.Duration(seconds) * time.Second),
+		VerifiedPair:      true,
+	}
+}
+
+func tradesBlock(begin int, end int, trades ...dia.Trade) *dia.TradesBlock {
+	return &dia.TradesBlock{TradesBlockData: dia.TradesBlockData{
+		BeginTime: testBegin.Add(time.Duration(begin) * time.Second),
+		EndTime:   testBegin.Add(time.Duration(end) * time.Second),
+		Trades:    trades,
+	}}
+}
+
+func TestBuilderTradesBlocks(t *testing.T) {
+	recorder := newCandleRecorder(nil)
+	b := NewBuilder(recorder, []string{"1m", "5m"})
+
+	// Trades within a block may be out of order.
+	err := b.ProcessTradesBlock(tradesBlock(0, 120,
+		testTrade(dia.BinanceExchange, 102, 1, 30),
+		testTrade(dia.BinanceExchange, 100, -2, 10),
+		testTrade(dia.CoinBaseExchange, 98, 1, 50),
+		testTrade(dia.BinanceExchange, 105, 1, 70),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := recorder.candles[savedCandle{"", "1m", testBegin}]
+	expected := dia.Candle{Asset: testAsset, Resolution: "1m", Time: testBegin, Open: 100, High: 102, Low: 98, Close: 98, Volume: 4, VolumeUSD: 200 + 102 + 98, NumTrades: 3}
+	if all != expected {
+		t.Errorf("got candle %v, expected %v", all, expected)
+	}
+	if binance := recorder.candles[savedCandle{dia.BinanceExchange, "1m", testBegin}]; binance.Close != 102 || binance.NumTrades != 2 {
+		t.Errorf("unexpected candle on Binance %v", binance)
+	}
+
+	// The open 5m candle is saved with the trades so far and updated by the next block.
+	if open := recorder.candles[savedCandle{"", "5m", testBegin}]; open.NumTrades != 4 || open.Close != 105 {
+		t.Errorf("unexpected open candle %v", open)
+	}
+	if err := b.ProcessTradesBlock(tradesBlock(120, 240, testTrade(dia.KrakenExchange, 90, 1, 130))); err != nil {
+		t.Fatal(err)
+	}
+	if candle := recorder.candles[savedCandle{"", "5m", testBegin}]; candle.NumTrades != 5 || candle.Close != 90 || candle.Low != 90 {
+		t.Errorf("unexpected updated candle %v", candle)
+	}
+
+	// Late trades for closed candles are left to Repair.
+	if err := b.ProcessTradesBlock(tradesBlock(240, 360, testTrade(dia.KrakenExchange, 1000, 1, 20))); err != nil {
+		t.Fatal(err)
+	}
+	if candle := recorder.candles[savedCandle{"", "1m", testBegin}]; candle != expected {
+		t.Errorf("closed candle modified by late trade: %v", candle)
+	}
+}
+
+func TestBackfillAndRepair(t *testing.T) {
+	trades := []dia.Trade{
+		testTrade(dia.BinanceExchange, 100, 1, 10),
+		testTrade(dia.BinanceExchange, 110, 1, 70),
+		testTrade(dia.CoinBaseExchange, 90, 1, 3700),
+	}
+	unverified := testTrade(dia.BinanceExchange, 1, 1, 20)
+	unverified.VerifiedPair = false
+	recorder := newCandleRecorder(append(trades, unverified))
+
+	// Candles ending after the backfill are kept open for the following tradesBlocks.
+	b := NewBuilder(recorder, []string{"1m", "1h"})
+	if err := b.Backfill(testBegin.Add(30*time.Minute), testBegin.Add(3720*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if candle := recorder.candles[savedCandle{"", "1h", testBegin}]; candle.NumTrades != 2 || candle.Open != 100 || candle.Close != 110 {
+		t.Errorf("unexpected backfilled candle %v", candle)
+	}
+	if _, ok := recorder.candles[savedCandle{"", "1h", testBegin.Add(time.Hour)}]; ok {
+		t.Error("open candle saved by backfill")
+	}
+	if err := b.ProcessTradesBlock(tradesBlock(3720, 3840, testTrade(dia.CoinBaseExchange, 95, 1, 3800))); err != nil {
+		t.Fatal(err)
+	}
+	if candle := recorder.candles[savedCandle{"", "1h", testBegin.Add(time.Hour)}]; candle.NumTrades != 2 || candle.Open != 90 || candle.Close != 95 {
+		t.Errorf("unexpected continued candle %v", candle)
+	}
+
+	// A late trade stored in influx is included when the candle is repaired.
+	recorder.trades = append(recorder.trades, testTrade(dia.KrakenExchange, 120, 1, 80))
+	if err := Repair(recorder, []string{"1m", "1h"}, testBegin.Add(30*time.Minute), testBegin.Add(3720*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if candle := recorder.candles[savedCandle{"", "1h", testBegin}]; candle.NumTrades != 3 || candle.High != 120 || candle.Close != 120 {
+		t.Errorf("unexpected repaired candle %v", candle)
+	}
+	if candle := recorder.candles[savedCandle{"", "1h", testBegin.Add(time.Hour)}]; candle.NumTrades != 2 {
+		t.Errorf("open candle overwritten by repair: %v", candle)
+	}
+}
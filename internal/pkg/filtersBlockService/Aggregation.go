@@ -0,0 +1,457 @@
+package filters
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/configCollectors"
+	"github.com/diadata-org/diadata/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Weightings of exchanges selectable in aggregation configurations.
+	WeightingEqual       = "EQUAL"
+	WeightingVolume      = "VOLUME"
+	WeightingReliability = "RELIABILITY"
+	WeightingManual      = "MANUAL"
+
+	defaultMinExchanges     = 3
+	defaultExclusionSeconds = 3600
+	// reliabilityWindow is the time-range in which the share of time an exchange was not excluded
+	// from an asset's aggregated price is its reliability score.
+	reliabilityWindow = 7 * 24 * time.Hour
+)
+
+// aggregatedFilterTypes are the filter types whose values are prices, which can be aggregated across exchanges.
+// Filters such as VOL and COUNT are still computed from all trades.
+var aggregatedFilterTypes = map[string]bool{
+	"MA":     true,
+	"MAIR":   true,
+	"MEDIR":  true,
+	"EMA":    true,
+	"VWAP":   true,
+	"VWAPIR": true,
+	"TWAP":   true,
+	"LWVWAP": true,
+}
+
+// LoadAggregationConfigs returns the aggregation configurations from the json file AGGREGATION_CONFIG_FILE
+// in the config folder. Without file, filters across all exchanges are computed from all trades.
+func LoadAggregationConfigs() ([]dia.AggregationConfig, error) {
+	filename := utils.Getenv("AGGREGATION_CONFIG_FILE", "")
+	if filename == "" {
+		return nil, nil
+	}
+	return ReadAggregationConfigsFromConfig(filename)
+}
+
+// ReadAggregationConfigsFromConfig returns the aggregation configurations from the json file @filename in the config folder.
+func ReadAggregationConfigsFromConfig(filename string) ([]dia.AggregationConfig, error) {
+	content, err := configCollectors.ReadJSONFromConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Aggregations []dia.AggregationConfig `json:"Aggregations"`
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.Aggregations, nil
+}
+
+// ExclusionLog records the exclusions of exchanges from aggregated prices. It is implemented by models.RelDB.
+type ExclusionLog interface {
+	SetExchangeExclusion(exclusion dia.ExchangeExclusion) error
+}
+
+type aggregationKey struct {
+	identifier string
+	filterName string
+}
+
+type exchangeAsset struct {
+	identifier string
+	exchange   string
+}
+
+type exchangeValue struct {
+	value     float64
+	lastTrade time.Time
+}
+
+type volumeSample struct {
+	time   time.Time
+	volume float64
+}
+
+type exclusionPeriod struct {
+	begin time.Time
+	end   time.Time
+}
+
+// Aggregator computes the prices of assets across all exchanges from the values of the same filter on
+// single exchanges, weighted as given by the asset's configuration. Exchanges deviating too far from the
+// median of all exchanges are excluded from the asset's price for a while.
+type Aggregator struct {
+	configs      []dia.AggregationConfig
+	exclusionLog ExclusionLog
+
+	mu         sync.Mutex
+	values     map[aggregationKey]map[string]exchangeValue
+	volumes    map[exchangeAsset][]volumeSample
+	exclusions map[exchangeAsset][]exclusionPeriod
+	// memory is the largest window of aggregated filters, for which volumes are kept.
+	memory time.Duration
+}
+
+// NewAggregator returns an Aggregator for @configs which records exclusions in @exclusionLog, if not nil.
+// It fails if a configuration has an unknown weighting or negative bounds.
+func NewAggregator(configs []dia.AggregationConfig, exclusionLog ExclusionLog) (*Aggregator, error) {
+	for _, c := range configs {
+		switch c.Weighting {
+		case "", WeightingEqual, WeightingVolume, WeightingReliability:
+		case WeightingManual:
+			if len(c.Weights) == 0 {
+				return nil, fmt.Errorf("weighting %s needs weights of exchanges", c.Weighting)
+			}
+		default:
+			return nil, fmt.Errorf("unknown weighting %s", c.Weighting)
+		}
+		if c.MaxDeviation < 0 || c.ExclusionSeconds < 0 || c.MinExchanges < 0 {
+			return nil, fmt.Errorf("aggregation for %s-%s needs non-negative bounds", c.Blockchain, c.Address)
+		}
+	}
+	return &Aggregator{
+		configs:      configs,
+		exclusionLog: exclusionLog,
+		values:       make(map[aggregationKey]map[string]exchangeValue),
+		volumes:      make(map[exchangeAsset][]volumeSample),
+		exclusions:   make(map[exchangeAsset][]exclusionPeriod),
+	}, nil
+}
+
+// config returns the most specific configuration matching @asset. @ok is false if none matches.
+func (agg *Aggregator) config(asset dia.Asset) (config dia.AggregationConfig, ok bool) {
+	for _, c := range agg.configs {
+		if (c.Blockchain != "" && c.Blockchain != asset.Blockchain) || (c.Address != "" && c.Address != asset.Address) {
+			continue
+		}
+		specific := c.Blockchain != "" || c.Address != ""
+		if !ok || (specific && config.Blockchain == "" && config.Address == "") {
+			config, ok = c, true
+		}
+	}
+	return
+}
+
+// wrap returns @filter configured by @c such that it is aggregated, or contributes to the aggregation
+// if @exchange is not empty. Other filters are returned unchanged.
+func (agg *Aggregator) wrap(filter Filter, c dia.FilterConfig, asset dia.Asset, exchange string) Filter {
+	if !aggregatedFilterTypes[c.Filter] {
+		return filter
+	}
+	if _, ok := agg.config(asset); !ok {
+		return filter
+	}
+	agg.mu.Lock()
+	if window := time.Duration(c.Window) * time.Second; window > agg.memory {
+		agg.memory = window
+	}
+	agg.mu.Unlock()
+	if exchange != "" {
+		return &exchangeValueFilter{Filter: filter, aggregator: agg, filterName: filterName(c), asset: asset, exchange: exchange}
+	}
+	return &aggregatedFilter{Filter: filter, aggregator: agg, filterName: filterName(c), asset: asset, window: c.Window}
+}
+
+// addTrade records the volume of @trade for the volume share of its exchange.
+func (agg *Aggregator) addTrade(trade dia.Trade) {
+	if _, ok := agg.config(trade.QuoteToken); !ok {
+		return
+	}
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	ea := exchangeAsset{identifier: getIdentifier(trade.QuoteToken), exchange: trade.Source}
+	samples := append(agg.volumes[ea], volumeSample{time: trade.Time, volume: math.Abs(trade.Volume) * trade.EstimatedUSDPrice})
+	windowStart := trade.Time.Add(-agg.memory)
+	first := 0
+	for first < len(samples)-1 && !samples[first].time.After(windowStart) {
+		first++
+	}
+	agg.volumes[ea] = samples[first:]
+}
+
+// rewind forgets the volumes and exclusions from @begin onwards, so that the tradesBlocks
+// beginning at @begin can be processed again.
+func (agg *Aggregator) rewind(begin time.Time) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	for ea, samples := range agg.volumes {
+		last := len(samples)
+		for last > 0 && !samples[last-1].time.Before(begin) {
+			last--
+		}
+		agg.volumes[ea] = samples[:last]
+	}
+	for ea, periods := range agg.exclusions {
+		last := len(periods)
+		for last > 0 && periods[last-1].begin.After(begin) {
+			last--
+		}
+		agg.exclusions[ea] = periods[:last]
+	}
+}
+
+// setValue records the value of the filter @filterName for @asset on @exchange, whose last trade was at @lastTrade.
+func (agg *Aggregator) setValue(asset dia.Asset, exchange string, filterName string, value float64, lastTrade time.Time) {
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+	key := aggregationKey{identifier: getIdentifier(asset), filterName: filterName}
+	if agg.values[key] == nil {
+		agg.values[key] = make(map[string]exchangeValue)
+	}
+	agg.values[key][exchange] = exchangeValue{value: value, lastTrade: lastTrade}
+}
+
+// aggregate returns the weighted mean at @t of the values of the filter @filterName for @asset on all exchanges
+// traded within the last @window seconds. @ok is false if no exchange contributes to the mean.
+func (agg *Aggregator) aggregate(asset dia.Asset, filterName string, window int, t time.Time) (value float64, ok bool) {
+	config, ok := agg.config(asset)
+	if !ok {
+		return
+	}
+	agg.mu.Lock()
+	defer agg.mu.Unlock()
+
+	key := aggregationKey{identifier: getIdentifier(asset), filterName: filterName}
+	windowStart := t.Add(-time.Duration(window) * time.Second)
+	var exchanges []string
+	for exchange, v := range agg.values[key] {
+		ea := exchangeAsset{identifier: key.identifier, exchange: exchange}
+		if v.value > 0 && v.lastTrade.After(windowStart) && !agg.excluded(ea, t) {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+	// Sorting makes the result independent of the order of the map.
+	sort.Strings(exchanges)
+
+	minExchanges := config.MinExchanges
+	if minExchanges == 0 {
+		minExchanges = defaultMinExchanges
+	}
+	if config.MaxDeviation > 0 && len(exchanges) >= minExchanges {
+		values := make([]float64, len(exchanges))
+		for i, exchange := range exchanges {
+			values[i] = agg.values[key][exchange].value
+		}
+		median := computeMedian(values)
+		var kept []string
+		for _, exchange := range exchanges {
+			v := agg.values[key][exchange].value
+			if deviation := math.Abs(v-median) / median; deviation > config.MaxDeviation {
+				agg.exclude(asset, exchange, filterName, v, median, deviation, t, config)
+				continue
+			}
+			kept = append(kept, exchange)
+		}
+		exchanges = kept
+	}
+
+	var prices, weights []float64
+	for _, exchange := range exchanges {
+		ea := exchangeAsset{identifier: key.identifier, exchange: exchange}
+		var weight float64
+		switch config.Weighting {
+		case WeightingVolume:
+			weight = agg.volume(ea, windowStart, t)
+		case WeightingReliability:
+			weight = agg.reliability(ea, t)
+		case WeightingManual:
+			weight = config.Weights[exchange]
+		default:
+			weight = 1
+		}
+		if weight > 0 {
+			prices = append(prices, agg.values[key][exchange].value)
+			weights = append(weights, weight)
+		}
+	}
+	if len(prices) == 0 {
+		return 0, false
+	}
+	value, err := computeMean(prices, weights)
+	return value, err == nil
+}
+
+// excluded returns whether exchange and asset @ea are excluded at @t.
+func (agg *Aggregator) excluded(ea exchangeAsset, t time.Time) bool {
+	for _, p := range agg.exclusions[ea] {
+		if !t.Before(p.begin) && t.Before(p.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// exclude excludes @exchange from the aggregated price of @asset from @t on and records the exclusion.
+func (agg *Aggregator) exclude(asset dia.Asset, exchange string, filterName string, value float64, median float64, deviation float64, t time.Time, config dia.AggregationConfig) {
+	exclusionSeconds := config.ExclusionSeconds
+	if exclusionSeconds == 0 {
+		exclusionSeconds = defaultExclusionSeconds
+	}
+	ea := exchangeAsset{identifier: getIdentifier(asset), exchange: exchange}
+	period := exclusionPeriod{begin: t, end: t.Add(time.Duration(exclusionSeconds) * time.Second)}
+	periods := append(agg.exclusions[ea], period)
+	first := 0
+	for first < len(periods)-1 && periods[first].end.Before(t.Add(-reliabilityWindow)) {
+		first++
+	}
+	agg.exclusions[ea] = periods[first:]
+
+	log.Warnf("exclude %s from aggregated price of %s until %v: %s deviates by %.2f%% from median %v.", exchange, ea.identifier, period.end, filterName, 100*deviation, median)
+	if agg.exclusionLog == nil {
+		return
+	}
+	err := agg.exclusionLog.SetExchangeExclusion(dia.ExchangeExclusion{
+		Asset:     asset,
+		Exchange:  exchange,
+		Filter:    filterName,
+		Value:     value,
+		Median:    median,
+		Deviation: deviation,
+		BeginTime: period.begin,
+		EndTime:   period.end,
+	})
+	if err != nil {
+		log.Errorf("record exclusion of %s for %s: %v", exchange, ea.identifier, err)
+	}
+}
+
+// volume returns the USD volume traded on @ea in the time-range (@starttime, @endtime].
+func (agg *Aggregator) volume(ea exchangeAsset, starttime time.Time, endtime time.Time) (volume float64) {
+	for _, s := range agg.volumes[ea] {
+		if s.time.After(starttime) && !s.time.After(endtime) {
+			volume += s.volume
+		}
+	}
+	return
+}
+
+// reliability returns the share of the reliabilityWindow before @t in which @ea was not excluded.
+func (agg *Aggregator) reliability(ea exchangeAsset, t time.Time) float64 {
+	windowStart := t.Add(-reliabilityWindow)
+	var excluded time.Duration
+	for _, p := range agg.exclusions[ea] {
+		begin, end := p.begin, p.end
+		if begin.Before(windowStart) {
+			begin = windowStart
+		}
+		if end.After(t) {
+			end = t
+		}
+		if end.After(begin) {
+			excluded += end.Sub(begin)
+		}
+	}
+	return 1 - excluded.Seconds()/reliabilityWindow.Seconds()
+}
+
+// exchangeValueFilter passes the values of a filter on a single exchange to the Aggregator.
+type exchangeValueFilter struct {
+	Filter
+	aggregator *Aggregator
+	filterName string
+	asset      dia.Asset
+	exchange   string
+	lastTrade  time.Time
+}
+
+func (ef *exchangeValueFilter) compute(trade dia.Trade) {
+	ef.Filter.compute(trade)
+	if trade.Time.After(ef.lastTrade) {
+		ef.lastTrade = trade.Time
+	}
+}
+
+func (ef *exchangeValueFilter) finalCompute(t time.Time) float64 {
+	value := ef.Filter.finalCompute(t)
+	ef.aggregator.setValue(ef.asset, ef.exchange, ef.filterName, value, ef.lastTrade)
+	return value
+}
+
+func (ef *exchangeValueFilter) clone() Filter {
+	c := *ef
+	c.Filter = ef.Filter.clone()
+	return &c
+}
+
+// aggregatedFilter replaces the value of a filter across all exchanges by the Aggregator's price
+// computed from the same filter on single exchanges. The filter's own value is kept if no exchange contributes.
+type aggregatedFilter struct {
+	Filter
+	aggregator *Aggregator
+	filterName string
+	asset      dia.Asset
+	window     int
+	lastTrade  time.Time
+	value      float64
+	modified   bool
+}
+
+func (af *aggregatedFilter) compute(trade dia.Trade) {
+	af.Filter.compute(trade)
+	if trade.Time.After(af.lastTrade) {
+		af.lastTrade = trade.Time
+	}
+	af.modified = true
+}
+
+func (af *aggregatedFilter) finalCompute(t time.Time) float64 {
+	af.value = af.Filter.finalCompute(t)
+	if value, ok := af.aggregator.aggregate(af.asset, af.filterName, af.window, t); ok {
+		af.value = value
+	}
+	return af.value
+}
+
+func (af *aggregatedFilter) filterPointForBlock() *dia.FilterPoint {
+	fp := af.Filter.filterPointForBlock()
+	if fp != nil {
+		fp.Value = af.value
+	}
+	return fp
+}
+
+// save saves the aggregated value instead of the filter's own one at the time of the last trade.
+func (af *aggregatedFilter) save(ds Datastore) error {
+	if !af.modified {
+		return nil
+	}
+	af.modified = false
+	err := ds.SetFilter(af.filterName, af.asset, "", af.value, af.lastTrade)
+	if err != nil {
+		log.Errorln("aggregatedFilter: Error:", err)
+		return err
+	}
+	if af.filterName == dia.FilterKing {
+		err = ds.SetAssetPriceUSD(af.asset, af.value, af.lastTrade)
+		if err != nil {
+			log.Errorln("aggregatedFilter: Error:", err)
+		}
+	}
+	return err
+}
+
+func (af *aggregatedFilter) clone() Filter {
+	c := *af
+	c.Filter = af.Filter.clone()
+	return &c
+}
@@ -0,0 +1,164 @@
+package filters
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// exclusionRecorder keeps the recorded exclusions instead of writing them to postgres.
+type exclusionRecorder struct {
+	exclusions []dia.ExchangeExclusion
+}
+
+func (er *exclusionRecorder) SetExchangeExclusion(exclusion dia.ExchangeExclusion) error {
+	er.exclusions = append(er.exclusions, exclusion)
+	return nil
+}
+
+func TestAggregatorWeighting(t *testing.T) {
+	asset := dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	end := time.Unix(1654041720, 0)
+	values := map[string]float64{"Binance": 100, "Coinbase": 104, "Kraken": 110}
+	volumes := map[string]float64{"Binance": 3, "Coinbase": 1, "Kraken": 0}
+
+	cases := []struct {
+		config   dia.AggregationConfig
+		expected float64
+	}{
+		{dia.AggregationConfig{}, (100 + 104 + 110) / 3.0},
+		// Exchanges are weighted by their USD volume.
+		{dia.AggregationConfig{Weighting: WeightingVolume}, (300*100 + 104*104) / 404.0},
+		{dia.AggregationConfig{Weighting: WeightingManual, Weights: map[string]float64{"Coinbase": 1, "Kraken": 3}}, (104 + 3*110) / 4.0},
+		{dia.AggregationConfig{Weighting: WeightingReliability}, (100 + 104 + 110) / 3.0},
+	}
+	for _, c := range cases {
+		agg, err := NewAggregator([]dia.AggregationConfig{c.config}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		agg.wrap(NewFilterMA(asset, "", end, 120), dia.FilterConfig{Filter: "MA", Window: 120}, asset, "")
+		for exchange, value := range values {
+			agg.addTrade(dia.Trade{QuoteToken: asset, Source: exchange, EstimatedUSDPrice: value, Volume: volumes[exchange], Time: end.Add(-time.Minute)})
+			agg.setValue(asset, exchange, "MA120", value, end.Add(-time.Minute))
+		}
+		// Values of exchanges not traded within the window are ignored.
+		agg.setValue(asset, "Bitfinex", "MA120", 1000, end.Add(-time.Hour))
+
+		value, ok := agg.aggregate(asset, "MA120", 120, end)
+		if !ok || math.Abs(value-c.expected) > 1e-9 {
+			t.Errorf("weighting %q: got %v, expected %v", c.config.Weighting, value, c.expected)
+		}
+	}
+
+	if _, err := NewAggregator([]dia.AggregationConfig{{Weighting: WeightingManual}}, nil); err == nil {
+		t.Error("expected error for manual weighting without weights")
+	}
+	if _, err := NewAggregator([]dia.AggregationConfig{{Weighting: "LARGEST"}}, nil); err == nil {
+		t.Error("expected error for unknown weighting")
+	}
+}
+
+func TestAggregatorExclusion(t *testing.T) {
+	asset := dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	end := time.Unix(1654041720, 0)
+	recorder := &exclusionRecorder{}
+	agg, err := NewAggregator([]dia.AggregationConfig{
+		{MaxDeviation: 0.1, ExclusionSeconds: 600},
+		// The configuration for the asset's blockchain takes precedence.
+		{Blockchain: dia.ETHEREUM, Weighting: WeightingReliability, MaxDeviation: 0.1, ExclusionSeconds: 600},
+	}, recorder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config, _ := agg.config(asset); config.Weighting != WeightingReliability {
+		t.Errorf("expected the configuration of the asset's blockchain, got %v", config)
+	}
+
+	values := map[string]float64{"Binance": 100, "Coinbase": 101, "Kraken": 102, "Bitfinex": 130}
+	for exchange, value := range values {
+		agg.setValue(asset, exchange, "MA120", value, end.Add(-time.Minute))
+	}
+	value, ok := agg.aggregate(asset, "MA120", 120, end)
+	if !ok || math.Abs(value-101) > 1e-9 {
+		t.Errorf("got %v, expected 101", value)
+	}
+	if len(recorder.exclusions) != 1 {
+		t.Fatalf("got %d exclusions, expected 1", len(recorder.exclusions))
+	}
+	exclusion := recorder.exclusions[0]
+	if exclusion.Exchange != "Bitfinex" || exclusion.Filter != "MA120" || exclusion.Median != 101.5 || !exclusion.EndTime.Equal(end.Add(600*time.Second)) {
+		t.Errorf("unexpected exclusion %v", exclusion)
+	}
+
+	// The exchange stays excluded for all filters of the asset, even when it is in line with the others.
+	later := end.Add(5 * time.Minute)
+	for exchange := range values {
+		agg.setValue(asset, exchange, "MA120", 101, later.Add(-time.Minute))
+	}
+	if value, _ := agg.aggregate(asset, "MA120", 120, later); math.Abs(value-101) > 1e-9 {
+		t.Errorf("got %v during exclusion, expected 101", value)
+	}
+	if len(recorder.exclusions) != 1 {
+		t.Errorf("got %d exclusions, expected 1", len(recorder.exclusions))
+	}
+
+	// After the exclusion, the exchange is weighted by its reliability.
+	after := end.Add(15 * time.Minute)
+	for exchange, value := range map[string]float64{"Binance": 100, "Coinbase": 101, "Kraken": 102, "Bitfinex": 110} {
+		agg.setValue(asset, exchange, "MA120", value, after.Add(-time.Minute))
+	}
+	reliability := 1 - 600/reliabilityWindow.Seconds()
+	expected := (100 + 101 + 102 + reliability*110) / (3 + reliability)
+	if value, _ := agg.aggregate(asset, "MA120", 120, after); math.Abs(value-expected) > 1e-9 {
+		t.Errorf("got %v after exclusion, expected %v", value, expected)
+	}
+
+	// Rewinding forgets exclusions which began afterwards.
+	agg.rewind(end.Add(-time.Minute))
+	if agg.excluded(exchangeAsset{identifier: getIdentifier(asset), exchange: "Bitfinex"}, later) {
+		t.Error("exclusion not forgotten after rewind")
+	}
+}
+
+func TestAggregatedFilters(t *testing.T) {
+	begin := time.Unix(1654041600, 0)
+	tb := testTradesBlock(begin, 100, 100)
+	tb.TradesBlockData.Trades = append(tb.TradesBlockData.Trades, tb.TradesBlockData.Trades[0], tb.TradesBlockData.Trades[1])
+	for i := 2; i < 4; i++ {
+		tb.TradesBlockData.Trades[i].Source = dia.CoinBaseExchange
+		tb.TradesBlockData.Trades[i].EstimatedUSDPrice = 110
+		tb.TradesBlockData.Trades[i].Volume = 3
+	}
+	asset := tb.TradesBlockData.Trades[0].QuoteToken
+
+	agg, err := NewAggregator([]dia.AggregationConfig{{Weighting: WeightingVolume}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterSets, err := NewFilterSets([]dia.FilterConfig{{Filter: "MA", Window: 120}, {Filter: "VOL", Window: 120}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterSets.SetAggregator(agg)
+	recorder := newFilterRecorder()
+	s := &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: recorder, filterSets: filterSets}
+	s.processTradesBlock(tb)
+
+	last := tb.TradesBlockData.Trades[1].Time
+	point := func(filter string, exchange string) float64 {
+		return recorder.values[recordedPoint{filter, getIdentifier(asset), exchange, last}]
+	}
+	if value := point("MA120", ""); math.Abs(value-(200*100+660*110)/860.0) > 1e-9 {
+		t.Errorf("got aggregated MA120 %v", value)
+	}
+	if point("MA120", dia.BinanceExchange) != 100 || point("MA120", dia.CoinBaseExchange) != 110 {
+		t.Errorf("unexpected MA120 on single exchanges %v, %v", point("MA120", dia.BinanceExchange), point("MA120", dia.CoinBaseExchange))
+	}
+	// Volumes are not aggregated.
+	if value := point("VOL120", ""); value != 860 {
+		t.Errorf("got VOL120 %v, expected 860", value)
+	}
+}
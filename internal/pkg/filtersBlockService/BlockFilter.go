@@ -0,0 +1,29 @@
+package filters
+
+import (
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// BlockFilter is implemented by the filters of this package for use outside of the FiltersBlockService,
+// such as over past trades by the queryhelper. A filter yields the same values as in the FiltersBlockService
+// if it receives all trades once and in order and FinalCompute is called at the end of each block.
+type BlockFilter interface {
+	Compute(trade dia.Trade)
+	FinalCompute(t time.Time) float64
+	FilterPointForBlock() *dia.FilterPoint
+}
+
+var (
+	_ BlockFilter = (*FilterMA)(nil)
+	_ BlockFilter = (*FilterMAIR)(nil)
+	_ BlockFilter = (*FilterMEDIR)(nil)
+	_ BlockFilter = (*FilterVWAP)(nil)
+	_ BlockFilter = (*FilterVWAPIR)(nil)
+	_ BlockFilter = (*FilterTWAP)(nil)
+	_ BlockFilter = (*FilterLWVWAP)(nil)
+	_ BlockFilter = (*FilterVOL)(nil)
+	_ BlockFilter = (*FilterCOUNT)(nil)
+	_ BlockFilter = (*FilterVolatility)(nil)
+)
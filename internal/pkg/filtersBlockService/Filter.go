@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 )
 
 // Filter interface defines a filter's methods processing trades from the tradesBlockService.
@@ -15,7 +14,34 @@ type Filter interface {
 	compute(trade dia.Trade)
 	finalCompute(t time.Time) float64
 	filterPointForBlock() *dia.FilterPoint
-	save(ds models.Datastore) error
+	save(ds Datastore) error
+	// clone returns a deep copy of the filter's state.
+	clone() Filter
+}
+
+// blockSum is the sum of a quantity such as the volume over a tradesBlock ending at @end.
+type blockSum struct {
+	end   time.Time
+	value float64
+}
+
+// appendBlockSum appends @sum to @blocks and removes all blocks ending more than @memory seconds before @sum.
+// For @memory up to the size of a tradesBlock, only @sum is kept.
+func appendBlockSum(blocks []blockSum, sum blockSum, memory int) []blockSum {
+	blocks = append(blocks, sum)
+	windowStart := sum.end.Add(-time.Duration(memory) * time.Second)
+	first := 0
+	for first < len(blocks)-1 && !blocks[first].end.After(windowStart) {
+		first++
+	}
+	return blocks[first:]
+}
+
+func sumBlocks(blocks []blockSum) (sum float64) {
+	for _, b := range blocks {
+		sum += b.value
+	}
+	return
 }
 
 func RemoveOutliers(samples []float64, scale float64) ([]float64, []int) {
@@ -5,15 +5,21 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("COUNT", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterCOUNT(asset, exchange, window)
+	})
+}
+
 type FilterCOUNT struct {
 	asset       dia.Asset
 	exchange    string
 	currentTime time.Time
 	numTrades   int64
+	blocks      []blockSum
 	value       int64
 	filterName  string
 	memory      int
@@ -34,8 +40,8 @@ func NewFilterCOUNT(asset dia.Asset, exchange string, memory int) *FilterCOUNT {
 func (filter *FilterCOUNT) Compute(trade dia.Trade) {
 	filter.compute(trade)
 }
-func (filter *FilterCOUNT) FinalCompute(t time.Time) {
-	filter.finalCompute(t)
+func (filter *FilterCOUNT) FinalCompute(t time.Time) float64 {
+	return filter.finalCompute(t)
 }
 
 func (filter *FilterCOUNT) compute(trade dia.Trade) {
@@ -45,7 +51,8 @@ func (filter *FilterCOUNT) compute(trade dia.Trade) {
 }
 
 func (filter *FilterCOUNT) finalCompute(t time.Time) float64 {
-	filter.value = filter.numTrades
+	filter.blocks = appendBlockSum(filter.blocks, blockSum{end: t, value: float64(filter.numTrades)}, filter.memory)
+	filter.value = int64(sumBlocks(filter.blocks))
 	filter.numTrades = int64(0)
 	return float64(filter.value)
 }
@@ -63,7 +70,7 @@ func (filter *FilterCOUNT) FilterPointForBlock() *dia.FilterPoint {
 	}
 }
 
-func (filter *FilterCOUNT) save(ds models.Datastore) error {
+func (filter *FilterCOUNT) save(ds Datastore) error {
 	if filter.modified {
 		filter.modified = false
 		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, float64(filter.value), filter.currentTime)
@@ -74,3 +81,9 @@ func (filter *FilterCOUNT) save(ds models.Datastore) error {
 	}
 	return nil
 }
+
+func (filter *FilterCOUNT) clone() Filter {
+	c := *filter
+	c.blocks = append([]blockSum(nil), filter.blocks...)
+	return &c
+}
@@ -5,10 +5,15 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("EMA", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterEMA(asset, exchange, beginTime, window)
+	})
+}
+
 // FilterEMA is the struct for a moving average filter implementing
 // the Filter interface.
 type FilterEMA struct {
@@ -42,10 +47,22 @@ func NewFilterEMA(asset dia.Asset, exchange string, currentTime time.Time, block
 }
 
 func (s *FilterEMA) Compute(trade dia.FilterPoint) {
-	s.compute(trade)
+	s.computePoint(trade)
+}
+
+// compute updates the average with the USD price of a trade from a tradesBlock.
+func (s *FilterEMA) compute(trade dia.Trade) {
+	if s.lastTrade != nil && trade.Time.Before(s.currentTime) {
+		log.Errorln("FilterEMA: Ignoring Trade out of order ", s.currentTime, trade.Time)
+		return
+	}
+	point := dia.FilterPoint{Asset: s.asset, Value: trade.EstimatedUSDPrice, Time: trade.Time}
+	s.modified = true
+	s.fill(trade.Time, point)
+	s.lastTrade = &point
 }
 
-func (s *FilterEMA) compute(trade dia.FilterPoint) {
+func (s *FilterEMA) computePoint(trade dia.FilterPoint) {
 	s.modified = true
 	if s.lastTrade != nil {
 		if trade.Time.After(s.currentTime) {
@@ -61,18 +78,18 @@ func (s *FilterEMA) compute(trade dia.FilterPoint) {
 }
 
 func (e *FilterEMA) fill(t time.Time, trade dia.FilterPoint) {
-	log.Println("FilterEMA fill ", trade)
-	log.Println("FilterEMA e.multiplier ", e.multiplier)
-	log.Println("FilterEMA e.value ", e.value)
+	log.Debugln("FilterEMA fill ", trade)
+	log.Debugln("FilterEMA e.multiplier ", e.multiplier)
+	log.Debugln("FilterEMA e.value ", e.value)
 	e.currentTime = trade.Time
 	if e.value == 0 { // this is a proxy for "uninitialized"
 		e.value = trade.Value
 	} else {
 		e.value = (trade.Value * float64(e.multiplier)) + (e.value * (1 - float64(e.multiplier)))
-		log.Println("FilterEMA e.value and multiplier ", e.value, e.multiplier)
+		log.Debugln("FilterEMA e.value and multiplier ", e.value, e.multiplier)
 
 	}
-	log.Println("FilterEMA e.value ", e.value)
+	log.Debugln("FilterEMA e.value ", e.value)
 
 }
 
@@ -108,7 +125,7 @@ func (s *FilterEMA) filterPointForBlock() *dia.FilterPoint {
 	}
 }
 
-func (s *FilterEMA) save(ds models.Datastore) error {
+func (s *FilterEMA) save(ds Datastore) error {
 
 	if s.modified {
 		s.modified = false
@@ -120,3 +137,14 @@ func (s *FilterEMA) save(ds models.Datastore) error {
 	}
 	return nil
 }
+
+func (s *FilterEMA) clone() Filter {
+	c := *s
+	c.previousPrices = append([]float64(nil), s.previousPrices...)
+	c.previousVolumes = append([]float64(nil), s.previousVolumes...)
+	if s.lastTrade != nil {
+		lastTrade := *s.lastTrade
+		c.lastTrade = &lastTrade
+	}
+	return &c
+}
@@ -0,0 +1,139 @@
+package filters
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterFilter("LWVWAP", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterLWVWAP(asset, exchange, beginTime, window, nil)
+	})
+}
+
+// liquidityWeighted is implemented by filters which weight trades by pool liquidity.
+// FilterSets provide them with their LiquiditySource.
+type liquidityWeighted interface {
+	setLiquiditySource(liquidity LiquiditySource)
+}
+
+// FilterLWVWAP implements a liquidity weighted average price of DEX trades. Each trade is
+// weighted by its volume times the liquidity of the quote token in the pools of the trade's pair,
+// so that trades in thin pools, which are cheap to manipulate, have little influence.
+// Trades without known pool liquidity are ignored.
+type FilterLWVWAP struct {
+	asset       dia.Asset
+	exchange    string
+	currentTime time.Time
+	prices      []float64
+	weights     []float64
+	times       []time.Time
+	lastTrade   dia.Trade
+	param       int
+	value       float64
+	filterName  string
+	modified    bool
+	liquidity   LiquiditySource
+}
+
+// NewFilterLWVWAP returns a liquidity weighted average price filter over a window of @param seconds.
+func NewFilterLWVWAP(asset dia.Asset, exchange string, currentTime time.Time, param int, liquidity LiquiditySource) *FilterLWVWAP {
+	return &FilterLWVWAP{
+		asset:       asset,
+		exchange:    exchange,
+		currentTime: currentTime,
+		param:       param,
+		filterName:  "LWVWAP" + strconv.Itoa(param),
+		liquidity:   liquidity,
+	}
+}
+
+func (filter *FilterLWVWAP) setLiquiditySource(liquidity LiquiditySource) {
+	filter.liquidity = liquidity
+}
+
+func (filter *FilterLWVWAP) Compute(trade dia.Trade) {
+	filter.compute(trade)
+}
+
+func (filter *FilterLWVWAP) compute(trade dia.Trade) {
+	if filter.liquidity == nil {
+		return
+	}
+	if filter.lastTrade != (dia.Trade{}) && trade.Time.Before(filter.currentTime) {
+		log.Errorln("FilterLWVWAP: Ignoring Trade out of order ", filter.currentTime, trade.Time)
+		return
+	}
+	liquidity, ok := filter.liquidity.QuoteLiquidity(trade)
+	if !ok {
+		return
+	}
+	filter.modified = true
+	filter.prices = append([]float64{trade.EstimatedUSDPrice}, filter.prices...)
+	filter.weights = append([]float64{math.Abs(trade.Volume) * liquidity}, filter.weights...)
+	filter.times = append([]time.Time{trade.Time}, filter.times...)
+	filter.lastTrade = trade
+	filter.currentTime = trade.Time
+
+	// Remove trades older than the window.
+	windowStart := trade.Time.Add(-time.Duration(filter.param) * time.Second)
+	last := len(filter.times)
+	for last > 1 && !filter.times[last-1].After(windowStart) {
+		last--
+	}
+	filter.prices = filter.prices[:last]
+	filter.weights = filter.weights[:last]
+	filter.times = filter.times[:last]
+}
+
+func (filter *FilterLWVWAP) FinalCompute(t time.Time) float64 {
+	return filter.finalCompute(t)
+}
+
+func (filter *FilterLWVWAP) finalCompute(t time.Time) float64 {
+	mean, err := computeMean(filter.prices, filter.weights)
+	if err == nil && mean > 0 {
+		filter.value = mean
+	}
+	return filter.value
+}
+
+func (filter *FilterLWVWAP) FilterPointForBlock() *dia.FilterPoint {
+	return &dia.FilterPoint{
+		Asset: filter.asset,
+		Value: filter.value,
+		Name:  filter.filterName,
+		Time:  filter.currentTime,
+	}
+}
+
+func (filter *FilterLWVWAP) filterPointForBlock() *dia.FilterPoint {
+	if filter.exchange != "" || filter.filterName != dia.FilterKing {
+		return nil
+	}
+	return filter.FilterPointForBlock()
+}
+
+func (filter *FilterLWVWAP) save(ds Datastore) error {
+	if filter.modified {
+		filter.modified = false
+		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
+		if err != nil {
+			log.Errorln("FilterLWVWAP: Error:", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (filter *FilterLWVWAP) clone() Filter {
+	c := *filter
+	c.prices = append([]float64(nil), filter.prices...)
+	c.weights = append([]float64(nil), filter.weights...)
+	c.times = append([]time.Time(nil), filter.times...)
+	return &c
+}
@@ -0,0 +1,97 @@
+package filters
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// staticLiquidity returns fixed liquidities by exchange.
+type staticLiquidity map[string]float64
+
+func (sl staticLiquidity) QuoteLiquidity(trade dia.Trade) (float64, bool) {
+	liquidity, ok := sl[trade.Source]
+	return liquidity, ok
+}
+
+func TestLWVWAP(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	liquidity := staticLiquidity{"UniswapV2": 1e6, "SushiSwap": 1e3}
+	lwvwapFilter := NewFilterLWVWAP(dia.Asset{}, "", start, 120, liquidity)
+
+	lwvwapFilter.Compute(dia.Trade{Source: "UniswapV2", EstimatedUSDPrice: 100, Volume: 1, Time: start})
+	// A large trade in a thin pool barely moves the price.
+	lwvwapFilter.Compute(dia.Trade{Source: "SushiSwap", EstimatedUSDPrice: 1000, Volume: -10, Time: start.Add(time.Second)})
+	// Trades without known pools are ignored.
+	lwvwapFilter.Compute(dia.Trade{Source: "Binance", EstimatedUSDPrice: 5000, Volume: 1, Time: start.Add(2 * time.Second)})
+	value := lwvwapFilter.FinalCompute(start.Add(120 * time.Second))
+
+	expected := (100*1e6 + 1000*1e4) / (1e6 + 1e4)
+	if math.Abs(value-expected) > 1e-9 {
+		t.Errorf("LWVWAP expected %v and got %v", expected, value)
+	}
+}
+
+func TestLWVWAPWithoutLiquiditySource(t *testing.T) {
+	fs, err := NewFilterSets([]dia.FilterConfig{{Filter: "LWVWAP", Window: 120}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1600000000, 0)
+	filter := fs.Filters(dia.Asset{}, "", start)[0]
+	filter.compute(dia.Trade{Source: "UniswapV2", EstimatedUSDPrice: 100, Volume: 1, Time: start})
+	if value := filter.finalCompute(start); value != 0 {
+		t.Errorf("LWVWAP without liquidity source expected 0 and got %v", value)
+	}
+
+	fs.SetLiquiditySource(staticLiquidity{"UniswapV2": 1})
+	filter = fs.Filters(dia.Asset{}, "", start)[0]
+	filter.compute(dia.Trade{Source: "UniswapV2", EstimatedUSDPrice: 100, Volume: 1, Time: start})
+	if value := filter.finalCompute(start); value != 100 {
+		t.Errorf("LWVWAP expected 100 and got %v", value)
+	}
+}
+
+type fakePools struct {
+	addresses []string
+	pools     map[string]dia.Pool
+	history   map[string][]dia.Pool
+}
+
+func (fp *fakePools) GetPoolAddrsByAssetPair(exchange string, asset0 dia.Asset, asset1 dia.Asset) ([]string, error) {
+	return fp.addresses, nil
+}
+
+func (fp *fakePools) GetPoolByAddress(blockchain string, address string) (dia.Pool, error) {
+	return fp.pools[address], nil
+}
+
+func (fp *fakePools) GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error) {
+	return fp.history[poolAddress], nil
+}
+
+func TestPoolLiquidity(t *testing.T) {
+	weth := dia.Asset{Blockchain: dia.ETHEREUM, Address: "0xweth"}
+	usdc := dia.Asset{Blockchain: dia.ETHEREUM, Address: "0xusdc"}
+	pool := func(wethVolume float64) dia.Pool {
+		return dia.Pool{Assetvolumes: []dia.AssetVolume{{Asset: weth, Volume: wethVolume}, {Asset: usdc, Volume: 1e6}}}
+	}
+	pools := &fakePools{
+		addresses: []string{"0xpool1", "0xpool2"},
+		pools:     map[string]dia.Pool{"0xpool1": pool(10), "0xpool2": pool(20)},
+		history:   map[string][]dia.Pool{"0xpool1": {pool(1), pool(2)}},
+	}
+	trade := dia.Trade{Source: dia.UniswapExchange, QuoteToken: weth, BaseToken: usdc, Time: time.Unix(1600000000, 0)}
+
+	liquidity, ok := NewPoolLiquidity(pools, nil).QuoteLiquidity(trade)
+	if !ok || liquidity != 30 {
+		t.Errorf("current liquidity expected 30 and got %v, %v", liquidity, ok)
+	}
+	// The latest state of each pool is used, pools without history are skipped.
+	liquidity, ok = NewPoolLiquidity(pools, pools).QuoteLiquidity(trade)
+	if !ok || liquidity != 1 {
+		t.Errorf("past liquidity expected 1 and got %v, %v", liquidity, ok)
+	}
+}
@@ -6,10 +6,15 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("MA", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterMA(asset, exchange, beginTime, window)
+	})
+}
+
 // FilterMA is the struct for a moving average filter implementing
 // the Filter interface.
 type FilterMA struct {
@@ -117,7 +122,8 @@ func (filter *FilterMA) finalCompute(t time.Time) float64 {
 		filter.value = totalPrice / totalVolume
 
 	}
-	if len(filter.prices) > 0 && len(filter.volumes) > 0 {
+	// Filters with windows longer than a tradesBlock keep their samples, which are bounded by one sample per second.
+	if filter.memory <= dia.BlockSizeSeconds && len(filter.prices) > 0 && len(filter.volumes) > 0 {
 		filter.prices = []float64{filter.lastTrade.EstimatedUSDPrice}
 		filter.volumes = []float64{filter.lastTrade.Volume}
 	}
@@ -145,7 +151,7 @@ func (filter *FilterMA) filterPointForBlock() *dia.FilterPoint {
 	}
 }
 
-func (filter *FilterMA) save(ds models.Datastore) error {
+func (filter *FilterMA) save(ds Datastore) error {
 	if filter.modified {
 		filter.modified = false
 		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
@@ -156,3 +162,10 @@ func (filter *FilterMA) save(ds models.Datastore) error {
 	}
 	return nil
 }
+
+func (filter *FilterMA) clone() Filter {
+	c := *filter
+	c.prices = append([]float64(nil), filter.prices...)
+	c.volumes = append([]float64(nil), filter.volumes...)
+	return &c
+}
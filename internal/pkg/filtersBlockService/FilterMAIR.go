@@ -5,12 +5,17 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("MAIR", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterMAIR(asset, exchange, beginTime, window)
+	})
+}
+
 // FilterMAIR implements a trimmed moving average.
-// Outliers are eliminated using interquartile range.
+// Outliers are eliminated using interquartile range unless another OutlierDetector is configured.
 // see: https://en.wikipedia.org/wiki/Interquartile_range
 type FilterMAIR struct {
 	asset       dia.Asset
@@ -18,11 +23,14 @@ type FilterMAIR struct {
 	currentTime time.Time
 	prices      []float64
 	volumes     []float64
+	trades      []dia.ExcludedTrade
 	lastTrade   dia.Trade
 	memory      int
 	value       float64
 	filterName  string
 	modified    bool
+	detector    OutlierDetector
+	excluded    []dia.ExcludedTrade
 }
 
 //NewFilterMAIR returns a FilterMAIR
@@ -35,10 +43,19 @@ func NewFilterMAIR(asset dia.Asset, exchange string, currentTime time.Time, memo
 		currentTime: currentTime,
 		memory:      memory,
 		filterName:  "MAIR" + strconv.Itoa(memory),
+		detector:    defaultOutlierDetector(),
 	}
 	return filter
 }
 
+func (filter *FilterMAIR) setOutlierDetector(detector OutlierDetector) {
+	filter.detector = detector
+}
+
+func (filter *FilterMAIR) outliers() []dia.ExcludedTrade {
+	return filter.excluded
+}
+
 func (filter *FilterMAIR) Compute(trade dia.Trade) {
 	filter.compute(trade)
 }
@@ -71,6 +88,7 @@ func (filter *FilterMAIR) fill(trade dia.Trade) {
 				/// Remove latest data point and update with newer
 				filter.prices = filter.prices[1:]
 				filter.volumes = filter.volumes[1:]
+				filter.trades = filter.trades[1:]
 			}
 		}
 		filter.processDataPoint(trade)
@@ -83,9 +101,11 @@ func (filter *FilterMAIR) processDataPoint(trade dia.Trade) {
 	if len(filter.prices) >= filter.memory {
 		filter.prices = filter.prices[0 : filter.memory-1]
 		filter.volumes = filter.volumes[0 : filter.memory-1]
+		filter.trades = filter.trades[0 : filter.memory-1]
 	}
 	filter.prices = append([]float64{trade.EstimatedUSDPrice}, filter.prices...)
 	filter.volumes = append([]float64{trade.Volume}, filter.volumes...)
+	filter.trades = append([]dia.ExcludedTrade{excludedTrade(trade)}, filter.trades...)
 }
 
 func (filter *FilterMAIR) FinalCompute(t time.Time) float64 {
@@ -98,27 +118,35 @@ func (filter *FilterMAIR) finalCompute(t time.Time) float64 {
 	}
 	// Add the last trade again to compensate for the delay since measurement to EOB
 	// adopted behaviour from FilterMA
-	filter.processDataPoint(filter.lastTrade)
-	cleanPrices, bounds := removeOutliers(filter.prices)
-	mean, err := computeMean(cleanPrices, filter.volumes[bounds[0]:bounds[1]])
+	// Windows longer than a tradesBlock are not reset, so that the last trade is only added if the
+	// window is empty. Otherwise it would accumulate in blocks without trades.
+	if filter.memory <= dia.BlockSizeSeconds || len(filter.prices) == 0 {
+		filter.processDataPoint(filter.lastTrade)
+	}
+	cleanPrices, cleanVolumes, outliers := removeOutliersWith(filter.detector, filter.prices, filter.volumes)
+	filter.excluded = excludedTrades(filter.trades, outliers)
+	mean, err := computeMean(cleanPrices, cleanVolumes)
 	if err != nil {
 		return 0.0
 	}
 	filter.value = mean
 	// Reduce the filter values to the last recorded value for the next tradesblock.
-	if len(filter.prices) > 0 && len(filter.volumes) > 0 {
+	// Filters with windows longer than a tradesBlock keep their samples, which are bounded by one sample per second.
+	if filter.memory <= dia.BlockSizeSeconds && len(filter.prices) > 0 && len(filter.volumes) > 0 {
 		filter.prices = []float64{filter.lastTrade.EstimatedUSDPrice}
 		filter.volumes = []float64{filter.lastTrade.Volume}
+		filter.trades = []dia.ExcludedTrade{excludedTrade(filter.lastTrade)}
 	}
 	return filter.value
 }
 
 func (filter *FilterMAIR) FilterPointForBlock() *dia.FilterPoint {
 	return &dia.FilterPoint{
-		Asset: filter.asset,
-		Value: filter.value,
-		Name:  filter.filterName,
-		Time:  filter.currentTime,
+		Asset:    filter.asset,
+		Value:    filter.value,
+		Name:     filter.filterName,
+		Time:     filter.currentTime,
+		Excluded: filter.excluded,
 	}
 }
 
@@ -126,15 +154,10 @@ func (filter *FilterMAIR) filterPointForBlock() *dia.FilterPoint {
 	if filter.exchange != "" || filter.filterName != dia.FilterKing {
 		return nil
 	}
-	return &dia.FilterPoint{
-		Asset: filter.asset,
-		Value: filter.value,
-		Name:  filter.filterName,
-		Time:  filter.currentTime,
-	}
+	return filter.FilterPointForBlock()
 }
 
-func (filter *FilterMAIR) save(ds models.Datastore) error {
+func (filter *FilterMAIR) save(ds Datastore) error {
 	if filter.modified {
 		filter.modified = false
 		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
@@ -144,7 +167,8 @@ func (filter *FilterMAIR) save(ds models.Datastore) error {
 
 		// Additionally, the price across exchanges is saved in influx as a quotation.
 		// This price is used for the estimation of quote tokens' prices in the tradesBlockService.
-		if filter.exchange == "" {
+		// Filters with other windows must not overwrite it.
+		if filter.exchange == "" && filter.filterName == dia.FilterKing {
 			err = ds.SetAssetPriceUSD(filter.asset, filter.value, filter.currentTime)
 			if err != nil {
 				log.Errorln("FilterMAIR: Error:", err)
@@ -154,3 +178,12 @@ func (filter *FilterMAIR) save(ds models.Datastore) error {
 	}
 	return nil
 }
+
+func (filter *FilterMAIR) clone() Filter {
+	c := *filter
+	c.prices = append([]float64(nil), filter.prices...)
+	c.volumes = append([]float64(nil), filter.volumes...)
+	c.trades = append([]dia.ExcludedTrade(nil), filter.trades...)
+	c.excluded = append([]dia.ExcludedTrade(nil), filter.excluded...)
+	return &c
+}
@@ -214,3 +214,44 @@ func TestFilterMAIRAverageCleanOutliers(t *testing.T) {
 		}
 	}
 }
+
+// TestFilterMAIRLongWindowBlocksWithoutTrades checks that blocks without trades do not add samples
+// to filters with windows longer than a tradesBlock.
+func TestFilterMAIRLongWindowBlocksWithoutTrades(t *testing.T) {
+	d := time.Date(2016, time.August, 15, 0, 0, 0, 0, time.UTC)
+	f := NewFilterMAIR(dia.Asset{Symbol: "XRP", Name: "XRP"}, "", d, 5*dia.BlockSizeSeconds)
+	f.compute(dia.Trade{EstimatedUSDPrice: 100, Volume: 1, Time: d.Add(time.Second)})
+	for i := 1; i <= 4; i++ {
+		v := f.finalCompute(d.Add(time.Duration(i*dia.BlockSizeSeconds) * time.Second))
+		if v != 100 {
+			t.Errorf("block %d: got %v, want 100", i, v)
+		}
+		if len(f.prices) != 1 {
+			t.Errorf("block %d: got %d samples, want 1", i, len(f.prices))
+		}
+	}
+}
+
+// TestFilterMAIRKingConsecutiveBlocks pins the values of MAIR120 over two consecutive blocks.
+// The last trade is added again at the end of each block and is the only sample kept for the next block.
+func TestFilterMAIRKingConsecutiveBlocks(t *testing.T) {
+	d := time.Date(2016, time.August, 15, 0, 0, 0, 0, time.UTC)
+	f := NewFilterMAIR(dia.Asset{Symbol: "XRP", Name: "XRP"}, "", d, dia.BlockSizeSeconds)
+	if f.filterName != dia.FilterKing {
+		t.Fatalf("got filter %s, want %s", f.filterName, dia.FilterKing)
+	}
+	f.compute(dia.Trade{EstimatedUSDPrice: 100, Volume: 1, Time: d.Add(1 * time.Second)})
+	f.compute(dia.Trade{EstimatedUSDPrice: 200, Volume: 1, Time: d.Add(2 * time.Second)})
+	f.compute(dia.Trade{EstimatedUSDPrice: 110, Volume: 1, Time: d.Add(3 * time.Second)})
+	f.compute(dia.Trade{EstimatedUSDPrice: 150, Volume: 1, Time: d.Add(4 * time.Second)})
+	// Samples 100, 200, 110, 150 and the last trade at 150.
+	if v := f.finalCompute(d.Add(dia.BlockSizeSeconds * time.Second)); v != 142 {
+		t.Errorf("first block: got %v, want 142", v)
+	}
+	f.compute(dia.Trade{EstimatedUSDPrice: 120, Volume: 1, Time: d.Add(5 * time.Second)})
+	f.compute(dia.Trade{EstimatedUSDPrice: 130, Volume: 1, Time: d.Add(6 * time.Second)})
+	// Samples 150 of the first block, 120, 130 and the last trade at 130.
+	if v := f.finalCompute(d.Add(2 * dia.BlockSizeSeconds * time.Second)); v != 132.5 {
+		t.Errorf("second block: got %v, want 132.5", v)
+	}
+}
@@ -5,23 +5,34 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("MEDIR", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterMEDIR(asset, exchange, beginTime, window)
+	})
+}
+
 // FilterMEDIR contains the configuration parameters of the filter.
 // It implements a trimmed median. Outliers are eliminated using interquartile range
+// unless another OutlierDetector is configured.
 // see: https://en.wikipedia.org/wiki/Interquartile_range
 type FilterMEDIR struct {
 	asset       dia.Asset
 	exchange    string
 	currentTime time.Time
 	prices      []float64
+	volumes     []float64
+	times       []time.Time
+	trades      []dia.ExcludedTrade
 	lastTrade   dia.Trade
 	memory      int
 	value       float64
 	filterName  string
 	modified    bool
+	detector    OutlierDetector
+	excluded    []dia.ExcludedTrade
 }
 
 //NewFilterMEDIR creates a FilterMEDIR
@@ -33,10 +44,19 @@ func NewFilterMEDIR(asset dia.Asset, exchange string, currentTime time.Time, mem
 		currentTime: currentTime,
 		memory:      memory,
 		filterName:  "MEDIR" + strconv.Itoa(memory),
+		detector:    defaultOutlierDetector(),
 	}
 	return filter
 }
 
+func (filter *FilterMEDIR) setOutlierDetector(detector OutlierDetector) {
+	filter.detector = detector
+}
+
+func (filter *FilterMEDIR) outliers() []dia.ExcludedTrade {
+	return filter.excluded
+}
+
 func (filter *FilterMEDIR) compute(trade dia.Trade) {
 	filter.modified = true
 	if filter.lastTrade != (dia.Trade{}) {
@@ -53,16 +73,37 @@ func (filter *FilterMEDIR) compute(trade dia.Trade) {
 func (filter *FilterMEDIR) Compute(trade dia.Trade) {
 	filter.compute(trade)
 }
-func (filter *FilterMEDIR) FinalCompute(t time.Time) {
-	filter.finalCompute(t)
+func (filter *FilterMEDIR) FinalCompute(t time.Time) float64 {
+	return filter.finalCompute(t)
 }
 
+// processDataPoint adds a trade's price. Filters with windows up to the size of a tradesBlock
+// keep at most @memory trades, longer windows keep all trades of the last @memory seconds.
 func (filter *FilterMEDIR) processDataPoint(trade dia.Trade) {
 	/// first remove extra value from buffer if already full
-	if len(filter.prices) >= filter.memory {
+	if filter.memory <= dia.BlockSizeSeconds && len(filter.prices) >= filter.memory {
 		filter.prices = filter.prices[0 : filter.memory-1]
+		filter.volumes = filter.volumes[0 : filter.memory-1]
+		filter.times = filter.times[0 : filter.memory-1]
+		filter.trades = filter.trades[0 : filter.memory-1]
 	}
 	filter.prices = append([]float64{trade.EstimatedUSDPrice}, filter.prices...)
+	filter.volumes = append([]float64{trade.Volume}, filter.volumes...)
+	filter.times = append([]time.Time{trade.Time}, filter.times...)
+	filter.trades = append([]dia.ExcludedTrade{excludedTrade(trade)}, filter.trades...)
+}
+
+// trim removes all prices from before the window ending at @t.
+func (filter *FilterMEDIR) trim(t time.Time) {
+	windowStart := t.Add(-time.Duration(filter.memory) * time.Second)
+	last := len(filter.times)
+	for last > 1 && !filter.times[last-1].After(windowStart) {
+		last--
+	}
+	filter.prices = filter.prices[:last]
+	filter.volumes = filter.volumes[:last]
+	filter.times = filter.times[:last]
+	filter.trades = filter.trades[:last]
 }
 
 func (filter *FilterMEDIR) finalCompute(t time.Time) float64 {
@@ -70,9 +111,18 @@ func (filter *FilterMEDIR) finalCompute(t time.Time) float64 {
 		log.Info("last trade emtpy")
 		return 0.0
 	}
-	cleanPrices, _ := removeOutliers(filter.prices)
+	if filter.memory > dia.BlockSizeSeconds {
+		filter.trim(t)
+	}
+	cleanPrices, _, outliers := removeOutliersWith(filter.detector, filter.prices, filter.volumes)
+	filter.excluded = excludedTrades(filter.trades, outliers)
 	filter.value = computeMedian(cleanPrices)
-	filter.prices = []float64{filter.lastTrade.EstimatedUSDPrice}
+	if filter.memory <= dia.BlockSizeSeconds {
+		filter.prices = []float64{filter.lastTrade.EstimatedUSDPrice}
+		filter.volumes = []float64{filter.lastTrade.Volume}
+		filter.times = []time.Time{filter.lastTrade.Time}
+		filter.trades = []dia.ExcludedTrade{excludedTrade(filter.lastTrade)}
+	}
 	return filter.value
 }
 
@@ -80,23 +130,19 @@ func (filter *FilterMEDIR) filterPointForBlock() *dia.FilterPoint {
 	if filter.exchange != "" || filter.filterName != dia.FilterKing {
 		return nil
 	}
-	return &dia.FilterPoint{
-		Asset: filter.asset,
-		Value: filter.value,
-		Name:  filter.filterName,
-		Time:  filter.currentTime,
-	}
+	return filter.FilterPointForBlock()
 }
 
 func (filter *FilterMEDIR) FilterPointForBlock() *dia.FilterPoint {
 	return &dia.FilterPoint{
-		Asset: filter.asset,
-		Value: filter.value,
-		Name:  filter.filterName,
-		Time:  filter.currentTime,
+		Asset:    filter.asset,
+		Value:    filter.value,
+		Name:     filter.filterName,
+		Time:     filter.currentTime,
+		Excluded: filter.excluded,
 	}
 }
-func (filter *FilterMEDIR) save(ds models.Datastore) error {
+func (filter *FilterMEDIR) save(ds Datastore) error {
 	if filter.modified {
 		filter.modified = false
 		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
@@ -108,3 +154,13 @@ func (filter *FilterMEDIR) save(ds models.Datastore) error {
 		return nil
 	}
 }
+
+func (filter *FilterMEDIR) clone() Filter {
+	c := *filter
+	c.prices = append([]float64(nil), filter.prices...)
+	c.volumes = append([]float64(nil), filter.volumes...)
+	c.times = append([]time.Time(nil), filter.times...)
+	c.trades = append([]dia.ExcludedTrade(nil), filter.trades...)
+	c.excluded = append([]dia.ExcludedTrade(nil), filter.excluded...)
+	return &c
+}
@@ -0,0 +1,161 @@
+package filters
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	log "github.com/sirupsen/logrus"
+)
+
+// qualitySample is the part of a trade needed for the quality of filter points.
+type qualitySample struct {
+	time     time.Time
+	price    float64
+	volume   float64
+	exchange string
+}
+
+// ComputeQuality returns the quality at @t of a filter point computed from @trades, of which
+// @excluded were removed as outliers. @lastTrade is the time of the latest trade of the asset,
+// which is before all @trades if these are empty.
+func ComputeQuality(trades []dia.Trade, excluded int, lastTrade time.Time, t time.Time) dia.FilterPointQuality {
+	samples := make([]qualitySample, len(trades))
+	for i, trade := range trades {
+		samples[i] = newQualitySample(trade)
+	}
+	return computeQuality(samples, excluded, lastTrade, t)
+}
+
+func newQualitySample(trade dia.Trade) qualitySample {
+	return qualitySample{
+		time:     trade.Time,
+		price:    trade.EstimatedUSDPrice,
+		volume:   math.Abs(trade.Volume),
+		exchange: trade.Source,
+	}
+}
+
+func computeQuality(samples []qualitySample, excluded int, lastTrade time.Time, t time.Time) (quality dia.FilterPointQuality) {
+	quality.NumTrades = len(samples)
+	if !lastTrade.IsZero() && t.After(lastTrade) {
+		quality.SecondsSinceLastTrade = t.Sub(lastTrade).Seconds()
+	}
+	if len(samples) == 0 {
+		return
+	}
+
+	exchanges := make(map[string]struct{})
+	prices := make([]float64, len(samples))
+	var sum float64
+	for i, s := range samples {
+		exchanges[s.exchange] = struct{}{}
+		prices[i] = s.price
+		sum += s.price
+		quality.VolumeUSD += s.price * s.volume
+	}
+	quality.NumExchanges = len(exchanges)
+
+	mean := sum / float64(len(prices))
+	var squares float64
+	for _, price := range prices {
+		squares += (price - mean) * (price - mean)
+	}
+	quality.StdDev = math.Sqrt(squares / float64(len(prices)))
+	Q1, Q3 := computeQuartiles(prices)
+	quality.IQR = Q3 - Q1
+	quality.OutlierFraction = math.Min(float64(excluded)/float64(len(samples)), 1)
+	return
+}
+
+// qualityFilter computes the quality of the points of the filter it wraps from the trades
+// within the filter's window and saves it along with the filter's value.
+type qualityFilter struct {
+	Filter
+	filterName string
+	asset      dia.Asset
+	exchange   string
+	window     int
+	samples    []qualitySample
+	lastTrade  time.Time
+	modified   bool
+	quality    dia.FilterPointQuality
+}
+
+func newQualityFilter(filter Filter, config dia.FilterConfig, asset dia.Asset, exchange string) *qualityFilter {
+	return &qualityFilter{
+		Filter:     filter,
+		filterName: filterName(config),
+		asset:      asset,
+		exchange:   exchange,
+		window:     config.Window,
+	}
+}
+
+func (qf *qualityFilter) compute(trade dia.Trade) {
+	qf.Filter.compute(trade)
+	qf.samples = append(qf.samples, newQualitySample(trade))
+	if trade.Time.After(qf.lastTrade) {
+		qf.lastTrade = trade.Time
+	}
+	qf.modified = true
+}
+
+func (qf *qualityFilter) finalCompute(t time.Time) float64 {
+	value := qf.Filter.finalCompute(t)
+
+	windowStart := t.Add(-time.Duration(qf.window) * time.Second)
+	samples := qf.samples[:0]
+	for _, s := range qf.samples {
+		if s.time.After(windowStart) {
+			samples = append(samples, s)
+		}
+	}
+	qf.samples = samples
+
+	var excluded int
+	if of, ok := qf.Filter.(outlierFiltered); ok {
+		excluded = len(of.outliers())
+	}
+	qf.quality = computeQuality(qf.samples, excluded, qf.lastTrade, t)
+	return value
+}
+
+func (qf *qualityFilter) filterPointForBlock() *dia.FilterPoint {
+	fp := qf.Filter.filterPointForBlock()
+	if fp != nil {
+		quality := qf.quality
+		fp.Quality = &quality
+	}
+	return fp
+}
+
+// save saves the quality at the time of the last trade, which filters use as the time of their points.
+func (qf *qualityFilter) save(ds Datastore) error {
+	err := qf.Filter.save(ds)
+	if !qf.modified {
+		return err
+	}
+	qf.modified = false
+	qualityErr := ds.SetFilterQuality(qf.filterName, qf.asset, qf.exchange, qf.quality, qf.lastTrade)
+	if qualityErr != nil {
+		log.Errorln("FilterQuality: Error:", qualityErr)
+		if err == nil {
+			err = qualityErr
+		}
+	}
+	return err
+}
+
+func (qf *qualityFilter) clone() Filter {
+	c := *qf
+	c.Filter = qf.Filter.clone()
+	c.samples = append([]qualitySample(nil), qf.samples...)
+	return &c
+}
+
+// filterName returns the name of the points of the filter configured by @config, e.g. MAIR120.
+func filterName(config dia.FilterConfig) string {
+	return config.Filter + strconv.Itoa(config.Window)
+}
@@ -0,0 +1,104 @@
+package filters
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// qualityRecorder additionally keeps the qualities saved for filter points.
+type qualityRecorder struct {
+	*filterRecorder
+	qualities map[recordedPoint]dia.FilterPointQuality
+}
+
+func (qr *qualityRecorder) SetFilterQuality(filterName string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	qr.qualities[recordedPoint{filterName, getIdentifier(asset), exchange, t}] = quality
+	return nil
+}
+
+func TestComputeQuality(t *testing.T) {
+	begin := time.Unix(1654041600, 0)
+	trades := []dia.Trade{
+		{Source: dia.BinanceExchange, EstimatedUSDPrice: 100, Volume: 2, Time: begin},
+		{Source: dia.BinanceExchange, EstimatedUSDPrice: 102, Volume: -1, Time: begin.Add(10 * time.Second)},
+		{Source: dia.KrakenExchange, EstimatedUSDPrice: 104, Volume: 1, Time: begin.Add(20 * time.Second)},
+		{Source: dia.KrakenExchange, EstimatedUSDPrice: 106, Volume: 1, Time: begin.Add(30 * time.Second)},
+	}
+	quality := ComputeQuality(trades, 1, begin.Add(30*time.Second), begin.Add(120*time.Second))
+
+	if quality.NumTrades != 4 || quality.NumExchanges != 2 {
+		t.Errorf("got %d trades on %d exchanges, expected 4 on 2", quality.NumTrades, quality.NumExchanges)
+	}
+	if quality.VolumeUSD != 200+102+104+106 {
+		t.Errorf("got volume %v, expected 512", quality.VolumeUSD)
+	}
+	if math.Abs(quality.StdDev-math.Sqrt(5)) > 1e-9 {
+		t.Errorf("got standard deviation %v, expected %v", quality.StdDev, math.Sqrt(5))
+	}
+	if quality.IQR != 4 {
+		t.Errorf("got interquartile range %v, expected 4", quality.IQR)
+	}
+	if quality.OutlierFraction != 0.25 {
+		t.Errorf("got outlier fraction %v, expected 0.25", quality.OutlierFraction)
+	}
+	if quality.SecondsSinceLastTrade != 90 {
+		t.Errorf("got %v seconds since last trade, expected 90", quality.SecondsSinceLastTrade)
+	}
+}
+
+func TestFilterPointQuality(t *testing.T) {
+	qualityEnabled := QualityEnabled
+	QualityEnabled = true
+	defer func() { QualityEnabled = qualityEnabled }()
+	recorder := &qualityRecorder{filterRecorder: newFilterRecorder(), qualities: make(map[recordedPoint]dia.FilterPointQuality)}
+	chanFiltersBlock := make(chan *dia.FiltersBlock, 2)
+	s := &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: recorder, chanFiltersBlock: chanFiltersBlock}
+
+	begin := time.Unix(1654041600, 0)
+	tb := testTradesBlock(begin, 100, 101, 102)
+	s.processTradesBlock(tb)
+	s.processTradesBlock(testTradesBlock(begin.Add(dia.BlockSizeSeconds * time.Second)))
+
+	// The filter point of the second block is computed without trades.
+	for i, expected := range []struct {
+		numTrades             int
+		secondsSinceLastTrade float64
+	}{
+		{3, float64(dia.BlockSizeSeconds - 30)},
+		{0, float64(2*dia.BlockSizeSeconds - 30)},
+	} {
+		fb := <-chanFiltersBlock
+		var found bool
+		for _, fp := range fb.FiltersBlockData.FilterPoints {
+			if fp.Name != dia.FilterKing {
+				continue
+			}
+			found = true
+			if fp.Quality == nil {
+				t.Fatalf("block %d: no quality", i)
+			}
+			if fp.Quality.NumTrades != expected.numTrades || fp.Quality.SecondsSinceLastTrade != expected.secondsSinceLastTrade {
+				t.Errorf("block %d: got %d trades and %v seconds since last trade, expected %d and %v",
+					i, fp.Quality.NumTrades, fp.Quality.SecondsSinceLastTrade, expected.numTrades, expected.secondsSinceLastTrade)
+			}
+		}
+		if !found {
+			t.Errorf("block %d: no point of %s", i, dia.FilterKing)
+		}
+	}
+
+	// The quality is saved along with the value of each filter point.
+	lastTrade := tb.TradesBlockData.Trades[2].Time
+	for _, name := range []string{"MA120", "MAIR120", "MEDIR120", "VOL120", "COUNT120"} {
+		point := recordedPoint{name, getIdentifier(tb.TradesBlockData.Trades[0].QuoteToken), "", lastTrade}
+		if _, ok := recorder.values[point]; !ok {
+			t.Errorf("%s: no value saved", name)
+		}
+		if quality, ok := recorder.qualities[point]; !ok || quality.NumTrades != 3 {
+			t.Errorf("%s: got quality %v, expected 3 trades", name, quality)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package filters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/configCollectors"
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/diadata-org/diadata/pkg/utils"
+)
+
+const (
+	// Sources of filter configurations selected by the env var FILTER_CONFIG_SOURCE.
+	ConfigSourceDefault  = "default"
+	ConfigSourceFile     = "file"
+	ConfigSourcePostgres = "postgres"
+
+	defaultFilterConfigFilename = "filters"
+)
+
+// FilterConstructor returns a new filter for @asset on @exchange, where an empty @exchange stands for
+// all exchanges. @beginTime is the begin time of the tradesBlock in which the asset is first traded
+// and @window is the size of the filter's window in seconds.
+type FilterConstructor func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter
+
+var filterConstructors = make(map[string]FilterConstructor)
+
+// RegisterFilter makes the filter type @filterType available for filter configurations.
+// It is called from the init function of each filter's file.
+func RegisterFilter(filterType string, constructor FilterConstructor) {
+	if _, ok := filterConstructors[filterType]; ok {
+		panic("filters: filter type registered twice: " + filterType)
+	}
+	filterConstructors[filterType] = constructor
+}
+
+// RegisteredFilters returns the sorted types of all registered filters.
+func RegisteredFilters() (filterTypes []string) {
+	for filterType := range filterConstructors {
+		filterTypes = append(filterTypes, filterType)
+	}
+	sort.Strings(filterTypes)
+	return
+}
+
+// DefaultFilterConfigs returns the filters computed for all assets if no other configuration is given.
+// Each filter is computed over all Windows.
+func DefaultFilterConfigs() (configs []dia.FilterConfig) {
+	for _, window := range Windows {
+		for _, filterType := range []string{"MA", "VOL", "MAIR", "MEDIR", "COUNT"} {
+			configs = append(configs, dia.FilterConfig{Filter: filterType, Window: window})
+		}
+	}
+	return
+}
+
+// LoadFilterConfigs returns the filter configurations from the source given by the env var
+// FILTER_CONFIG_SOURCE, i.e. the defaults, the json file FILTER_CONFIG_FILE in the config
+// folder or the postgres table filterconfig.
+func LoadFilterConfigs(relDB models.FilterConfigStore) ([]dia.FilterConfig, error) {
+	source := utils.Getenv("FILTER_CONFIG_SOURCE", ConfigSourceDefault)
+	switch source {
+	case ConfigSourceDefault:
+		return DefaultFilterConfigs(), nil
+	case ConfigSourceFile:
+		return ReadFilterConfigsFromConfig(utils.Getenv("FILTER_CONFIG_FILE", defaultFilterConfigFilename))
+	case ConfigSourcePostgres:
+		if relDB == nil {
+			return nil, errors.New("no postgres connection for filter configurations")
+		}
+		return relDB.GetFilterConfigs()
+	}
+	return nil, errors.New("unknown source for filter configurations: " + source)
+}
+
+// ReadFilterConfigsFromConfig returns the filter configurations from the json file @filename in the config folder.
+func ReadFilterConfigsFromConfig(filename string) ([]dia.FilterConfig, error) {
+	content, err := configCollectors.ReadJSONFromConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Filters []dia.FilterConfig `json:"Filters"`
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.Filters, nil
+}
+
+// FilterSets decides which filters are computed for an asset on an exchange.
+// Only the configurations of the most specific matching selector are used, i.e. configurations
+// for an asset on an exchange replace those for the asset, which replace those for the exchange,
+// which replace those matching all assets.
+type FilterSets struct {
+	configs    []dia.FilterConfig
+	liquidity  LiquiditySource
+	aggregator *Aggregator
+}
+
+// NewFilterSets returns FilterSets for @configs. It fails if a configuration refers to a filter
+// type which is not registered or has no positive window.
+func NewFilterSets(configs []dia.FilterConfig) (*FilterSets, error) {
+	for _, c := range configs {
+		if _, ok := filterConstructors[c.Filter]; !ok {
+			return nil, fmt.Errorf("unknown filter type %s. registered filters: %v", c.Filter, RegisteredFilters())
+		}
+		if c.Window <= 0 {
+			return nil, fmt.Errorf("filter %s needs a positive window", c.Filter)
+		}
+		if c.OutlierDetector != "" {
+			if _, err := NewOutlierDetector(c.OutlierDetector, c.OutlierThreshold); err != nil {
+				return nil, err
+			}
+			if _, ok := filterConstructors[c.Filter](dia.Asset{}, "", time.Time{}, c.Window).(outlierFiltered); !ok {
+				return nil, fmt.Errorf("filter %s does not remove outliers", c.Filter)
+			}
+		}
+	}
+	return &FilterSets{configs: configs}, nil
+}
+
+// SetLiquiditySource sets the pool liquidities used by liquidity weighted filters such as LWVWAP.
+// Without it, these filters ignore all trades.
+func (fs *FilterSets) SetLiquiditySource(liquidity LiquiditySource) {
+	fs.liquidity = liquidity
+}
+
+// SetAggregator makes the price filters across all exchanges aggregate the values of the same filters
+// on single exchanges for the assets configured in @aggregator.
+func (fs *FilterSets) SetAggregator(aggregator *Aggregator) {
+	fs.aggregator = aggregator
+}
+
+// aggregation returns the Aggregator of @fs or nil, if filters are computed from all trades.
+func (fs *FilterSets) aggregation() *Aggregator {
+	if fs == nil {
+		return nil
+	}
+	return fs.aggregator
+}
+
+// Filters returns new instances of all filters configured for @asset on @exchange.
+func (fs *FilterSets) Filters(asset dia.Asset, exchange string, beginTime time.Time) (filters []Filter) {
+	for _, c := range fs.Configs(asset, exchange) {
+		filters = append(filters, fs.newFilter(c, asset, exchange, beginTime))
+	}
+	return
+}
+
+// filtersWithQuality returns the filters for @asset on @exchange as Filters, each of which computes
+// the quality of its points. Filters without points of their own, such as TLT, are not wrapped.
+func (fs *FilterSets) filtersWithQuality(asset dia.Asset, exchange string, beginTime time.Time) (filters []Filter) {
+	for _, c := range fs.Configs(asset, exchange) {
+		filter := fs.newFilter(c, asset, exchange, beginTime)
+		if _, ok := filter.(*FilterTLT); !ok {
+			filter = newQualityFilter(filter, c, asset, exchange)
+		}
+		filters = append(filters, filter)
+	}
+	return
+}
+
+func (fs *FilterSets) newFilter(c dia.FilterConfig, asset dia.Asset, exchange string, beginTime time.Time) Filter {
+	filter := filterConstructors[c.Filter](asset, exchange, beginTime, c.Window)
+	if lw, ok := filter.(liquidityWeighted); ok && fs != nil {
+		lw.setLiquiditySource(fs.liquidity)
+	}
+	if of, ok := filter.(outlierFiltered); ok && c.OutlierDetector != "" {
+		// Configurations are validated in NewFilterSets.
+		detector, _ := NewOutlierDetector(c.OutlierDetector, c.OutlierThreshold)
+		of.setOutlierDetector(detector)
+	}
+	if agg := fs.aggregation(); agg != nil {
+		filter = agg.wrap(filter, c, asset, exchange)
+	}
+	return filter
+}
+
+// Configs returns the configurations applying to @asset on @exchange without duplicates.
+// A nil FilterSets applies DefaultFilterConfigs to all assets.
+func (fs *FilterSets) Configs(asset dia.Asset, exchange string) (configs []dia.FilterConfig) {
+	if fs == nil {
+		return DefaultFilterConfigs()
+	}
+	best := -1
+	for _, c := range fs.configs {
+		if configMatches(c, asset, exchange) && configSpecificity(c) > best {
+			best = configSpecificity(c)
+		}
+	}
+	type filterWindow struct {
+		filter string
+		window int
+	}
+	seen := make(map[filterWindow]bool)
+	for _, c := range fs.configs {
+		if !configMatches(c, asset, exchange) || configSpecificity(c) != best {
+			continue
+		}
+		fw := filterWindow{filter: c.Filter, window: c.Window}
+		if !seen[fw] {
+			seen[fw] = true
+			configs = append(configs, c)
+		}
+	}
+	return
+}
+
+func configMatches(c dia.FilterConfig, asset dia.Asset, exchange string) bool {
+	return (c.Exchange == "" || c.Exchange == exchange) &&
+		(c.Blockchain == "" || c.Blockchain == asset.Blockchain) &&
+		(c.Address == "" || c.Address == asset.Address)
+}
+
+// configSpecificity ranks selectors by asset first and exchange second.
+func configSpecificity(c dia.FilterConfig) (specificity int) {
+	if c.Blockchain != "" || c.Address != "" {
+		specificity += 2
+	}
+	if c.Exchange != "" {
+		specificity++
+	}
+	return
+}
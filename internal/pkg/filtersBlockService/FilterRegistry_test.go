@@ -0,0 +1,82 @@
+package filters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func TestFilterSets(t *testing.T) {
+	eth := dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	thin := dia.Asset{Symbol: "THIN", Address: "0x1111111111111111111111111111111111111111", Blockchain: dia.ETHEREUM}
+	fs, err := NewFilterSets([]dia.FilterConfig{
+		{Filter: "MA", Window: 120},
+		{Filter: "MAIR", Window: 120},
+		{Exchange: dia.UniswapExchange, Filter: "VOL", Window: 120},
+		{Blockchain: thin.Blockchain, Address: thin.Address, Filter: "VWAPIR", Window: 120},
+		{Blockchain: thin.Blockchain, Address: thin.Address, Filter: "VWAPIR", Window: 120},
+		{Blockchain: thin.Blockchain, Address: thin.Address, Filter: "VWAPIR", Window: 600},
+		{Blockchain: thin.Blockchain, Address: thin.Address, Exchange: dia.UniswapExchange, Filter: "TLT", Window: 120},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		asset    dia.Asset
+		exchange string
+		expected []string
+	}{
+		{eth, "", []string{"MA", "MAIR"}},
+		{eth, dia.BinanceExchange, []string{"MA", "MAIR"}},
+		{eth, dia.UniswapExchange, []string{"VOL"}},
+		{thin, "", []string{"VWAPIR", "VWAPIR"}},
+		{thin, dia.UniswapExchange, []string{"TLT"}},
+	}
+	for i, c := range cases {
+		configs := fs.Configs(c.asset, c.exchange)
+		if len(configs) != len(c.expected) {
+			t.Errorf("case %d: got %+v, expected %v", i, configs, c.expected)
+			continue
+		}
+		for j, config := range configs {
+			if config.Filter != c.expected[j] {
+				t.Errorf("case %d: got %+v, expected %v", i, configs, c.expected)
+			}
+		}
+	}
+
+	if _, err = NewFilterSets([]dia.FilterConfig{{Filter: "UNKNOWN", Window: 120}}); err == nil {
+		t.Error("expected error for unknown filter type")
+	}
+	if _, err = NewFilterSets([]dia.FilterConfig{{Filter: "MA"}}); err == nil {
+		t.Error("expected error for missing window")
+	}
+}
+
+func TestRegisteredFiltersInService(t *testing.T) {
+	var configs []dia.FilterConfig
+	for _, filterType := range RegisteredFilters() {
+		configs = append(configs, dia.FilterConfig{Filter: filterType, Window: dia.BlockSizeSeconds})
+	}
+	fs, err := NewFilterSets(configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := newFilterRecorder()
+	s := &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: recorder, filterSets: fs, graceBlocks: 1}
+	begin := time.Unix(1654041600, 0)
+	s.processTradesBlock(testTradesBlock(begin, 100, 101, 102))
+	s.processTradesBlock(testTradesBlock(begin.Add(dia.BlockSizeSeconds*time.Second), 103, 104))
+
+	saved := make(map[string]bool)
+	for p := range recorder.values {
+		saved[p.filter] = true
+	}
+	for _, name := range []string{"MA120", "MAIR120", "MEDIR120", "EMA120", "VWAP120", "VWAPIR120"} {
+		if !saved[name] {
+			t.Errorf("no filter points saved for %s", name)
+		}
+	}
+}
@@ -6,10 +6,15 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("TLT", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterTLT(asset, exchange)
+	})
+}
+
 type FilterTLT struct {
 	asset         dia.Asset
 	exchange      string
@@ -32,7 +37,10 @@ func (s *FilterTLT) compute(trade dia.Trade) {
 	s.lastTradeTime = trade.Time
 }
 
-func (s *FilterTLT) save(ds models.Datastore) error {
+func (s *FilterTLT) save(ds Datastore) error {
+	if s.lastTradeTime.IsZero() {
+		return nil
+	}
 	err := ds.SetLastTradeTimeForExchange(s.asset, s.exchange, s.lastTradeTime)
 	if err != nil {
 		log.Errorln("FilterTLT Error:", err)
@@ -43,3 +51,8 @@ func (s *FilterTLT) save(ds models.Datastore) error {
 func (s *FilterTLT) finalCompute(time time.Time) float64 {
 	return 0.0
 }
+
+func (s *FilterTLT) clone() Filter {
+	c := *s
+	return &c
+}
@@ -0,0 +1,137 @@
+package filters
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	RegisterFilter("TWAP", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterTWAP(asset, exchange, beginTime, window)
+	})
+}
+
+// FilterTWAP implements a time weighted average price. Each trade's price is weighted
+// by the time until the next trade, or until the end of the window for the last trade.
+// In contrast to volume weighted filters, a single large trade cannot move the price
+// for longer than it remains the latest trade.
+type FilterTWAP struct {
+	asset       dia.Asset
+	exchange    string
+	currentTime time.Time
+	// prices and times are in ascending order of time. The first sample may be from before
+	// the window, as its price holds until the next trade.
+	prices     []float64
+	times      []time.Time
+	lastTrade  dia.Trade
+	param      int
+	value      float64
+	filterName string
+	modified   bool
+}
+
+// NewFilterTWAP returns a time weighted average price filter over a window of @param seconds.
+func NewFilterTWAP(asset dia.Asset, exchange string, currentTime time.Time, param int) *FilterTWAP {
+	return &FilterTWAP{
+		asset:       asset,
+		exchange:    exchange,
+		currentTime: currentTime,
+		param:       param,
+		filterName:  "TWAP" + strconv.Itoa(param),
+	}
+}
+
+func (filter *FilterTWAP) Compute(trade dia.Trade) {
+	filter.compute(trade)
+}
+
+func (filter *FilterTWAP) compute(trade dia.Trade) {
+	if filter.lastTrade != (dia.Trade{}) && trade.Time.Before(filter.currentTime) {
+		log.Errorln("FilterTWAP: Ignoring Trade out of order ", filter.currentTime, trade.Time)
+		return
+	}
+	filter.modified = true
+	filter.prices = append(filter.prices, trade.EstimatedUSDPrice)
+	filter.times = append(filter.times, trade.Time)
+	filter.lastTrade = trade
+	filter.currentTime = trade.Time
+}
+
+func (filter *FilterTWAP) FinalCompute(t time.Time) float64 {
+	return filter.finalCompute(t)
+}
+
+// finalCompute computes the average over the window ending at @t.
+func (filter *FilterTWAP) finalCompute(t time.Time) float64 {
+	if len(filter.prices) == 0 {
+		return filter.value
+	}
+	windowStart := t.Add(-time.Duration(filter.param) * time.Second)
+	// Keep the last sample before the window, as its price holds at the window's start.
+	first := 0
+	for first < len(filter.times)-1 && !filter.times[first+1].After(windowStart) {
+		first++
+	}
+	filter.prices = filter.prices[first:]
+	filter.times = filter.times[first:]
+
+	var weightedPrice, totalDuration float64
+	for i, price := range filter.prices {
+		start := filter.times[i]
+		if start.Before(windowStart) {
+			start = windowStart
+		}
+		end := t
+		if i < len(filter.times)-1 {
+			end = filter.times[i+1]
+		}
+		if duration := end.Sub(start).Seconds(); duration > 0 {
+			weightedPrice += price * duration
+			totalDuration += duration
+		}
+	}
+	if totalDuration > 0 {
+		filter.value = weightedPrice / totalDuration
+	} else {
+		filter.value = filter.prices[len(filter.prices)-1]
+	}
+	return filter.value
+}
+
+func (filter *FilterTWAP) FilterPointForBlock() *dia.FilterPoint {
+	return &dia.FilterPoint{
+		Asset: filter.asset,
+		Value: filter.value,
+		Name:  filter.filterName,
+		Time:  filter.currentTime,
+	}
+}
+
+func (filter *FilterTWAP) filterPointForBlock() *dia.FilterPoint {
+	if filter.exchange != "" || filter.filterName != dia.FilterKing {
+		return nil
+	}
+	return filter.FilterPointForBlock()
+}
+
+func (filter *FilterTWAP) save(ds Datastore) error {
+	if filter.modified {
+		filter.modified = false
+		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
+		if err != nil {
+			log.Errorln("FilterTWAP: Error:", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (filter *FilterTWAP) clone() Filter {
+	c := *filter
+	c.prices = append([]float64(nil), filter.prices...)
+	c.times = append([]time.Time(nil), filter.times...)
+	return &c
+}
@@ -0,0 +1,42 @@
+package filters
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func TestTWAP(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	twapFilter := NewFilterTWAP(dia.Asset{}, "", start, 60)
+
+	// 100 holds from before the window until second 20, 200 until 50 and 110 until the end.
+	twapFilter.Compute(dia.Trade{EstimatedUSDPrice: 100, Volume: 1, Time: start.Add(-30 * time.Second)})
+	twapFilter.Compute(dia.Trade{EstimatedUSDPrice: 200, Volume: 1000, Time: start.Add(20 * time.Second)})
+	twapFilter.Compute(dia.Trade{EstimatedUSDPrice: 110, Volume: 1, Time: start.Add(50 * time.Second)})
+	value := twapFilter.FinalCompute(start.Add(60 * time.Second))
+
+	expected := (100*20 + 200*30 + 110*10) / 60.0
+	if math.Abs(value-expected) > 1e-9 {
+		t.Errorf("TWAP expected %v and got %v", expected, value)
+	}
+
+	// The next window only holds 110 and later trades.
+	twapFilter.Compute(dia.Trade{EstimatedUSDPrice: 120, Volume: 1, Time: start.Add(90 * time.Second)})
+	value = twapFilter.FinalCompute(start.Add(120 * time.Second))
+	expected = (110*30 + 120*30) / 60.0
+	if math.Abs(value-expected) > 1e-9 {
+		t.Errorf("TWAP expected %v and got %v", expected, value)
+	}
+}
+
+func TestTWAPSingleTradeAtEnd(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	twapFilter := NewFilterTWAP(dia.Asset{}, "", start, 60)
+	twapFilter.Compute(dia.Trade{EstimatedUSDPrice: 42, Volume: 1, Time: start.Add(60 * time.Second)})
+	if value := twapFilter.FinalCompute(start.Add(60 * time.Second)); value != 42 {
+		t.Errorf("TWAP expected 42 and got %v", value)
+	}
+}
@@ -6,15 +6,21 @@ import (
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("VOL", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterVOL(asset, exchange, window)
+	})
+}
+
 type FilterVOL struct {
 	asset       dia.Asset
 	exchange    string
 	currentTime time.Time
 	volumeUSD   float64
+	blocks      []blockSum
 	value       float64
 	filterName  string
 	memory      int
@@ -35,8 +41,8 @@ func NewFilterVOL(asset dia.Asset, exchange string, memory int) *FilterVOL {
 func (filter *FilterVOL) Compute(trade dia.Trade) {
 	filter.compute(trade)
 }
-func (filter *FilterVOL) FinalCompute(t time.Time) {
-	filter.finalCompute(t)
+func (filter *FilterVOL) FinalCompute(t time.Time) float64 {
+	return filter.finalCompute(t)
 }
 
 func (filter *FilterVOL) compute(trade dia.Trade) {
@@ -45,8 +51,9 @@ func (filter *FilterVOL) compute(trade dia.Trade) {
 	filter.currentTime = trade.Time
 }
 
-func (filter *FilterVOL) finalCompute(time time.Time) float64 {
-	filter.value = filter.volumeUSD
+func (filter *FilterVOL) finalCompute(t time.Time) float64 {
+	filter.blocks = appendBlockSum(filter.blocks, blockSum{end: t, value: filter.volumeUSD}, filter.memory)
+	filter.value = sumBlocks(filter.blocks)
 	filter.volumeUSD = 0.0
 	return filter.value
 }
@@ -64,7 +71,7 @@ func (filter *FilterVOL) FilterPointForBlock() *dia.FilterPoint {
 	}
 }
 
-func (filter *FilterVOL) save(ds models.Datastore) error {
+func (filter *FilterVOL) save(ds Datastore) error {
 	if filter.modified {
 		filter.modified = false
 		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
@@ -75,3 +82,9 @@ func (filter *FilterVOL) save(ds models.Datastore) error {
 	}
 	return nil
 }
+
+func (filter *FilterVOL) clone() Filter {
+	c := *filter
+	c.blocks = append([]blockSum(nil), filter.blocks...)
+	return &c
+}
@@ -9,6 +9,12 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("VWAP", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterVWAP(asset, exchange, beginTime, window)
+	})
+}
+
 // FilterVWAP ...
 type FilterVWAP struct {
 	asset       dia.Asset
@@ -16,6 +22,7 @@ type FilterVWAP struct {
 	currentTime time.Time
 	prices      []float64
 	volumes     []float64
+	times       []time.Time
 	lastTrade   dia.Trade
 	param       int
 	value       float64
@@ -30,6 +37,7 @@ func NewFilterVWAP(asset dia.Asset, exchange string, currentTime time.Time, para
 		exchange:    exchange,
 		prices:      []float64{},
 		volumes:     []float64{},
+		times:       []time.Time{},
 		currentTime: currentTime,
 		param:       param,
 		filterName:  "VWAP" + strconv.Itoa(param),
@@ -61,9 +69,19 @@ func (filter *FilterVWAP) fill(trade dia.Trade) {
 	filter.currentTime = trade.Time
 }
 
+// processDataPoint adds a trade and removes all trades older than the filter's window of @param seconds.
 func (filter *FilterVWAP) processDataPoint(trade dia.Trade) {
 	filter.prices = append([]float64{trade.EstimatedUSDPrice}, filter.prices...)
 	filter.volumes = append([]float64{trade.Volume}, filter.volumes...)
+	filter.times = append([]time.Time{trade.Time}, filter.times...)
+	windowStart := trade.Time.Add(-time.Duration(filter.param) * time.Second)
+	last := len(filter.times)
+	for last > 1 && !filter.times[last-1].After(windowStart) {
+		last--
+	}
+	filter.prices = filter.prices[:last]
+	filter.volumes = filter.volumes[:last]
+	filter.times = filter.times[:last]
 }
 
 // FinalCompute ...
@@ -101,22 +119,37 @@ func (s *FilterVWAP) finalCompute(t time.Time) float64 {
 
 // FilterPointForBlock ...
 func (s *FilterVWAP) FilterPointForBlock() *dia.FilterPoint {
-	return s.filterPointForBlock()
+	return &dia.FilterPoint{
+		Value: s.value,
+		Name:  s.filterName,
+		Time:  s.currentTime,
+		Asset: s.asset,
+	}
 }
+
 func (s *FilterVWAP) filterPointForBlock() *dia.FilterPoint {
-	if s.exchange != "" {
-		return &dia.FilterPoint{
-			Value: s.value,
-			Name:  "VWAP" + strconv.Itoa(s.param),
-			Time:  s.currentTime,
-			Asset: s.asset,
-		}
-	} else {
-		return &dia.FilterPoint{
-			Value: s.value,
-			Name:  "VWAP" + strconv.Itoa(s.param),
-			Time:  s.currentTime,
-			Asset: s.asset,
+	if s.exchange != "" || s.filterName != dia.FilterKing {
+		return nil
+	}
+	return s.FilterPointForBlock()
+}
+
+func (s *FilterVWAP) save(ds Datastore) error {
+	if s.modified {
+		s.modified = false
+		err := ds.SetFilter(s.filterName, s.asset, s.exchange, s.value, s.currentTime)
+		if err != nil {
+			log.Errorln("FilterVWAP: Error:", err)
 		}
+		return err
 	}
+	return nil
+}
+
+func (s *FilterVWAP) clone() Filter {
+	c := *s
+	c.prices = append([]float64(nil), s.prices...)
+	c.volumes = append([]float64(nil), s.volumes...)
+	c.times = append([]time.Time(nil), s.times...)
+	return &c
 }
@@ -9,18 +9,28 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+func init() {
+	RegisterFilter("VWAPIR", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterVWAPIR(asset, exchange, beginTime, window)
+	})
+}
+
 // FilterVWAP ...
 type FilterVWAPIR struct {
 	exchange    string
 	currentTime time.Time
 	prices      []float64
 	volumes     []float64
+	times       []time.Time
+	trades      []dia.ExcludedTrade
 	lastTrade   dia.Trade
 	param       int
 	value       float64
 	modified    bool
 	filterName  string
 	asset       dia.Asset
+	detector    OutlierDetector
+	excluded    []dia.ExcludedTrade
 }
 
 // NewFilterVWAP ...
@@ -30,13 +40,23 @@ func NewFilterVWAPIR(asset dia.Asset, exchange string, currentTime time.Time, pa
 		exchange:    exchange,
 		prices:      []float64{},
 		volumes:     []float64{},
+		times:       []time.Time{},
 		currentTime: currentTime,
 		param:       param,
-		filterName:  "VWAP" + strconv.Itoa(param),
+		filterName:  "VWAPIR" + strconv.Itoa(param),
+		detector:    defaultOutlierDetector(),
 	}
 	return s
 }
 
+func (s *FilterVWAPIR) setOutlierDetector(detector OutlierDetector) {
+	s.detector = detector
+}
+
+func (s *FilterVWAPIR) outliers() []dia.ExcludedTrade {
+	return s.excluded
+}
+
 // Compute ...
 func (s *FilterVWAPIR) Compute(trade dia.Trade) {
 	s.compute(trade)
@@ -61,9 +81,21 @@ func (filter *FilterVWAPIR) fill(trade dia.Trade) {
 	filter.currentTime = trade.Time
 }
 
+// processDataPoint adds a trade and removes all trades older than the filter's window of @param seconds.
 func (filter *FilterVWAPIR) processDataPoint(trade dia.Trade) {
 	filter.prices = append([]float64{trade.EstimatedUSDPrice}, filter.prices...)
 	filter.volumes = append([]float64{trade.Volume}, filter.volumes...)
+	filter.times = append([]time.Time{trade.Time}, filter.times...)
+	filter.trades = append([]dia.ExcludedTrade{excludedTrade(trade)}, filter.trades...)
+	windowStart := trade.Time.Add(-time.Duration(filter.param) * time.Second)
+	last := len(filter.times)
+	for last > 1 && !filter.times[last-1].After(windowStart) {
+		last--
+	}
+	filter.prices = filter.prices[:last]
+	filter.volumes = filter.volumes[:last]
+	filter.times = filter.times[:last]
+	filter.trades = filter.trades[:last]
 }
 
 // FinalCompute ...
@@ -76,18 +108,11 @@ func (s *FilterVWAPIR) finalCompute(t time.Time) float64 {
 		return 0.0
 	}
 
-	// s.processDataPoint(*s.lastTrade)
-	cleanPrices, bounds := removeOutliers(s.prices)
+	cleanPrices, cleanedVolumes, outliers := removeOutliersWith(s.detector, s.prices, s.volumes)
+	s.excluded = excludedTrades(s.trades, outliers)
 
 	priceVolume := []float64{}
 
-	// TODO handle bounds
-	if len(bounds) == 0 {
-		return 0.0
-	}
-
-	cleanedVolumes := s.volumes[bounds[0]:bounds[1]]
-
 	for index, price := range cleanPrices {
 		priceVolume = append(priceVolume, price*math.Abs(cleanedVolumes[index]))
 	}
@@ -103,29 +128,49 @@ func (s *FilterVWAPIR) finalCompute(t time.Time) float64 {
 		total += v
 	}
 
-	s.value = total / totalVolume
+	if totalVolume > 0 {
+		s.value = total / totalVolume
+	}
 
 	return s.value
 }
 
 // FilterPointForBlock ...
 func (s *FilterVWAPIR) FilterPointForBlock() *dia.FilterPoint {
-	return s.filterPointForBlock()
+	return &dia.FilterPoint{
+		Value:    s.value,
+		Name:     s.filterName,
+		Time:     s.currentTime,
+		Asset:    s.asset,
+		Excluded: s.excluded,
+	}
 }
+
 func (s *FilterVWAPIR) filterPointForBlock() *dia.FilterPoint {
-	if s.exchange != "" {
-		return &dia.FilterPoint{
-			Value: s.value,
-			Name:  s.filterName,
-			Time:  s.currentTime,
-			Asset: s.asset,
-		}
-	} else {
-		return &dia.FilterPoint{
-			Value: s.value,
-			Name:  s.filterName,
-			Time:  s.currentTime,
-			Asset: s.asset,
+	if s.exchange != "" || s.filterName != dia.FilterKing {
+		return nil
+	}
+	return s.FilterPointForBlock()
+}
+
+func (s *FilterVWAPIR) save(ds Datastore) error {
+	if s.modified {
+		s.modified = false
+		err := ds.SetFilter(s.filterName, s.asset, s.exchange, s.value, s.currentTime)
+		if err != nil {
+			log.Errorln("FilterVWAPIR: Error:", err)
 		}
+		return err
 	}
+	return nil
+}
+
+func (s *FilterVWAPIR) clone() Filter {
+	c := *s
+	c.prices = append([]float64(nil), s.prices...)
+	c.volumes = append([]float64(nil), s.volumes...)
+	c.times = append([]time.Time(nil), s.times...)
+	c.trades = append([]dia.ExcludedTrade(nil), s.trades...)
+	c.excluded = append([]dia.ExcludedTrade(nil), s.excluded...)
+	return &c
 }
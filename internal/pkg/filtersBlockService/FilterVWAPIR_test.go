@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"math"
 	"testing"
 
 	"github.com/diadata-org/diadata/pkg/dia"
@@ -11,17 +12,29 @@ func TestVWAPIR(t *testing.T) {
 	trades := getTrades()
 	maFilter := NewFilterVWAPIR(dia.Asset{}, "Binance", trades[len(trades)-1].Time, dia.BlockSizeSeconds)
 
+	// None of the three trades is an outlier, so each price is weighted by its own trade's volume as in VWAP.
+	totalVolume := 0.0
+	totalPrice := 0.0
 	for _, trade := range trades {
-
+		totalVolume = totalVolume + math.Abs(trade.Volume)
+		totalPrice = totalPrice + (trade.EstimatedUSDPrice * math.Abs(trade.Volume))
 		maFilter.Compute(trade)
 	}
+	expectedAns := totalPrice / totalVolume
 
 	maFilter.FinalCompute(trades[0].Time)
 	fp := maFilter.FilterPointForBlock()
 	filterPoints = append(filterPoints, *fp)
 
-	if filterPoints[0].Value != 39873.659462014075 {
-		t.Errorf("Error vwap expected %v  and got %v ", 39873.659462014075, filterPoints[0].Value)
+	// Before prices and volumes were kept aligned, the sorted prices were weighted with the volumes in trade order.
+	if filterPoints[0].Value == 39873.659462014075 {
+		t.Errorf("Error vwap got value %v of misaligned volumes", filterPoints[0].Value)
+	}
+	if math.Abs(filterPoints[0].Value-expectedAns) > 1e-9 {
+		t.Errorf("Error vwap expected %v  and got %v ", expectedAns, filterPoints[0].Value)
+	}
+	if len(filterPoints[0].Excluded) != 0 {
+		t.Errorf("Error vwap expected no excluded trades and got %v ", filterPoints[0].Excluded)
 	}
 
 }
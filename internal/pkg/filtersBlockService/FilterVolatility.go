@@ -0,0 +1,238 @@
+package filters
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// volatilitySampleSeconds is the length of the intervals into which the trades of a
+	// volatility filter's window are divided. Intervals are aligned to full minutes.
+	volatilitySampleSeconds = 60
+	secondsPerYear          = 365 * 24 * 60 * 60
+)
+
+func init() {
+	RegisterFilter("RVOL", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterRVOL(asset, exchange, beginTime, window)
+	})
+	RegisterFilter("PVOL", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterPVOL(asset, exchange, beginTime, window)
+	})
+	RegisterFilter("RVAR", func(asset dia.Asset, exchange string, beginTime time.Time, window int) Filter {
+		return NewFilterRVAR(asset, exchange, beginTime, window)
+	})
+}
+
+// priceInterval holds the prices of the trades in one sample interval.
+type priceInterval struct {
+	open  float64
+	high  float64
+	low   float64
+	close float64
+}
+
+// volatilityEstimator returns the volatility estimated from the non-empty @intervals of a window
+// of @window seconds in ascending order of time. It returns false if there are too few intervals.
+type volatilityEstimator func(intervals []priceInterval, window int) (float64, bool)
+
+// FilterVolatility estimates the volatility of an asset's price over a window from its trades.
+// The window is divided into intervals of one minute and the volatility is estimated from the
+// prices in the intervals with trades, so that the bouncing of prices between exchanges within
+// an interval does not add to the volatility.
+type FilterVolatility struct {
+	asset       dia.Asset
+	exchange    string
+	currentTime time.Time
+	// prices and times are in ascending order of time.
+	prices     []float64
+	times      []time.Time
+	lastTrade  dia.Trade
+	param      int
+	value      float64
+	filterName string
+	modified   bool
+	estimator  volatilityEstimator
+}
+
+// NewFilterRVOL returns a filter for the annualized realized volatility over a window of @param seconds,
+// i.e. the square root of the sum of the squared log returns between the closing prices of consecutive
+// intervals, scaled to a year.
+func NewFilterRVOL(asset dia.Asset, exchange string, currentTime time.Time, param int) *FilterVolatility {
+	return newFilterVolatility("RVOL", realizedVolatility, asset, exchange, currentTime, param)
+}
+
+// NewFilterPVOL returns a filter for the annualized Parkinson volatility over a window of @param seconds,
+// which is estimated from the high and low prices of each interval.
+func NewFilterPVOL(asset dia.Asset, exchange string, currentTime time.Time, param int) *FilterVolatility {
+	return newFilterVolatility("PVOL", parkinsonVolatility, asset, exchange, currentTime, param)
+}
+
+// NewFilterRVAR returns a filter for the sample variance of the log returns between the closing prices
+// of consecutive intervals over a window of @param seconds. The variance is not annualized.
+func NewFilterRVAR(asset dia.Asset, exchange string, currentTime time.Time, param int) *FilterVolatility {
+	return newFilterVolatility("RVAR", returnVariance, asset, exchange, currentTime, param)
+}
+
+func newFilterVolatility(filterType string, estimator volatilityEstimator, asset dia.Asset, exchange string, currentTime time.Time, param int) *FilterVolatility {
+	return &FilterVolatility{
+		asset:       asset,
+		exchange:    exchange,
+		currentTime: currentTime,
+		param:       param,
+		filterName:  filterType + strconv.Itoa(param),
+		estimator:   estimator,
+	}
+}
+
+func (filter *FilterVolatility) Compute(trade dia.Trade) {
+	filter.compute(trade)
+}
+
+func (filter *FilterVolatility) compute(trade dia.Trade) {
+	if filter.lastTrade != (dia.Trade{}) && trade.Time.Before(filter.currentTime) {
+		log.Errorln("FilterVolatility: Ignoring Trade out of order ", filter.currentTime, trade.Time)
+		return
+	}
+	if trade.EstimatedUSDPrice <= 0 {
+		return
+	}
+	filter.modified = true
+	filter.prices = append(filter.prices, trade.EstimatedUSDPrice)
+	filter.times = append(filter.times, trade.Time)
+	filter.lastTrade = trade
+	filter.currentTime = trade.Time
+}
+
+func (filter *FilterVolatility) FinalCompute(t time.Time) float64 {
+	return filter.finalCompute(t)
+}
+
+// finalCompute estimates the volatility over the window ending at @t. If there are too few
+// intervals with trades, the value is 0 and not saved.
+func (filter *FilterVolatility) finalCompute(t time.Time) float64 {
+	windowStart := t.Add(-time.Duration(filter.param) * time.Second)
+	first := 0
+	for first < len(filter.times) && !filter.times[first].After(windowStart) {
+		first++
+	}
+	filter.prices = filter.prices[first:]
+	filter.times = filter.times[first:]
+
+	value, ok := filter.estimator(sampleIntervals(filter.prices, filter.times), filter.param)
+	if !ok {
+		filter.value = 0
+		filter.modified = false
+		return 0
+	}
+	filter.value = value
+	return filter.value
+}
+
+// sampleIntervals divides @prices at @times into intervals of volatilitySampleSeconds
+// and returns the non-empty intervals in ascending order of time.
+func sampleIntervals(prices []float64, times []time.Time) (intervals []priceInterval) {
+	var current time.Time
+	for i, price := range prices {
+		start := times[i].Truncate(volatilitySampleSeconds * time.Second)
+		if len(intervals) == 0 || !start.Equal(current) {
+			current = start
+			intervals = append(intervals, priceInterval{open: price, high: price, low: price, close: price})
+			continue
+		}
+		interval := &intervals[len(intervals)-1]
+		interval.high = math.Max(interval.high, price)
+		interval.low = math.Min(interval.low, price)
+		interval.close = price
+	}
+	return
+}
+
+// logReturns returns the log returns between the closing prices of consecutive @intervals.
+func logReturns(intervals []priceInterval) (returns []float64) {
+	for i := 1; i < len(intervals); i++ {
+		returns = append(returns, math.Log(intervals[i].close/intervals[i-1].close))
+	}
+	return
+}
+
+func realizedVolatility(intervals []priceInterval, window int) (float64, bool) {
+	returns := logReturns(intervals)
+	if len(returns) == 0 {
+		return 0, false
+	}
+	var squares float64
+	for _, r := range returns {
+		squares += r * r
+	}
+	return math.Sqrt(squares * secondsPerYear / float64(window)), true
+}
+
+func parkinsonVolatility(intervals []priceInterval, window int) (float64, bool) {
+	if len(intervals) < 2 {
+		return 0, false
+	}
+	var squares float64
+	for _, interval := range intervals {
+		hl := math.Log(interval.high / interval.low)
+		squares += hl * hl
+	}
+	variance := squares / (4 * math.Ln2 * float64(len(intervals)))
+	return math.Sqrt(variance * secondsPerYear / volatilitySampleSeconds), true
+}
+
+func returnVariance(intervals []priceInterval, window int) (float64, bool) {
+	returns := logReturns(intervals)
+	if len(returns) < 2 {
+		return 0, false
+	}
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / float64(len(returns))
+	var squares float64
+	for _, r := range returns {
+		squares += (r - mean) * (r - mean)
+	}
+	return squares / float64(len(returns)-1), true
+}
+
+func (filter *FilterVolatility) FilterPointForBlock() *dia.FilterPoint {
+	return &dia.FilterPoint{
+		Asset: filter.asset,
+		Value: filter.value,
+		Name:  filter.filterName,
+		Time:  filter.currentTime,
+	}
+}
+
+func (filter *FilterVolatility) filterPointForBlock() *dia.FilterPoint {
+	if filter.exchange != "" || filter.filterName != dia.FilterKing {
+		return nil
+	}
+	return filter.FilterPointForBlock()
+}
+
+func (filter *FilterVolatility) save(ds Datastore) error {
+	if filter.modified {
+		filter.modified = false
+		err := ds.SetFilter(filter.filterName, filter.asset, filter.exchange, filter.value, filter.currentTime)
+		if err != nil {
+			log.Errorln("FilterVolatility: Error:", err)
+		}
+		return err
+	}
+	return nil
+}
+
+func (filter *FilterVolatility) clone() Filter {
+	c := *filter
+	c.prices = append([]float64(nil), filter.prices...)
+	c.times = append([]time.Time(nil), filter.times...)
+	return &c
+}
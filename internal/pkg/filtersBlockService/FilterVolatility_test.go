@@ -0,0 +1,64 @@
+package filters
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// volatilityTrades returns trades in three minutes with closing prices 100, 110 and 99
+// and a high-low range of 100 - 105 in the first minute.
+func volatilityTrades(start time.Time) []dia.Trade {
+	return []dia.Trade{
+		{EstimatedUSDPrice: 105, Volume: 1, Time: start.Add(10 * time.Second)},
+		{EstimatedUSDPrice: 100, Volume: 1, Time: start.Add(50 * time.Second)},
+		{EstimatedUSDPrice: 110, Volume: 1, Time: start.Add(70 * time.Second)},
+		{EstimatedUSDPrice: 99, Volume: 1, Time: start.Add(130 * time.Second)},
+	}
+}
+
+func TestVolatilityFilters(t *testing.T) {
+	start := time.Unix(1600000020, 0).Truncate(time.Minute)
+	end := start.Add(3 * time.Minute)
+	r1, r2 := math.Log(110.0/100), math.Log(99.0/110)
+
+	mean := (r1 + r2) / 2
+	cases := []struct {
+		filter   *FilterVolatility
+		expected float64
+	}{
+		{NewFilterRVOL(dia.Asset{}, "", start, 180), math.Sqrt((r1*r1 + r2*r2) * secondsPerYear / 180)},
+		{NewFilterPVOL(dia.Asset{}, "", start, 180), math.Sqrt(math.Pow(math.Log(105.0/100), 2) / (4 * math.Ln2 * 3) * secondsPerYear / 60)},
+		{NewFilterRVAR(dia.Asset{}, "", start, 180), (r1-mean)*(r1-mean) + (r2-mean)*(r2-mean)},
+	}
+	for _, c := range cases {
+		for _, trade := range volatilityTrades(start) {
+			c.filter.Compute(trade)
+		}
+		if value := c.filter.FinalCompute(end); math.Abs(value-c.expected) > 1e-9 {
+			t.Errorf("%s expected %v and got %v", c.filter.filterName, c.expected, value)
+		}
+	}
+}
+
+func TestVolatilityTooFewTrades(t *testing.T) {
+	start := time.Unix(1600000020, 0).Truncate(time.Minute)
+	recorder := newFilterRecorder()
+
+	// Only the last two minutes are in the window, which yield a single return.
+	filter := NewFilterRVAR(dia.Asset{}, "", start, 120)
+	for _, trade := range volatilityTrades(start) {
+		filter.Compute(trade)
+	}
+	if value := filter.FinalCompute(start.Add(3 * time.Minute)); value != 0 {
+		t.Errorf("RVAR expected 0 and got %v", value)
+	}
+	if err := filter.save(recorder); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.values) != 0 {
+		t.Errorf("saved values without enough trades: %v", recorder.values)
+	}
+}
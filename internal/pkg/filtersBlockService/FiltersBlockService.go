@@ -2,15 +2,62 @@ package filters
 
 import (
 	"errors"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/cnf/structhash"
 	"github.com/diadata-org/diadata/pkg/dia"
-	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/diadata-org/diadata/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
 
+// GraceBlocks is the number of past tradesBlocks which can be amended by the tradesBlockService.
+// The state of the filters before each of these blocks is kept, so that filter points can be
+// recomputed upon reception of an amended tradesBlock.
+var GraceBlocks int
+
+// Windows are the sizes in seconds of the windows the default filters are computed over,
+// such as 120,600,3600. Each window results in filters named after it, e.g. MAIR600.
+var Windows []int
+
+// QualityEnabled determines whether the quality of filter points is computed and saved.
+// It is disabled by default and enabled by setting FILTER_QUALITY=true.
+var QualityEnabled bool
+
+// Workers is the number of goroutines among which the assets are divided for computing and saving filters.
+// It defaults to the number of CPUs.
+var Workers int
+
+func init() {
+	var err error
+	GraceBlocks, err = strconv.Atoi(utils.Getenv("LATE_TRADE_GRACE_BLOCKS", "0"))
+	if err != nil {
+		log.Error("parse LATE_TRADE_GRACE_BLOCKS: ", err)
+	}
+	QualityEnabled, err = strconv.ParseBool(utils.Getenv("FILTER_QUALITY", "false"))
+	if err != nil {
+		log.Error("parse FILTER_QUALITY: ", err)
+	}
+	workers := utils.Getenv("FILTER_WORKERS", strconv.Itoa(runtime.NumCPU()))
+	Workers, err = strconv.Atoi(workers)
+	if err != nil || Workers < 1 {
+		log.Errorf("parse FILTER_WORKERS: invalid number of workers %q. use 1.", workers)
+		Workers = 1
+	}
+	for _, w := range strings.Split(utils.Getenv("FILTER_WINDOWS", strconv.Itoa(dia.BlockSizeSeconds)), ",") {
+		window, err := strconv.Atoi(strings.TrimSpace(w))
+		if err != nil || window <= 0 {
+			log.Errorf("parse FILTER_WINDOWS: invalid window %q", w)
+			continue
+		}
+		Windows = append(Windows, window)
+	}
+}
+
 /*
 const (
 	filtersParam = dia.BlockSizeSeconds
@@ -34,12 +81,41 @@ type filtersAsset struct {
 	Source     string
 }
 
+// blockState is a processed tradesBlock along with the state of the filters before processing it.
+type blockState struct {
+	tradesBlock          *dia.TradesBlock
+	revision             int
+	filters              map[filtersAsset][]Filter
+	previousBlockFilters []dia.FilterPoint
+}
+
+// tradesBlockRequest is a tradesBlock sent to the mainLoop. If @done is not nil, it receives
+// the result of flushing the filter points once processing is finished.
+type tradesBlockRequest struct {
+	tradesBlock *dia.TradesBlock
+	done        chan error
+}
+
+// Datastore is the part of models.Datastore filter points are saved to. It consists of the writing
+// methods of models.FilterStore and models.BatchStore, the quotation written by the MAIR filter across
+// exchanges and the last trade times written by FilterTLT.
+type Datastore interface {
+	SetFilter(filterName string, asset dia.Asset, exchange string, value float64, t time.Time) error
+	SetFilterQuality(filterName string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error
+	DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error
+	SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error
+	SetLastTradeTimeForExchange(asset dia.Asset, exchange string, t time.Time) error
+	Flush() error
+	ExecuteRedisPipe() error
+	FlushRedisPipe() error
+}
+
 // FiltersBlockService is the data structure containing all objects
 // necessary for the processing of a tradesBlock.
 type FiltersBlockService struct {
 	shutdown         chan nothing
 	shutdownDone     chan nothing
-	chanTradesBlock  chan *dia.TradesBlock
+	chanTradesBlock  chan tradesBlockRequest
 	chanFiltersBlock chan *dia.FiltersBlock
 	errorLock        sync.RWMutex
 	error            error
@@ -48,28 +124,35 @@ type FiltersBlockService struct {
 	// currentTime          time.Time
 	filters              map[filtersAsset][]Filter
 	lastLog              time.Time
-	calculationValues    []int
 	previousBlockFilters []dia.FilterPoint
-	datastore            models.Datastore
+	datastore            Datastore
+	filterSets           *FilterSets
+	graceBlocks          int
+	history              []blockState
+	// workers is the number of goroutines computing filters. Values below 2 compute them serially.
+	workers int
 }
 
 // NewFiltersBlockService returns a new FiltersBlockService and
-// runs mainLoop() in a go routine.
-func NewFiltersBlockService(previousBlockFilters []dia.FilterPoint, datastore models.Datastore, chanFiltersBlock chan *dia.FiltersBlock) *FiltersBlockService {
+// runs mainLoop() in a go routine. The filters given by DefaultFilterConfigs are
+// computed for all assets if @filterSets is nil. Filters of different assets are saved concurrently,
+// so @datastore must be safe for concurrent use unless Workers is 1.
+func NewFiltersBlockService(previousBlockFilters []dia.FilterPoint, datastore Datastore, chanFiltersBlock chan *dia.FiltersBlock, filterSets *FilterSets) *FiltersBlockService {
 	s := &FiltersBlockService{
 		shutdown:             make(chan nothing),
 		shutdownDone:         make(chan nothing),
-		chanTradesBlock:      make(chan *dia.TradesBlock),
+		chanTradesBlock:      make(chan tradesBlockRequest),
 		chanFiltersBlock:     chanFiltersBlock,
 		error:                nil,
 		started:              false,
 		filters:              make(map[filtersAsset][]Filter),
 		lastLog:              time.Now(),
-		calculationValues:    make([]int, 0),
 		previousBlockFilters: previousBlockFilters,
 		datastore:            datastore,
+		filterSets:           filterSets,
+		graceBlocks:          GraceBlocks,
+		workers:              Workers,
 	}
-	s.calculationValues = append(s.calculationValues, dia.BlockSizeSeconds)
 
 	go s.mainLoop()
 	return s
@@ -84,41 +167,131 @@ func (s *FiltersBlockService) mainLoop() {
 			log.Println("Filters shutting down")
 			s.cleanup(nil)
 			return
-		case tb, ok := <-s.chanTradesBlock:
+		case req, ok := <-s.chanTradesBlock:
 			log.Info("receive tradesBlock for further processing ok: ", ok)
-			s.processTradesBlock(tb)
+			err := s.processTradesBlock(req.tradesBlock)
+			if req.done != nil {
+				req.done <- err
+			}
+		}
+	}
+}
+
+// processTradesBlock computes the filters for a new tradesBlock or recomputes them for an amended one.
+// It returns the first error which occured while writing filter points.
+func (s *FiltersBlockService) processTradesBlock(tb *dia.TradesBlock) error {
+	if tb.Revision > 0 {
+		return s.recomputeFrom(tb)
+	}
+	if s.graceBlocks > 0 {
+		s.history = append(s.history, blockState{
+			tradesBlock:          tb,
+			filters:              cloneFilters(s.filters),
+			previousBlockFilters: s.previousBlockFilters,
+		})
+		if len(s.history) > s.graceBlocks {
+			s.history = s.history[1:]
+		}
+	}
+	return s.computeBlock(tb, 0)
+}
+
+// recomputeFrom restores the filters' state before the amended tradesBlock @tb and recomputes
+// the filters for @tb and all subsequent blocks. Previously stored filter points of the
+// affected assets are deleted, as recomputed points may have different timestamps.
+func (s *FiltersBlockService) recomputeFrom(tb *dia.TradesBlock) (err error) {
+	index := -1
+	for i, state := range s.history {
+		if state.tradesBlock.TradesBlockData.BeginTime.Equal(tb.TradesBlockData.BeginTime) {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		log.Warnf("amended tradesBlock with begin time %v is out of grace window. ignore revision %d.", tb.TradesBlockData.BeginTime, tb.Revision)
+		return nil
+	}
+	log.Infof("recompute filters from tradesBlock with begin time %v, revision %d.", tb.TradesBlockData.BeginTime, tb.Revision)
+
+	if agg := s.filterSets.aggregation(); agg != nil {
+		agg.rewind(tb.TradesBlockData.BeginTime)
+	}
+	s.filters = cloneFilters(s.history[index].filters)
+	s.previousBlockFilters = s.history[index].previousBlockFilters
+	// Points of assets traded in the previous revision only are deleted as well.
+	s.deleteFilterPoints(s.history[index].tradesBlock, tb)
+	s.history[index].tradesBlock = tb
+	for i := index; i < len(s.history); i++ {
+		if i > index {
+			s.deleteFilterPoints(s.history[i].tradesBlock)
+		}
+		s.history[i].filters = cloneFilters(s.filters)
+		s.history[i].previousBlockFilters = s.previousBlockFilters
+		s.history[i].revision++
+		if blockErr := s.computeBlock(s.history[i].tradesBlock, s.history[i].revision); blockErr != nil && err == nil {
+			err = blockErr
 		}
 	}
+	return err
 }
 
-// processTradesBlock is the 'main' function in the sense that all mathematical
-// computations are done here.
-func (s *FiltersBlockService) processTradesBlock(tb *dia.TradesBlock) {
+// computeBlock is the 'main' function in the sense that all mathematical
+// computations are done here. It returns the first error which occured while flushing filter points.
+func (s *FiltersBlockService) computeBlock(tb *dia.TradesBlock, revision int) (flushErr error) {
 
 	log.Infoln("processTradesBlock starting")
 	t0 := time.Now()
 
+	// Filters are created serially, as they are added to s.filters. Trades are then computed in
+	// parallel by asset, so that the filters of each asset receive its trades in order.
+	aggregator := s.filterSets.aggregation()
+	var tradesByAsset [][]dia.Trade
+	assetIndex := make(map[string]int)
 	for _, trade := range tb.TradesBlockData.Trades {
 		s.createFilters(trade.QuoteToken, "", tb.TradesBlockData.BeginTime)
 		s.createFilters(trade.QuoteToken, trade.Source, tb.TradesBlockData.BeginTime)
-		s.computeFilters(trade, "")
-		s.computeFilters(trade, trade.Source)
+		if aggregator != nil {
+			aggregator.addTrade(trade)
+		}
+		identifier := getIdentifier(trade.QuoteToken)
+		i, ok := assetIndex[identifier]
+		if !ok {
+			i = len(tradesByAsset)
+			assetIndex[identifier] = i
+			tradesByAsset = append(tradesByAsset, nil)
+		}
+		tradesByAsset[i] = append(tradesByAsset[i], trade)
 	}
+	s.parallel(len(tradesByAsset), func(i int) {
+		for _, trade := range tradesByAsset[i] {
+			s.computeFilters(trade, "")
+			s.computeFilters(trade, trade.Source)
+		}
+	})
 
 	log.Info("time spent for create and compute filters: ", time.Since(t0))
 	log.Info("filter begin time: ", tb.TradesBlockData.BeginTime)
-	resultFilters := []dia.FilterPoint{}
 
 	t0 = time.Now()
 
-	for _, filters := range s.filters {
-		for _, f := range filters {
-			f.finalCompute(tb.TradesBlockData.EndTime)
-			fp := f.filterPointForBlock()
-			if fp != nil {
-				resultFilters = append(resultFilters, *fp)
+	// Filter points are collected by asset and concatenated in the order of the shards,
+	// so that the filtersBlock and its hash do not depend on the scheduling of the workers.
+	shards := s.assetShards()
+	shardFilters := make([][]dia.FilterPoint, len(shards))
+	s.parallel(len(shards), func(i int) {
+		for _, fa := range shards[i] {
+			for _, f := range s.filters[fa] {
+				f.finalCompute(tb.TradesBlockData.EndTime)
+				fp := f.filterPointForBlock()
+				if fp != nil {
+					shardFilters[i] = append(shardFilters[i], *fp)
+				}
 			}
 		}
+	})
+	resultFilters := []dia.FilterPoint{}
+	for _, points := range shardFilters {
+		resultFilters = append(resultFilters, points...)
 	}
 	log.Info("time spent for final compute: ", time.Since(t0))
 
@@ -134,6 +307,7 @@ func (s *FiltersBlockService) processTradesBlock(tb *dia.TradesBlock) {
 			BeginTime:       tb.TradesBlockData.BeginTime,
 			TradesBlockHash: tb.BlockHash,
 		},
+		Revision: revision,
 	}
 
 	hash, err := structhash.Hash(fb.FiltersBlockData, 1)
@@ -149,31 +323,39 @@ func (s *FiltersBlockService) processTradesBlock(tb *dia.TradesBlock) {
 	}
 
 	t0 = time.Now()
-	for _, filters := range s.filters {
-		for _, f := range filters {
-			err = f.save(s.datastore)
-			if err != nil {
-				log.Error(err)
+	s.parallel(len(shards), func(i int) {
+		for _, fa := range shards[i] {
+			for _, f := range s.filters[fa] {
+				if err := f.save(s.datastore); err != nil {
+					log.Error(err)
+				}
 			}
 		}
-	}
+	})
 	log.Info("time spent for save filters: ", time.Since(t0))
 
 	err = s.datastore.ExecuteRedisPipe()
 	if err != nil {
 		log.Error("execute redis pipe: ", err)
+		flushErr = err
 	}
 
 	err = s.datastore.FlushRedisPipe()
 	if err != nil {
 		log.Error("flush redis pipe: ", err)
+		if flushErr == nil {
+			flushErr = err
+		}
 	}
 
 	err = s.datastore.Flush()
 	if err != nil {
 		log.Error("flush influx batch: ", err)
+		if flushErr == nil {
+			flushErr = err
+		}
 	}
-
+	return flushErr
 }
 
 func (s *FiltersBlockService) createFilters(asset dia.Asset, exchange string, BeginTime time.Time) {
@@ -183,17 +365,114 @@ func (s *FiltersBlockService) createFilters(asset dia.Asset, exchange string, Be
 	}
 	_, ok := s.filters[fa]
 	if !ok {
-		s.filters[fa] = []Filter{
-			NewFilterMA(asset, exchange, BeginTime, dia.BlockSizeSeconds),
-			// NewFilterTLT(asset, exchange),
-			NewFilterVOL(asset, exchange, dia.BlockSizeSeconds),
-			NewFilterMAIR(asset, exchange, BeginTime, dia.BlockSizeSeconds),
-			NewFilterMEDIR(asset, exchange, BeginTime, dia.BlockSizeSeconds),
-			NewFilterCOUNT(asset, exchange, dia.BlockSizeSeconds),
+		if QualityEnabled {
+			s.filters[fa] = s.filterSets.filtersWithQuality(asset, exchange, BeginTime)
+		} else {
+			s.filters[fa] = s.filterSets.Filters(asset, exchange, BeginTime)
 		}
 	}
 }
 
+// assetShard holds the keys of the filters of an asset on all exchanges. The filters on single exchanges
+// come first in the order of the exchanges, as aggregated filters across all exchanges use their values.
+type assetShard []filtersAsset
+
+// assetShards returns the shards of all assets with filters, ordered by the assets' identifiers.
+func (s *FiltersBlockService) assetShards() []assetShard {
+	byAsset := make(map[string]assetShard)
+	for fa := range s.filters {
+		byAsset[fa.Identifier] = append(byAsset[fa.Identifier], fa)
+	}
+	identifiers := make([]string, 0, len(byAsset))
+	for identifier := range byAsset {
+		identifiers = append(identifiers, identifier)
+	}
+	sort.Strings(identifiers)
+
+	shards := make([]assetShard, len(identifiers))
+	for i, identifier := range identifiers {
+		shard := byAsset[identifier]
+		sort.Slice(shard, func(m, n int) bool {
+			if (shard[m].Source == "") != (shard[n].Source == "") {
+				return shard[n].Source == ""
+			}
+			return shard[m].Source < shard[n].Source
+		})
+		shards[i] = shard
+	}
+	return shards
+}
+
+// parallel calls @f for 0 <= i < @n on up to s.workers goroutines and returns once all calls returned.
+func (s *FiltersBlockService) parallel(n int, f func(i int)) {
+	workers := s.workers
+	if workers > n {
+		workers = n
+	}
+	if workers < 2 {
+		for i := 0; i < n; i++ {
+			f(i)
+		}
+		return
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
+
+// deleteFilterPoints deletes the stored filter points of all assets traded in @tbs within the time range of
+// the first block. All @tbs are revisions of the same block.
+// The values in redis are deleted for the filters configured for the asset on the exchange.
+func (s *FiltersBlockService) deleteFilterPoints(tbs ...*dia.TradesBlock) {
+	beginTime, endTime := tbs[0].TradesBlockData.BeginTime, tbs[0].TradesBlockData.EndTime
+	deleted := make(map[filtersAsset]bool)
+	for _, tb := range tbs {
+		for _, trade := range tb.TradesBlockData.Trades {
+			for _, exchange := range []string{"", trade.Source} {
+				fa := filtersAsset{Identifier: getIdentifier(trade.QuoteToken), Source: exchange}
+				if deleted[fa] {
+					continue
+				}
+				deleted[fa] = true
+				var filters []string
+				for _, c := range s.filterSets.Configs(trade.QuoteToken, exchange) {
+					filters = append(filters, filterName(c))
+				}
+				err := s.datastore.DeleteFilterPoints(filters, trade.QuoteToken, exchange, beginTime, endTime)
+				if err != nil {
+					log.Errorf("delete filter points of %s on %s: %v", fa.Identifier, exchange, err)
+				}
+			}
+		}
+	}
+}
+
+// cloneFilters returns a deep copy of @filters.
+func cloneFilters(filters map[filtersAsset][]Filter) map[filtersAsset][]Filter {
+	clone := make(map[filtersAsset][]Filter, len(filters))
+	for fa, assetFilters := range filters {
+		clonedFilters := make([]Filter, len(assetFilters))
+		for i, f := range assetFilters {
+			clonedFilters[i] = f.clone()
+		}
+		clone[fa] = clonedFilters
+	}
+	return clone
+}
+
 func (s *FiltersBlockService) computeFilters(t dia.Trade, exchange string) {
 	fa := filtersAsset{
 		Identifier: getIdentifier(t.QuoteToken),
@@ -246,10 +525,22 @@ func addMissingPoints(previousBlockFilters []dia.FilterPoint, newFilters []dia.F
 
 // ProcessTradesBlock sends a filled tradesBlock into the filtersBlock channel.
 func (s *FiltersBlockService) ProcessTradesBlock(tradesBlock *dia.TradesBlock) {
-	s.chanTradesBlock <- tradesBlock
+	s.chanTradesBlock <- tradesBlockRequest{tradesBlock: tradesBlock}
 	log.Info("Processing TradesBlock done.")
 }
 
+// ProcessTradesBlockSync processes @tradesBlock and waits until its filter points are flushed
+// to redis and influx. A nil error means the tradesBlock can be acknowledged to its source.
+func (s *FiltersBlockService) ProcessTradesBlockSync(tradesBlock *dia.TradesBlock) error {
+	done := make(chan error, 1)
+	select {
+	case s.chanTradesBlock <- tradesBlockRequest{tradesBlock: tradesBlock, done: done}:
+	case <-s.shutdownDone:
+		return errors.New("filters: closed")
+	}
+	return <-done
+}
+
 // Close gracefully closes the Filtersblockservice
 func (s *FiltersBlockService) Close() error {
 	if s.closed {
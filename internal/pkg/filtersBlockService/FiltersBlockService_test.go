@@ -0,0 +1,252 @@
+package filters
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+type recordedPoint struct {
+	filter   string
+	asset    string
+	exchange string
+	time     time.Time
+}
+
+// filterRecorder keeps the last value saved for each filter point instead of writing it to a database.
+type filterRecorder struct {
+	mu        sync.Mutex
+	values    map[recordedPoint]float64
+	deletions int
+}
+
+func newFilterRecorder() *filterRecorder {
+	return &filterRecorder{values: make(map[recordedPoint]float64)}
+}
+
+func (fr *filterRecorder) SetFilter(filterName string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.values[recordedPoint{filterName, getIdentifier(asset), exchange, t}] = value
+	return nil
+}
+
+func (fr *filterRecorder) SetFilterQuality(filterName string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	return nil
+}
+
+func (fr *filterRecorder) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.deletions++
+	for p := range fr.values {
+		if p.asset == getIdentifier(asset) && p.exchange == exchange && !p.time.Before(starttime) && !p.time.After(endtime) {
+			delete(fr.values, p)
+		}
+	}
+	return nil
+}
+
+func (fr *filterRecorder) SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error {
+	return nil
+}
+
+func (fr *filterRecorder) SetLastTradeTimeForExchange(asset dia.Asset, exchange string, t time.Time) error {
+	return nil
+}
+
+func (fr *filterRecorder) ExecuteRedisPipe() error {
+	return nil
+}
+
+func (fr *filterRecorder) FlushRedisPipe() error {
+	return nil
+}
+
+func (fr *filterRecorder) Flush() error {
+	return nil
+}
+
+func testTradesBlock(begin time.Time, prices ...float64) *dia.TradesBlock {
+	asset := dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	tb := &dia.TradesBlock{
+		TradesBlockData: dia.TradesBlockData{
+			BeginTime: begin,
+			EndTime:   begin.Add(dia.BlockSizeSeconds * time.Second),
+		},
+	}
+	for i, price := range prices {
+		tb.TradesBlockData.Trades = append(tb.TradesBlockData.Trades, dia.Trade{
+			QuoteToken:        asset,
+			Price:             price,
+			EstimatedUSDPrice: price,
+			Volume:            1,
+			Time:              begin.Add(time.Duration(10*(i+1)) * time.Second),
+			Source:            dia.BinanceExchange,
+		})
+	}
+	return tb
+}
+
+func TestRecomputeAmendedBlock(t *testing.T) {
+	begin := time.Unix(1654041600, 0)
+	blocks := []*dia.TradesBlock{
+		testTradesBlock(begin, 100, 101, 102),
+		testTradesBlock(begin.Add(dia.BlockSizeSeconds*time.Second), 103, 104),
+		testTradesBlock(begin.Add(2*dia.BlockSizeSeconds*time.Second), 105, 106, 107),
+	}
+	amended := testTradesBlock(begin.Add(dia.BlockSizeSeconds*time.Second), 103, 104, 150)
+
+	// Filters computed from the amended block right away.
+	expected := newFilterRecorder()
+	s := &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: expected}
+	for _, tb := range []*dia.TradesBlock{blocks[0], amended, blocks[2]} {
+		s.processTradesBlock(tb)
+	}
+
+	// Filters recomputed after reception of the amended block.
+	recorder := newFilterRecorder()
+	s = &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: recorder, graceBlocks: 2}
+	for _, tb := range blocks {
+		s.processTradesBlock(tb)
+	}
+	before := make(map[recordedPoint]float64)
+	for key, value := range recorder.values {
+		before[key] = value
+	}
+	amended.Revision = 1
+	s.processTradesBlock(amended)
+
+	if len(recorder.values) != len(expected.values) {
+		t.Fatalf("got %d filter points, expected %d", len(recorder.values), len(expected.values))
+	}
+	var changed int
+	for key, value := range expected.values {
+		if recorder.values[key] != value {
+			t.Errorf("%v: got %v, expected %v", key, recorder.values[key], value)
+		}
+		if before[key] != value {
+			changed++
+		}
+	}
+	if changed == 0 {
+		t.Error("amended block did not change any filter point")
+	}
+	if s.history[0].revision != 1 || s.history[1].revision != 1 {
+		t.Errorf("unexpected revisions %d, %d", s.history[0].revision, s.history[1].revision)
+	}
+	// Points of both recomputed blocks are deleted once for the asset and once for the exchange.
+	if recorder.deletions != 4 {
+		t.Errorf("got %d deletions of filter points, expected 4", recorder.deletions)
+	}
+
+	// Amended blocks out of the grace window are ignored.
+	outdated := testTradesBlock(begin, 1)
+	outdated.Revision = 1
+	s.processTradesBlock(outdated)
+	for key, value := range expected.values {
+		if recorder.values[key] != value {
+			t.Errorf("%v changed by outdated block", key)
+		}
+	}
+}
+
+func TestMultipleWindows(t *testing.T) {
+	fs, err := NewFilterSets([]dia.FilterConfig{
+		{Filter: "VOL", Window: 120},
+		{Filter: "VOL", Window: 360},
+		{Filter: "MAIR", Window: 120},
+		{Filter: "MAIR", Window: 360},
+		{Filter: "MEDIR", Window: 360},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := newFilterRecorder()
+	s := &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: recorder, filterSets: fs}
+	begin := time.Unix(1654041600, 0)
+	blocks := []*dia.TradesBlock{
+		testTradesBlock(begin, 100, 100),
+		testTradesBlock(begin.Add(dia.BlockSizeSeconds*time.Second), 110, 110),
+		testTradesBlock(begin.Add(2*dia.BlockSizeSeconds*time.Second), 120, 120),
+		testTradesBlock(begin.Add(3*dia.BlockSizeSeconds*time.Second), 130, 130),
+	}
+	for _, tb := range blocks {
+		s.processTradesBlock(tb)
+	}
+
+	last := blocks[3].TradesBlockData.Trades[1]
+	value := func(filter string) float64 {
+		return recorder.values[recordedPoint{filter, getIdentifier(last.QuoteToken), "", last.Time}]
+	}
+	if v := value("VOL120"); v != 260 {
+		t.Errorf("VOL120: got %v, expected 260", v)
+	}
+	// The last three blocks are within 360 seconds.
+	if v := value("VOL360"); v != 720 {
+		t.Errorf("VOL360: got %v, expected 720", v)
+	}
+	if v := value("MAIR120"); v != 130 {
+		t.Errorf("MAIR120: got %v, expected 130", v)
+	}
+	if v := value("MAIR360"); v <= 110 || v >= 130 {
+		t.Errorf("MAIR360: got %v, expected an average of the last blocks", v)
+	}
+	if v := value("MEDIR360"); v != 120 {
+		t.Errorf("MEDIR360: got %v, expected 120", v)
+	}
+}
+
+func TestParallelFilters(t *testing.T) {
+	begin := time.Unix(1654041600, 0)
+	var blocks []*dia.TradesBlock
+	for b := 0; b < 3; b++ {
+		tb := testTradesBlock(begin.Add(time.Duration(b*dia.BlockSizeSeconds) * time.Second))
+		for i := 0; i < 40; i++ {
+			asset := dia.Asset{Symbol: "T", Address: strconv.Itoa(i), Blockchain: dia.ETHEREUM}
+			for j, exchange := range []string{dia.BinanceExchange, dia.CoinBaseExchange, dia.KrakenExchange} {
+				tb.TradesBlockData.Trades = append(tb.TradesBlockData.Trades, dia.Trade{
+					QuoteToken:        asset,
+					EstimatedUSDPrice: float64(100 + i + j + b),
+					Volume:            float64(1 + j),
+					Time:              tb.TradesBlockData.BeginTime.Add(time.Duration(i+j+1) * time.Second),
+					Source:            exchange,
+				})
+			}
+		}
+		blocks = append(blocks, tb)
+	}
+
+	process := func(workers int) (*filterRecorder, []string) {
+		recorder := newFilterRecorder()
+		chanFiltersBlock := make(chan *dia.FiltersBlock, len(blocks))
+		s := &FiltersBlockService{filters: make(map[filtersAsset][]Filter), datastore: recorder, chanFiltersBlock: chanFiltersBlock, workers: workers}
+		var hashes []string
+		for _, tb := range blocks {
+			s.processTradesBlock(tb)
+			hashes = append(hashes, (<-chanFiltersBlock).BlockHash)
+		}
+		return recorder, hashes
+	}
+
+	expected, expectedHashes := process(1)
+	for run := 0; run < 3; run++ {
+		recorder, hashes := process(8)
+		if len(recorder.values) != len(expected.values) {
+			t.Fatalf("got %d filter points, expected %d", len(recorder.values), len(expected.values))
+		}
+		for key, value := range expected.values {
+			if recorder.values[key] != value {
+				t.Errorf("%v: got %v, expected %v", key, recorder.values[key], value)
+			}
+		}
+		for i := range hashes {
+			if hashes[i] != expectedHashes[i] {
+				t.Errorf("block %d: got hash %s, expected %s", i, hashes[i], expectedHashes[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package filters
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+const (
+	// Outlier detectors selectable in filter configurations.
+	OutlierIQR    = "IQR"
+	OutlierMAD    = "MAD"
+	OutlierHampel = "HAMPEL"
+	OutlierVolume = "VOLUME"
+	OutlierBPS    = "BPS"
+
+	// madScale makes the median absolute deviation a consistent estimator of the standard deviation of normal data.
+	madScale = 1.4826
+	// meanADScale does the same for the mean absolute deviation, which is used if more than half the samples are equal.
+	meanADScale = 1.2533
+	// hampelHalfWindow is the number of neighbours on each side of a sample used by the Hampel filter.
+	hampelHalfWindow = 5
+)
+
+// OutlierDetector decides which samples of a filter are outliers.
+type OutlierDetector interface {
+	// Outliers returns the ascending indices of the outliers in @prices. @volumes are the
+	// corresponding volumes. Neither slice is modified.
+	Outliers(prices []float64, volumes []float64) []int
+}
+
+// outlierFiltered is implemented by filters which remove outliers before computing their value.
+// FilterSets provide them with the detector selected in their configuration.
+type outlierFiltered interface {
+	setOutlierDetector(detector OutlierDetector)
+	// outliers returns the trades excluded in the last computation.
+	outliers() []dia.ExcludedTrade
+}
+
+// NewOutlierDetector returns the detector named @name with parameter @threshold.
+// A zero @threshold selects the detector's default.
+func NewOutlierDetector(name string, threshold float64) (OutlierDetector, error) {
+	if threshold < 0 {
+		return nil, fmt.Errorf("outlier detector %s needs a non-negative threshold", name)
+	}
+	switch name {
+	case OutlierIQR:
+		return IQRDetector{Scale: threshold}, nil
+	case OutlierMAD:
+		return MADDetector{Threshold: threshold}, nil
+	case OutlierHampel:
+		return HampelDetector{Threshold: threshold, HalfWindow: hampelHalfWindow}, nil
+	case OutlierVolume:
+		if threshold >= 0.5 {
+			return nil, fmt.Errorf("outlier detector %s can trim less than half of the volume on each side", name)
+		}
+		return VolumeTrimDetector{Fraction: threshold}, nil
+	case OutlierBPS:
+		return BasisPointsDetector{BasisPoints: threshold}, nil
+	}
+	return nil, fmt.Errorf("unknown outlier detector %s", name)
+}
+
+// defaultOutlierDetector is used by filters without configured detector.
+func defaultOutlierDetector() OutlierDetector {
+	return IQRDetector{}
+}
+
+// IQRDetector discards samples outside the Tukey fences Q1 - Scale*IQR and Q3 + Scale*IQR.
+// The default Scale is 1.5.
+type IQRDetector struct {
+	Scale float64
+}
+
+func (d IQRDetector) Outliers(prices []float64, volumes []float64) (outliers []int) {
+	if len(prices) < 2 {
+		return
+	}
+	scale := d.Scale
+	if scale == 0 {
+		scale = 1.5
+	}
+	Q1, Q3 := computeQuartiles(append([]float64(nil), prices...))
+	IQR := Q3 - Q1
+	lowerBound := Q1 - scale*IQR
+	upperBound := Q3 + scale*IQR
+	for i, price := range prices {
+		if price < lowerBound || price > upperBound {
+			outliers = append(outliers, i)
+		}
+	}
+	return
+}
+
+// MADDetector discards samples deviating from the median by more than Threshold times the
+// scaled median absolute deviation. The default Threshold is 3.
+type MADDetector struct {
+	Threshold float64
+}
+
+func (d MADDetector) Outliers(prices []float64, volumes []float64) (outliers []int) {
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = 3
+	}
+	median, deviation := robustDeviation(prices)
+	if deviation == 0 {
+		return
+	}
+	for i, price := range prices {
+		if math.Abs(price-median) > threshold*deviation {
+			outliers = append(outliers, i)
+		}
+	}
+	return
+}
+
+// HampelDetector discards samples deviating from the median of their HalfWindow neighbours on
+// each side by more than Threshold times the neighbourhood's scaled median absolute deviation.
+// In contrast to MADDetector, it follows trends within the filter's window. The default Threshold is 3.
+type HampelDetector struct {
+	Threshold  float64
+	HalfWindow int
+}
+
+func (d HampelDetector) Outliers(prices []float64, volumes []float64) (outliers []int) {
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = 3
+	}
+	halfWindow := d.HalfWindow
+	if halfWindow == 0 {
+		halfWindow = hampelHalfWindow
+	}
+	for i, price := range prices {
+		lower := i - halfWindow
+		if lower < 0 {
+			lower = 0
+		}
+		upper := i + halfWindow + 1
+		if upper > len(prices) {
+			upper = len(prices)
+		}
+		median, deviation := robustDeviation(prices[lower:upper])
+		if deviation > 0 && math.Abs(price-median) > threshold*deviation {
+			outliers = append(outliers, i)
+		}
+	}
+	return
+}
+
+// VolumeTrimDetector sorts samples by price and discards those whose volume lies entirely within
+// the Fraction of the total volume at the low or at the high end. In contrast to trimming a number
+// of samples, many small trades at extreme prices are discarded, while a trade carrying a large
+// share of the volume is kept. The default Fraction is 0.1.
+type VolumeTrimDetector struct {
+	Fraction float64
+}
+
+func (d VolumeTrimDetector) Outliers(prices []float64, volumes []float64) (outliers []int) {
+	fraction := d.Fraction
+	if fraction == 0 {
+		fraction = 0.1
+	}
+	order := make([]int, len(prices))
+	var totalVolume float64
+	for i := range prices {
+		order[i] = i
+		totalVolume += math.Abs(volumes[i])
+	}
+	if totalVolume == 0 {
+		return
+	}
+	sort.SliceStable(order, func(a, b int) bool { return prices[order[a]] < prices[order[b]] })
+
+	lowerVolume, upperVolume := fraction*totalVolume, (1-fraction)*totalVolume
+	var cumulativeVolume float64
+	for _, i := range order {
+		begin := cumulativeVolume
+		cumulativeVolume += math.Abs(volumes[i])
+		// A sample is kept if its share of the volume overlaps the untrimmed range.
+		if cumulativeVolume <= lowerVolume || begin >= upperVolume {
+			outliers = append(outliers, i)
+		}
+	}
+	sort.Ints(outliers)
+	return
+}
+
+// BasisPointsDetector discards samples deviating from the median by BasisPoints or more,
+// as utils.DiscardOutliers does. The default BasisPoints are 500.
+type BasisPointsDetector struct {
+	BasisPoints float64
+}
+
+func (d BasisPointsDetector) Outliers(prices []float64, volumes []float64) (outliers []int) {
+	basisPoints := d.BasisPoints
+	if basisPoints == 0 {
+		basisPoints = 500
+	}
+	median := computeMedian(append([]float64(nil), prices...))
+	threshold := basisPoints * 0.0001 * median
+	for i, price := range prices {
+		if math.Abs(price-median) >= threshold {
+			outliers = append(outliers, i)
+		}
+	}
+	return
+}
+
+// robustDeviation returns the median of @samples and their scaled median absolute deviation.
+// If more than half of the samples equal the median, the scaled mean absolute deviation is returned instead.
+func robustDeviation(samples []float64) (median float64, deviation float64) {
+	if len(samples) == 0 {
+		return
+	}
+	median = computeMedian(append([]float64(nil), samples...))
+	deviations := make([]float64, len(samples))
+	var sumDeviations float64
+	for i, s := range samples {
+		deviations[i] = math.Abs(s - median)
+		sumDeviations += deviations[i]
+	}
+	deviation = madScale * computeMedian(deviations)
+	if deviation == 0 {
+		deviation = meanADScale * sumDeviations / float64(len(samples))
+	}
+	return
+}
+
+// removeOutliersWith returns @prices and @volumes without the outliers found by @detector,
+// keeping prices and volumes of the same sample together, and the indices of the outliers.
+func removeOutliersWith(detector OutlierDetector, prices []float64, volumes []float64) (cleanPrices []float64, cleanVolumes []float64, outliers []int) {
+	outliers = detector.Outliers(prices, volumes)
+	next := 0
+	for i := range prices {
+		if next < len(outliers) && outliers[next] == i {
+			next++
+			continue
+		}
+		cleanPrices = append(cleanPrices, prices[i])
+		cleanVolumes = append(cleanVolumes, volumes[i])
+	}
+	return
+}
+
+// excludedTrade identifies @trade in the outliers reported with a FilterPoint.
+func excludedTrade(trade dia.Trade) dia.ExcludedTrade {
+	return dia.ExcludedTrade{
+		Exchange:       trade.Source,
+		ForeignTradeID: trade.ForeignTradeID,
+		Price:          trade.EstimatedUSDPrice,
+		Volume:         trade.Volume,
+		Time:           trade.Time,
+	}
+}
+
+// excludedTrades returns the trades of the samples at @outliers without duplicates.
+// Filters sampling once per second repeat a trade in several samples.
+func excludedTrades(trades []dia.ExcludedTrade, outliers []int) (excluded []dia.ExcludedTrade) {
+	seen := make(map[dia.ExcludedTrade]bool)
+	for _, i := range outliers {
+		if i < len(trades) && !seen[trades[i]] {
+			seen[trades[i]] = true
+			excluded = append(excluded, trades[i])
+		}
+	}
+	return
+}
@@ -0,0 +1,123 @@
+package filters
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func TestOutlierDetectors(t *testing.T) {
+	prices := []float64{100, 101, 99, 100.5, 150, 99.5, 100, 60}
+	volumes := []float64{1, 1, 1, 1, 1, 1, 1, 1}
+
+	cases := []struct {
+		name     string
+		detector OutlierDetector
+		volumes  []float64
+		outliers []int
+	}{
+		{"IQR", IQRDetector{}, volumes, []int{4, 7}},
+		{"MAD", MADDetector{}, volumes, []int{4, 7}},
+		{"Hampel", HampelDetector{}, volumes, []int{4, 7}},
+		{"BPS", BasisPointsDetector{BasisPoints: 1000}, volumes, []int{4, 7}},
+		{"Volume", VolumeTrimDetector{Fraction: 0.2}, volumes, []int{4, 7}},
+		// The trade at 150 carries most of the volume and is kept, the lowest 20% of the volume are trimmed.
+		{"VolumeKeepsLargeTrade", VolumeTrimDetector{Fraction: 0.2}, []float64{1, 1, 1, 1, 20, 1, 1, 1}, []int{0, 2, 5, 6, 7}},
+	}
+	for _, c := range cases {
+		pricesCopy := append([]float64(nil), prices...)
+		outliers := c.detector.Outliers(pricesCopy, c.volumes)
+		if !reflect.DeepEqual(outliers, c.outliers) {
+			t.Errorf("%s: expected outliers %v and got %v", c.name, c.outliers, outliers)
+		}
+		if !reflect.DeepEqual(pricesCopy, prices) {
+			t.Errorf("%s: prices modified to %v", c.name, pricesCopy)
+		}
+	}
+}
+
+func TestRemoveOutliersKeepsVolumesAligned(t *testing.T) {
+	prices := []float64{102, 100, 101, 101, 100, 102, 150}
+	volumes := []float64{3, 1, 1, 1, 1, 1, 2}
+	cleanPrices, cleanVolumes, outliers := removeOutliersWith(IQRDetector{}, prices, volumes)
+	if !reflect.DeepEqual(cleanPrices, prices[:6]) || !reflect.DeepEqual(cleanVolumes, volumes[:6]) {
+		t.Errorf("expected prices %v with volumes %v and got %v with %v", prices[:6], volumes[:6], cleanPrices, cleanVolumes)
+	}
+	if !reflect.DeepEqual(outliers, []int{6}) {
+		t.Errorf("expected outliers [6] and got %v", outliers)
+	}
+	// Weighting the sorted prices 100, 100, 101, 101, 102, 102 with the volumes in trade order gave 100.75.
+	if mean, _ := computeMean(cleanPrices, cleanVolumes); mean != 101.25 {
+		t.Errorf("expected mean 101.25 and got %v", mean)
+	}
+}
+
+func TestMADWithEqualPrices(t *testing.T) {
+	// More than half of the prices are equal, so the median absolute deviation is zero.
+	outliers := MADDetector{}.Outliers([]float64{10, 10, 10, 10, 10, 10, 30}, nil)
+	if !reflect.DeepEqual(outliers, []int{6}) {
+		t.Errorf("expected outliers [6] and got %v", outliers)
+	}
+}
+
+func TestNewOutlierDetector(t *testing.T) {
+	for _, name := range []string{OutlierIQR, OutlierMAD, OutlierHampel, OutlierVolume, OutlierBPS} {
+		if _, err := NewOutlierDetector(name, 0); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := NewOutlierDetector("ZSCORE", 0); err == nil {
+		t.Error("expected error for unknown detector")
+	}
+	if _, err := NewOutlierDetector(OutlierVolume, 0.5); err == nil {
+		t.Error("expected error for trimming all volume")
+	}
+}
+
+func TestConfiguredOutlierDetector(t *testing.T) {
+	if _, err := NewFilterSets([]dia.FilterConfig{{Filter: "MA", Window: 120, OutlierDetector: OutlierMAD}}); err == nil {
+		t.Error("expected error for outlier detector on filter without outlier removal")
+	}
+	fs, err := NewFilterSets([]dia.FilterConfig{
+		{Filter: "VWAPIR", Window: 120},
+		{Blockchain: "Ethereum", Address: "0x1", Filter: "VWAPIR", Window: 120, OutlierDetector: OutlierBPS, OutlierThreshold: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1600000000, 0)
+	trades := []dia.Trade{
+		{Source: "Uniswap", ForeignTradeID: "a", EstimatedUSDPrice: 100, Volume: 1, Time: start},
+		{Source: "Uniswap", ForeignTradeID: "b", EstimatedUSDPrice: 102, Volume: 1, Time: start.Add(time.Second)},
+		{Source: "Uniswap", ForeignTradeID: "c", EstimatedUSDPrice: 100, Volume: 1, Time: start.Add(2 * time.Second)},
+	}
+	// The default IQR rule keeps all trades, a deviation of 100 basis points from the median excludes trade b.
+	for _, c := range []struct {
+		asset    dia.Asset
+		value    float64
+		excluded []string
+	}{
+		{dia.Asset{Blockchain: "Ethereum", Address: "0x2"}, 302.0 / 3, nil},
+		{dia.Asset{Blockchain: "Ethereum", Address: "0x1"}, 100, []string{"b"}},
+	} {
+		filter := fs.Filters(c.asset, "", start)[0].(*FilterVWAPIR)
+		for _, trade := range trades {
+			filter.compute(trade)
+		}
+		filter.finalCompute(start.Add(120 * time.Second))
+		fp := filter.FilterPointForBlock()
+		if fp.Value != c.value {
+			t.Errorf("%s: expected %v and got %v", c.asset.Address, c.value, fp.Value)
+		}
+		var excluded []string
+		for _, trade := range fp.Excluded {
+			excluded = append(excluded, trade.ForeignTradeID)
+		}
+		if !reflect.DeepEqual(excluded, c.excluded) {
+			t.Errorf("%s: expected excluded trades %v and got %v", c.asset.Address, c.excluded, excluded)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package filters
+
+import (
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Pool addresses and current liquidities are read from postgres at most once per poolLiquidityRefresh.
+	poolLiquidityRefresh = 10 * time.Minute
+	// Past liquidities are looked up once per poolHistoryFrame of trade time, using the latest pool
+	// state recorded within poolHistoryLookback before the end of the frame.
+	poolHistoryFrame    = time.Hour
+	poolHistoryLookback = 24 * time.Hour
+)
+
+// LiquiditySource returns the liquidity of the quote token of @trade in the pools of the trade's pair
+// on its exchange. @ok is false if no pool is known, e.g. for trades on centralized exchanges.
+type LiquiditySource interface {
+	QuoteLiquidity(trade dia.Trade) (liquidity float64, ok bool)
+}
+
+// PoolSource is the subset of models.RelDB used for looking up pools and their current liquidity.
+type PoolSource interface {
+	GetPoolAddrsByAssetPair(exchange string, asset0 dia.Asset, asset1 dia.Asset) ([]string, error)
+	GetPoolByAddress(blockchain string, address string) (dia.Pool, error)
+}
+
+// PoolHistory is the subset of models.DB used for looking up past liquidities of pools.
+type PoolHistory interface {
+	GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error)
+}
+
+type poolPairKey struct {
+	exchange   string
+	blockchain string
+	quote      string
+	base       string
+}
+
+type poolStateKey struct {
+	blockchain string
+	address    string
+	// frame is the begin of the frame of trade time for past liquidities and zero otherwise.
+	frame int64
+}
+
+type cachedPoolAddrs struct {
+	addresses []string
+	fetched   time.Time
+}
+
+type cachedPoolState struct {
+	pool    dia.Pool
+	ok      bool
+	fetched time.Time
+}
+
+// PoolLiquidity is a LiquiditySource reading pools from postgres. If a PoolHistory is given,
+// liquidities at the time of the trade are used instead of the current ones.
+type PoolLiquidity struct {
+	pools   PoolSource
+	history PoolHistory
+
+	mu        sync.Mutex
+	addresses map[poolPairKey]cachedPoolAddrs
+	states    map[poolStateKey]cachedPoolState
+}
+
+// NewPoolLiquidity returns a PoolLiquidity looking up pools in @pools and, if not nil, past liquidities in @history.
+func NewPoolLiquidity(pools PoolSource, history PoolHistory) *PoolLiquidity {
+	return &PoolLiquidity{
+		pools:     pools,
+		history:   history,
+		addresses: make(map[poolPairKey]cachedPoolAddrs),
+		states:    make(map[poolStateKey]cachedPoolState),
+	}
+}
+
+// QuoteLiquidity returns the summed liquidity of @trade's quote token in all pools of its pair on its exchange.
+func (pl *PoolLiquidity) QuoteLiquidity(trade dia.Trade) (liquidity float64, ok bool) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	for _, address := range pl.poolAddresses(trade) {
+		pool, found := pl.poolState(trade.QuoteToken.Blockchain, address, trade.Time)
+		if !found {
+			continue
+		}
+		for _, av := range pool.Assetvolumes {
+			if av.Asset.Address == trade.QuoteToken.Address {
+				liquidity += av.Volume
+			}
+		}
+	}
+	return liquidity, liquidity > 0
+}
+
+func (pl *PoolLiquidity) poolAddresses(trade dia.Trade) []string {
+	key := poolPairKey{
+		exchange:   trade.Source,
+		blockchain: trade.QuoteToken.Blockchain,
+		quote:      trade.QuoteToken.Address,
+		base:       trade.BaseToken.Address,
+	}
+	if cached, ok := pl.addresses[key]; ok && time.Since(cached.fetched) < poolLiquidityRefresh {
+		return cached.addresses
+	}
+	addresses, err := pl.pools.GetPoolAddrsByAssetPair(trade.Source, trade.QuoteToken, trade.BaseToken)
+	if err != nil {
+		log.Errorf("get pools of %s-%s on %s: %v", trade.QuoteToken.Symbol, trade.BaseToken.Symbol, trade.Source, err)
+	}
+	pl.addresses[key] = cachedPoolAddrs{addresses: addresses, fetched: time.Now()}
+	return addresses
+}
+
+func (pl *PoolLiquidity) poolState(blockchain string, address string, timestamp time.Time) (dia.Pool, bool) {
+	key := poolStateKey{blockchain: blockchain, address: address}
+	if pl.history != nil {
+		key.frame = timestamp.Truncate(poolHistoryFrame).Unix()
+	}
+	if cached, ok := pl.states[key]; ok && (pl.history != nil || time.Since(cached.fetched) < poolLiquidityRefresh) {
+		return cached.pool, cached.ok
+	}
+
+	state := cachedPoolState{fetched: time.Now()}
+	if pl.history != nil {
+		frameEnd := time.Unix(key.frame, 0).Add(poolHistoryFrame)
+		pools, err := pl.history.GetPoolInflux(address, frameEnd.Add(-poolHistoryLookback), frameEnd)
+		if err != nil {
+			log.Errorf("get liquidity of pool %s: %v", address, err)
+		}
+		// Pools are returned in descending order of time.
+		if len(pools) > 0 {
+			state.pool, state.ok = pools[0], true
+		}
+	} else {
+		pool, err := pl.pools.GetPoolByAddress(blockchain, address)
+		if err != nil {
+			log.Errorf("get liquidity of pool %s: %v", address, err)
+		} else {
+			state.pool, state.ok = pool, true
+		}
+	}
+	pl.states[key] = state
+	return state.pool, state.ok
+}
@@ -0,0 +1,59 @@
+package priceResolver
+
+import (
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	usdcEthereum = dia.Asset{Symbol: "USDC", Address: "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", Blockchain: dia.ETHEREUM}
+	usdtEthereum = dia.Asset{Symbol: "USDT", Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Blockchain: dia.ETHEREUM}
+	ethEthereum  = dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+)
+
+// DefaultBridges returns the wrapped native tokens and bridged stablecoins which are priced
+// as their native or original counterparts.
+func DefaultBridges() []Bridge {
+	return []Bridge{
+		// Wrapped native tokens.
+		{From: dia.Asset{Symbol: "WETH", Address: "0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", Blockchain: dia.ETHEREUM}, To: ethEthereum},
+		{From: dia.Asset{Symbol: "WETH", Address: common.HexToAddress("0x82aF49447D8a07e3bd95BD0d56f35241523fBab1").Hex(), Blockchain: dia.ARBITRUM}, To: ethEthereum},
+		{
+			From: dia.Asset{Symbol: "WFTM", Address: "0x21be370D5312f44cB42ce377BC9b8a0cEF1A4C83", Blockchain: dia.FANTOM},
+			To:   dia.Asset{Symbol: "FTM", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.FANTOM},
+		},
+		{
+			From: dia.Asset{Symbol: "WTLOS", Address: common.HexToAddress("0xd102ce6a4db07d247fcc28f366a623df0938ca9e").Hex(), Blockchain: dia.TELOS},
+			To:   dia.Asset{Symbol: "TLOS", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.TELOS},
+		},
+		{
+			From: dia.Asset{Symbol: "WGLMR", Address: common.HexToAddress("0xAcc15dC74880C9944775448304B263D191c6077F").Hex(), Blockchain: dia.MOONBEAM},
+			To:   dia.Asset{Symbol: "GLMR", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.MOONBEAM},
+		},
+		{
+			From: dia.Asset{Symbol: "WMATIC", Address: common.HexToAddress("0x0d500B1d8E8eF31E21C99d1Db9A6444d3ADf1270").Hex(), Blockchain: dia.POLYGON},
+			To:   dia.Asset{Symbol: "MATIC", Address: "0x0000000000000000000000000000000000001010", Blockchain: dia.POLYGON},
+		},
+		{
+			From: dia.Asset{Symbol: "WASTR", Address: common.HexToAddress("0xAeaaf0e2c81Af264101B9129C00F4440cCF0F720").Hex(), Blockchain: dia.ASTAR},
+			To:   dia.Asset{Symbol: "ASTR", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ASTAR},
+		},
+		{
+			From: dia.Asset{Symbol: "WAVAX", Address: common.HexToAddress("0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7").Hex(), Blockchain: dia.AVALANCHE},
+			To:   dia.Asset{Symbol: "AVAX", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.AVALANCHE},
+		},
+		{
+			From: dia.Asset{Symbol: "WWAN", Address: common.HexToAddress("0xdabD997aE5E4799BE47d6E69D9431615CBa28f48").Hex(), Blockchain: dia.WANCHAIN},
+			To:   dia.Asset{Symbol: "WAN", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.WANCHAIN},
+		},
+
+		// Bridged stablecoins.
+		{From: dia.Asset{Symbol: "USDC", Address: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", Blockchain: dia.SOLANA}, To: usdcEthereum},
+		{From: dia.Asset{Symbol: "USDT", Address: "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB", Blockchain: dia.SOLANA}, To: usdtEthereum},
+		{From: dia.Asset{Symbol: "m.USDC", Address: "0xEA32A96608495e54156Ae48931A7c20f0dcc1a21", Blockchain: dia.METIS}, To: usdcEthereum},
+		{From: dia.Asset{Symbol: "USDC", Address: "0x04068DA6C83AFCFA0e13ba15A6696662335D5B75", Blockchain: dia.FANTOM}, To: usdcEthereum},
+		{From: dia.Asset{Symbol: "USDC", Address: common.HexToAddress("0x51e44FfaD5C2B122C8b635671FCC8139dc636E82").Hex(), Blockchain: dia.EVMOS}, To: usdcEthereum},
+		{From: dia.Asset{Symbol: "USDC", Address: common.HexToAddress("0x2791Bca1f2de4661ED88A30C99A7a9449Aa84174").Hex(), Blockchain: dia.POLYGON}, To: usdcEthereum},
+		{From: dia.Asset{Symbol: "USDC", Address: common.HexToAddress("0x6a2d262D56735DbA19Dd70682B39F6bE9a931D98").Hex(), Blockchain: dia.ASTAR}, To: usdcEthereum},
+	}
+}
@@ -0,0 +1,283 @@
+package priceResolver
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/diadata-org/diadata/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// Quotations, including failed lookups, are cached for quotationCacheFrame of trade time.
+	quotationCacheFrame = 60 * time.Second
+	// Asset groups are read from postgres at most once per assetMapRefresh.
+	assetMapRefresh = time.Hour
+)
+
+var (
+	log *logrus.Logger
+	// MaxHops is the maximal number of conversions between the base token and a priced asset.
+	MaxHops int
+	// Staleness is the maximal age of quotations and exchange rates used for resolving a price.
+	// Zero means no limit.
+	Staleness time.Duration
+	// ErrNoRoute is returned if no priced asset can be reached from the base token.
+	ErrNoRoute = errors.New("no route to a priced asset")
+)
+
+func init() {
+	log = logrus.New()
+
+	var err error
+	MaxHops, err = strconv.Atoi(utils.Getenv("PRICE_RESOLVER_MAX_HOPS", "3"))
+	if err != nil {
+		log.Error("parse PRICE_RESOLVER_MAX_HOPS: ", err)
+	}
+	stalenessSeconds, err := strconv.Atoi(utils.Getenv("PRICE_RESOLVER_STALENESS_SECONDS", "86400"))
+	if err != nil {
+		log.Error("parse PRICE_RESOLVER_STALENESS_SECONDS: ", err)
+	}
+	Staleness = time.Duration(stalenessSeconds) * time.Second
+}
+
+// QuotationFunc returns the latest quotation of @asset at @timestamp.
+type QuotationFunc func(asset dia.Asset, timestamp time.Time) (*models.AssetQuotation, error)
+
+// AssetMapSource is the subset of models.RelDB used for looking up assets in the same asset group.
+type AssetMapSource interface {
+	GetAssetID(asset dia.Asset) (string, error)
+	GetAssetMap(assetID string) (string, error)
+	GetAssetByGroupID(groupID string) ([]dia.Asset, error)
+}
+
+// Bridge states that @From can be priced as @To, e.g. a wrapped native token as the native token.
+type Bridge struct {
+	From dia.Asset
+	To   dia.Asset
+}
+
+type assetKey struct {
+	blockchain string
+	address    string
+}
+
+func keyOf(asset dia.Asset) assetKey {
+	return assetKey{blockchain: asset.Blockchain, address: asset.Address}
+}
+
+type rate struct {
+	to        dia.Asset
+	rate      float64
+	timestamp time.Time
+}
+
+type cachedQuotation struct {
+	price     float64
+	timestamp time.Time
+	// lookup is the trade time of the lookup, as opposed to the time of the quotation.
+	lookup time.Time
+}
+
+type assetGroup struct {
+	assets  []dia.Asset
+	fetched time.Time
+}
+
+// Resolver determines the USD price of a base token. If there is no quotation for the base token,
+// it follows bridges, asset groups and exchange rates observed in trades, such as
+// token->WAVAX->USDC, until an asset with quotation is reached.
+type Resolver struct {
+	quotation QuotationFunc
+	assetMap  AssetMapSource
+	maxHops   int
+	staleness time.Duration
+	bridges   map[assetKey][]dia.Asset
+
+	mu         sync.Mutex
+	rates      map[assetKey]map[assetKey]rate
+	quotations map[assetKey]cachedQuotation
+	groups     map[assetKey]assetGroup
+}
+
+// NewResolver returns a Resolver looking up quotations with @quotation and asset groups in @assetMap.
+// Asset groups are not used if @assetMap is nil.
+func NewResolver(quotation QuotationFunc, assetMap AssetMapSource, bridges []Bridge, maxHops int, staleness time.Duration) *Resolver {
+	r := &Resolver{
+		quotation:  quotation,
+		assetMap:   assetMap,
+		maxHops:    maxHops,
+		staleness:  staleness,
+		bridges:    make(map[assetKey][]dia.Asset),
+		rates:      make(map[assetKey]map[assetKey]rate),
+		quotations: make(map[assetKey]cachedQuotation),
+		groups:     make(map[assetKey]assetGroup),
+	}
+	for _, b := range bridges {
+		r.bridges[keyOf(b.From)] = append(r.bridges[keyOf(b.From)], b.To)
+	}
+	return r
+}
+
+// Observe remembers the exchange rate between the quote and base token of @t.
+func (r *Resolver) Observe(t dia.Trade) {
+	if t.Price <= 0 || math.IsInf(t.Price, 0) || keyOf(t.QuoteToken) == keyOf(t.BaseToken) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setRate(t.QuoteToken, t.BaseToken, t.Price, t.Time)
+	r.setRate(t.BaseToken, t.QuoteToken, 1/t.Price, t.Time)
+}
+
+// Price returns the USD price of @asset at @timestamp, resolved with the least number of hops.
+func (r *Resolver) Price(asset dia.Asset, timestamp time.Time) (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type node struct {
+		asset  dia.Asset
+		factor float64
+	}
+	// Bridged assets are priced as their counterpart rather than by their own quotation.
+	start := asset
+	if bridged := r.bridges[keyOf(asset)]; len(bridged) > 0 {
+		start = bridged[0]
+	}
+	visited := map[assetKey]bool{keyOf(asset): true, keyOf(start): true}
+	frontier := []node{{asset: start, factor: 1}}
+	for hops := 0; len(frontier) > 0; hops++ {
+		for _, n := range frontier {
+			if price, ok := r.quotedPrice(n.asset, timestamp); ok {
+				if hops > 0 {
+					log.Debugf("resolved price of %s via %s in %d hops", asset.Symbol, n.asset.Symbol, hops)
+				}
+				return n.factor * price, nil
+			}
+		}
+		if hops == r.maxHops {
+			break
+		}
+		var next []node
+		for _, n := range frontier {
+			for _, e := range r.edges(n.asset, timestamp) {
+				if visited[keyOf(e.to)] {
+					continue
+				}
+				visited[keyOf(e.to)] = true
+				next = append(next, node{asset: e.to, factor: n.factor * e.rate})
+			}
+		}
+		frontier = next
+	}
+	return 0, ErrNoRoute
+}
+
+// edges returns all assets @asset can be converted to with the corresponding rates.
+// Bridges and asset groups come first, followed by exchange rates in a deterministic order.
+func (r *Resolver) edges(asset dia.Asset, timestamp time.Time) (edges []rate) {
+	for _, to := range r.bridges[keyOf(asset)] {
+		edges = append(edges, rate{to: to, rate: 1})
+	}
+	for _, to := range r.group(asset) {
+		edges = append(edges, rate{to: to, rate: 1})
+	}
+	var rates []rate
+	for _, e := range r.rates[keyOf(asset)] {
+		if r.fresh(e.timestamp, timestamp) {
+			rates = append(rates, e)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].to.Blockchain != rates[j].to.Blockchain {
+			return rates[i].to.Blockchain < rates[j].to.Blockchain
+		}
+		return rates[i].to.Address < rates[j].to.Address
+	})
+	return append(edges, rates...)
+}
+
+// quotedPrice returns the price of @asset if there is a quotation which is not stale.
+func (r *Resolver) quotedPrice(asset dia.Asset, timestamp time.Time) (float64, bool) {
+	if asset.Blockchain == dia.FIAT && asset.Address == "840" {
+		return 1, true
+	}
+	key := keyOf(asset)
+	cached, ok := r.quotations[key]
+	if !ok || math.Abs(timestamp.Sub(cached.lookup).Seconds()) >= quotationCacheFrame.Seconds() {
+		cached = cachedQuotation{lookup: timestamp}
+		quotation, err := r.quotation(asset, timestamp)
+		if err == nil && quotation != nil {
+			cached.price = quotation.Price
+			cached.timestamp = quotation.Time
+		}
+		r.quotations[key] = cached
+	}
+	return cached.price, cached.price > 0 && r.fresh(cached.timestamp, timestamp)
+}
+
+// group returns the other assets in the asset group of @asset.
+func (r *Resolver) group(asset dia.Asset) []dia.Asset {
+	if r.assetMap == nil {
+		return nil
+	}
+	key := keyOf(asset)
+	if g, ok := r.groups[key]; ok && time.Since(g.fetched) < assetMapRefresh {
+		return g.assets
+	}
+	g := assetGroup{fetched: time.Now()}
+	assetID, err := r.assetMap.GetAssetID(asset)
+	if err == nil {
+		var groupID string
+		groupID, err = r.assetMap.GetAssetMap(assetID)
+		if err == nil {
+			var assets []dia.Asset
+			assets, err = r.assetMap.GetAssetByGroupID(groupID)
+			for _, a := range assets {
+				if keyOf(a) != key {
+					g.assets = append(g.assets, a)
+				}
+			}
+		}
+	}
+	if err != nil {
+		log.Debugf("no asset group for %s: %v", asset.Symbol, err)
+	}
+	r.groups[key] = g
+	return g.assets
+}
+
+func (r *Resolver) setRate(from dia.Asset, to dia.Asset, value float64, timestamp time.Time) {
+	rates, ok := r.rates[keyOf(from)]
+	if !ok {
+		rates = make(map[assetKey]rate)
+		r.rates[keyOf(from)] = rates
+	}
+	if e, ok := rates[keyOf(to)]; ok && e.timestamp.After(timestamp) {
+		return
+	}
+	rates[keyOf(to)] = rate{to: to, rate: value, timestamp: timestamp}
+}
+
+// fresh returns true if a value from @valueTime can be used at @timestamp.
+func (r *Resolver) fresh(valueTime time.Time, timestamp time.Time) bool {
+	return r.staleness <= 0 || timestamp.Sub(valueTime) <= r.staleness
+}
+
+// LatestQuotation returns a QuotationFunc for live trades which looks up the latest quotation in the cache.
+func LatestQuotation(datastore models.QuotationStore) QuotationFunc {
+	return func(asset dia.Asset, timestamp time.Time) (*models.AssetQuotation, error) {
+		return datastore.GetAssetQuotationCache(asset)
+	}
+}
+
+// HistoricalQuotation returns a QuotationFunc which looks up the last quotation before the trade time.
+func HistoricalQuotation(datastore models.QuotationStore) QuotationFunc {
+	return datastore.GetAssetQuotation
+}
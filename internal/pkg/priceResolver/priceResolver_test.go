@@ -0,0 +1,123 @@
+package priceResolver
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	models "github.com/diadata-org/diadata/pkg/model"
+)
+
+var (
+	testBegin   = time.Unix(1654041600, 0)
+	token       = dia.Asset{Symbol: "JOE", Address: "0x6e84a6216eA6dACC71eE8E6b0a5B7322EEbC0fDd", Blockchain: dia.AVALANCHE}
+	wavax       = dia.Asset{Symbol: "WAVAX", Address: "0xB31f66AA3C1e785363F0875A1B74E27b85FD66c7", Blockchain: dia.AVALANCHE}
+	usdcBridged = dia.Asset{Symbol: "USDC.e", Address: "0xA7D7079b0FEaD91F3e65f86E8915Cb59c1a4C664", Blockchain: dia.AVALANCHE}
+)
+
+// testQuotations serves quotations from a map instead of the cache.
+type testQuotations map[assetKey]models.AssetQuotation
+
+func (tq testQuotations) quotation(asset dia.Asset, timestamp time.Time) (*models.AssetQuotation, error) {
+	q, ok := tq[keyOf(asset)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &q, nil
+}
+
+type testAssetMap struct{}
+
+func (testAssetMap) GetAssetID(asset dia.Asset) (string, error) {
+	return asset.Blockchain + "-" + asset.Address, nil
+}
+
+func (testAssetMap) GetAssetMap(assetID string) (string, error) {
+	if assetID == usdcBridged.Blockchain+"-"+usdcBridged.Address || assetID == usdcEthereum.Blockchain+"-"+usdcEthereum.Address {
+		return "usdc", nil
+	}
+	return "", errors.New("no group")
+}
+
+func (testAssetMap) GetAssetByGroupID(groupID string) ([]dia.Asset, error) {
+	return []dia.Asset{usdcBridged, usdcEthereum}, nil
+}
+
+func testTrade(quoteToken dia.Asset, baseToken dia.Asset, price float64, timestamp time.Time) dia.Trade {
+	return dia.Trade{QuoteToken: quoteToken, BaseToken: baseToken, Price: price, Time: timestamp}
+}
+
+func TestResolverMultiHop(t *testing.T) {
+	quotations := testQuotations{keyOf(usdcEthereum): {Asset: usdcEthereum, Price: 0.99, Time: testBegin}}
+	r := NewResolver(quotations.quotation, testAssetMap{}, nil, 3, time.Hour)
+
+	// USDC.e is priced via its asset group.
+	price, err := r.Price(usdcBridged, testBegin)
+	if err != nil || price != 0.99 {
+		t.Errorf("USDC.e: got %v, %v", price, err)
+	}
+
+	if _, err = r.Price(token, testBegin); err != ErrNoRoute {
+		t.Errorf("expected no route without observed trades, got %v", err)
+	}
+
+	// JOE -> WAVAX -> USDC.e -> USDC.
+	r.Observe(testTrade(token, wavax, 0.05, testBegin))
+	r.Observe(testTrade(wavax, usdcBridged, 20, testBegin))
+	price, err = r.Price(token, testBegin.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := 0.05 * 20 * 0.99; price < expected*(1-1e-9) || price > expected*(1+1e-9) {
+		t.Errorf("JOE: got %v, expected %v", price, expected)
+	}
+
+	// Rates are used in both directions.
+	price, err = r.Price(wavax, testBegin.Add(time.Minute))
+	if err != nil || price != 20*0.99 {
+		t.Errorf("WAVAX: got %v, %v", price, err)
+	}
+
+	// The route needs 3 hops.
+	r.maxHops = 2
+	r.quotations = make(map[assetKey]cachedQuotation)
+	if _, err = r.Price(token, testBegin.Add(time.Minute)); err != ErrNoRoute {
+		t.Errorf("expected no route within 2 hops, got %v", err)
+	}
+	r.maxHops = 3
+
+	// Observed rates become stale, even if the quotation is recent.
+	quotations[keyOf(usdcEthereum)] = models.AssetQuotation{Asset: usdcEthereum, Price: 0.99, Time: testBegin.Add(2 * time.Hour)}
+	if price, err = r.Price(usdcBridged, testBegin.Add(2*time.Hour)); err != nil {
+		t.Errorf("USDC.e with recent quotation: got %v, %v", price, err)
+	}
+	if _, err = r.Price(token, testBegin.Add(2*time.Hour)); err != ErrNoRoute {
+		t.Errorf("expected no route with stale rates, got %v", err)
+	}
+}
+
+func TestResolverBridges(t *testing.T) {
+	avax := dia.Asset{Symbol: "AVAX", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.AVALANCHE}
+	quotations := testQuotations{
+		keyOf(avax):  {Asset: avax, Price: 20, Time: testBegin},
+		keyOf(wavax): {Asset: wavax, Price: 25, Time: testBegin},
+	}
+	r := NewResolver(quotations.quotation, nil, DefaultBridges(), 3, time.Hour)
+
+	// The wrapped token is priced as the native token, regardless of its own quotation.
+	price, err := r.Price(wavax, testBegin)
+	if err != nil || price != 20 {
+		t.Errorf("WAVAX: got %v, %v", price, err)
+	}
+
+	// Stale quotations are not used.
+	if _, err = r.Price(wavax, testBegin.Add(2*time.Hour)); err != ErrNoRoute {
+		t.Errorf("expected stale quotation to be ignored, got %v", err)
+	}
+
+	usd := dia.Asset{Symbol: "USD", Address: "840", Blockchain: dia.FIAT}
+	if price, err = r.Price(usd, testBegin); err != nil || price != 1 {
+		t.Errorf("USD: got %v, %v", price, err)
+	}
+}
@@ -27,13 +27,13 @@ type RateScraper struct {
 	error            error
 	closed           bool
 	ticker           *time.Ticker
-	datastore        models.Datastore
+	datastore        models.RateStore
 	chanInterestRate chan *models.InterestRate
 }
 
 // SpawnRateScraper returns a new RateScraper initialized with default values.
 // The instance is asynchronously scraping as soon as it is created.
-func SpawnRateScraper(datastore models.Datastore, rateType string) *RateScraper {
+func SpawnRateScraper(datastore models.RateStore, rateType string) *RateScraper {
 	s := &RateScraper{
 		shutdown:         make(chan nothing),
 		shutdownDone:     make(chan nothing),
@@ -0,0 +1,393 @@
+package sanityRules
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/configCollectors"
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/diadata-org/diadata/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// Reasons for which a trade is rejected.
+const (
+	ReasonLowVolume      = "low_volume"
+	ReasonDeviation      = "deviation_from_quotation"
+	ReasonStablecoinPeg  = "stablecoin_peg"
+	ReasonPriceJump      = "price_jump"
+	SourceDefault        = "default"
+	SourceFile           = "file"
+	SourcePostgres       = "postgres"
+	quotationCacheFrame  = 120 * time.Second
+	defaultRulesFilename = "sanityRules"
+)
+
+var (
+	log *logrus.Logger
+	// ISO 4217 codes of the currencies stablecoins can be pegged to.
+	// Fiat assets are identified by these codes in the asset table.
+	fiatCodes = map[string]string{
+		"USD": "840",
+		"EUR": "978",
+		"GBP": "826",
+		"CHF": "756",
+		"JPY": "392",
+	}
+)
+
+func init() {
+	log = logrus.New()
+}
+
+// StablecoinRules returns rules for the pegs of major USD and EUR stablecoins with relative tolerance @tolerance.
+func StablecoinRules(tolerance float64) (rules []dia.SanityRule) {
+	pegs := map[string][]string{
+		"USD": {"USDC", "USDT", "TUSD", "DAI", "PAX", "BUSD"},
+		"EUR": {"EURS", "EURT", "EUROC"},
+	}
+	for _, peg := range []string{"USD", "EUR"} {
+		for _, symbol := range pegs[peg] {
+			rules = append(rules, dia.SanityRule{Symbol: symbol, Peg: peg, PegTolerance: tolerance})
+		}
+	}
+	return
+}
+
+// PriceFunc returns the USD price of @asset at @timestamp.
+type PriceFunc func(asset dia.Asset, timestamp time.Time) (float64, error)
+
+// RejectionStore persists the numbers of rejected trades.
+type RejectionStore interface {
+	SetTradeRejection(rejection dia.TradeRejection) error
+}
+
+type rejectionKey struct {
+	blockchain string
+	address    string
+	exchange   string
+	reason     string
+}
+
+type jumpKey struct {
+	blockchain string
+	address    string
+	exchange   string
+}
+
+// jumpState holds the last accepted price of an asset on an exchange. reference is the last
+// accepted price in a block previous to @block.
+type jumpState struct {
+	block     int64
+	reference float64
+	last      float64
+}
+
+type cachedPrice struct {
+	price     float64
+	timestamp time.Time
+}
+
+// Engine checks trades against a set of sanity rules and counts the rejected trades.
+// Counts are written to the RejectionStore once per block of trade time.
+type Engine struct {
+	rules         []dia.SanityRule
+	blockDuration int64
+	price         PriceFunc
+	store         RejectionStore
+
+	mu          sync.Mutex
+	prices      map[dia.Asset]cachedPrice
+	jumps       map[jumpKey]jumpState
+	rejections  map[rejectionKey]int64
+	assets      map[rejectionKey]dia.Asset
+	flushedTime time.Time
+}
+
+// NewEngine returns an Engine for @rules. Trades are grouped into blocks of @blockDuration seconds
+// for the price jump check and for storing the numbers of rejected trades.
+// Rules depending on prices are not checked if @price is nil. Rejections are only logged if @store is nil.
+func NewEngine(rules []dia.SanityRule, blockDuration int64, price PriceFunc, store RejectionStore) *Engine {
+	return &Engine{
+		rules:         rules,
+		blockDuration: blockDuration,
+		price:         price,
+		store:         store,
+		prices:        make(map[dia.Asset]cachedPrice),
+		jumps:         make(map[jumpKey]jumpState),
+		rejections:    make(map[rejectionKey]int64),
+		assets:        make(map[rejectionKey]dia.Asset),
+	}
+}
+
+// LoadRules returns the sanity rules from the source given by the env var SANITY_RULES_SOURCE.
+// It is either "default", "file" for the json file SANITY_RULES_FILE in the config folder or "postgres".
+// @defaults are returned for source "default".
+func LoadRules(relDB models.SanityRuleStore, defaults []dia.SanityRule) ([]dia.SanityRule, error) {
+	source := utils.Getenv("SANITY_RULES_SOURCE", SourceDefault)
+	switch source {
+	case SourceDefault:
+		return defaults, nil
+	case SourceFile:
+		return ReadRulesFromConfig(utils.Getenv("SANITY_RULES_FILE", defaultRulesFilename))
+	case SourcePostgres:
+		if relDB == nil {
+			return nil, errors.New("no postgres connection for sanity rules")
+		}
+		return relDB.GetSanityRules()
+	}
+	return nil, errors.New("unknown source for sanity rules: " + source)
+}
+
+// ReadRulesFromConfig returns the sanity rules from the json file @filename in the config folder.
+func ReadRulesFromConfig(filename string) ([]dia.SanityRule, error) {
+	content, err := configCollectors.ReadJSONFromConfig(filename)
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Rules []dia.SanityRule `json:"Rules"`
+	}
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, err
+	}
+	return config.Rules, nil
+}
+
+// Rule returns the rule applying to @t. All matching rules are merged in the order of their
+// specificity, i.e. nonzero bounds of rules for an asset override bounds of rules for an exchange,
+// which override bounds of rules matching all trades.
+func (e *Engine) Rule(t dia.Trade) (rule dia.SanityRule) {
+	for specificity := 0; specificity < 4; specificity++ {
+		for _, r := range e.rules {
+			if ruleSpecificity(r) != specificity || !matches(r, t) {
+				continue
+			}
+			if r.MinVolume != 0 {
+				rule.MinVolume = r.MinVolume
+			}
+			if r.MaxDeviation != 0 {
+				rule.MaxDeviation = r.MaxDeviation
+			}
+			if r.Peg != "" {
+				rule.Peg = r.Peg
+			}
+			if r.PegTolerance != 0 {
+				rule.PegTolerance = r.PegTolerance
+			}
+			if r.MaxPriceJump != 0 {
+				rule.MaxPriceJump = r.MaxPriceJump
+			}
+		}
+	}
+	return
+}
+
+// Check returns true if @t, whose EstimatedUSDPrice is already filled, satisfies the rule applying to it.
+// Otherwise, the rejection is counted and its reason is returned.
+func (e *Engine) Check(t dia.Trade) (ok bool, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.flush(t.Time)
+	rule := e.Rule(t)
+	reason = e.reject(t, rule)
+	if reason != "" {
+		log.Debugf("reject trade %s on %s: %s", t.Pair, t.Source, reason)
+		key := rejectionKey{
+			blockchain: t.QuoteToken.Blockchain,
+			address:    t.QuoteToken.Address,
+			exchange:   t.Source,
+			reason:     reason,
+		}
+		e.rejections[key]++
+		e.assets[key] = t.QuoteToken
+		return false, reason
+	}
+	e.accept(t)
+	return true, ""
+}
+
+// Rejections returns the numbers of rejected trades counted since the last write to the store.
+func (e *Engine) Rejections() (rejections []dia.TradeRejection) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for key, count := range e.rejections {
+		rejections = append(rejections, dia.TradeRejection{
+			Asset:    e.assets[key],
+			Exchange: key.exchange,
+			Reason:   key.reason,
+			Count:    count,
+		})
+	}
+	return
+}
+
+// reject returns the reason for rejecting @t or an empty string.
+func (e *Engine) reject(t dia.Trade, rule dia.SanityRule) string {
+	if rule.MinVolume > 0 && math.Abs(t.Volume) < rule.MinVolume {
+		return ReasonLowVolume
+	}
+	// Checks depending on prices are skipped if no price is available.
+	if rule.Peg != "" && rule.PegTolerance > 0 {
+		if peg, ok := e.pegPrice(rule.Peg, t.Time); ok && relativeDeviation(t.EstimatedUSDPrice, peg) > rule.PegTolerance {
+			return ReasonStablecoinPeg
+		}
+	}
+	if rule.MaxDeviation > 0 {
+		if quotation, ok := e.cachedPrice(t.QuoteToken, t.Time); ok && relativeDeviation(t.EstimatedUSDPrice, quotation) > rule.MaxDeviation {
+			return ReasonDeviation
+		}
+	}
+	if rule.MaxPriceJump > 0 {
+		state := e.jumpState(t)
+		if state.reference > 0 && relativeDeviation(t.EstimatedUSDPrice, state.reference) > rule.MaxPriceJump {
+			return ReasonPriceJump
+		}
+	}
+	return ""
+}
+
+// accept remembers the price of the accepted trade @t as reference for the price jump check.
+func (e *Engine) accept(t dia.Trade) {
+	key := jumpKey{blockchain: t.QuoteToken.Blockchain, address: t.QuoteToken.Address, exchange: t.Source}
+	state := e.jumpState(t)
+	state.last = t.EstimatedUSDPrice
+	e.jumps[key] = state
+}
+
+// jumpState returns the state of the asset and exchange of @t for the block of @t.
+func (e *Engine) jumpState(t dia.Trade) jumpState {
+	key := jumpKey{blockchain: t.QuoteToken.Blockchain, address: t.QuoteToken.Address, exchange: t.Source}
+	state := e.jumps[key]
+	if block := e.block(t.Time); block > state.block {
+		state.reference = state.last
+		state.block = block
+		e.jumps[key] = state
+	}
+	return state
+}
+
+// pegPrice returns the USD price of the fiat currency @peg.
+func (e *Engine) pegPrice(peg string, timestamp time.Time) (float64, bool) {
+	if peg == "USD" {
+		return 1, true
+	}
+	code, ok := fiatCodes[peg]
+	if !ok {
+		log.Errorf("unknown peg currency %s", peg)
+		return 0, false
+	}
+	return e.cachedPrice(dia.Asset{Symbol: peg, Address: code, Blockchain: dia.FIAT}, timestamp)
+}
+
+// cachedPrice returns the price of @asset and false if it is not available. Prices are looked up
+// at most once per quotationCacheFrame of trade time, including failed lookups.
+func (e *Engine) cachedPrice(asset dia.Asset, timestamp time.Time) (float64, bool) {
+	if e.price == nil {
+		return 0, false
+	}
+	cached, ok := e.prices[asset]
+	if !ok || timestamp.Sub(cached.timestamp) >= quotationCacheFrame || cached.timestamp.Sub(timestamp) >= quotationCacheFrame {
+		price, err := e.price(asset, timestamp)
+		if err != nil {
+			log.Warnf("get price of %s for sanity check: %v", asset.Symbol, err)
+			price = 0
+		}
+		cached = cachedPrice{price: price, timestamp: timestamp}
+		e.prices[asset] = cached
+	}
+	return cached.price, cached.price > 0
+}
+
+// flush writes the counted rejections to the store once @timestamp is in a block later than the last write.
+func (e *Engine) flush(timestamp time.Time) {
+	if e.flushedTime.IsZero() {
+		e.flushedTime = e.blockBegin(timestamp)
+		return
+	}
+	if e.block(timestamp) <= e.block(e.flushedTime) {
+		return
+	}
+	end := e.blockBegin(timestamp)
+	for key, count := range e.rejections {
+		rejection := dia.TradeRejection{
+			Asset:            e.assets[key],
+			Exchange:         key.exchange,
+			Reason:           key.reason,
+			Count:            count,
+			TimeRangeSeconds: int64(end.Sub(e.flushedTime).Seconds()),
+			Timestamp:        end,
+		}
+		log.Infof("rejected %d trades of %s on %s: %s", count, rejection.Asset.Symbol, key.exchange, key.reason)
+		if e.store != nil {
+			if err := e.store.SetTradeRejection(rejection); err != nil {
+				log.Error("set trade rejection: ", err)
+			}
+		}
+	}
+	e.rejections = make(map[rejectionKey]int64)
+	e.assets = make(map[rejectionKey]dia.Asset)
+	e.flushedTime = end
+}
+
+func (e *Engine) block(timestamp time.Time) int64 {
+	return timestamp.Unix() / e.blockDuration
+}
+
+func (e *Engine) blockBegin(timestamp time.Time) time.Time {
+	return time.Unix(e.block(timestamp)*e.blockDuration, 0)
+}
+
+// ruleSpecificity is 0 for rules matching all trades, 1 for exchange rules, 2 for asset rules
+// and 3 for rules of an asset on an exchange.
+func ruleSpecificity(r dia.SanityRule) (specificity int) {
+	if r.Exchange != "" {
+		specificity++
+	}
+	if r.Address != "" || r.Symbol != "" {
+		specificity += 2
+	}
+	return
+}
+
+func matches(r dia.SanityRule, t dia.Trade) bool {
+	if r.Exchange != "" && r.Exchange != t.Source {
+		return false
+	}
+	if r.Address != "" && (r.Address != t.QuoteToken.Address || r.Blockchain != t.QuoteToken.Blockchain) {
+		return false
+	}
+	if r.Address == "" && r.Blockchain != "" && r.Blockchain != t.QuoteToken.Blockchain {
+		return false
+	}
+	if r.Symbol != "" && r.Symbol != t.QuoteToken.Symbol && r.Symbol != t.Symbol {
+		return false
+	}
+	return true
+}
+
+func relativeDeviation(price float64, reference float64) float64 {
+	return math.Abs(price-reference) / reference
+}
+
+// LatestPrice returns a PriceFunc for live trades which looks up the latest quotation in the cache.
+func LatestPrice(datastore models.QuotationStore) PriceFunc {
+	return func(asset dia.Asset, timestamp time.Time) (float64, error) {
+		quotation, err := datastore.GetAssetQuotationCache(asset)
+		if err != nil {
+			return 0, err
+		}
+		return quotation.Price, nil
+	}
+}
+
+// HistoricalPrice returns a PriceFunc which looks up the last quotation before the trade time.
+func HistoricalPrice(datastore models.QuotationStore) PriceFunc {
+	return datastore.GetAssetPriceUSD
+}
@@ -0,0 +1,134 @@
+package sanityRules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+type rejectionRecorder struct {
+	rejections []dia.TradeRejection
+}
+
+func (rr *rejectionRecorder) SetTradeRejection(rejection dia.TradeRejection) error {
+	rr.rejections = append(rr.rejections, rejection)
+	return nil
+}
+
+func testTrade(symbol string, exchange string, price float64, volume float64, timestamp time.Time) dia.Trade {
+	return dia.Trade{
+		Symbol:            symbol,
+		Pair:              symbol + "-USD",
+		QuoteToken:        dia.Asset{Symbol: symbol, Address: "0x" + symbol, Blockchain: dia.ETHEREUM},
+		EstimatedUSDPrice: price,
+		Volume:            volume,
+		Time:              timestamp,
+		Source:            exchange,
+	}
+}
+
+func testPrices(asset dia.Asset, timestamp time.Time) (float64, error) {
+	switch asset.Symbol {
+	case "EUR":
+		return 1.1, nil
+	case "ETH":
+		return 2000, nil
+	}
+	return 0, errors.New("no quotation")
+}
+
+func TestRule(t *testing.T) {
+	e := NewEngine([]dia.SanityRule{
+		{Exchange: dia.BinanceExchange, Symbol: "ETH", MaxPriceJump: 0.2},
+		{MinVolume: 1, MaxDeviation: 0.5},
+		{Symbol: "ETH", MinVolume: 0.1},
+		{Exchange: dia.BinanceExchange, MinVolume: 10},
+	}, 120, nil, nil)
+
+	rule := e.Rule(testTrade("ETH", dia.BinanceExchange, 2000, 1, time.Now()))
+	expected := dia.SanityRule{MinVolume: 0.1, MaxDeviation: 0.5, MaxPriceJump: 0.2}
+	if rule != expected {
+		t.Errorf("got %+v, expected %+v", rule, expected)
+	}
+	rule = e.Rule(testTrade("BTC", dia.BinanceExchange, 20000, 1, time.Now()))
+	expected = dia.SanityRule{MinVolume: 10, MaxDeviation: 0.5}
+	if rule != expected {
+		t.Errorf("got %+v, expected %+v", rule, expected)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	rules := append(StablecoinRules(0.04),
+		dia.SanityRule{MinVolume: 0.01},
+		dia.SanityRule{Symbol: "ETH", MaxDeviation: 0.1, MaxPriceJump: 0.2},
+	)
+	begin := time.Unix(1654041600, 0)
+	store := &rejectionRecorder{}
+	e := NewEngine(rules, 120, testPrices, store)
+
+	cases := []struct {
+		trade  dia.Trade
+		reason string
+	}{
+		{testTrade("BTC", dia.BinanceExchange, 20000, 0.001, begin), ReasonLowVolume},
+		{testTrade("BTC", dia.BinanceExchange, 20000, 0.1, begin), ""},
+		{testTrade("USDT", dia.BinanceExchange, 0.99, 100, begin), ""},
+		{testTrade("USDT", dia.BinanceExchange, 0.9, 100, begin), ReasonStablecoinPeg},
+		{testTrade("EURS", dia.BinanceExchange, 1.1, 100, begin), ""},
+		{testTrade("EURS", dia.BinanceExchange, 1, 100, begin), ReasonStablecoinPeg},
+		{testTrade("ETH", dia.BinanceExchange, 2100, 1, begin), ""},
+		{testTrade("ETH", dia.BinanceExchange, 2500, 1, begin), ReasonDeviation},
+		// A jump within the block of the reference price is not checked.
+		{testTrade("ETH", dia.BinanceExchange, 1850, 1, begin.Add(10*time.Second)), ""},
+		// The price of the previous block is 1850.
+		{testTrade("ETH", dia.BinanceExchange, 2150, 1, begin.Add(130*time.Second)), ""},
+		{testTrade("ETH", dia.BinanceExchange, 1800, 1, begin.Add(250*time.Second)), ""},
+		// Deviation from the quotation is within bounds, but the jump from 1800 is too large.
+		{testTrade("ETH", dia.BinanceExchange, 2190, 1, begin.Add(370*time.Second)), ReasonPriceJump},
+		{testTrade("ETH", dia.KrakenExchange, 2190, 1, begin.Add(370*time.Second)), ""},
+	}
+	for i, c := range cases {
+		ok, reason := e.Check(c.trade)
+		if ok != (c.reason == "") || reason != c.reason {
+			t.Errorf("case %d: got %v, %q, expected %q", i, ok, reason, c.reason)
+		}
+	}
+
+	// Rejections of the first block were written once the second block began.
+	// Later rejections are not written before the end of their block.
+	counts := make(map[string]int64)
+	for _, r := range store.rejections {
+		counts[r.Reason] += r.Count
+		if r.TimeRangeSeconds != 120 || !r.Timestamp.Equal(begin.Add(120*time.Second)) {
+			t.Errorf("unexpected time range of rejection %+v", r)
+		}
+	}
+	if len(store.rejections) != 4 || counts[ReasonLowVolume] != 1 || counts[ReasonStablecoinPeg] != 2 || counts[ReasonDeviation] != 1 {
+		t.Errorf("unexpected rejections %+v", store.rejections)
+	}
+}
+
+func TestCheckPriceJump(t *testing.T) {
+	begin := time.Unix(1654041600, 0)
+	e := NewEngine([]dia.SanityRule{{MaxPriceJump: 0.1}}, 120, nil, nil)
+
+	if ok, _ := e.Check(testTrade("ETH", dia.BinanceExchange, 2000, 1, begin)); !ok {
+		t.Error("first trade rejected")
+	}
+	if ok, reason := e.Check(testTrade("ETH", dia.BinanceExchange, 2500, 1, begin.Add(130*time.Second))); ok || reason != ReasonPriceJump {
+		t.Errorf("price jump accepted")
+	}
+	// Other exchanges have their own reference price.
+	if ok, _ := e.Check(testTrade("ETH", dia.KrakenExchange, 2500, 1, begin.Add(130*time.Second))); !ok {
+		t.Error("trade on other exchange rejected")
+	}
+	// Rejected prices do not become the reference.
+	if ok, _ := e.Check(testTrade("ETH", dia.BinanceExchange, 2050, 1, begin.Add(200*time.Second))); !ok {
+		t.Error("trade close to reference rejected")
+	}
+	if rejections := e.Rejections(); len(rejections) != 1 || rejections[0].Reason != ReasonPriceJump {
+		t.Errorf("unexpected rejections %+v", rejections)
+	}
+}
@@ -29,7 +29,7 @@ func LoadHistoricRate(rateType string) error {
 }
 
 // WriteHistoricRate writes the historic rate data into the redis database.
-func WriteHistoricRate(ds models.Datastore, rateType string) error {
+func WriteHistoricRate(ds models.RateStore, rateType string) error {
 
 	switch rateType {
 	case "PRE-ESTER":
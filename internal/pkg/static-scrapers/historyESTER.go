@@ -64,7 +64,7 @@ func GetHistoricESTER() error {
 
 // WriteHistoricESTER makes a GET request to fetch the historic data of the SOFR index
 // and writes it into the redis database.
-func WriteHistoricESTER(ds models.Datastore) (err error) {
+func WriteHistoricESTER(ds models.RateStore) (err error) {
 
 	log.Printf("Writing historic ESTER data")
 
@@ -65,7 +65,7 @@ func GetHistoricPreESTER() error {
 
 // WriteHistoricPreESTER makes a GET request to fetch the historic data of the SOFR index
 // and writes it into the redis database.
-func WriteHistoricPreESTER(ds models.Datastore) (err error) {
+func WriteHistoricPreESTER(ds models.RateStore) (err error) {
 	log.Printf("Writing historic Pre-ESTER data")
 
 	// The path relative to the calling main / executable
@@ -55,7 +55,7 @@ type (
 
 // WriteHistoricSAFR makes a GET request to fetch the historic data of the SOFR
 // average index and writes it into the redis database.
-func WriteHistoricSAFR(ds models.Datastore) error {
+func WriteHistoricSAFR(ds models.RateStore) error {
 	log.Printf("Writing historic SAFR values")
 
 	// Get rss from fed webpage
@@ -61,7 +61,7 @@ type (
 
 // WriteHistoricSAFRAvgs makes a GET request to fetch the historic data of the SOFR
 // average index and writes it into the redis database.
-func WriteHistoricSAFRAvgs(ds models.Datastore) error {
+func WriteHistoricSAFRAvgs(ds models.RateStore) error {
 	log.Printf("Writing historic SAFR average values")
 
 	// Get rss from fed webpage
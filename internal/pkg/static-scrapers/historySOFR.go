@@ -72,7 +72,7 @@ type (
 
 // WriteHistoricSOFR makes a GET request to fetch the historic data of the SOFR index
 // and writes it into the redis database.
-func WriteHistoricSOFR(ds models.Datastore) error {
+func WriteHistoricSOFR(ds models.RateStore) error {
 	log.Printf("Writing historic SOFR data")
 
 	// Get rss from fed webpage
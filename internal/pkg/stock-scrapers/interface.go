@@ -23,7 +23,7 @@ type StockScraper struct {
 	errorLock *sync.RWMutex
 	error     error
 	closed    bool
-	datastore models.Datastore
+	datastore models.StockStore
 	chanStock chan models.StockQuotation
 	source    string
 }
@@ -0,0 +1,153 @@
+package tradesBlockService
+
+import (
+	"sort"
+	"time"
+
+	"github.com/cnf/structhash"
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// BlockBuilder assigns trades to consecutive tradesBlocks of fixed duration.
+// It contains the block-boundary logic of the TradesBlockService, so that
+// tradesBlocks can be rebuilt identically from recorded trades.
+//
+// The last @graceBlocks finished blocks are kept open for late trades. A block amended by
+// late trades is re-emitted with an incremented revision through TakeAmended.
+type BlockBuilder struct {
+	blockDuration int64
+	graceBlocks   int
+	currentBlock  *dia.TradesBlock
+	// recentBlocks are the last finished blocks in chronological order.
+	recentBlocks []*dia.TradesBlock
+	amended      map[int64]bool
+}
+
+// NewBlockBuilder returns a BlockBuilder for blocks of @blockDuration seconds which keeps
+// the last @graceBlocks finished blocks open for late trades.
+func NewBlockBuilder(blockDuration int64, graceBlocks int) *BlockBuilder {
+	return &BlockBuilder{
+		blockDuration: blockDuration,
+		graceBlocks:   graceBlocks,
+		amended:       make(map[int64]bool),
+	}
+}
+
+// IsLate returns true if @t should have been in a block previous to the current one.
+func (bb *BlockBuilder) IsLate(t dia.Trade) bool {
+	return bb.currentBlock != nil && bb.currentBlock.TradesBlockData.BeginTime.After(t.Time)
+}
+
+// Add appends @t to the current block. If @t is past the end of the current block,
+// a new block is begun. In this case, @newBlock is true and the finalised previous
+// block is returned in @finished, if any.
+// Late trades must be filtered by the caller using IsLate.
+func (bb *BlockBuilder) Add(t dia.Trade) (finished *dia.TradesBlock, newBlock bool) {
+	if bb.currentBlock == nil || bb.currentBlock.TradesBlockData.EndTime.Before(t.Time) {
+		if bb.currentBlock != nil {
+			finished = bb.currentBlock
+			FinaliseTradesBlock(finished)
+			bb.keep(finished)
+		}
+		bb.currentBlock = NewTradesBlock(t.Time, bb.blockDuration)
+		newBlock = true
+	}
+	bb.currentBlock.TradesBlockData.Trades = append(bb.currentBlock.TradesBlockData.Trades, t)
+	return
+}
+
+// Amend adds the late trade @t to the finished block it belongs to.
+// It returns false if the block is no longer within the grace window.
+func (bb *BlockBuilder) Amend(t dia.Trade) bool {
+	for i, b := range bb.recentBlocks {
+		if t.Time.Before(b.TradesBlockData.BeginTime) || b.TradesBlockData.EndTime.Before(t.Time) {
+			continue
+		}
+		// Finished blocks may still be in use by their receivers, hence a copy is amended.
+		trades := make([]dia.Trade, len(b.TradesBlockData.Trades), len(b.TradesBlockData.Trades)+1)
+		copy(trades, b.TradesBlockData.Trades)
+		amendedBlock := &dia.TradesBlock{
+			TradesBlockData: dia.TradesBlockData{
+				BeginTime: b.TradesBlockData.BeginTime,
+				EndTime:   b.TradesBlockData.EndTime,
+				Trades:    append(trades, t),
+			},
+			Revision: b.Revision,
+		}
+		FinaliseTradesBlock(amendedBlock)
+		bb.recentBlocks[i] = amendedBlock
+		bb.amended[amendedBlock.TradesBlockData.BeginTime.Unix()] = true
+		return true
+	}
+	return false
+}
+
+// TakeAmended returns all blocks amended since the last call in chronological order.
+// The revision of each returned block is incremented.
+func (bb *BlockBuilder) TakeAmended() (amendedBlocks []*dia.TradesBlock) {
+	if len(bb.amended) == 0 {
+		return
+	}
+	for _, b := range bb.recentBlocks {
+		if bb.amended[b.TradesBlockData.BeginTime.Unix()] {
+			b.Revision++
+			amendedBlocks = append(amendedBlocks, b)
+		}
+	}
+	bb.amended = make(map[int64]bool)
+	return
+}
+
+// Flush finalises and returns the current block. It returns nil if there is none.
+func (bb *BlockBuilder) Flush() *dia.TradesBlock {
+	b := bb.currentBlock
+	if b != nil {
+		FinaliseTradesBlock(b)
+		bb.keep(b)
+		bb.currentBlock = nil
+	}
+	return b
+}
+
+// CurrentBlock returns the block trades are currently added to.
+func (bb *BlockBuilder) CurrentBlock() *dia.TradesBlock {
+	return bb.currentBlock
+}
+
+// keep adds the finished block @b to the blocks open for late trades.
+func (bb *BlockBuilder) keep(b *dia.TradesBlock) {
+	if bb.graceBlocks <= 0 {
+		return
+	}
+	bb.recentBlocks = append(bb.recentBlocks, b)
+	if len(bb.recentBlocks) > bb.graceBlocks {
+		delete(bb.amended, bb.recentBlocks[0].TradesBlockData.BeginTime.Unix())
+		bb.recentBlocks = bb.recentBlocks[1:]
+	}
+}
+
+// NewTradesBlock returns an empty tradesBlock of @blockDuration seconds containing @t.
+func NewTradesBlock(t time.Time, blockDuration int64) *dia.TradesBlock {
+	return &dia.TradesBlock{
+		TradesBlockData: dia.TradesBlockData{
+			Trades:    []dia.Trade{},
+			EndTime:   time.Unix((t.Unix()/blockDuration)*blockDuration+blockDuration, 0),
+			BeginTime: time.Unix((t.Unix()/blockDuration)*blockDuration, 0),
+		},
+	}
+}
+
+// FinaliseTradesBlock sorts the trades of @b by time and sets the block's hash and number of trades.
+func FinaliseTradesBlock(b *dia.TradesBlock) {
+	sort.Slice(b.TradesBlockData.Trades, func(i, j int) bool {
+		return b.TradesBlockData.Trades[i].Time.Before(b.TradesBlockData.Trades[j].Time)
+	})
+
+	hash, err := structhash.Hash(b.TradesBlockData, 1)
+	if err != nil {
+		log.Printf("error on hash")
+		hash = "hashError"
+	}
+	b.BlockHash = hash
+	b.TradesBlockData.TradesNumber = len(b.TradesBlockData.Trades)
+}
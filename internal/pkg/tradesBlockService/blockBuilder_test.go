@@ -0,0 +1,66 @@
+package tradesBlockService
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func TestBlockBuilderAmend(t *testing.T) {
+	begin := time.Unix(1654041600, 0)
+	at := func(seconds int) dia.Trade {
+		return dedupTestTrade("", begin.Add(time.Duration(seconds)*time.Second))
+	}
+	bb := NewBlockBuilder(120, 1)
+
+	bb.Add(at(10))
+	finished, _ := bb.Add(at(130))
+	if finished == nil || finished.TradesBlockData.TradesNumber != 1 {
+		t.Fatalf("unexpected first block %v", finished)
+	}
+	hash := finished.BlockHash
+
+	// A late trade for the finished block amends it.
+	late := at(50)
+	if !bb.IsLate(late) || !bb.Amend(late) {
+		t.Fatal("late trade within grace window was not accepted")
+	}
+	if finished.TradesBlockData.TradesNumber != 1 {
+		t.Error("block already sent was modified")
+	}
+
+	amended := bb.TakeAmended()
+	if len(amended) != 1 {
+		t.Fatalf("expected 1 amended block, got %d", len(amended))
+	}
+	if amended[0].Revision != 1 || amended[0].TradesBlockData.TradesNumber != 2 || amended[0].BlockHash == hash {
+		t.Errorf("unexpected amended block: revision %d, %d trades", amended[0].Revision, amended[0].TradesBlockData.TradesNumber)
+	}
+	if !amended[0].TradesBlockData.Trades[0].Time.Before(amended[0].TradesBlockData.Trades[1].Time) {
+		t.Error("trades of amended block not sorted")
+	}
+	if len(bb.TakeAmended()) != 0 {
+		t.Error("amended block returned twice")
+	}
+
+	// A second amendment increments the revision again.
+	bb.Amend(at(60))
+	if amended = bb.TakeAmended(); len(amended) != 1 || amended[0].Revision != 2 {
+		t.Error("expected second revision")
+	}
+
+	// Once the next block is finished, the first one is out of the grace window.
+	bb.Add(at(250))
+	if bb.Amend(at(70)) {
+		t.Error("trade out of grace window was accepted")
+	}
+
+	// Without grace window late trades are rejected.
+	bb = NewBlockBuilder(120, 0)
+	bb.Add(at(10))
+	bb.Add(at(130))
+	if bb.Amend(at(50)) {
+		t.Error("late trade accepted without grace window")
+	}
+}
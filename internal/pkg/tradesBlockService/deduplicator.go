@@ -0,0 +1,211 @@
+package tradesBlockService
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// DedupResult is the outcome of checking a trade against the trades seen before.
+type DedupResult int
+
+const (
+	// DedupUnique trades have not been seen before.
+	DedupUnique DedupResult = iota
+	// DedupDuplicate trades were already seen with the same source, foreign trade ID, pair, price and volume.
+	// They mostly stem from websocket reconnects.
+	DedupDuplicate
+	// DedupMirrored trades equal an already seen trade in source, foreign trade ID, assets, price and volume,
+	// but differ in their pair string. They are produced if dia.SwapTrade is applied to a trade whose
+	// reverse was already delivered by the scraper.
+	DedupMirrored
+
+	// Relative tolerance for comparing prices and volumes of mirrored trades.
+	mirrorTolerance = 1e-6
+)
+
+// DedupCounts holds the numbers of trades dropped by a Deduplicator for an exchange.
+type DedupCounts struct {
+	Duplicates int64
+	Mirrored   int64
+}
+
+type dedupKey struct {
+	source         string
+	foreignTradeID string
+	pair           string
+}
+
+type directionKey struct {
+	source         string
+	foreignTradeID string
+	quoteToken     string
+	baseToken      string
+}
+
+type dedupFingerprint struct {
+	price  float64
+	volume float64
+}
+
+type dedupEntry struct {
+	key       dedupKey
+	direction directionKey
+	time      time.Time
+}
+
+// Deduplicator detects duplicate and mirrored trades among the trades seen within a time window.
+// Trades without foreign trade ID are always unique.
+// The number of remembered trades is bounded by @maxEntries, so that the memory is bounded even
+// if trades arrive faster than they expire.
+type Deduplicator struct {
+	window     time.Duration
+	maxEntries int
+
+	mu         sync.Mutex
+	seen       map[dedupKey][]dedupFingerprint
+	directions map[directionKey]map[string][]dedupFingerprint
+	// entries in the order of arrival. Entries before head are expired.
+	entries []dedupEntry
+	head    int
+	latest  time.Time
+	counts  map[string]DedupCounts
+}
+
+// NewDeduplicator returns a Deduplicator remembering trades for @window, measured in trade time.
+func NewDeduplicator(window time.Duration, maxEntries int) *Deduplicator {
+	return &Deduplicator{
+		window:     window,
+		maxEntries: maxEntries,
+		seen:       make(map[dedupKey][]dedupFingerprint),
+		directions: make(map[directionKey]map[string][]dedupFingerprint),
+		counts:     make(map[string]DedupCounts),
+	}
+}
+
+// Check returns whether @t is unique, a duplicate or a mirrored trade. Unique trades are remembered.
+func (d *Deduplicator) Check(t dia.Trade) DedupResult {
+	if t.ForeignTradeID == "" {
+		return DedupUnique
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if t.Time.After(d.latest) {
+		d.latest = t.Time
+	}
+	d.expire()
+
+	key := dedupKey{source: t.Source, foreignTradeID: t.ForeignTradeID, pair: t.Pair}
+	direction := directionKey{
+		source:         t.Source,
+		foreignTradeID: t.ForeignTradeID,
+		quoteToken:     t.QuoteToken.Blockchain + "-" + t.QuoteToken.Address,
+		baseToken:      t.BaseToken.Blockchain + "-" + t.BaseToken.Address,
+	}
+	fingerprint := dedupFingerprint{price: t.Price, volume: t.Volume}
+
+	// Several trades may share a foreign trade ID, e.g. swaps in the same transaction.
+	// Hence, only trades with equal price and volume are duplicates.
+	for _, f := range d.seen[key] {
+		if f == fingerprint {
+			d.count(t.Source, DedupDuplicate)
+			return DedupDuplicate
+		}
+	}
+	for pair, fingerprints := range d.directions[direction] {
+		if pair == t.Pair {
+			continue
+		}
+		for _, f := range fingerprints {
+			if approxEqual(f.price, fingerprint.price) && approxEqual(f.volume, fingerprint.volume) {
+				d.count(t.Source, DedupMirrored)
+				return DedupMirrored
+			}
+		}
+	}
+
+	d.seen[key] = append(d.seen[key], fingerprint)
+	if d.directions[direction] == nil {
+		d.directions[direction] = make(map[string][]dedupFingerprint)
+	}
+	d.directions[direction][t.Pair] = append(d.directions[direction][t.Pair], fingerprint)
+	d.entries = append(d.entries, dedupEntry{key: key, direction: direction, time: t.Time})
+	return DedupUnique
+}
+
+// Counts returns the numbers of dropped trades per exchange since the Deduplicator was created.
+func (d *Deduplicator) Counts() map[string]DedupCounts {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	counts := make(map[string]DedupCounts, len(d.counts))
+	for exchange, c := range d.counts {
+		counts[exchange] = c
+	}
+	return counts
+}
+
+// Len returns the number of remembered trades.
+func (d *Deduplicator) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries) - d.head
+}
+
+func (d *Deduplicator) count(exchange string, result DedupResult) {
+	c := d.counts[exchange]
+	switch result {
+	case DedupDuplicate:
+		c.Duplicates++
+	case DedupMirrored:
+		c.Mirrored++
+	}
+	d.counts[exchange] = c
+}
+
+// expire forgets trades older than the window and the oldest trades beyond maxEntries.
+// Entries are removed in the order of arrival, which approximately equals trade time.
+func (d *Deduplicator) expire() {
+	threshold := d.latest.Add(-d.window)
+	for d.head < len(d.entries) {
+		e := d.entries[d.head]
+		if !e.time.Before(threshold) && (d.maxEntries <= 0 || len(d.entries)-d.head < d.maxEntries) {
+			break
+		}
+		d.forget(e)
+		d.entries[d.head] = dedupEntry{}
+		d.head++
+	}
+	// Release the memory of expired entries once they make up half of the slice.
+	if d.head > 0 && d.head >= len(d.entries)/2 {
+		d.entries = append([]dedupEntry(nil), d.entries[d.head:]...)
+		d.head = 0
+	}
+}
+
+// forget removes the oldest fingerprint of @e. Fingerprints are appended in the order of arrival.
+func (d *Deduplicator) forget(e dedupEntry) {
+	if fingerprints := d.seen[e.key]; len(fingerprints) > 1 {
+		d.seen[e.key] = fingerprints[1:]
+	} else {
+		delete(d.seen, e.key)
+	}
+	pairs := d.directions[e.direction]
+	if fingerprints := pairs[e.key.pair]; len(fingerprints) > 1 {
+		pairs[e.key.pair] = fingerprints[1:]
+	} else {
+		delete(pairs, e.key.pair)
+	}
+	if len(pairs) == 0 {
+		delete(d.directions, e.direction)
+	}
+}
+
+func approxEqual(a float64, b float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= mirrorTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
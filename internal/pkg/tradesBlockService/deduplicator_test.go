@@ -0,0 +1,120 @@
+package tradesBlockService
+
+import (
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func dedupTestTrade(id string, tradeTime time.Time) dia.Trade {
+	return dia.Trade{
+		Symbol:         "BTC",
+		Pair:           "BTC-USDT",
+		QuoteToken:     dia.Asset{Symbol: "BTC", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.BITCOIN},
+		BaseToken:      dia.Asset{Symbol: "USDT", Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Blockchain: dia.ETHEREUM},
+		Price:          20000,
+		Volume:         0.5,
+		Time:           tradeTime,
+		ForeignTradeID: id,
+		Source:         dia.BinanceExchange,
+	}
+}
+
+func TestDeduplicatorDuplicates(t *testing.T) {
+	d := NewDeduplicator(time.Minute, 0)
+	t0 := time.Now()
+	trade := dedupTestTrade("1", t0)
+
+	if r := d.Check(trade); r != DedupUnique {
+		t.Errorf("first trade: got %v", r)
+	}
+	if r := d.Check(trade); r != DedupDuplicate {
+		t.Errorf("repeated trade: got %v", r)
+	}
+
+	// Same ID, but different volume, e.g. several swaps in one transaction.
+	other := trade
+	other.Volume = 0.7
+	if r := d.Check(other); r != DedupUnique {
+		t.Errorf("trade with same ID and different volume: got %v", r)
+	}
+
+	// The swapped trade has a different pair and is not a duplicate.
+	swapped, err := dia.SwapTrade(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := d.Check(swapped); r != DedupUnique {
+		t.Errorf("swapped trade: got %v", r)
+	}
+	if r := d.Check(swapped); r != DedupDuplicate {
+		t.Errorf("repeated swapped trade: got %v", r)
+	}
+
+	// Trades without ID cannot be deduplicated.
+	noID := dedupTestTrade("", t0)
+	d.Check(noID)
+	if r := d.Check(noID); r != DedupUnique {
+		t.Errorf("trade without ID: got %v", r)
+	}
+
+	counts := d.Counts()[dia.BinanceExchange]
+	if counts.Duplicates != 2 || counts.Mirrored != 0 {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+}
+
+func TestDeduplicatorMirrored(t *testing.T) {
+	d := NewDeduplicator(time.Minute, 0)
+	trade := dedupTestTrade("1", time.Now())
+
+	// The scraper delivers the reverse of the trade with its own pair notation.
+	reverse, err := dia.SwapTrade(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reverse.Pair = "USDTBTC"
+	if r := d.Check(trade); r != DedupUnique {
+		t.Errorf("trade: got %v", r)
+	}
+	if r := d.Check(reverse); r != DedupUnique {
+		t.Errorf("reverse trade from scraper: got %v", r)
+	}
+
+	// Swapping in the collector produces the same trade a second time.
+	swapped, err := dia.SwapTrade(trade)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := d.Check(swapped); r != DedupMirrored {
+		t.Errorf("mirrored trade: got %v", r)
+	}
+	if counts := d.Counts()[dia.BinanceExchange]; counts.Mirrored != 1 {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+}
+
+func TestDeduplicatorExpiry(t *testing.T) {
+	d := NewDeduplicator(time.Minute, 3)
+	t0 := time.Now()
+
+	d.Check(dedupTestTrade("1", t0))
+	d.Check(dedupTestTrade("2", t0.Add(2*time.Minute)))
+	if d.Len() != 1 {
+		t.Errorf("expected trade 1 to expire, %d trades remembered", d.Len())
+	}
+	if r := d.Check(dedupTestTrade("1", t0)); r != DedupUnique {
+		t.Errorf("expired trade: got %v", r)
+	}
+
+	for i := 3; i < 10; i++ {
+		d.Check(dedupTestTrade(string(rune('0'+i)), t0.Add(2*time.Minute)))
+	}
+	if d.Len() > 3 {
+		t.Errorf("expected at most 3 remembered trades, got %d", d.Len())
+	}
+	if r := d.Check(dedupTestTrade("9", t0.Add(2*time.Minute))); r != DedupDuplicate {
+		t.Errorf("recent trade: got %v", r)
+	}
+}
@@ -0,0 +1,62 @@
+package tradesBlockService
+
+import (
+	"github.com/diadata-org/diadata/internal/pkg/priceResolver"
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// USDPricer fills the EstimatedUSDPrice of trades with the USD price of their base token.
+// Base token prices are resolved by a priceResolver.Resolver and cached until ResetCache is called,
+// which the tradesBlockService does whenever a tradesBlock is finished.
+type USDPricer struct {
+	resolver   *priceResolver.Resolver
+	priceCache map[dia.Asset]float64
+}
+
+// NewUSDPricer returns a USDPricer resolving base token prices with @resolver.
+func NewUSDPricer(resolver *priceResolver.Resolver) *USDPricer {
+	return &USDPricer{
+		resolver:   resolver,
+		priceCache: make(map[dia.Asset]float64),
+	}
+}
+
+// EstimateUSDPrice sets the EstimatedUSDPrice of @t and returns true if it is positive.
+// Price estimation can only be done for verified pairs, so that trades of unverified pairs are left untouched.
+func (p *USDPricer) EstimateUSDPrice(t *dia.Trade) bool {
+	if !t.VerifiedPair {
+		return false
+	}
+	p.resolver.Observe(*t)
+	if t.BaseToken.Address == "840" && t.BaseToken.Blockchain == dia.FIAT {
+		// All prices are measured in US-Dollar, so just price for base token == USD
+		t.EstimatedUSDPrice = t.Price
+		return true
+	}
+	// Get price of base token. Failed lookups are cached as well until the block is finished.
+	price, ok := p.priceCache[t.BaseToken]
+	if !ok {
+		var err error
+		price, err = p.resolver.Price(t.BaseToken, t.Time)
+		if err != nil {
+			log.Errorf("Can't find quotation for base token in trade %s: %v.\n Basetoken address -- blockchain:  %s --- %s",
+				t.Pair,
+				err,
+				t.BaseToken.Address,
+				t.BaseToken.Blockchain,
+			)
+		} else {
+			log.Infof("quotation for %s: %v", t.BaseToken.Symbol, price)
+		}
+		p.priceCache[t.BaseToken] = price
+	}
+	if price > 0.0 {
+		t.EstimatedUSDPrice = t.Price * price
+	}
+	return t.EstimatedUSDPrice > 0
+}
+
+// ResetCache discards all cached base token prices.
+func (p *USDPricer) ResetCache() {
+	p.priceCache = make(map[dia.Asset]float64)
+}
@@ -2,10 +2,11 @@ package tradesEstimationService
 
 import (
 	"errors"
-	"math"
 	"sync"
 	"time"
 
+	"github.com/diadata-org/diadata/internal/pkg/priceResolver"
+	"github.com/diadata-org/diadata/internal/pkg/sanityRules"
 	"github.com/diadata-org/diadata/pkg/dia"
 	models "github.com/diadata-org/diadata/pkg/model"
 	"github.com/sirupsen/logrus"
@@ -19,18 +20,6 @@ func init() {
 	log = logrus.New()
 }
 
-var (
-	stablecoins = map[string]interface{}{
-		"USDC": "",
-		"USDT": "",
-		"TUSD": "",
-		"DAI":  "",
-		"PAX":  "",
-		"BUSD": "",
-	}
-	tol = float64(0.1)
-)
-
 const (
 	priceFrame = 1000 * 120
 )
@@ -49,10 +38,14 @@ type TradesEstimationService struct {
 	closed       bool
 	started      bool
 	priceCache   map[dia.Asset]pricetime
-	datastore    models.Datastore
+	datastore    models.TradeStore
+	sanity       *sanityRules.Engine
+	resolver     *priceResolver.Resolver
 }
 
-func NewTradesEstimationService(datastore models.Datastore) *TradesEstimationService {
+// NewTradesEstimationService returns a TradesEstimationService which saves trades
+// satisfying the rules of @sanity with their estimated USD price. Base tokens are priced by @resolver.
+func NewTradesEstimationService(datastore models.TradeStore, sanity *sanityRules.Engine, resolver *priceResolver.Resolver) *TradesEstimationService {
 	s := &TradesEstimationService{
 		shutdown:     make(chan nothing),
 		shutdownDone: make(chan nothing),
@@ -61,6 +54,8 @@ func NewTradesEstimationService(datastore models.Datastore) *TradesEstimationSer
 		started:      false,
 		priceCache:   make(map[dia.Asset]pricetime),
 		datastore:    datastore,
+		sanity:       sanity,
+		resolver:     resolver,
 	}
 	go s.mainLoop()
 	return s
@@ -88,6 +83,7 @@ func (s *TradesEstimationService) process(t dia.Trade) {
 
 	// Price estimation can only be done for verified pairs.
 	if t.VerifiedPair {
+		s.resolver.Observe(t)
 		if t.BaseToken.Address == "840" && t.BaseToken.Blockchain == dia.FIAT {
 			// All prices are measured in US-Dollar, so just price for base token == USD
 			t.EstimatedUSDPrice = t.Price
@@ -99,7 +95,7 @@ func (s *TradesEstimationService) process(t dia.Trade) {
 				price = s.priceCache[t.BaseToken].Price
 			} else {
 				// Look for historic price of base token at trade time...
-				price, err = s.datastore.GetAssetPriceUSD(t.BaseToken, t.Time)
+				price, err = s.resolver.Price(t.BaseToken, t.Time)
 				s.priceCache[t.BaseToken] = pricetime{
 					Price:     price,
 					Timestamp: t.Time,
@@ -118,10 +114,9 @@ func (s *TradesEstimationService) process(t dia.Trade) {
 		}
 	}
 
-	// If estimated price for stablecoin diverges too much ignore trade.
-	if _, ok := stablecoins[t.Symbol]; ok {
-		if math.Abs(t.EstimatedUSDPrice-1) > tol {
-			log.Errorf("price for stablecoin %s diverges by %v", t.Symbol, math.Abs(t.EstimatedUSDPrice-1))
+	// Ignore trades violating the sanity rules, such as stablecoin trades diverging too much from the peg.
+	if verifiedTrade {
+		if ok, _ := s.sanity.Check(t); !ok {
 			verifiedTrade = false
 		}
 	}
@@ -134,6 +129,11 @@ func (s *TradesEstimationService) process(t dia.Trade) {
 	}
 }
 
+// DefaultSanityRules returns the rules applied if no other source is configured.
+func DefaultSanityRules() []dia.SanityRule {
+	return sanityRules.StablecoinRules(0.1)
+}
+
 func (s *TradesEstimationService) ProcessTrade(trade *dia.Trade) {
 	s.chanTrades <- trade
 }
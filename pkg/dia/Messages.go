@@ -272,6 +272,84 @@ type TradesDistribution struct {
 	Timestamp        time.Time `json:"Timestamp"`
 }
 
+// FilterConfig enables the filter of type Filter with a window of Window seconds for all assets
+// matching Blockchain/Address on Exchange. Empty selectors match all assets and exchanges.
+// An empty Exchange also matches the filters computed across all exchanges.
+type FilterConfig struct {
+	Exchange   string `json:"Exchange"`
+	Blockchain string `json:"Blockchain"`
+	Address    string `json:"Address"`
+	// Filter is the type of a filter registered in the filtersBlockService such as MA or VWAPIR.
+	Filter string `json:"Filter"`
+	Window int    `json:"Window"`
+	// OutlierDetector selects the outlier detection of filters removing outliers, i.e. IQR, MAD, HAMPEL,
+	// VOLUME or BPS. OutlierThreshold is its parameter. Empty and zero values select the defaults.
+	OutlierDetector  string  `json:"OutlierDetector"`
+	OutlierThreshold float64 `json:"OutlierThreshold"`
+}
+
+// SanityRule holds the bounds a trade has to satisfy in order to be used for price determination.
+// The rule applies to all trades matching Exchange, Blockchain/Address of the quote token and Symbol.
+// Empty selectors match all trades and zero bounds are not checked.
+type SanityRule struct {
+	Exchange   string `json:"Exchange"`
+	Blockchain string `json:"Blockchain"`
+	Address    string `json:"Address"`
+	Symbol     string `json:"Symbol"`
+	// MinVolume is the minimal absolute volume of a trade in units of the quote token.
+	MinVolume float64 `json:"MinVolume"`
+	// MaxDeviation is the maximal relative deviation of the estimated USD price from the last quotation.
+	MaxDeviation float64 `json:"MaxDeviation"`
+	// Peg is the fiat currency of a stablecoin such as USD or EUR.
+	Peg string `json:"Peg"`
+	// PegTolerance is the maximal relative deviation of the estimated price from the peg.
+	PegTolerance float64 `json:"PegTolerance"`
+	// MaxPriceJump is the maximal relative change of the price with respect to the previous block.
+	MaxPriceJump float64 `json:"MaxPriceJump"`
+}
+
+// TradeRejection is the number of trades of an asset on an exchange which were
+// excluded from price determination for the given reason.
+type TradeRejection struct {
+	Asset            Asset     `json:"Asset"`
+	Exchange         string    `json:"Exchange"`
+	Reason           string    `json:"Reason"`
+	Count            int64     `json:"Count"`
+	TimeRangeSeconds int64     `json:"TimeRangeSeconds"`
+	Timestamp        time.Time `json:"Timestamp"`
+}
+
+// AggregationConfig determines how the price of the assets matching Blockchain/Address across all
+// exchanges is aggregated from their prices on single exchanges. Empty selectors match all assets.
+type AggregationConfig struct {
+	Blockchain string `json:"Blockchain"`
+	Address    string `json:"Address"`
+	// Weighting of the exchanges, i.e. EQUAL, VOLUME, RELIABILITY or MANUAL. Empty selects EQUAL.
+	Weighting string `json:"Weighting"`
+	// Weights are the weights of the exchanges for MANUAL weighting. Exchanges without weight are ignored.
+	Weights map[string]float64 `json:"Weights"`
+	// MaxDeviation is the maximal relative deviation of an exchange's price from the median of all
+	// exchanges. Exchanges deviating further are excluded for ExclusionSeconds, by default an hour.
+	// Zero disables exclusions.
+	MaxDeviation     float64 `json:"MaxDeviation"`
+	ExclusionSeconds int64   `json:"ExclusionSeconds"`
+	// MinExchanges is the minimal number of exchanges needed for excluding any of them. Zero selects 3.
+	MinExchanges int `json:"MinExchanges"`
+}
+
+// ExchangeExclusion records the exclusion of Exchange from the aggregated price of Asset in the
+// time-range BeginTime - EndTime, because the value of the filter Filter deviated from the median of all exchanges.
+type ExchangeExclusion struct {
+	Asset     Asset     `json:"Asset"`
+	Exchange  string    `json:"Exchange"`
+	Filter    string    `json:"Filter"`
+	Value     float64   `json:"Value"`
+	Median    float64   `json:"Median"`
+	Deviation float64   `json:"Deviation"`
+	BeginTime time.Time `json:"BeginTime"`
+	EndTime   time.Time `json:"EndTime"`
+}
+
 type EthereumBlockData struct {
 	GasLimit    uint64             `json:"gas_limit"`
 	GasUsed     uint64             `json:"gas_used"`
@@ -462,11 +540,15 @@ type TradesBlockData struct {
 type TradesBlock struct {
 	BlockHash       string
 	TradesBlockData TradesBlockData
+	// Revision is incremented each time a block is amended by late trades after it was sent.
+	Revision int
 }
 
 type FiltersBlock struct {
 	BlockHash        string
 	FiltersBlockData FiltersBlockData
+	// Revision is incremented each time the filters of a block are recomputed due to an amended tradesBlock.
+	Revision int
 }
 
 type FiltersBlockData struct {
@@ -487,6 +569,66 @@ type FilterPoint struct {
 	Min        float64
 	FirstTrade Trade
 	LastTrade  Trade
+	// Excluded are the trades discarded as outliers by filters removing outliers.
+	Excluded []ExcludedTrade
+	// Quality describes the trades the point is computed from. It is nil if not computed.
+	Quality *FilterPointQuality `json:",omitempty"`
+}
+
+// FilterPointQuality describes the trades within the window of a filter point, so that consumers
+// can reject points computed from few, dispersed or stale trades.
+type FilterPointQuality struct {
+	NumTrades    int
+	NumExchanges int
+	VolumeUSD    float64
+	// StdDev and IQR are the standard deviation and interquartile range of the trades' USD prices.
+	StdDev float64
+	IQR    float64
+	// OutlierFraction is the fraction of the trades removed as outliers by the filter.
+	OutlierFraction       float64
+	SecondsSinceLastTrade float64
+}
+
+// ExcludedTrade identifies a trade which a filter discarded as outlier.
+type ExcludedTrade struct {
+	Exchange       string
+	ForeignTradeID string
+	Price          float64
+	Volume         float64
+	Time           time.Time
+}
+
+// Candle is the OHLCV bar of the trades of an asset on an exchange, or on all exchanges if Exchange
+// is empty, in the interval of length Resolution beginning at Time. Prices are in USD.
+type Candle struct {
+	Asset      Asset     `json:"Asset"`
+	Exchange   string    `json:"Exchange"`
+	Resolution string    `json:"Resolution"`
+	Time       time.Time `json:"Time"`
+	Open       float64   `json:"Open"`
+	High       float64   `json:"High"`
+	Low        float64   `json:"Low"`
+	Close      float64   `json:"Close"`
+	// Volume is the traded amount of the asset and VolumeUSD its value in USD.
+	Volume    float64 `json:"Volume"`
+	VolumeUSD float64 `json:"VolumeUSD"`
+	NumTrades int     `json:"NumTrades"`
+}
+
+// CandleResolutions are the names of the resolutions candles are built for in ascending order of length.
+var CandleResolutions = []string{"1m", "5m", "1h", "1d"}
+
+var candleResolutionDurations = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+	"1d": 24 * time.Hour,
+}
+
+// CandleResolution returns the length of candles with resolution @resolution and false if there is no such resolution.
+func CandleResolution(resolution string) (time.Duration, bool) {
+	d, ok := candleResolutionDurations[resolution]
+	return d, ok
 }
 
 type IndexBlock struct {
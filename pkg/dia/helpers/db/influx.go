@@ -35,3 +35,26 @@ func GetInfluxClient(url string) clientInfluxdb.Client {
 
 	return influxClient
 }
+
+// InfluxV2Config is the configuration of a connection to influx 2.x.
+type InfluxV2Config struct {
+	URL    string
+	Org    string
+	Bucket string
+	Token  string
+}
+
+// GetInfluxV2Config returns the configuration of influx 2.x given in the environment
+// variables INFLUXV2URL, INFLUXV2ORG, INFLUXV2BUCKET and INFLUXV2TOKEN.
+// If INFLUXV2URL is not set, it connects to @url per default. Data is written to
+// the bucket @bucket per default.
+func GetInfluxV2Config(url string, bucket string) InfluxV2Config {
+	config := InfluxV2Config{
+		URL:    utils.Getenv("INFLUXV2URL", url),
+		Org:    utils.Getenv("INFLUXV2ORG", ""),
+		Bucket: utils.Getenv("INFLUXV2BUCKET", bucket),
+		Token:  utils.Getenv("INFLUXV2TOKEN", ""),
+	}
+	log.Info("INFLUXV2URL: ", config.URL)
+	return config
+}
@@ -312,33 +312,12 @@ func GetElements(topic int, offset int64, nbElements int) ([]interface{}, error)
 			}
 			b2 := b[:z]
 
-			switch topic {
-			case TopicFiltersBlock:
-				var e dia.FiltersBlock
-				err = e.UnmarshalBinary(b2)
-				if err == nil {
-					result = append(result, e)
-				}
-			case TopicTrades:
-				var e dia.Trade
-				err = e.UnmarshalBinary(b2)
-				if err == nil {
-					result = append(result, e)
-				}
-			case TopicTradesBlock:
-				var e dia.TradesBlock
-				err = e.UnmarshalBinary(b2)
-				if err == nil {
-					result = append(result, e)
-				}
-			default:
-				return nil, errors.New("missing case unknown topic in switch... function GetElements / Kafka.go")
-			}
-
+			e, err := UnmarshalElement(topic, b2)
 			if err != nil {
 				errorMsg := fmt.Sprintf("parsing error while processing offset: %v/%v", c, maxOffset)
 				return nil, errors.New(errorMsg)
 			}
+			result = append(result, e)
 			if len(result) == nbElements {
 				break
 			}
@@ -347,3 +326,23 @@ func GetElements(topic int, offset int64, nbElements int) ([]interface{}, error)
 		return result, nil
 	}
 }
+
+// UnmarshalElement returns the message @value read from @topic as trade, tradesBlock or filtersBlock.
+func UnmarshalElement(topic int, value []byte) (interface{}, error) {
+	switch topic {
+	case TopicFiltersBlock:
+		var e dia.FiltersBlock
+		err := e.UnmarshalBinary(value)
+		return e, err
+	case TopicTrades:
+		var e dia.Trade
+		err := e.UnmarshalBinary(value)
+		return e, err
+	case TopicTradesBlock:
+		var e dia.TradesBlock
+		err := e.UnmarshalBinary(value)
+		return e, err
+	default:
+		return nil, errors.New("missing case unknown topic in switch... function UnmarshalElement / Kafka.go")
+	}
+}
@@ -0,0 +1,211 @@
+package kafkaHelper
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// KafkaBus is the MessageBus backed by the kafka brokers in KafkaConfig.
+type KafkaBus struct {
+	async   bool
+	lock    sync.Mutex
+	writers map[int]*kafka.Writer
+	closed  bool
+}
+
+// NewKafkaBus returns a MessageBus writing to and reading from kafka.
+// If @async is true, messages are published without waiting for acknowledgement of the brokers.
+func NewKafkaBus(async bool) *KafkaBus {
+	return &KafkaBus{
+		async:   async,
+		writers: make(map[int]*kafka.Writer),
+	}
+}
+
+func (kb *KafkaBus) writer(topic int) (*kafka.Writer, error) {
+	kb.lock.Lock()
+	defer kb.lock.Unlock()
+	if kb.closed {
+		return nil, ErrBusClosed
+	}
+	w, ok := kb.writers[topic]
+	if !ok {
+		if kb.async {
+			w = NewWriter(topic)
+		} else {
+			w = NewSyncWriterWithCompression(topic)
+		}
+		kb.writers[topic] = w
+	}
+	return w, nil
+}
+
+// Publish writes @m to @topic.
+func (kb *KafkaBus) Publish(ctx context.Context, topic int, m KafkaMessage) error {
+	w, err := kb.writer(topic)
+	if err != nil {
+		return err
+	}
+	value, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	err = w.WriteMessages(ctx, kafka.Message{Key: []byte("helloKafka"), Value: value})
+	if err != nil {
+		log.Errorln("Publish error:", err, "sizeMessage:", float64(len(value))/(1024.0*1024.0), "MB")
+	}
+	return err
+}
+
+// Subscribe returns a reader on partition 0 of @topic starting at @offset.
+func (kb *KafkaBus) Subscribe(topic int, offset int64) (Subscription, error) {
+	if offset == OffsetLast {
+		// Resolve the last offset explicitly in order to retry until kafka is reachable.
+		offset = ReadOffsetWithRetryOnError(topic)
+	}
+	r := NewReader(topic)
+	if err := r.SetOffset(offset); err != nil {
+		return nil, err
+	}
+	log.Printf("Reading from offset %d on topic %s", offset, getTopic(topic))
+	return &kafkaSubscription{topic: topic, reader: r}, nil
+}
+
+// SubscribeGroup returns a reader on @topic which is a member of the consumer group @groupID.
+// Offsets are only committed through CommitMessages.
+func (kb *KafkaBus) SubscribeGroup(topic int, groupID string) (Subscription, error) {
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     KafkaConfig.KafkaUrl,
+		Topic:       getTopic(topic),
+		GroupID:     groupID,
+		StartOffset: kafka.FirstOffset,
+		MinBytes:    0,
+		MaxBytes:    10e6, // 10MB
+	})
+	return &kafkaSubscription{topic: topic, reader: r, group: true}, nil
+}
+
+// SetGroupOffset commits @offset on partition 0 of @topic for the consumer group @groupID.
+// It has to be called while the group has no active members, as their commits would overwrite @offset.
+func (kb *KafkaBus) SetGroupOffset(topic int, groupID string, offset int64) (err error) {
+	switch offset {
+	case OffsetFirst:
+		offset, err = readFirstOffset(topic)
+	case OffsetLast:
+		offset, err = ReadOffset(topic)
+	}
+	if err != nil {
+		return err
+	}
+	client := &kafka.Client{Addr: kafka.TCP(KafkaConfig.KafkaUrl...)}
+	resp, err := client.OffsetCommit(context.Background(), &kafka.OffsetCommitRequest{
+		GroupID: groupID,
+		// Commits from outside the group are accepted for generation -1.
+		GenerationID: -1,
+		Topics: map[string][]kafka.OffsetCommit{
+			getTopic(topic): {{Partition: 0, Offset: offset}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	for _, partition := range resp.Topics[getTopic(topic)] {
+		if partition.Error != nil {
+			return fmt.Errorf("commit offset %d for group %s: %w", offset, groupID, partition.Error)
+		}
+	}
+	log.Infof("set offset of group %s on topic %s to %d", groupID, getTopic(topic), offset)
+	return nil
+}
+
+// LastOffset returns the last offset of partition 0 of @topic.
+func (kb *KafkaBus) LastOffset(topic int) (int64, error) {
+	return ReadOffset(topic)
+}
+
+// Close flushes and closes all writers of the bus.
+func (kb *KafkaBus) Close() (err error) {
+	kb.lock.Lock()
+	defer kb.lock.Unlock()
+	if kb.closed {
+		return ErrBusClosed
+	}
+	kb.closed = true
+	for _, w := range kb.writers {
+		if cerr := w.Close(); cerr != nil {
+			log.Error("close kafka writer: ", cerr)
+			err = cerr
+		}
+	}
+	return
+}
+
+// readFirstOffset returns the oldest offset retained on partition 0 of @topic.
+func readFirstOffset(topic int) (offset int64, err error) {
+	for _, ip := range KafkaConfig.KafkaUrl {
+		var conn *kafka.Conn
+		conn, err = kafka.DialLeader(context.Background(), "tcp", ip, getTopic(topic), 0)
+		if err != nil {
+			log.Errorln("readFirstOffset conn error: <", err, "> ", ip)
+			continue
+		}
+		offset, err = conn.ReadFirstOffset()
+		if cerr := conn.Close(); cerr != nil {
+			log.Error(cerr)
+		}
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
+type kafkaSubscription struct {
+	topic  int
+	reader *kafka.Reader
+	group  bool
+}
+
+func (ks *kafkaSubscription) ReadMessage(ctx context.Context) (Message, error) {
+	var (
+		m   kafka.Message
+		err error
+	)
+	if ks.group {
+		m, err = ks.reader.FetchMessage(ctx)
+	} else {
+		m, err = ks.reader.ReadMessage(ctx)
+	}
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Topic:  ks.topic,
+		Offset: m.Offset,
+		Key:    m.Key,
+		Value:  m.Value,
+		Time:   m.Time,
+	}, nil
+}
+
+func (ks *kafkaSubscription) CommitMessages(ctx context.Context, msgs ...Message) error {
+	if !ks.group || len(msgs) == 0 {
+		return nil
+	}
+	kafkaMsgs := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		kafkaMsgs[i] = kafka.Message{
+			Topic:  getTopic(m.Topic),
+			Offset: m.Offset,
+		}
+	}
+	return ks.reader.CommitMessages(ctx, kafkaMsgs...)
+}
+
+func (ks *kafkaSubscription) Close() error {
+	return ks.reader.Close()
+}
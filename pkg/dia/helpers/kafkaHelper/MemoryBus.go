@@ -0,0 +1,264 @@
+package kafkaHelper
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryBus is an in-process MessageBus backed by per-topic logs.
+// It mimics the semantics of a single kafka partition per topic, including
+// offsets and consumer groups, so that services can be connected in one binary.
+type MemoryBus struct {
+	lock      sync.Mutex
+	retention int
+	topics    map[int]*memoryTopic
+	groups    map[memoryGroupKey]*memoryGroup
+	closed    bool
+	done      chan struct{}
+}
+
+type memoryTopic struct {
+	// baseOffset is the offset of messages[0].
+	baseOffset int64
+	messages   []Message
+	// published is closed and replaced whenever a message is appended.
+	published chan struct{}
+}
+
+type memoryGroupKey struct {
+	topic   int
+	groupID string
+}
+
+// memoryGroup keeps the offsets of a consumer group. All members of a group
+// share @next, so that each message is delivered to exactly one member.
+type memoryGroup struct {
+	next      int64
+	committed int64
+	// members is the number of open subscriptions of the group.
+	members int
+}
+
+// DefaultMemoryBusRetention is the number of messages kept per topic by a MemoryBus
+// created without positive retention.
+const DefaultMemoryBusRetention = 100000
+
+// NewMemoryBus returns an empty MemoryBus keeping the latest @retention messages per topic.
+// If @retention is not positive, DefaultMemoryBusRetention messages are kept.
+func NewMemoryBus(retention int) *MemoryBus {
+	if retention <= 0 {
+		retention = DefaultMemoryBusRetention
+	}
+	return &MemoryBus{
+		retention: retention,
+		topics:    make(map[int]*memoryTopic),
+		groups:    make(map[memoryGroupKey]*memoryGroup),
+		done:      make(chan struct{}),
+	}
+}
+
+// topic must be called with mb.lock held.
+func (mb *MemoryBus) topic(topic int) *memoryTopic {
+	t, ok := mb.topics[topic]
+	if !ok {
+		t = &memoryTopic{published: make(chan struct{})}
+		mb.topics[topic] = t
+	}
+	return t
+}
+
+// Publish appends @m to @topic and wakes up all waiting subscribers.
+func (mb *MemoryBus) Publish(ctx context.Context, topic int, m KafkaMessage) error {
+	value, err := m.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	if mb.closed {
+		return ErrBusClosed
+	}
+	t := mb.topic(topic)
+	t.messages = append(t.messages, Message{
+		Topic:  topic,
+		Offset: t.baseOffset + int64(len(t.messages)),
+		Value:  value,
+		Time:   time.Now(),
+	})
+	if len(t.messages) > mb.retention {
+		drop := len(t.messages) - mb.retention
+		t.messages = append([]Message(nil), t.messages[drop:]...)
+		t.baseOffset += int64(drop)
+	}
+	close(t.published)
+	t.published = make(chan struct{})
+	return nil
+}
+
+// Subscribe returns a subscription on @topic starting at @offset.
+func (mb *MemoryBus) Subscribe(topic int, offset int64) (Subscription, error) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	if mb.closed {
+		return nil, ErrBusClosed
+	}
+	t := mb.topic(topic)
+	switch offset {
+	case OffsetFirst:
+		offset = t.baseOffset
+	case OffsetLast:
+		offset = t.baseOffset + int64(len(t.messages))
+	}
+	return &memorySubscription{bus: mb, topic: topic, position: offset, done: make(chan struct{})}, nil
+}
+
+// SubscribeGroup returns a subscription on @topic for consumer group @groupID.
+// The first member of a group without open subscriptions reads from the last committed offset.
+// Further members continue at the group's read position, so that no message is delivered twice.
+func (mb *MemoryBus) SubscribeGroup(topic int, groupID string) (Subscription, error) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	if mb.closed {
+		return nil, ErrBusClosed
+	}
+	key := memoryGroupKey{topic: topic, groupID: groupID}
+	g, ok := mb.groups[key]
+	if !ok {
+		g = &memoryGroup{committed: mb.topic(topic).baseOffset}
+		mb.groups[key] = g
+	}
+	if g.members == 0 {
+		g.next = g.committed
+	}
+	g.members++
+	return &memorySubscription{bus: mb, topic: topic, group: g, done: make(chan struct{})}, nil
+}
+
+// SetGroupOffset sets the committed offset of consumer group @groupID on @topic to @offset.
+func (mb *MemoryBus) SetGroupOffset(topic int, groupID string, offset int64) error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	if mb.closed {
+		return ErrBusClosed
+	}
+	t := mb.topic(topic)
+	switch offset {
+	case OffsetFirst:
+		offset = t.baseOffset
+	case OffsetLast:
+		offset = t.baseOffset + int64(len(t.messages))
+	}
+	key := memoryGroupKey{topic: topic, groupID: groupID}
+	g, ok := mb.groups[key]
+	if !ok {
+		g = &memoryGroup{}
+		mb.groups[key] = g
+	}
+	g.committed = offset
+	return nil
+}
+
+// LastOffset returns the offset the next message on @topic will get.
+func (mb *MemoryBus) LastOffset(topic int) (int64, error) {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	t := mb.topic(topic)
+	return t.baseOffset + int64(len(t.messages)), nil
+}
+
+// Close unblocks all subscribers. Subsequent calls to Publish fail.
+func (mb *MemoryBus) Close() error {
+	mb.lock.Lock()
+	defer mb.lock.Unlock()
+	if mb.closed {
+		return ErrBusClosed
+	}
+	mb.closed = true
+	close(mb.done)
+	return nil
+}
+
+type memorySubscription struct {
+	bus      *MemoryBus
+	topic    int
+	position int64
+	group    *memoryGroup
+	closed   bool
+	done     chan struct{}
+}
+
+// next returns the message at the subscription's read position if available.
+// Otherwise, it returns a channel which is closed on the next publication.
+func (ms *memorySubscription) next() (m Message, ok bool, wait chan struct{}, err error) {
+	ms.bus.lock.Lock()
+	defer ms.bus.lock.Unlock()
+	if ms.closed {
+		return Message{}, false, nil, ErrSubscriptionClosed
+	}
+	if ms.bus.closed {
+		return Message{}, false, nil, ErrBusClosed
+	}
+	t := ms.bus.topic(ms.topic)
+	position := &ms.position
+	if ms.group != nil {
+		position = &ms.group.next
+	}
+	// Messages before the retention window are lost, continue with the oldest one.
+	if *position < t.baseOffset {
+		*position = t.baseOffset
+	}
+	index := *position - t.baseOffset
+	if index < int64(len(t.messages)) {
+		*position++
+		return t.messages[index], true, nil, nil
+	}
+	return Message{}, false, t.published, nil
+}
+
+func (ms *memorySubscription) ReadMessage(ctx context.Context) (Message, error) {
+	for {
+		m, ok, wait, err := ms.next()
+		if err != nil || ok {
+			return m, err
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-ms.done:
+			return Message{}, ErrSubscriptionClosed
+		case <-ms.bus.done:
+			return Message{}, ErrBusClosed
+		}
+	}
+}
+
+// CommitMessages sets the committed offset of the group to the offset following the latest of @msgs.
+func (ms *memorySubscription) CommitMessages(ctx context.Context, msgs ...Message) error {
+	if ms.group == nil {
+		return nil
+	}
+	ms.bus.lock.Lock()
+	defer ms.bus.lock.Unlock()
+	for _, m := range msgs {
+		if m.Offset+1 > ms.group.committed {
+			ms.group.committed = m.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (ms *memorySubscription) Close() error {
+	ms.bus.lock.Lock()
+	defer ms.bus.lock.Unlock()
+	if ms.closed {
+		return ErrSubscriptionClosed
+	}
+	ms.closed = true
+	if ms.group != nil {
+		ms.group.members--
+	}
+	close(ms.done)
+	return nil
+}
@@ -0,0 +1,194 @@
+package kafkaHelper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func publishTrades(t *testing.T, bus MessageBus, topic int, symbols ...string) {
+	for _, symbol := range symbols {
+		err := bus.Publish(context.Background(), topic, &dia.Trade{Symbol: symbol})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readSymbol(t *testing.T, sub Subscription) (string, Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m, err := sub.ReadMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var trade dia.Trade
+	if err := trade.UnmarshalBinary(m.Value); err != nil {
+		t.Fatal(err)
+	}
+	return trade.Symbol, m
+}
+
+func TestMemoryBusSubscribe(t *testing.T) {
+	bus := NewMemoryBus(0)
+	publishTrades(t, bus, TopicTrades, "BTC", "ETH")
+
+	first, err := bus.Subscribe(TopicTrades, OffsetFirst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := bus.Subscribe(TopicTrades, OffsetLast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishTrades(t, bus, TopicTrades, "DIA")
+
+	for _, expected := range []string{"BTC", "ETH", "DIA"} {
+		if symbol, _ := readSymbol(t, first); symbol != expected {
+			t.Errorf("expected %s, got %s", expected, symbol)
+		}
+	}
+	if symbol, m := readSymbol(t, last); symbol != "DIA" || m.Offset != 2 {
+		t.Errorf("expected DIA at offset 2, got %s at offset %d", symbol, m.Offset)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := last.ReadMessage(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded on empty topic, got %v", err)
+	}
+}
+
+func TestMemoryBusGroupResumesFromCommit(t *testing.T) {
+	bus := NewMemoryBus(0)
+	publishTrades(t, bus, TopicTradesBlock, "BTC", "ETH", "DIA")
+
+	sub, err := bus.SubscribeGroup(TopicTradesBlock, "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, m := readSymbol(t, sub)
+	if err := sub.CommitMessages(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	// Read without commit, as if the consumer crashed before flushing.
+	readSymbol(t, sub)
+	if err := sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err = bus.SubscribeGroup(TopicTradesBlock, "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if symbol, _ := readSymbol(t, sub); symbol != "ETH" {
+		t.Errorf("expected group to resume at ETH, got %s", symbol)
+	}
+}
+
+func TestMemoryBusGroupMemberJoins(t *testing.T) {
+	bus := NewMemoryBus(0)
+	publishTrades(t, bus, TopicTradesBlock, "BTC", "ETH", "DIA")
+
+	first, err := bus.SubscribeGroup(TopicTradesBlock, "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Consume and commit BTC, consume ETH without commit.
+	_, m := readSymbol(t, first)
+	if err := first.CommitMessages(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	readSymbol(t, first)
+
+	second, err := bus.SubscribeGroup(TopicTradesBlock, "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if symbol, _ := readSymbol(t, second); symbol != "DIA" {
+		t.Errorf("expected joining member to continue at DIA, got %s", symbol)
+	}
+	publishTrades(t, bus, TopicTradesBlock, "EUR")
+	if symbol, _ := readSymbol(t, first); symbol != "EUR" {
+		t.Errorf("expected EUR, got %s", symbol)
+	}
+}
+
+func TestMemoryBusSetGroupOffset(t *testing.T) {
+	bus := NewMemoryBus(0)
+	publishTrades(t, bus, TopicTradesBlock, "BTC", "ETH", "DIA")
+
+	if err := bus.SetGroupOffset(TopicTradesBlock, "filters", 2); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := bus.SubscribeGroup(TopicTradesBlock, "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if symbol, m := readSymbol(t, sub); symbol != "DIA" || m.Offset != 2 {
+		t.Errorf("expected DIA at offset 2, got %s at offset %d", symbol, m.Offset)
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A group set to the last offset only reads new messages.
+	if err := bus.SetGroupOffset(TopicTradesBlock, "filters", OffsetLast); err != nil {
+		t.Fatal(err)
+	}
+	sub, err = bus.SubscribeGroup(TopicTradesBlock, "filters")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishTrades(t, bus, TopicTradesBlock, "EUR")
+	if symbol, _ := readSymbol(t, sub); symbol != "EUR" {
+		t.Errorf("expected EUR, got %s", symbol)
+	}
+}
+
+func TestMemoryBusRetention(t *testing.T) {
+	bus := NewMemoryBus(2)
+	publishTrades(t, bus, TopicTrades, "BTC", "ETH", "DIA")
+
+	sub, err := bus.Subscribe(TopicTrades, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if symbol, m := readSymbol(t, sub); symbol != "ETH" || m.Offset != 1 {
+		t.Errorf("expected ETH at offset 1, got %s at offset %d", symbol, m.Offset)
+	}
+	if offset, _ := bus.LastOffset(TopicTrades); offset != 3 {
+		t.Errorf("expected last offset 3, got %d", offset)
+	}
+}
+
+func TestMemoryBusDefaultRetention(t *testing.T) {
+	bus := NewMemoryBus(0)
+	if bus.retention != DefaultMemoryBusRetention {
+		t.Errorf("expected retention %d, got %d", DefaultMemoryBusRetention, bus.retention)
+	}
+}
+
+func TestNewMessageBusMemoryStandalone(t *testing.T) {
+	t.Setenv("MESSAGE_BUS", MessageBusMemory)
+	if _, err := NewMessageBus(); err != ErrMemoryBusStandalone {
+		t.Errorf("expected ErrMemoryBusStandalone, got %v", err)
+	}
+}
+
+func TestMemoryBusClose(t *testing.T) {
+	bus := NewMemoryBus(0)
+	sub, err := bus.Subscribe(TopicTrades, OffsetLast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		bus.Close()
+	}()
+	if _, err := sub.ReadMessage(context.Background()); err != ErrBusClosed {
+		t.Errorf("expected ErrBusClosed, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package kafkaHelper
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	// OffsetFirst starts a subscription at the oldest message retained on a topic.
+	OffsetFirst = kafka.FirstOffset
+	// OffsetLast starts a subscription at the next message published on a topic.
+	OffsetLast = kafka.LastOffset
+
+	MessageBusKafka  = "kafka"
+	MessageBusMemory = "memory"
+)
+
+var (
+	ErrBusClosed          = errors.New("message bus closed")
+	ErrSubscriptionClosed = errors.New("subscription closed")
+	// ErrMemoryBusStandalone is returned when a standalone service selects the in-memory bus,
+	// which cannot connect it to the services running in other processes.
+	ErrMemoryBusStandalone = errors.New("in-memory message bus is only available in the pipeline")
+)
+
+// Message is a single record read from a topic of a MessageBus.
+type Message struct {
+	Topic  int
+	Offset int64
+	Key    []byte
+	Value  []byte
+	Time   time.Time
+}
+
+// MessageBus is the transport connecting the services of the price pipeline,
+// i.e. collector -> tradesBlockService -> filtersBlockService.
+// Topics are identified by the integer topic constants of this package.
+type MessageBus interface {
+	// Publish marshals @m and appends it to @topic.
+	Publish(ctx context.Context, topic int, m KafkaMessage) error
+	// Subscribe returns a subscription on @topic starting at @offset. @offset is either
+	// an absolute offset or one of OffsetFirst and OffsetLast.
+	Subscribe(topic int, offset int64) (Subscription, error)
+	// SubscribeGroup returns a subscription on @topic as a member of the consumer group @groupID.
+	// Reading resumes from the last offset committed by the group.
+	SubscribeGroup(topic int, groupID string) (Subscription, error)
+	// SetGroupOffset sets the committed offset of the consumer group @groupID on @topic, so that
+	// the group resumes reading at @offset. @offset may be OffsetFirst or OffsetLast.
+	SetGroupOffset(topic int, groupID string, offset int64) error
+	// LastOffset returns the offset the next message published on @topic will get.
+	LastOffset(topic int) (int64, error)
+	Close() error
+}
+
+// Subscription reads messages from a topic of a MessageBus.
+type Subscription interface {
+	// ReadMessage blocks until the next message is available or @ctx is done.
+	ReadMessage(ctx context.Context) (Message, error)
+	// CommitMessages marks @msgs as processed. It is a no-op for subscriptions without consumer group.
+	CommitMessages(ctx context.Context, msgs ...Message) error
+	Close() error
+}
+
+// NewMessageBus returns the message bus of a standalone service selected by the env var MESSAGE_BUS.
+// Kafka is used by default.
+func NewMessageBus() (MessageBus, error) {
+	return NewMessageBusWithOptions(false)
+}
+
+// NewMessageBusWithOptions returns the message bus of a standalone service selected by the env var MESSAGE_BUS.
+// If @async is true, a kafka bus publishes without waiting for acknowledgement of the brokers.
+// The in-memory bus only connects services within one process and is created by the pipeline itself.
+func NewMessageBusWithOptions(async bool) (MessageBus, error) {
+	switch os.Getenv("MESSAGE_BUS") {
+	case MessageBusMemory:
+		return nil, ErrMemoryBusStandalone
+	default:
+		return NewKafkaBus(async), nil
+	}
+}
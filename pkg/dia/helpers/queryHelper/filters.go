@@ -10,190 +10,56 @@ import (
 
 var log = logrus.New()
 
+// FilterMA returns the moving average of each block.
 func FilterMA(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
-
-	lastfp := &dia.FilterPoint{}
-	metadata = dia.NewFilterPointMetadata()
-	for _, block := range tradeBlocks {
-		if len(block.Trades) > 0 {
-			maFilter := filters.NewFilterMA(asset, "", time.Unix(block.TimeStamp/1e9, 0), blockSize)
-
-			for _, trade := range block.Trades {
-				maFilter.Compute(trade)
-			}
-
-			maFilter.FinalCompute(time.Unix(block.TimeStamp/1e9, 0))
-			fp := maFilter.FilterPointForBlock()
-
-			metadata.AddPoint(fp.Value)
-
-			fp.FirstTrade = block.Trades[0]
-			fp.LastTrade = block.Trades[len(block.Trades)-1]
-
-			if fp != nil {
-				filterPoints = append(filterPoints, *fp)
-				lastfp = fp
-			} else if lastfp != nil {
-				log.Println("block.TimeStamp", block.TimeStamp)
-				lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *lastfp)
-
-			}
-		} else {
-			lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-			filterPoints = append(filterPoints, *lastfp)
-
-		}
-	}
-
-	return
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterMA(asset, "", beginTime, blockSize)
+	})
 }
-func FilterMAIR(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
-	var lastfp *dia.FilterPoint
-	metadata = dia.NewFilterPointMetadata()
-
-	for _, block := range tradeBlocks {
-
-		if len(block.Trades) > 0 {
-			mairFilter := filters.NewFilterMAIR(asset, "", time.Unix(block.TimeStamp/1e9, 0), blockSize)
 
-			for _, trade := range block.Trades {
-				mairFilter.Compute(trade)
-			}
-
-			mairFilter.FinalCompute(time.Unix(block.TimeStamp/1e9, 0))
-			fp := mairFilter.FilterPointForBlock()
-
-			metadata.AddPoint(fp.Value)
-
-			fp.FirstTrade = block.Trades[0]
-			fp.LastTrade = block.Trades[len(block.Trades)-1]
-
-			if fp != nil {
-				fp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *fp)
-				lastfp = fp
-			} else if lastfp != nil {
-				log.Println("block.TimeStamp", block.TimeStamp)
-				lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *lastfp)
-			}
-		} else {
-			lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-			filterPoints = append(filterPoints, *lastfp)
-		}
-	}
-
-	return
+// FilterMAIR returns the moving average of each block without outliers.
+func FilterMAIR(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterMAIR(asset, "", beginTime, blockSize)
+	})
 }
 
+// FilterVWAP returns the volume weighted average price of each block.
 func FilterVWAP(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
-	var lastfp *dia.FilterPoint
-	metadata = dia.NewFilterPointMetadata()
-	for _, block := range tradeBlocks {
-		if len(block.Trades) > 0 {
-			maFilter := filters.NewFilterVWAP(asset, "", time.Unix(block.TimeStamp/1e9, 0), blockSize)
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterVWAP(asset, "", beginTime, blockSize)
+	})
+}
 
-			for _, trade := range block.Trades {
-				maFilter.Compute(trade)
-			}
+// FilterTWAP returns the time weighted average price of each block. The price of a block's last
+// trade is weighted until the end of the block.
+func FilterTWAP(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterTWAP(asset, "", beginTime, blockSize)
+	})
+}
 
-			maFilter.FinalCompute(block.Trades[0].Time)
-			fp := maFilter.FilterPointForBlock()
-			metadata.AddPoint(fp.Value)
-			fp.FirstTrade = block.Trades[0]
-			fp.LastTrade = block.Trades[len(block.Trades)-1]
-			if fp != nil {
-				fp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *fp)
-				lastfp = fp
-			} else {
-				lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *lastfp)
-			}
-		} else {
-			lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-			filterPoints = append(filterPoints, *lastfp)
-		}
-	}
-	return
+// FilterLWVWAP returns the liquidity weighted average price of each block, where trades are
+// weighted by their volume times the liquidity of their pools as given by @liquidity.
+// Blocks without trades in known pools repeat the previous value.
+func FilterLWVWAP(tradeBlocks []Block, asset dia.Asset, blockSize int, liquidity filters.LiquiditySource) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterLWVWAP(asset, "", beginTime, blockSize, liquidity)
+	})
 }
 
+// FilterVWAPIR returns the volume weighted average price of each block without outliers.
 func FilterVWAPIR(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
-	var lastfp *dia.FilterPoint
-	metadata = dia.NewFilterPointMetadata()
-
-	for _, block := range tradeBlocks {
-		if len(block.Trades) > 0 {
-			maFilter := filters.NewFilterVWAPIR(asset, "", time.Unix(block.TimeStamp/1e9, 0), blockSize)
-
-			for _, trade := range block.Trades {
-
-				maFilter.Compute(trade)
-			}
-
-			maFilter.FinalCompute(time.Unix(block.TimeStamp/1e9, 0))
-			fp := maFilter.FilterPointForBlock()
-
-			metadata.AddPoint(fp.Value)
-			fp.FirstTrade = block.Trades[0]
-			fp.LastTrade = block.Trades[len(block.Trades)-1]
-			if fp != nil && fp.Value > 0 {
-				fp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *fp)
-				lastfp = fp
-			} else {
-				if lastfp != nil {
-					lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-					filterPoints = append(filterPoints, *lastfp)
-				}
-			}
-		} else {
-			if lastfp != nil {
-				lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *lastfp)
-			}
-		}
-	}
-	return
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterVWAPIR(asset, "", beginTime, blockSize)
+	})
 }
 
+// FilterMEDIR returns the median price of each block without outliers.
 func FilterMEDIR(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
-	var lastfp *dia.FilterPoint
-	metadata = dia.NewFilterPointMetadata()
-
-	for _, block := range tradeBlocks {
-		if len(block.Trades) > 0 {
-			medirFilter := filters.NewFilterMEDIR(asset, "", time.Unix(block.TimeStamp/1e9, 0), blockSize)
-
-			for _, trade := range block.Trades {
-
-				medirFilter.Compute(trade)
-			}
-
-			medirFilter.FinalCompute(time.Unix(block.TimeStamp/1e9, 0))
-			fp := medirFilter.FilterPointForBlock()
-			metadata.AddPoint(fp.Value)
-			fp.FirstTrade = block.Trades[0]
-			fp.LastTrade = block.Trades[len(block.Trades)-1]
-			if fp != nil && fp.Value > 0 {
-				fp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *fp)
-				lastfp = fp
-			} else {
-				if lastfp != nil {
-					lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-					filterPoints = append(filterPoints, *lastfp)
-				}
-			}
-		} else {
-			if lastfp != nil {
-				lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *lastfp)
-			}
-		}
-	}
-	return
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterMEDIR(asset, "", beginTime, blockSize)
+	})
 }
 
 func FilterEMA(points []dia.FilterPoint, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
@@ -207,13 +73,13 @@ func FilterEMA(points []dia.FilterPoint, asset dia.Asset, blockSize int) (filter
 			metadata.AddPoint(fp.Value)
 			if fp.Value > 0 {
 				filterPoints = append(filterPoints, *fp)
-				log.Println("append index%5  %v  points %v filterPoints %v filterPoints size %v", index%5, point.Value, fp.Value, len(filterPoints))
+				log.Printf("append index%%5  %v  points %v filterPoints %v filterPoints size %v", index%5, point.Value, fp.Value, len(filterPoints))
 
 			}
-			log.Println("index%5  %v  points %v filterPoints %v", index%5, point.Value, fp.Value)
+			log.Printf("index%%5  %v  points %v filterPoints %v", index%5, point.Value, fp.Value)
 
 		} else {
-			log.Println("Compute index%5  %v  points %v ", index%5, point.Value)
+			log.Printf("Compute index%%5  %v  points %v ", index%5, point.Value)
 			emaFilter.Compute(point)
 		}
 	}
@@ -221,27 +87,119 @@ func FilterEMA(points []dia.FilterPoint, asset dia.Asset, blockSize int) (filter
 	return
 }
 
+// FilterVOL returns the USD volume of each block with trades.
 func FilterVOL(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, false, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterVOL(asset, "", blockSize)
+	})
+}
+
+// FilterRVOL returns the annualized realized volatility over each block. Volatilities are
+// estimated from the one minute intervals of a block, so blocks should span many minutes.
+func FilterRVOL(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterRVOL(asset, "", beginTime, blockSize)
+	})
+}
+
+// FilterPVOL returns the annualized Parkinson high-low volatility over each block.
+func FilterPVOL(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterPVOL(asset, "", beginTime, blockSize)
+	})
+}
+
+// FilterRVAR returns the variance of the one minute log returns over each block.
+func FilterRVAR(tradeBlocks []Block, asset dia.Asset, blockSize int) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	return filterBlocks(tradeBlocks, blockSize, true, func(beginTime time.Time) filters.BlockFilter {
+		return filters.NewFilterRVAR(asset, "", beginTime, blockSize)
+	})
+}
+
+// tradeKey identifies a trade contained in several overlapping blocks.
+type tradeKey struct {
+	source         string
+	foreignTradeID string
+	time           int64
+	price          float64
+	volume         float64
+}
+
+func newTradeKey(trade dia.Trade) tradeKey {
+	return tradeKey{
+		source:         trade.Source,
+		foreignTradeID: trade.ForeignTradeID,
+		time:           trade.Time.UnixNano(),
+		price:          trade.EstimatedUSDPrice,
+		volume:         trade.Volume,
+	}
+}
+
+// filterBlocks computes the filter returned by @newFilter over @tradeBlocks just as the filtersBlockService
+// computes it over tradesBlocks: the filter is created upon the first trade, receives each trade once and in
+// order and is finalized at the end of each block. Trades repeated in overlapping blocks, as generated by
+// GenerateShift, are only computed once.
+// Price filters, indicated by @prices, yield a point for each block. Blocks without trades or without positive
+// value repeat the previous point. Volatility filters are computed like price filters, as their value is 0
+// if there are too few trades. Other filters, such as VOL, only yield points for blocks with trades.
+func filterBlocks(tradeBlocks []Block, blockSize int, prices bool, newFilter func(beginTime time.Time) filters.BlockFilter) (filterPoints []dia.FilterPoint, metadata *dia.FilterPointMetadata) {
+	var filter filters.BlockFilter
+	var lastfp *dia.FilterPoint
+	var previousTrades map[tradeKey]bool
 	metadata = dia.NewFilterPointMetadata()
 
 	for _, block := range tradeBlocks {
-		if len(block.Trades) > 0 {
-			volFilter := filters.NewFilterVOL(asset, "", blockSize)
-
-			for _, trade := range block.Trades {
-				volFilter.Compute(trade)
+		blockTrades := make(map[tradeKey]bool, len(block.Trades))
+		for _, trade := range block.Trades {
+			key := newTradeKey(trade)
+			blockTrades[key] = true
+			if previousTrades[key] {
+				continue
+			}
+			if filter == nil {
+				filter = newFilter(time.Unix(0, block.TimeStamp))
 			}
+			filter.Compute(trade)
+		}
+		previousTrades = blockTrades
+		if filter == nil {
+			continue
+		}
+		value := filter.FinalCompute(blockEnd(block, blockSize))
 
-			volFilter.FinalCompute(time.Unix(block.TimeStamp/1e9, 0))
-			fp := volFilter.FilterPointForBlock()
+		if len(block.Trades) > 0 && (value > 0 || !prices) {
+			fp := filter.FilterPointForBlock()
 			metadata.AddPoint(fp.Value)
 			fp.FirstTrade = block.Trades[0]
 			fp.LastTrade = block.Trades[len(block.Trades)-1]
-			if fp != nil {
-				fp.Time = time.Unix(block.TimeStamp/1e9, 0)
-				filterPoints = append(filterPoints, *fp)
-			}
+			fp.Time = time.Unix(block.TimeStamp/1e9, 0)
+			setQuality(fp, block.Trades, blockEnd(block, blockSize))
+			filterPoints = append(filterPoints, *fp)
+			lastfp = fp
+		} else if prices && lastfp != nil {
+			lastfp.Time = time.Unix(block.TimeStamp/1e9, 0)
+			setQuality(lastfp, nil, blockEnd(block, blockSize))
+			filterPoints = append(filterPoints, *lastfp)
 		}
 	}
 	return
 }
+
+// blockEnd returns the end of @block with a size of @blockSize seconds.
+func blockEnd(block Block, blockSize int) time.Time {
+	return time.Unix(0, block.TimeStamp).Add(time.Duration(blockSize) * time.Second)
+}
+
+// setQuality sets the quality of @fp at @t computed from @trades. Points repeated for blocks
+// without valid value are given the quality of a point without trades.
+func setQuality(fp *dia.FilterPoint, trades []dia.Trade, t time.Time) {
+	if fp == nil {
+		return
+	}
+	lastTrade := fp.LastTrade.Time
+	if len(trades) > 0 {
+		lastTrade = trades[len(trades)-1].Time
+	}
+	quality := filters.ComputeQuality(trades, len(fp.Excluded), lastTrade, t)
+	fp.Quality = &quality
+}
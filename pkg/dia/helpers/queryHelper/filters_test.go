@@ -0,0 +1,176 @@
+package queryhelper
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/quick"
+	"time"
+
+	filters "github.com/diadata-org/diadata/internal/pkg/filtersBlockService"
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+type streamedPoint struct {
+	filter string
+	time   int64
+}
+
+// streamRecorder keeps the values the filtersBlockService saves for all exchanges instead of writing them.
+type streamRecorder struct {
+	mu     sync.Mutex
+	values map[streamedPoint]float64
+}
+
+func (sr *streamRecorder) SetFilter(filterName string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if exchange == "" {
+		sr.values[streamedPoint{filterName, t.UnixNano()}] = value
+	}
+	return nil
+}
+
+func (sr *streamRecorder) SetFilterQuality(filterName string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	return nil
+}
+
+func (sr *streamRecorder) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	return nil
+}
+
+func (sr *streamRecorder) SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error {
+	return nil
+}
+
+func (sr *streamRecorder) SetLastTradeTimeForExchange(asset dia.Asset, exchange string, t time.Time) error {
+	return nil
+}
+
+func (sr *streamRecorder) ExecuteRedisPipe() error {
+	return nil
+}
+
+func (sr *streamRecorder) FlushRedisPipe() error {
+	return nil
+}
+
+func (sr *streamRecorder) Flush() error {
+	return nil
+}
+
+// constantLiquidity gives all trades the same pool liquidity.
+type constantLiquidity struct{}
+
+func (constantLiquidity) QuoteLiquidity(trade dia.Trade) (float64, bool) {
+	return 1000, true
+}
+
+// randomTrades returns between 1 and 300 trades in ascending order of time with prices around 100,
+// including outliers and trades at the same time.
+func randomTrades(r *rand.Rand, asset dia.Asset) (trades []dia.Trade) {
+	exchanges := []string{dia.BinanceExchange, dia.CoinBaseExchange, dia.KrakenExchange}
+	t := time.Unix(1654041600, 0).Add(time.Duration(r.Int63n(int64(time.Minute))))
+	for i := 1 + r.Intn(300); i > 0; i-- {
+		price := 100 + r.NormFloat64()
+		if r.Intn(20) == 0 {
+			price *= 1 + 2*r.Float64()
+		}
+		volume := 0.01 + 10*r.Float64()
+		if r.Intn(2) == 0 {
+			volume = -volume
+		}
+		trades = append(trades, dia.Trade{
+			QuoteToken:        asset,
+			Price:             price,
+			EstimatedUSDPrice: price,
+			Volume:            volume,
+			Time:              t,
+			Source:            exchanges[r.Intn(len(exchanges))],
+			ForeignTradeID:    strconv.Itoa(len(trades)),
+		})
+		if r.Intn(5) > 0 {
+			t = t.Add(time.Duration(r.Int63n(int64(40 * time.Second))))
+		}
+	}
+	return
+}
+
+// streamFilter computes @filterType over @blocks in the filtersBlockService and returns the values it saved.
+func streamFilter(t *testing.T, filterType string, blocks []Block, blockSize int) map[streamedPoint]float64 {
+	filterSets, err := filters.NewFilterSets([]dia.FilterConfig{{Filter: filterType, Window: blockSize}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filterSets.SetLiquiditySource(constantLiquidity{})
+	recorder := &streamRecorder{values: make(map[streamedPoint]float64)}
+	fbs := filters.NewFiltersBlockService(nil, recorder, nil, filterSets)
+	defer fbs.Close()
+	for _, block := range blocks {
+		tb := &dia.TradesBlock{
+			TradesBlockData: dia.TradesBlockData{
+				BeginTime: time.Unix(0, block.TimeStamp),
+				EndTime:   blockEnd(block, blockSize),
+				Trades:    block.Trades,
+			},
+		}
+		if err := fbs.ProcessTradesBlockSync(tb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return recorder.values
+}
+
+// TestStreamingEqualsBatch checks that the filters computed over blocks of trades by the queryhelper
+// yield the values the filtersBlockService computes over tradesBlocks with the same trades.
+func TestStreamingEqualsBatch(t *testing.T) {
+	asset := dia.Asset{Symbol: "ETH", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.ETHEREUM}
+	batchFilters := map[string]func(tradeBlocks []Block, blockSize int) []dia.FilterPoint{
+		"MA":     func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterMA(b, asset, size); return fps },
+		"MAIR":   func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterMAIR(b, asset, size); return fps },
+		"MEDIR":  func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterMEDIR(b, asset, size); return fps },
+		"VWAP":   func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterVWAP(b, asset, size); return fps },
+		"VWAPIR": func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterVWAPIR(b, asset, size); return fps },
+		"TWAP":   func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterTWAP(b, asset, size); return fps },
+		"VOL":    func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterVOL(b, asset, size); return fps },
+		"RVOL":   func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterRVOL(b, asset, size); return fps },
+		"PVOL":   func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterPVOL(b, asset, size); return fps },
+		"RVAR":   func(b []Block, size int) []dia.FilterPoint { fps, _ := FilterRVAR(b, asset, size); return fps },
+		"LWVWAP": func(b []Block, size int) []dia.FilterPoint {
+			fps, _ := FilterLWVWAP(b, asset, size, constantLiquidity{})
+			return fps
+		},
+	}
+
+	for filterType, batch := range batchFilters {
+		filterType, batch := filterType, batch
+		property := func(seed int64) bool {
+			r := rand.New(rand.NewSource(seed))
+			blockSize := []int{30, 60, dia.BlockSizeSeconds, 300}[r.Intn(4)]
+			blocks := NewBlockGenerator(randomTrades(r, asset)).GenerateSize(int64(blockSize))
+			streamed := streamFilter(t, filterType, blocks, blockSize)
+
+			points := make(map[int64]float64)
+			for _, fp := range batch(blocks, blockSize) {
+				points[fp.LastTrade.Time.UnixNano()] = fp.Value
+			}
+			for _, block := range blocks {
+				lastTrade := block.Trades[len(block.Trades)-1].Time.UnixNano()
+				expected, ok := streamed[streamedPoint{filterType + strconv.Itoa(blockSize), lastTrade}]
+				if !ok || expected <= 0 {
+					continue
+				}
+				if value, ok := points[lastTrade]; !ok || math.Abs(value-expected) > 1e-9*math.Abs(expected) {
+					t.Logf("%s, seed %d, block size %d: got %v, streamed %v", filterType, seed, blockSize, value, expected)
+					return false
+				}
+			}
+			return true
+		}
+		if err := quick.Check(property, &quick.Config{MaxCount: 50}); err != nil {
+			t.Errorf("%s: %v", filterType, err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package tradeDump
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+const (
+	maxLineSize      = 1 << 20
+	parquetBatchSize = 10000
+)
+
+// Reader reads trades from a trade dump.
+type Reader interface {
+	Header() Header
+	// Read returns the next trade of the dump and io.EOF after the last one.
+	Read() (dia.Trade, error)
+	Close() error
+}
+
+// NewReader opens the trade dump at @path. The format is determined by the file's suffix.
+func NewReader(path string) (Reader, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatJSONL:
+		return newJSONLReader(path)
+	case FormatParquet:
+		return newParquetReader(path)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ReadAll returns all trades from the trade dump at @path.
+func ReadAll(path string) (trades []dia.Trade, err error) {
+	r, err := NewReader(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		cerr := r.Close()
+		if err == nil {
+			err = cerr
+		}
+	}()
+	for {
+		var t dia.Trade
+		t, err = r.Read()
+		if err == io.EOF {
+			return trades, nil
+		}
+		if err != nil {
+			return
+		}
+		trades = append(trades, t)
+	}
+}
+
+type jsonlReader struct {
+	file    *os.File
+	gz      *gzip.Reader
+	scanner *bufio.Scanner
+	header  Header
+}
+
+func newJSONLReader(path string) (*jsonlReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	jr := &jsonlReader{file: file}
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		jr.gz, err = gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		r = jr.gz
+	}
+	jr.scanner = bufio.NewScanner(r)
+	jr.scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	if !jr.scanner.Scan() {
+		jr.Close()
+		if jr.scanner.Err() != nil {
+			return nil, jr.scanner.Err()
+		}
+		return nil, errors.New("empty trade dump " + path)
+	}
+	if err = json.Unmarshal(jr.scanner.Bytes(), &jr.header); err == nil {
+		err = jr.header.validate()
+	}
+	if err != nil {
+		jr.Close()
+		return nil, err
+	}
+	return jr, nil
+}
+
+func (jr *jsonlReader) Header() Header {
+	return jr.header
+}
+
+func (jr *jsonlReader) Read() (t dia.Trade, err error) {
+	for jr.scanner.Scan() {
+		line := jr.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		err = t.UnmarshalBinary(line)
+		return
+	}
+	if jr.scanner.Err() != nil {
+		return t, jr.scanner.Err()
+	}
+	return t, io.EOF
+}
+
+func (jr *jsonlReader) Close() error {
+	if jr.gz != nil {
+		if err := jr.gz.Close(); err != nil {
+			jr.file.Close()
+			return err
+		}
+	}
+	return jr.file.Close()
+}
+
+// localFile implements parquet-go's source.ParquetFile for files on disk.
+type localFile struct {
+	*os.File
+}
+
+func (lf *localFile) Open(name string) (source.ParquetFile, error) {
+	if name == "" {
+		name = lf.Name()
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{file}, nil
+}
+
+func (lf *localFile) Create(name string) (source.ParquetFile, error) {
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{file}, nil
+}
+
+type parquetReader struct {
+	file   *localFile
+	pr     *reader.ParquetReader
+	header Header
+	buffer []parquetTrade
+	read   int64
+}
+
+func newParquetReader(path string) (*parquetReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	lf := &localFile{file}
+	pr, err := reader.NewParquetReader(lf, new(parquetTrade), 1)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	pqr := &parquetReader{file: lf, pr: pr}
+	err = errors.New("missing header in trade dump " + path)
+	for _, kv := range pr.Footer.KeyValueMetadata {
+		if kv.Key == headerMetadataKey && kv.Value != nil {
+			if err = json.Unmarshal([]byte(*kv.Value), &pqr.header); err == nil {
+				err = pqr.header.validate()
+			}
+		}
+	}
+	if err != nil {
+		pqr.Close()
+		return nil, err
+	}
+	return pqr, nil
+}
+
+func (pqr *parquetReader) Header() Header {
+	return pqr.header
+}
+
+func (pqr *parquetReader) Read() (dia.Trade, error) {
+	if len(pqr.buffer) == 0 {
+		remaining := pqr.pr.GetNumRows() - pqr.read
+		if remaining <= 0 {
+			return dia.Trade{}, io.EOF
+		}
+		if remaining > parquetBatchSize {
+			remaining = parquetBatchSize
+		}
+		pqr.buffer = make([]parquetTrade, remaining)
+		if err := pqr.pr.Read(&pqr.buffer); err != nil {
+			return dia.Trade{}, err
+		}
+		pqr.read += remaining
+	}
+	t := pqr.buffer[0].trade()
+	pqr.buffer = pqr.buffer[1:]
+	return t, nil
+}
+
+func (pqr *parquetReader) Close() error {
+	pqr.pr.ReadStop()
+	return pqr.file.Close()
+}
@@ -0,0 +1,158 @@
+package tradeDump
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.New()
+
+const fileTimeLayout = "20060102T150405Z"
+
+// RotatingWriter writes trades to a sequence of trade dumps in a directory.
+// A new file is begun once the current one contains @maxTrades trades or is older than @maxAge.
+// Files are named <prefix>_<creation time>_<sequence number>.<suffix>, so that they sort chronologically.
+// RotatingWriter is safe for concurrent use.
+type RotatingWriter struct {
+	dir       string
+	prefix    string
+	suffix    string
+	compress  bool
+	maxTrades int
+	maxAge    time.Duration
+
+	mu        sync.Mutex
+	current   Writer
+	path      string
+	numTrades int
+	created   time.Time
+	sequence  int
+	closed    bool
+}
+
+// NewRotatingWriter returns a RotatingWriter writing dumps of @format to @dir.
+// If @compress is true, JSONL dumps are gzip compressed and parquet dumps are snappy compressed.
+// A limit of zero disables rotation by number of trades or by age respectively.
+func NewRotatingWriter(dir string, prefix string, format string, compress bool, maxTrades int, maxAge time.Duration) (*RotatingWriter, error) {
+	var suffix string
+	switch format {
+	case FormatJSONL:
+		suffix = ".jsonl"
+		if compress {
+			suffix += ".gz"
+		}
+	case FormatParquet:
+		suffix = ".parquet"
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &RotatingWriter{
+		dir:       dir,
+		prefix:    prefix,
+		suffix:    suffix,
+		compress:  compress,
+		maxTrades: maxTrades,
+		maxAge:    maxAge,
+	}, nil
+}
+
+// Write appends @t to the current dump, after rotating it if necessary.
+func (rw *RotatingWriter) Write(t *dia.Trade) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	if rw.closed {
+		return errors.New("write to closed RotatingWriter")
+	}
+	if rw.current != nil && rw.isFull() {
+		if err := rw.closeCurrent(); err != nil {
+			return err
+		}
+	}
+	if rw.current == nil {
+		if err := rw.open(); err != nil {
+			return err
+		}
+	}
+	if err := rw.current.Write(t); err != nil {
+		return err
+	}
+	rw.numTrades++
+	return nil
+}
+
+// Rotate completes the current dump. The next trade is written to a new file.
+func (rw *RotatingWriter) Rotate() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return rw.closeCurrent()
+}
+
+// Close completes the current dump. Subsequent writes fail.
+func (rw *RotatingWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	rw.closed = true
+	return rw.closeCurrent()
+}
+
+func (rw *RotatingWriter) isFull() bool {
+	if rw.maxTrades > 0 && rw.numTrades >= rw.maxTrades {
+		return true
+	}
+	return rw.maxAge > 0 && time.Since(rw.created) >= rw.maxAge
+}
+
+func (rw *RotatingWriter) open() error {
+	rw.created = time.Now().UTC()
+	var path string
+	// The sequence number orders dumps begun within the same second and avoids overwriting existing files.
+	for path == "" || fileExists(path) {
+		rw.sequence++
+		path = filepath.Join(rw.dir, fmt.Sprintf("%s_%s_%06d%s", rw.prefix, rw.created.Format(fileTimeLayout), rw.sequence, rw.suffix))
+	}
+
+	var (
+		w   Writer
+		err error
+	)
+	if rw.suffix == ".parquet" {
+		w, err = newParquetWriter(path, rw.compress)
+	} else {
+		w, err = newJSONLWriter(path)
+	}
+	if err != nil {
+		return err
+	}
+	rw.current = w
+	rw.path = path
+	rw.numTrades = 0
+	return nil
+}
+
+func (rw *RotatingWriter) closeCurrent() error {
+	if rw.current == nil {
+		return nil
+	}
+	err := rw.current.Close()
+	rw.current = nil
+	if err != nil {
+		return err
+	}
+	log.Infof("completed trade dump %s with %d trades.", rw.path, rw.numTrades)
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
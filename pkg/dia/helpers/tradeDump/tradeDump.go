@@ -0,0 +1,147 @@
+// Package tradeDump defines the file formats used for recording dia.Trades
+// outside of a database, i.e. for fixture datasets, market captures and replays.
+//
+// Two formats are supported:
+//   - JSONL: The first line is a Header, each subsequent line is a JSON encoded dia.Trade.
+//     Files with the suffix .gz are gzip compressed.
+//   - Parquet: One row per trade with flattened quote and base token. The Header is
+//     stored in the file's key-value metadata.
+//
+// Dumps recorded by the collector hold the trades in the order they were published in production,
+// including the reversed trades published for exchanges in scrapers.SwapTradesOnExchange.
+package tradeDump
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+const (
+	// SchemaVersion is incremented on each incompatible change of the dump formats.
+	SchemaVersion = 1
+	// SchemaName identifies a trade dump in the header of a file.
+	SchemaName = "dia.Trade"
+
+	FormatJSONL   = "jsonl"
+	FormatParquet = "parquet"
+
+	headerMetadataKey = "diaTradeDumpHeader"
+)
+
+var ErrUnknownFormat = errors.New("unknown trade dump format")
+
+// Header is written at the beginning of each trade dump.
+type Header struct {
+	Schema        string    `json:"schema"`
+	SchemaVersion int       `json:"schemaVersion"`
+	Created       time.Time `json:"created"`
+}
+
+// NewHeader returns the header for dumps of the current schema version.
+func NewHeader() Header {
+	return Header{
+		Schema:        SchemaName,
+		SchemaVersion: SchemaVersion,
+		Created:       time.Now().UTC(),
+	}
+}
+
+func (h Header) validate() error {
+	if h.Schema != SchemaName {
+		return errors.New("not a trade dump: schema " + h.Schema)
+	}
+	if h.SchemaVersion < 1 || h.SchemaVersion > SchemaVersion {
+		return errors.New("unsupported schema version of trade dump")
+	}
+	return nil
+}
+
+// FormatFromPath returns the dump format according to the suffix of @path.
+func FormatFromPath(path string) (string, error) {
+	switch {
+	case strings.HasSuffix(path, ".jsonl"), strings.HasSuffix(path, ".jsonl.gz"):
+		return FormatJSONL, nil
+	case strings.HasSuffix(path, ".parquet"):
+		return FormatParquet, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// parquetTrade is the flat representation of a dia.Trade in parquet files.
+type parquetTrade struct {
+	Symbol               string  `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Pair                 string  `parquet:"name=pair, type=BYTE_ARRAY, convertedtype=UTF8"`
+	QuoteTokenSymbol     string  `parquet:"name=quote_token_symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	QuoteTokenName       string  `parquet:"name=quote_token_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	QuoteTokenAddress    string  `parquet:"name=quote_token_address, type=BYTE_ARRAY, convertedtype=UTF8"`
+	QuoteTokenDecimals   int32   `parquet:"name=quote_token_decimals, type=INT32"`
+	QuoteTokenBlockchain string  `parquet:"name=quote_token_blockchain, type=BYTE_ARRAY, convertedtype=UTF8"`
+	BaseTokenSymbol      string  `parquet:"name=base_token_symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	BaseTokenName        string  `parquet:"name=base_token_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+	BaseTokenAddress     string  `parquet:"name=base_token_address, type=BYTE_ARRAY, convertedtype=UTF8"`
+	BaseTokenDecimals    int32   `parquet:"name=base_token_decimals, type=INT32"`
+	BaseTokenBlockchain  string  `parquet:"name=base_token_blockchain, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Price                float64 `parquet:"name=price, type=DOUBLE"`
+	Volume               float64 `parquet:"name=volume, type=DOUBLE"`
+	TimeNano             int64   `parquet:"name=time_nano, type=INT64"`
+	ForeignTradeID       string  `parquet:"name=foreign_trade_id, type=BYTE_ARRAY, convertedtype=UTF8"`
+	EstimatedUSDPrice    float64 `parquet:"name=estimated_usd_price, type=DOUBLE"`
+	Source               string  `parquet:"name=source, type=BYTE_ARRAY, convertedtype=UTF8"`
+	VerifiedPair         bool    `parquet:"name=verified_pair, type=BOOLEAN"`
+}
+
+func newParquetTrade(t dia.Trade) parquetTrade {
+	return parquetTrade{
+		Symbol:               t.Symbol,
+		Pair:                 t.Pair,
+		QuoteTokenSymbol:     t.QuoteToken.Symbol,
+		QuoteTokenName:       t.QuoteToken.Name,
+		QuoteTokenAddress:    t.QuoteToken.Address,
+		QuoteTokenDecimals:   int32(t.QuoteToken.Decimals),
+		QuoteTokenBlockchain: t.QuoteToken.Blockchain,
+		BaseTokenSymbol:      t.BaseToken.Symbol,
+		BaseTokenName:        t.BaseToken.Name,
+		BaseTokenAddress:     t.BaseToken.Address,
+		BaseTokenDecimals:    int32(t.BaseToken.Decimals),
+		BaseTokenBlockchain:  t.BaseToken.Blockchain,
+		Price:                t.Price,
+		Volume:               t.Volume,
+		TimeNano:             t.Time.UnixNano(),
+		ForeignTradeID:       t.ForeignTradeID,
+		EstimatedUSDPrice:    t.EstimatedUSDPrice,
+		Source:               t.Source,
+		VerifiedPair:         t.VerifiedPair,
+	}
+}
+
+func (pt parquetTrade) trade() dia.Trade {
+	return dia.Trade{
+		Symbol: pt.Symbol,
+		Pair:   pt.Pair,
+		QuoteToken: dia.Asset{
+			Symbol:     pt.QuoteTokenSymbol,
+			Name:       pt.QuoteTokenName,
+			Address:    pt.QuoteTokenAddress,
+			Decimals:   uint8(pt.QuoteTokenDecimals),
+			Blockchain: pt.QuoteTokenBlockchain,
+		},
+		BaseToken: dia.Asset{
+			Symbol:     pt.BaseTokenSymbol,
+			Name:       pt.BaseTokenName,
+			Address:    pt.BaseTokenAddress,
+			Decimals:   uint8(pt.BaseTokenDecimals),
+			Blockchain: pt.BaseTokenBlockchain,
+		},
+		Price:             pt.Price,
+		Volume:            pt.Volume,
+		Time:              time.Unix(0, pt.TimeNano).UTC(),
+		ForeignTradeID:    pt.ForeignTradeID,
+		EstimatedUSDPrice: pt.EstimatedUSDPrice,
+		Source:            pt.Source,
+		VerifiedPair:      pt.VerifiedPair,
+	}
+}
@@ -0,0 +1,159 @@
+package tradeDump
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func testTrades(n int) []dia.Trade {
+	var trades []dia.Trade
+	t0 := time.Date(2022, 6, 1, 12, 0, 0, 123456789, time.UTC)
+	for i := 0; i < n; i++ {
+		trades = append(trades, dia.Trade{
+			Symbol: "ETH",
+			Pair:   "ETH-USDT",
+			QuoteToken: dia.Asset{
+				Symbol:     "ETH",
+				Name:       "Ether",
+				Address:    "0x0000000000000000000000000000000000000000",
+				Decimals:   18,
+				Blockchain: dia.ETHEREUM,
+			},
+			BaseToken: dia.Asset{
+				Symbol:     "USDT",
+				Name:       "Tether USD",
+				Address:    "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+				Decimals:   6,
+				Blockchain: dia.ETHEREUM,
+			},
+			Price:             1800.5 + float64(i),
+			Volume:            -0.25 * float64(i+1),
+			Time:              t0.Add(time.Duration(i) * time.Second),
+			ForeignTradeID:    "id" + string(rune('a'+i%26)),
+			EstimatedUSDPrice: 1800.25 + float64(i),
+			Source:            dia.BinanceExchange,
+			VerifiedPair:      i%2 == 0,
+		})
+	}
+	return trades
+}
+
+func TestRoundtrip(t *testing.T) {
+	trades := testTrades(25)
+	for _, name := range []string{"trades.jsonl", "trades.jsonl.gz", "trades.parquet"} {
+		path := filepath.Join(t.TempDir(), name)
+		w, err := NewWriter(path)
+		if err != nil {
+			t.Fatalf("%s: NewWriter: %v", name, err)
+		}
+		for i := range trades {
+			if err := w.Write(&trades[i]); err != nil {
+				t.Fatalf("%s: Write: %v", name, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: Close: %v", name, err)
+		}
+
+		r, err := NewReader(path)
+		if err != nil {
+			t.Fatalf("%s: NewReader: %v", name, err)
+		}
+		if r.Header().Schema != SchemaName || r.Header().SchemaVersion != SchemaVersion {
+			t.Errorf("%s: unexpected header %v", name, r.Header())
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("%s: Close reader: %v", name, err)
+		}
+
+		read, err := ReadAll(path)
+		if err != nil {
+			t.Fatalf("%s: ReadAll: %v", name, err)
+		}
+		if len(read) != len(trades) {
+			t.Fatalf("%s: read %d trades, expected %d", name, len(read), len(trades))
+		}
+		for i := range trades {
+			if !read[i].Time.Equal(trades[i].Time) {
+				t.Errorf("%s: trade %d: time %v, expected %v", name, i, read[i].Time, trades[i].Time)
+			}
+			read[i].Time = trades[i].Time
+			if !reflect.DeepEqual(read[i], trades[i]) {
+				t.Errorf("%s: trade %d: got %v, expected %v", name, i, read[i], trades[i])
+			}
+		}
+	}
+}
+
+func TestRotatingWriter(t *testing.T) {
+	trades := testTrades(25)
+	for _, format := range []string{FormatJSONL, FormatParquet} {
+		dir := t.TempDir()
+		rw, err := NewRotatingWriter(dir, "test", format, true, 10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range trades {
+			if err := rw.Write(&trades[i]); err != nil {
+				t.Fatalf("%s: Write: %v", format, err)
+			}
+		}
+		if err := rw.Close(); err != nil {
+			t.Fatalf("%s: Close: %v", format, err)
+		}
+		if err := rw.Write(&trades[0]); err == nil {
+			t.Errorf("%s: expected error on write after close", format)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("%s: expected 3 files, got %d", format, len(entries))
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		var read []dia.Trade
+		for _, name := range names {
+			fileTrades, err := ReadAll(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatalf("%s: ReadAll %s: %v", format, name, err)
+			}
+			read = append(read, fileTrades...)
+		}
+		if len(read) != len(trades) {
+			t.Fatalf("%s: read %d trades, expected %d", format, len(read), len(trades))
+		}
+		for i := range trades {
+			if read[i].ForeignTradeID != trades[i].ForeignTradeID || read[i].Price != trades[i].Price {
+				t.Errorf("%s: trade %d out of order", format, i)
+			}
+		}
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	cases := map[string]string{
+		"a.jsonl":      FormatJSONL,
+		"a.jsonl.gz":   FormatJSONL,
+		"a.parquet":    FormatParquet,
+		"a.parquet.gz": "",
+		"a.csv":        "",
+	}
+	for path, expected := range cases {
+		format, err := FormatFromPath(path)
+		if format != expected || (expected == "" && err != ErrUnknownFormat) {
+			t.Errorf("%s: got %q, %v", path, format, err)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package tradeDump
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Writer writes trades to a trade dump. A dump is only complete once Close is called.
+type Writer interface {
+	Write(t *dia.Trade) error
+	Close() error
+}
+
+// NewWriter creates the trade dump at @path. The format is determined by the file's suffix.
+// JSONL dumps are gzip compressed if @path ends with .gz, parquet dumps are snappy compressed.
+func NewWriter(path string) (Writer, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FormatJSONL:
+		return newJSONLWriter(path)
+	case FormatParquet:
+		return newParquetWriter(path, true)
+	}
+	return nil, ErrUnknownFormat
+}
+
+type jsonlWriter struct {
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+}
+
+func newJSONLWriter(path string) (*jsonlWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	jw := &jsonlWriter{file: file}
+	if strings.HasSuffix(path, ".gz") {
+		jw.gz = gzip.NewWriter(file)
+		jw.buf = bufio.NewWriter(jw.gz)
+	} else {
+		jw.buf = bufio.NewWriter(file)
+	}
+	header, err := json.Marshal(NewHeader())
+	if err == nil {
+		err = jw.writeLine(header)
+	}
+	if err != nil {
+		jw.Close()
+		return nil, err
+	}
+	return jw, nil
+}
+
+func (jw *jsonlWriter) Write(t *dia.Trade) error {
+	line, err := t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return jw.writeLine(line)
+}
+
+func (jw *jsonlWriter) writeLine(line []byte) error {
+	if _, err := jw.buf.Write(line); err != nil {
+		return err
+	}
+	return jw.buf.WriteByte('\n')
+}
+
+func (jw *jsonlWriter) Close() error {
+	err := jw.buf.Flush()
+	if jw.gz != nil {
+		if gzErr := jw.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	if fileErr := jw.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
+
+type parquetWriter struct {
+	file *localFile
+	pw   *writer.ParquetWriter
+}
+
+// newParquetWriter creates a parquet dump at @path. Snappy compression is used if @compress is true.
+func newParquetWriter(path string, compress bool) (*parquetWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	lf := &localFile{file}
+	pw, err := writer.NewParquetWriter(lf, new(parquetTrade), 1)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	pw.CompressionType = parquet.CompressionCodec_UNCOMPRESSED
+	if compress {
+		pw.CompressionType = parquet.CompressionCodec_SNAPPY
+	}
+
+	header, err := json.Marshal(NewHeader())
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	value := string(header)
+	pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, &parquet.KeyValue{Key: headerMetadataKey, Value: &value})
+	return &parquetWriter{file: lf, pw: pw}, nil
+}
+
+func (pqw *parquetWriter) Write(t *dia.Trade) error {
+	return pqw.pw.Write(newParquetTrade(*t))
+}
+
+func (pqw *parquetWriter) Close() error {
+	err := pqw.pw.WriteStop()
+	if fileErr := pqw.file.Close(); err == nil {
+		err = fileErr
+	}
+	return err
+}
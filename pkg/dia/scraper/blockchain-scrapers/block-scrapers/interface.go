@@ -30,6 +30,6 @@ type BlockScraper struct {
 	errorLock *sync.RWMutex
 	error     error
 	closed    bool
-	relDB     models.RelDatastore
+	relDB     models.BlockchainStore
 	chanData  chan dia.BlockData
 }
@@ -23,6 +23,24 @@ var (
 	Exchanges    = make(map[string]dia.Exchange)
 	blockchains  map[string]dia.BlockChain
 	chainConfigs map[string]dia.ChainConfig
+
+	// SwapTradesOnExchange contains all exchanges whose trades are forwarded
+	// a second time with swapped quote and base token.
+	SwapTradesOnExchange = []string{
+		dia.CurveFIExchange,
+		dia.CurveFIExchangeFantom,
+		dia.CurveFIExchangeMoonbeam,
+		dia.CurveFIExchangePolygon,
+		dia.PlatypusExchange,
+		dia.WanswapExchange,
+		dia.OmniDexExchange,
+		dia.DiffusionExchange,
+		dia.SolarbeamExchange,
+		dia.AnyswapExchange,
+		dia.HermesExchange,
+		dia.HuckleberryExchange,
+		dia.NetswapExchange,
+	}
 )
 
 var evmID map[string]string
@@ -187,11 +187,12 @@ func NewBridgeSwapScraper(exchange dia.Exchange, scrape bool, relDB *models.RelD
 		shutdown:     make(chan nothing),
 		shutdownDone: make(chan nothing),
 		pairScrapers: make(map[string]*BridgeSwapPairScraper),
+		exchangeName: exchange.Name,
 		relDB:        relDB,
 	}
 
 	if scrape {
-		s.loop()
+		go s.loop()
 	}
 
 	return s
@@ -319,63 +320,18 @@ func (s *BridgeSwapScraper) checkTransactionOnChain(events chan types.Log) {
 				QuoteToken:     quoteToken,
 				Time:           time.Now(),
 				ForeignTradeID: msg.TxHash.Hex(),
-				// Source:         s.exchangeName,
-				VerifiedPair: true,
+				Source:         s.exchangeName,
+				VerifiedPair:   true,
 			}
 			log.Println("trade", t)
-			s.mapasset(*t)
-			// s.chanTrades <- t
+			// Bridged assets are mapped by the collector in assetmap mode.
+			s.chanTrades <- t
 
 		}
 	}
 
 }
 
-func (s *BridgeSwapScraper) mapasset(t dia.Trade) {
-	//check if quote token exists
-
-	quoteToken_id, err := s.relDB.GetAssetID(t.QuoteToken)
-	if err != nil {
-		log.Errorln("Error getting quotetoken asset id", err)
-	}
-	baseToken_id, err := s.relDB.GetAssetID(t.BaseToken)
-	if err != nil {
-		log.Errorln("Error getting basetoken asset id", err)
-	}
-
-	quote_group_id, err := s.relDB.GetAssetMap(quoteToken_id)
-	if err != nil {
-		log.Errorln("quotetoken not exists", quoteToken_id)
-	} else if quote_group_id != "" {
-		log.Errorln("InsertAssetMap1 ", quote_group_id, baseToken_id)
-		err := s.relDB.InsertAssetMap(quote_group_id, baseToken_id)
-		log.Errorln("err InsertAssetMap1", err)
-		return
-	}
-
-	base_group_id, err := s.relDB.GetAssetMap(baseToken_id)
-	if err != nil {
-		log.Errorln("base does not exists ")
-	} else if quote_group_id != "" {
-		log.Errorln("InsertAssetMap2 ", quote_group_id, baseToken_id)
-		err := s.relDB.InsertAssetMap(base_group_id, quoteToken_id)
-		log.Errorln("err InsertAssetMap2", err)
-
-		return
-	}
-	log.Errorln("InsertAssetMap3 ", quoteToken_id)
-
-	err = s.relDB.InsertNewAssetMap(quoteToken_id)
-	log.Errorln("err InsertAssetMap3", err)
-
-	gpid, err := s.relDB.GetAssetMap(quoteToken_id)
-	if err != nil {
-		log.Errorln("gpid generated err ", err)
-	}
-	s.relDB.InsertAssetMap(gpid, baseToken_id)
-	log.Infoln("quote_group_id, base_group_id", baseToken_id, gpid)
-}
-
 func GetDecimals(tokenAddress common.Address, chainid string) (decimals uint8, err error) {
 
 	var contract *uniswap.IERC20Caller
@@ -62,7 +62,7 @@ type CoinMarketCapScraper struct {
 	foreignScrapper ForeignScraper
 }
 
-func NewCoinMarketCapScraper(datastore models.Datastore) *CoinMarketCapScraper {
+func NewCoinMarketCapScraper(datastore models.ForeignQuotationStore) *CoinMarketCapScraper {
 
 	foreignScrapper := ForeignScraper{
 		shutdown:      make(chan nothing),
@@ -48,7 +48,7 @@ func init() {
 	}
 }
 
-func NewCoingeckoScraper(datastore models.Datastore, apiKey string, apiSecret string) *CoingeckoScraper {
+func NewCoingeckoScraper(datastore models.ForeignQuotationStore, apiKey string, apiSecret string) *CoingeckoScraper {
 
 	foreignScrapper := ForeignScraper{
 		shutdown:      make(chan nothing),
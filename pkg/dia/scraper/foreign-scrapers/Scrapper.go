@@ -25,7 +25,7 @@ type ForeignScraper struct {
 	// TODO: check after linting
 	//	tickerRate    *time.Ticker
 	//	tickerState   *time.Ticker
-	datastore     models.Datastore
+	datastore     models.ForeignQuotationStore
 	chanQuotation chan *models.ForeignQuotation
 }
 
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"context"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/graph-gophers/graphql-go"
+)
+
+type CandleResolver struct {
+	q dia.Candle
+}
+
+func (cr *CandleResolver) Symbol(ctx context.Context) (*string, error) {
+	return &cr.q.Asset.Symbol, nil
+}
+
+func (cr *CandleResolver) Address(ctx context.Context) (*string, error) {
+	return &cr.q.Asset.Address, nil
+}
+
+func (cr *CandleResolver) Blockchain(ctx context.Context) (*string, error) {
+	return &cr.q.Asset.Blockchain, nil
+}
+
+func (cr *CandleResolver) Exchange(ctx context.Context) (*string, error) {
+	return &cr.q.Exchange, nil
+}
+
+func (cr *CandleResolver) Resolution(ctx context.Context) (*string, error) {
+	return &cr.q.Resolution, nil
+}
+
+func (cr *CandleResolver) Time(ctx context.Context) (*graphql.Time, error) {
+	return &graphql.Time{Time: cr.q.Time}, nil
+}
+
+func (cr *CandleResolver) Open(ctx context.Context) (*float64, error) {
+	return &cr.q.Open, nil
+}
+
+func (cr *CandleResolver) High(ctx context.Context) (*float64, error) {
+	return &cr.q.High, nil
+}
+
+func (cr *CandleResolver) Low(ctx context.Context) (*float64, error) {
+	return &cr.q.Low, nil
+}
+
+func (cr *CandleResolver) Close(ctx context.Context) (*float64, error) {
+	return &cr.q.Close, nil
+}
+
+func (cr *CandleResolver) Volume(ctx context.Context) (*float64, error) {
+	return &cr.q.Volume, nil
+}
+
+func (cr *CandleResolver) VolumeUSD(ctx context.Context) (*float64, error) {
+	return &cr.q.VolumeUSD, nil
+}
+
+func (cr *CandleResolver) NumTrades(ctx context.Context) (*int32, error) {
+	numTrades := int32(cr.q.NumTrades)
+	return &numTrades, nil
+}
@@ -44,6 +44,48 @@ func (qr *FilterPointResolver) LastTrade(ctx context.Context) (*TradeResolver, e
 	return &TradeResolver{q: qr.q.LastTrade}, nil
 }
 
+// Quality returns nil for filter points without quality, such as EMA points.
+func (qr *FilterPointResolver) Quality(ctx context.Context) (*FilterPointQualityResolver, error) {
+	if qr.q.Quality == nil {
+		return nil, nil
+	}
+	return &FilterPointQualityResolver{q: *qr.q.Quality}, nil
+}
+
+type FilterPointQualityResolver struct {
+	q dia.FilterPointQuality
+}
+
+func (qr *FilterPointQualityResolver) NumTrades(ctx context.Context) (*int32, error) {
+	numTrades := int32(qr.q.NumTrades)
+	return &numTrades, nil
+}
+
+func (qr *FilterPointQualityResolver) NumExchanges(ctx context.Context) (*int32, error) {
+	numExchanges := int32(qr.q.NumExchanges)
+	return &numExchanges, nil
+}
+
+func (qr *FilterPointQualityResolver) VolumeUSD(ctx context.Context) (*float64, error) {
+	return &qr.q.VolumeUSD, nil
+}
+
+func (qr *FilterPointQualityResolver) StdDev(ctx context.Context) (*float64, error) {
+	return &qr.q.StdDev, nil
+}
+
+func (qr *FilterPointQualityResolver) IQR(ctx context.Context) (*float64, error) {
+	return &qr.q.IQR, nil
+}
+
+func (qr *FilterPointQualityResolver) OutlierFraction(ctx context.Context) (*float64, error) {
+	return &qr.q.OutlierFraction, nil
+}
+
+func (qr *FilterPointQualityResolver) SecondsSinceLastTrade(ctx context.Context) (*float64, error) {
+	return &qr.q.SecondsSinceLastTrade, nil
+}
+
 func (qr *TradeResolver) Price(ctx context.Context) (*float64, error) {
 	return &qr.q.Price, nil
 }
@@ -2,8 +2,10 @@ package resolver
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	filters "github.com/diadata-org/diadata/internal/pkg/filtersBlockService"
 	"github.com/diadata-org/diadata/pkg/dia"
 	queryhelper "github.com/diadata-org/diadata/pkg/dia/helpers/queryHelper"
 	"github.com/diadata-org/diadata/pkg/utils"
@@ -17,7 +19,7 @@ var log = logrus.New()
 
 // Resolver is the root resolver
 type DiaResolver struct {
-	DS              models.DB
+	DS              models.Datastore
 	RelDB           models.RelDB
 	InfluxBatchSize int64
 }
@@ -316,6 +318,28 @@ func (r *DiaResolver) GetChartMeta(ctx context.Context, args struct {
 		{
 			filterPoints, filterMetadata = queryhelper.FilterVOL(tradeBlocks, asset, int(blockSizeSeconds))
 		}
+	case "twap":
+		{
+			filterPoints, filterMetadata = queryhelper.FilterTWAP(tradeBlocks, asset, int(blockSizeSeconds))
+		}
+	case "rvol":
+		{
+			filterPoints, filterMetadata = queryhelper.FilterRVOL(tradeBlocks, asset, int(blockSizeSeconds))
+		}
+	case "pvol":
+		{
+			filterPoints, filterMetadata = queryhelper.FilterPVOL(tradeBlocks, asset, int(blockSizeSeconds))
+		}
+	case "rvar":
+		{
+			filterPoints, filterMetadata = queryhelper.FilterRVAR(tradeBlocks, asset, int(blockSizeSeconds))
+		}
+	case "lwvwap":
+		{
+			// Trades are weighted by the liquidity their pools had at the time of the trade.
+			liquidity := filters.NewPoolLiquidity(&r.RelDB, r.DS)
+			filterPoints, filterMetadata = queryhelper.FilterLWVWAP(tradeBlocks, asset, int(blockSizeSeconds), liquidity)
+		}
 
 	}
 
@@ -330,6 +354,49 @@ func (r *DiaResolver) GetChartMeta(ctx context.Context, args struct {
 	return &FilterPointMetaResolver{fpr: &fpr, min: filterMetadata.Min, max: filterMetadata.Max}, nil
 }
 
+// maxCandles is the maximal number of candles returned by GetCandles.
+const maxCandles = 1440
+
+// GetCandles returns the OHLCV candles of an asset on an exchange, or on all exchanges if no exchange
+// is given, beginning in the given time-range.
+func (r *DiaResolver) GetCandles(ctx context.Context, args struct {
+	Address    graphql.NullString
+	BlockChain graphql.NullString
+	Resolution graphql.NullString
+	StartTime  graphql.NullTime
+	EndTime    graphql.NullTime
+	Exchange   graphql.NullString
+}) (*[]*CandleResolver, error) {
+	candleDuration, ok := dia.CandleResolution(*args.Resolution.Value)
+	if !ok {
+		return nil, fmt.Errorf("unknown resolution %s. resolutions: %v", *args.Resolution.Value, dia.CandleResolutions)
+	}
+	starttime := args.StartTime.Value.Time
+	endtime := args.EndTime.Value.Time
+	if !endtime.After(starttime) || endtime.Sub(starttime) > maxCandles*candleDuration {
+		return nil, fmt.Errorf("time-range must be positive and span at most %d candles", maxCandles)
+	}
+	var exchange string
+	if args.Exchange.Value != nil {
+		exchange = *args.Exchange.Value
+	}
+
+	asset, err := r.RelDB.GetAsset(*args.Address.Value, *args.BlockChain.Value)
+	if err != nil {
+		return nil, err
+	}
+	candles, err := r.DS.GetCandles(asset, exchange, *args.Resolution.Value, starttime, endtime)
+	if err != nil {
+		return nil, err
+	}
+
+	cr := []*CandleResolver{}
+	for _, candle := range candles {
+		cr = append(cr, &CandleResolver{q: candle})
+	}
+	return &cr, nil
+}
+
 func (r *DiaResolver) GetVWALP(ctx context.Context, args struct {
 	Quotetokenblockchain graphql.NullString
 	Quotetokenaddress    graphql.NullString
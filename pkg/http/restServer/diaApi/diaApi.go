@@ -31,24 +31,24 @@ var (
 	BLOCKCHAINS    = make(map[string]dia.BlockChain)
 )
 
+// maxInputLength is the maximal length of path and query parameters.
+const maxInputLength = 256
+
 type Env struct {
 	DataStore models.Datastore
 	RelDB     models.RelDB
 }
 
-func init() {
-	relDB, err := models.NewRelDataStore()
-	if err != nil {
-		log.Errorln("Error connecting to asset DB: ", err)
-		return
-	}
+// LoadBlockchains fills BLOCKCHAINS with all blockchains from @relDB. It must be called before serving requests.
+func LoadBlockchains(relDB *models.RelDB) error {
 	chains, err := relDB.GetAllBlockchains(false)
 	if err != nil {
-		log.Fatal("get all chains: ", err)
+		return err
 	}
 	for _, chain := range chains {
 		BLOCKCHAINS[chain.Name] = chain
 	}
+	return nil
 }
 
 // PostSupply deprecated? TO DO
@@ -66,6 +66,8 @@ func (env *Env) PostSupply(c *gin.Context) {
 			if t.Asset.Symbol == "" || t.CirculatingSupply == 0.0 {
 				log.Errorln("received supply:", t)
 				restApi.SendError(c, http.StatusInternalServerError, errors.New("missing symbol or circulating supply value"))
+			} else if !validInput("blockchain", t.Asset.Blockchain) || !validInput("address", t.Asset.Address) || !validInput("source", t.Source) {
+				restApi.SendError(c, http.StatusBadRequest, errors.New("invalid input params"))
 			} else {
 				log.Println("received supply:", t)
 				source := dia.Diadata
@@ -111,6 +113,10 @@ func (env *Env) SetQuotation(c *gin.Context) {
 		return
 	}
 
+	if !utils.ValidBlockchain(input[0]) || !utils.ValidAddress(input[1]) {
+		restApi.SendError(c, http.StatusBadRequest, errors.New("invalid input params"))
+		return
+	}
 	quotation.Asset.Blockchain = input[0]
 	quotation.Asset.Address = input[1]
 	price, err := strconv.ParseFloat(input[2], 64)
@@ -651,6 +657,7 @@ func (env *Env) GetNFTExchanges(c *gin.Context) {
 }
 
 // GetAssetChartPoints queries for filter points of asset given by address and blockchain.
+// If the query parameter quality is true, the quality of each filter point is returned as well.
 func (env *Env) GetAssetChartPoints(c *gin.Context) {
 	if !validateInputParams(c) {
 		return
@@ -707,7 +714,13 @@ func (env *Env) GetAssetChartPoints(c *gin.Context) {
 		return
 	}
 
-	p, err := env.DataStore.GetFilterPointsAsset(filter, exchange, address, blockchain, starttime, endtime)
+	withQuality, err := strconv.ParseBool(c.DefaultQuery("quality", "false"))
+	if err != nil {
+		restApi.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := env.DataStore.GetFilterPointsAsset(filter, exchange, address, blockchain, starttime, endtime, withQuality)
 	if err != nil {
 		restApi.SendError(c, http.StatusInternalServerError, err)
 	} else {
@@ -717,6 +730,7 @@ func (env *Env) GetAssetChartPoints(c *gin.Context) {
 
 // GetChartPoints godoc
 // @Param   scale      query   string     false       "scale 5m 30m 1h 4h 1d 1w"
+// @Param   quality    query   bool       false       "include the quality of filter points, not available with scale"
 func (env *Env) GetChartPoints(c *gin.Context) {
 	if !validateInputParams(c) {
 		return
@@ -772,7 +786,13 @@ func (env *Env) GetChartPoints(c *gin.Context) {
 		return
 	}
 
-	p, err := env.DataStore.GetFilterPoints(filter, exchange, symbol, scale, starttime, endtime)
+	withQuality, err := strconv.ParseBool(c.DefaultQuery("quality", "false"))
+	if err != nil {
+		restApi.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := env.DataStore.GetFilterPoints(filter, exchange, symbol, scale, starttime, endtime, withQuality)
 	if err != nil {
 		restApi.SendError(c, http.StatusInternalServerError, err)
 	} else {
@@ -782,6 +802,7 @@ func (env *Env) GetChartPoints(c *gin.Context) {
 
 // GetChartPointsAllExchanges godoc
 // @Param   scale      query   string     false       "scale 5m 30m 1h 4h 1d 1w"
+// @Param   quality    query   bool       false       "include the quality of filter points, not available with scale"
 func (env *Env) GetChartPointsAllExchanges(c *gin.Context) {
 	if !validateInputParams(c) {
 		return
@@ -836,7 +857,13 @@ func (env *Env) GetChartPointsAllExchanges(c *gin.Context) {
 		return
 	}
 
-	p, err := env.DataStore.GetFilterPoints(filter, "", symbol, scale, starttime, endtime)
+	withQuality, err := strconv.ParseBool(c.DefaultQuery("quality", "false"))
+	if err != nil {
+		restApi.SendError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := env.DataStore.GetFilterPoints(filter, "", symbol, scale, starttime, endtime, withQuality)
 	if err != nil {
 		restApi.SendError(c, http.StatusInternalServerError, err)
 	} else {
@@ -1241,6 +1268,10 @@ func (env *Env) GetQuotedAssets(c *gin.Context) {
 // the interest rate with symbol @symbol at the date @time.
 // Optional query parameters allow to obtain data in a time range.
 func (env *Env) GetInterestRate(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
+
 	symbol := c.Param("symbol")
 	date := c.Param("time")
 	// Add optional query parameters for requesting a range of values
@@ -1274,6 +1305,9 @@ func (env *Env) GetInterestRate(c *gin.Context) {
 
 // GetCompoundedRate is the delegate method to fetch compounded rate values for interest rates
 func (env *Env) GetCompoundedRate(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
 
 	// Import and cast input from API call
 	symbol := c.Param("symbol")
@@ -1342,6 +1376,9 @@ func (env *Env) GetCompoundedRate(c *gin.Context) {
 
 // GetCompoundedAvg is the delegate method to fetch averaged compounded rate values for interest rates
 func (env *Env) GetCompoundedAvg(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
 
 	tInit := time.Now()
 
@@ -1410,6 +1447,9 @@ func (env *Env) GetCompoundedAvg(c *gin.Context) {
 
 // GetCompoundedAvgDIA is the delegate method to fetch averaged compounded rate values for interest rates
 func (env *Env) GetCompoundedAvgDIA(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
 
 	tInit := time.Now()
 
@@ -1544,6 +1584,10 @@ func (env *Env) GetStockSymbols(c *gin.Context) {
 // quotations of asset with @symbol from @source.
 // Last value is retrieved. Otional query parameters allow to obtain data in a time range.
 func (env *Env) GetStockQuotation(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
+
 	source := c.Param("source")
 	symbol := c.Param("symbol")
 	date := c.Param("time")
@@ -2123,6 +2167,9 @@ func (env *Env) GetNFTFloor(c *gin.Context) {
 
 // GetNFTFloorMA returns the moving average floor price of the nft class over the last 30 days.
 func (env *Env) GetNFTFloorMA(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
 
 	// NFT collection.
 	blockchain := c.Param("blockchain")
@@ -2535,6 +2582,9 @@ func (env *Env) GetTopNFTClasses(c *gin.Context) {
 }
 
 func (env *Env) GetNFTVolume(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
 
 	type localReturn struct {
 		Collection   string
@@ -2763,6 +2813,7 @@ func (env *Env) GetFeedStats(c *gin.Context) {
 		TradesDistribution localDistType
 		ExchangeVolumes    []dia.ExchangeVolume
 		PairVolumes        []dia.PairVolume
+		ExcludedTrades     []dia.TradeRejection
 	}
 
 	var retVal []localReturn
@@ -2789,6 +2840,11 @@ func (env *Env) GetFeedStats(c *gin.Context) {
 		if len(tradesDistReduced) > i {
 			l.TradesDistribution = tradesDistReduced[i]
 		}
+		// Trades excluded by the sanity rules in the time range of the volumes.
+		l.ExcludedTrades, err = env.RelDB.GetTradeRejections(asset, l.Timestamp.Add(-time.Duration(models.WindowVolume)*time.Second), l.Timestamp)
+		if err != nil {
+			log.New().Errorf("get trade rejections for asset %v: %v", asset, err)
+		}
 		retVal = append(retVal, l)
 	}
 
@@ -2897,6 +2953,86 @@ func (env *Env) GetAssetUpdates(c *gin.Context) {
 	c.JSON(http.StatusOK, lrt)
 }
 
+// GetExchangeExclusions returns the exclusions of exchanges from the aggregated price of an asset
+// which began in the given time-range.
+func (env *Env) GetExchangeExclusions(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
+
+	blockchain := c.Param("blockchain")
+	address := makeAddressEIP55Compliant(c.Param("address"), blockchain)
+
+	starttime, endtime, err := utils.MakeTimerange(c.Query("starttime"), c.Query("endtime"), time.Duration(24*60)*time.Minute)
+	if err != nil {
+		restApi.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if ok, err := validTimeRange(starttime, endtime, time.Duration(30*24*time.Hour)); !ok {
+		restApi.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	asset, err := env.RelDB.GetAsset(address, blockchain)
+	if err != nil {
+		restApi.SendError(c, http.StatusNotFound, err)
+		return
+	}
+
+	exclusions, err := env.RelDB.GetExchangeExclusions(asset, starttime, endtime)
+	if err != nil {
+		restApi.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, exclusions)
+}
+
+// maxCandles is the maximal number of candles per resolution and exchange returned by GetCandles.
+const maxCandles = 1440
+
+// GetCandles returns the OHLCV candles of an asset with the resolution given by the query parameter
+// resolution, which defaults to 1m. Candles are computed from the trades on the exchange given by the
+// query parameter exchange, or from the trades on all exchanges if it is empty. If no time-range is
+// given, the latest 100 candles are returned.
+func (env *Env) GetCandles(c *gin.Context) {
+	if !validateInputParams(c) {
+		return
+	}
+
+	blockchain := c.Param("blockchain")
+	address := makeAddressEIP55Compliant(c.Param("address"), blockchain)
+	exchange := c.Query("exchange")
+	resolution := c.DefaultQuery("resolution", dia.CandleResolutions[0])
+	candleDuration, ok := dia.CandleResolution(resolution)
+	if !ok {
+		restApi.SendError(c, http.StatusBadRequest, fmt.Errorf("unknown resolution %s. resolutions: %v", resolution, dia.CandleResolutions))
+		return
+	}
+
+	starttime, endtime, err := utils.MakeTimerange(c.Query("starttime"), c.Query("endtime"), 100*candleDuration)
+	if err != nil {
+		restApi.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if ok, err := validTimeRange(starttime, endtime, maxCandles*candleDuration); !ok {
+		restApi.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	asset, err := env.RelDB.GetAsset(address, blockchain)
+	if err != nil {
+		restApi.SendError(c, http.StatusNotFound, err)
+		return
+	}
+
+	candles, err := env.DataStore.GetCandles(asset, exchange, resolution, starttime, endtime)
+	if err != nil {
+		restApi.SendError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, candles)
+}
+
 // GetAssetInfo returns quotation of asset with highest market cap among
 // all assets with symbol ticker @symbol. Additionally information on exchanges and volumes.
 func (env *Env) GetAssetInfo(c *gin.Context) {
@@ -3116,21 +3252,31 @@ func validTimeRange(starttime time.Time, endtime time.Time, maxDuration time.Dur
 	return
 }
 
+// identifierValidators maps names of path and query parameters to the validation of their values.
+var identifierValidators = map[string]func(string) bool{
+	"blockchain": utils.ValidBlockchain,
+	"Network":    utils.ValidBlockchain,
+	"address":    utils.ValidAddress,
+	"exchange":   utils.ValidExchange,
+}
+
+// validateInputParams checks all path and query parameters of the request and responds
+// with StatusBadRequest if any of them is invalid.
 func validateInputParams(c *gin.Context) bool {
 
 	// Validate input parameters.
 	for _, input := range c.Params {
-		if containsSpecialChars(input.Value) {
-			restApi.SendError(c, http.StatusInternalServerError, errors.New("invalid input params"))
+		if !validInput(input.Key, input.Value) {
+			restApi.SendError(c, http.StatusBadRequest, errors.New("invalid input params"))
 			return false
 		}
 	}
 
 	// Validate query parameters.
-	for _, value := range c.Request.URL.Query() {
+	for key, value := range c.Request.URL.Query() {
 		for _, input := range value {
-			if containsSpecialChars(input) {
-				restApi.SendError(c, http.StatusInternalServerError, errors.New("invalid input params"))
+			if !validInput(key, input) {
+				restApi.SendError(c, http.StatusBadRequest, errors.New("invalid input params"))
 				return false
 			}
 		}
@@ -3139,8 +3285,20 @@ func validateInputParams(c *gin.Context) bool {
 	return true
 }
 
+// validInput returns true if @value is a valid value for parameter @key. Empty values are valid, as they
+// denote omitted optional parameters.
+func validInput(key string, value string) bool {
+	if containsSpecialChars(value) || len(value) > maxInputLength {
+		return false
+	}
+	if validator, ok := identifierValidators[key]; ok && value != "" {
+		return validator(value)
+	}
+	return true
+}
+
 func containsSpecialChars(s string) bool {
-	return strings.ContainsAny(s, "!@#$%^&*()'\"|{}[];><?/`~,")
+	return strings.ContainsAny(s, "!@#$%^&*()'\"|{}[];><?/`~,\\\n\r\x00")
 }
 
 // Returns the EIP55 compliant address in case @blockchain has an Ethereum ChainID.
@@ -0,0 +1,218 @@
+package diaApi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	models "github.com/diadata-org/diadata/pkg/model"
+	"github.com/gin-gonic/gin"
+)
+
+// hostileInputs are fed into every path and query parameter. They must be rejected before reaching a store.
+var hostileInputs = []string{
+	"' OR 1=1 --",
+	"Ethereum'; DROP TABLE asset;--",
+	`0x0" OR "1"="1`,
+	"Binance/ OR exchange=~/.*/",
+	"0x0\nSELECT * FROM trades",
+	`0x0\`,
+	"$p1",
+	"0x0) OR (1=1",
+	strings.Repeat("0", maxInputLength+1),
+}
+
+// hostileIdentifiers are rejected for blockchain, address and exchange parameters only.
+var hostileIdentifiers = []string{
+	"0x0 OR 1=1",
+	"-Ethereum",
+	".*",
+}
+
+var benignInputs = map[string]string{
+	"blockchain": "Ethereum",
+	"address":    "0x0000000000000000000000000000000000000000",
+	"exchange":   "Binance",
+	"time":       "2021-01-01",
+}
+
+type testRoute struct {
+	path    string
+	handler gin.HandlerFunc
+	query   []string
+}
+
+func testRoutes(env *Env) []testRoute {
+	return []testRoute{
+		{"/quotation/:symbol", env.GetQuotation, nil},
+		{"/assetQuotation/:blockchain/:address", env.GetAssetQuotation, nil},
+		{"/lastTrades/:symbol", env.GetLastTrades, nil},
+		{"/lastTradesAsset/:blockchain/:address", env.GetLastTradesAsset, []string{"numTrades", "exchange"}},
+		{"/chartPoints/:filter/:exchange/:symbol", env.GetChartPoints, []string{"scale", "starttime", "endtime", "quality"}},
+		{"/assetChartPoints/:filter/:blockchain/:address", env.GetAssetChartPoints, []string{"exchange", "starttime", "endtime", "quality"}},
+		{"/chartPointsAllExchanges/:filter/:symbol", env.GetChartPointsAllExchanges, []string{"scale", "starttime", "endtime", "quality"}},
+		{"/supply/:symbol", env.GetSupply, nil},
+		{"/assetSupply/:blockchain/:address", env.GetAssetSupply, []string{"starttime", "endtime"}},
+		{"/supplies/:symbol", env.GetSupplies, []string{"starttime", "endtime"}},
+		{"/topAssets/:numAssets", env.GetTopAssets, []string{"Page", "Cex", "Network"}},
+		{"/symbols/:substring", env.GetAllSymbols, []string{"exchange", "top"}},
+		{"/quotedAssets", env.GetQuotedAssets, []string{"blockchain"}},
+		{"/poolLiquidity/:blockchain/:address", env.GetPoolLiquidityByAddress, nil},
+		{"/pairsCex/:exchange", env.GetExchangePairs, []string{"verified"}},
+		{"/pairsAssetCex/:blockchain/:address", env.GetAssetPairs, []string{"verified"}},
+		{"/volume/:symbol", env.GetVolume, []string{"starttime", "endtime"}},
+		{"/volume24/:exchange", env.Get24hVolume, nil},
+		{"/feedStats/:blockchain/:address", env.GetFeedStats, []string{"starttime", "endtime"}},
+		{"/search/:query", env.SearchAsset, nil},
+		{"/searchnft/:query", env.SearchNFTs, nil},
+		{"/assetInfo/:blockchain/:address", env.GetAssetInfo, nil},
+		{"/token/:symbol", env.GetAsset, nil},
+		{"/missingToken/:exchange", env.GetMissingExchangeSymbol, nil},
+		{"/tokenexchanges/:symbol", env.GetAssetExchanges, nil},
+		{"/interestrate/:symbol/:time", env.GetInterestRate, []string{"dateInit", "dateFinal"}},
+		{"/compoundedRate/:symbol/:dpy/:time", env.GetCompoundedRate, []string{"dateInit", "dateFinal"}},
+		{"/compoundedAvg/:symbol/:days/:dpy/:time", env.GetCompoundedAvg, []string{"dateInit", "dateFinal"}},
+		{"/compoundedAvgDIA/:symbol/:days/:dpy/:time", env.GetCompoundedAvgDIA, []string{"dateInit", "dateFinal"}},
+		{"/stockQuotation/:source/:symbol/:time", env.GetStockQuotation, []string{"dateInit", "dateFinal"}},
+		{"/foreignQuotation/:source/:symbol/:time", env.GetForeignQuotation, []string{"time"}},
+		{"/foreignSymbols/:source", env.GetForeignSymbols, nil},
+		{"/custom/vwapFirefly/:ticker", env.GetVwapFirefly, []string{"starttime", "endtime"}},
+		{"/benchmarkedIndexValue/:symbol", env.GetBenchmarkedIndexValue, []string{"starttime", "endtime"}},
+		{"/AllNFTClasses/:blockchain", env.GetAllNFTClasses, nil},
+		{"/NFTClasses/:limit/:offset", env.GetNFTClasses, nil},
+		{"/NFT/:blockchain/:address/:id", env.GetNFT, nil},
+		{"/NFTTrades/:blockchain/:address/:id", env.GetNFTTrades, []string{"starttime", "endtime"}},
+		{"/NFTTradesCollection/:blockchain/:address", env.GetNFTTradesCollection, []string{"starttime", "endtime"}},
+		{"/NFTFloor/:blockchain/:address", env.GetNFTFloor, []string{"timestamp", "floorWindow", "bundles"}},
+		{"/NFTFloorMA/:blockchain/:address", env.GetNFTFloorMA, []string{"lookbackSeconds", "floorWindow", "bundles"}},
+		{"/NFTDownday/:blockchain/:address", env.GetNFTDownday, []string{"lookbackSeconds", "floorWindow", "bundles"}},
+		{"/NFTVolatility/:blockchain/:address", env.GetNFTFloorVola, []string{"time", "lookbackSeconds", "floorWindow", "bundles"}},
+		{"/topNFT/:numCollections", env.GetTopNFTClasses, []string{"page", "starttime", "endtime", "exchanges", "bundles"}},
+		{"/NFTVolume/:blockchain/:address", env.GetNFTVolume, []string{"starttime", "endtime", "bundles"}},
+		{"/assetmap/:blockchain/:address", env.GetAssetMap, nil},
+		{"/assetUpdates/:blockchain/:address/:deviation/:frequencySeconds", env.GetAssetUpdates, []string{"starttime", "endtime"}},
+		{"/exchangeExclusions/:blockchain/:address", env.GetExchangeExclusions, []string{"starttime", "endtime"}},
+		{"/candles/:blockchain/:address", env.GetCandles, []string{"exchange", "resolution", "starttime", "endtime"}},
+		{"/synthasset/:blockchain/:protocol", env.GetSyntheticAsset, []string{"address", "starttime", "endtime"}},
+	}
+}
+
+// newTestRouter returns a router serving @routes. The environment has no stores, so that a handler passing
+// hostile input on to a store panics and the recovery responds with StatusInternalServerError.
+func newTestRouter(env *Env, routes []testRoute) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.UseRawPath = true
+	r.POST("/quotation", env.SetQuotation)
+	r.POST("/supply", env.PostSupply)
+	for _, route := range routes {
+		r.GET(route.path, route.handler)
+	}
+	return r
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// requestPath returns @path with each parameter replaced by its value in @values or a benign value.
+func requestPath(path string, values map[string]string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		value, ok := values[segment[1:]]
+		if !ok {
+			value, ok = benignInputs[segment[1:]]
+		}
+		if !ok {
+			value = "1"
+		}
+		segments[i] = url.PathEscape(value)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hostileInputsFor(param string) []string {
+	if _, ok := identifierValidators[param]; ok {
+		return append(append([]string{}, hostileInputs...), hostileIdentifiers...)
+	}
+	return hostileInputs
+}
+
+func expectBadRequest(t *testing.T, r *gin.Engine, req *http.Request) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("%s %s: got status %d, want %d", req.Method, req.URL, w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHostilePathParams(t *testing.T) {
+	env := &Env{}
+	routes := testRoutes(env)
+	r := newTestRouter(env, routes)
+	for _, route := range routes {
+		for _, param := range pathParams(route.path) {
+			for _, input := range hostileInputsFor(param) {
+				target := requestPath(route.path, map[string]string{param: input})
+				expectBadRequest(t, r, httptest.NewRequest(http.MethodGet, target, nil))
+			}
+		}
+	}
+}
+
+func TestHostileQueryParams(t *testing.T) {
+	env := &Env{}
+	routes := testRoutes(env)
+	r := newTestRouter(env, routes)
+	for _, route := range routes {
+		for _, key := range route.query {
+			for _, input := range hostileInputsFor(key) {
+				target := requestPath(route.path, nil) + "?" + url.Values{key: {input}}.Encode()
+				expectBadRequest(t, r, httptest.NewRequest(http.MethodGet, target, nil))
+			}
+		}
+	}
+}
+
+func TestHostileBody(t *testing.T) {
+	env := &Env{DataStore: models.NewMemoryDataStore()}
+	r := newTestRouter(env, nil)
+	bodies := map[string][]string{
+		"/quotation": {
+			`["Ethereum' OR 1=1 --","0x0","1"]`,
+			`["Ethereum","0x0\"; DROP TABLE asset;--","1"]`,
+		},
+		"/supply": {
+			`{"Asset":{"Symbol":"DIA","Blockchain":"Ethereum'--","Address":"0x0"},"CirculatingSupply":1}`,
+			`{"Asset":{"Symbol":"DIA","Blockchain":"Ethereum","Address":"0x0 OR 1=1"},"CirculatingSupply":1}`,
+		},
+	}
+	for target, inputs := range bodies {
+		for _, body := range inputs {
+			expectBadRequest(t, r, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+		}
+	}
+}
+
+func TestValidInput(t *testing.T) {
+	for key, value := range benignInputs {
+		if !validInput(key, value) {
+			t.Errorf("valid %s %s was rejected", key, value)
+		}
+	}
+	if !validInput("exchange", "") {
+		t.Error("omitted exchange was rejected")
+	}
+}
@@ -1,8 +1,11 @@
 package kafkaApi
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
 	"github.com/gin-gonic/gin"
@@ -10,13 +13,19 @@ import (
 )
 
 var (
-	Apis = map[int]*RestApi{
-		kafkaHelper.TopicFiltersBlock: NewRestApi(kafkaHelper.TopicFiltersBlock),
-		kafkaHelper.TopicTrades:       NewRestApi(kafkaHelper.TopicTrades),
-		kafkaHelper.TopicTradesBlock:  NewRestApi(kafkaHelper.TopicTradesBlock),
-	}
+	// Apis holds the apis of all served topics. It is filled by InitApis.
+	Apis = map[int]*RestApi{}
 )
 
+const readTimeout = 10 * time.Second
+
+// InitApis sets up the apis of all served topics reading from @bus.
+func InitApis(bus kafkaHelper.MessageBus) {
+	for _, topic := range []int{kafkaHelper.TopicFiltersBlock, kafkaHelper.TopicTrades, kafkaHelper.TopicTradesBlock} {
+		Apis[topic] = NewRestApi(bus, topic)
+	}
+}
+
 // @hello
 // returns some kafka messages
 type resultApi struct {
@@ -25,8 +34,8 @@ type resultApi struct {
 }
 
 type RestApi struct {
+	bus   kafkaHelper.MessageBus
 	topic int
-	// writer *kafka.Writer //TOFIX reorganise the api
 }
 
 func SendError(c *gin.Context, errorCode int, err error) {
@@ -49,7 +58,12 @@ func getOffset(c *gin.Context) int64 {
 
 func Process(c *gin.Context, topic int) {
 	elements, _ := strconv.Atoi(c.Query("elements"))
-	result, err := Apis[topic].Get(getOffset(c), elements)
+	api, ok := Apis[topic]
+	if !ok {
+		SendError(c, http.StatusInternalServerError, errors.New("message bus not initialized"))
+		return
+	}
+	result, err := api.Get(getOffset(c), elements)
 	if err == nil {
 		c.JSON(http.StatusOK, result)
 	} else {
@@ -76,13 +90,16 @@ func (s *RestApi) Get(offset int64, elements int) (map[string]interface{}, error
 
 	result := &resultApi{}
 
-	maxOffset, err := kafkaHelper.ReadOffset(s.topic)
+	maxOffset, err := s.bus.LastOffset(s.topic)
 
 	if err != nil {
 		return nil, err
 	}
 
 	maxOffset--
+	if maxOffset < 0 {
+		return nil, errors.New("no messages on topic")
+	}
 
 	if offset > maxOffset {
 		offset = maxOffset
@@ -98,7 +115,7 @@ func (s *RestApi) Get(offset int64, elements int) (map[string]interface{}, error
 	}
 	log.Printf("Get: maxOffset %v offset:%v nbElements:%v ", maxOffset, offset, nbElements)
 
-	element, err := kafkaHelper.GetElements(s.topic, offset, nbElements)
+	element, err := s.getElements(offset, nbElements)
 
 	if err != nil {
 		return nil, err
@@ -113,8 +130,38 @@ func (s *RestApi) Get(offset int64, elements int) (map[string]interface{}, error
 
 }
 
-func NewRestApi(topic int) *RestApi {
+// getElements returns @nbElements messages of the api's topic starting at @offset.
+func (s *RestApi) getElements(offset int64, nbElements int) ([]interface{}, error) {
+	var result []interface{}
+	sub, err := s.bus.Subscribe(s.topic, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := sub.Close(); err != nil {
+			log.Error("close subscription: ", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), readTimeout)
+	defer cancel()
+	for len(result) < nbElements {
+		m, err := sub.ReadMessage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		e, err := kafkaHelper.UnmarshalElement(s.topic, m.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func NewRestApi(bus kafkaHelper.MessageBus, topic int) *RestApi {
 	s := &RestApi{
+		bus:   bus,
 		topic: topic,
 	}
 	return s
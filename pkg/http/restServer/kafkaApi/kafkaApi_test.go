@@ -0,0 +1,45 @@
+package kafkaApi
+
+import (
+	"context"
+	"testing"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/kafkaHelper"
+)
+
+func TestGet(t *testing.T) {
+	bus := kafkaHelper.NewMemoryBus(0)
+	for _, symbol := range []string{"BTC", "ETH", "DIA"} {
+		if err := bus.Publish(context.Background(), kafkaHelper.TopicTrades, &dia.Trade{Symbol: symbol}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	api := NewRestApi(bus, kafkaHelper.TopicTrades)
+
+	r, err := api.Get(1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := r["Result"].(*resultApi)
+	messages := result.Messages[0].([]interface{})
+	if result.Offset != 1 || len(messages) != 2 {
+		t.Fatalf("expected 2 messages from offset 1, got %d from offset %d", len(messages), result.Offset)
+	}
+	if trade := messages[1].(dia.Trade); trade.Symbol != "DIA" {
+		t.Errorf("expected DIA, got %s", trade.Symbol)
+	}
+
+	// Without offset, the latest message is returned.
+	r, err = api.Get(-1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := r["Result"].(*resultApi); result.Offset != 2 {
+		t.Errorf("expected offset 2, got %d", result.Offset)
+	}
+
+	if _, err := NewRestApi(bus, kafkaHelper.TopicTradesBlock).Get(-1, 0); err == nil {
+		t.Error("expected error on empty topic")
+	}
+}
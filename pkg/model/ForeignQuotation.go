@@ -43,9 +43,10 @@ func (datastore *DB) GetForeignQuotationInflux(symbol, source string, timestamp
 	retval := ForeignQuotation{}
 
 	unixtime := timestamp.UnixNano()
-	q := fmt.Sprintf("SELECT price,priceYesterday,volumeYesterdayUSD,\"name\" FROM %s WHERE source='%s' and \"symbol\"='%s' and time<%d order by time desc limit 1", influxDbForeignQuotationTable, source, symbol, unixtime)
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT price,priceYesterday,volumeYesterdayUSD,\"name\" FROM %s WHERE source=%s and \"symbol\"=%s and time<%d order by time desc limit 1", influxDbForeignQuotationTable, params.bind(source), params.bind(symbol), unixtime)
 	fmt.Println("query: ", q)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		fmt.Println("Error querying influx")
 		return retval, err
@@ -94,8 +95,9 @@ func (datastore *DB) GetForeignPriceYesterday(symbol, source string) (float64, e
 	unixtimeInit := strconv.Itoa(timeInit) + "000000000"
 
 	// Make corresponding influx query
-	q := fmt.Sprintf("SELECT price FROM %s WHERE source='%s' and symbol='%s' and time>%s and time<%s", influxDbForeignQuotationTable, source, symbol, unixtimeInit, unixtimeFinal)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT price FROM %s WHERE source=%s and symbol=%s and time>%s and time<%s", influxDbForeignQuotationTable, params.bind(source), params.bind(symbol), unixtimeInit, unixtimeFinal)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		fmt.Println("Error querying influx")
 		return 0, err
@@ -126,8 +128,9 @@ func (datastore *DB) GetForeignPriceYesterday(symbol, source string) (float64, e
 // GetForeignSymbolsInflux returns a list with all symbols available for quotation from @source.
 func (datastore *DB) GetForeignSymbolsInflux(source string) (symbols []string, err error) {
 
-	q := fmt.Sprintf("SELECT symbol,source FROM %s WHERE time>now()-7d and source='%s'", influxDbForeignQuotationTable, source)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT symbol,source FROM %s WHERE time>now()-7d and source=%s", influxDbForeignQuotationTable, params.bind(source))
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		fmt.Println("Error querying influx")
 		return
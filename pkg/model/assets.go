@@ -189,6 +189,7 @@ func (rdb *RelDB) GetAssetsBySymbolName(symbol, name string) (assets []dia.Asset
 	var decimals string
 	var rows pgx.Rows
 	var query string
+	var args sqlArgs
 	if name == "" {
 		query = fmt.Sprintf(`
 		SELECT symbol,name,address,decimals,blockchain 
@@ -197,11 +198,11 @@ func (rdb *RelDB) GetAssetsBySymbolName(symbol, name string) (assets []dia.Asset
 		ON av.asset_id=a.asset_id
 		WHERE av.volume>0
 		AND av.time_stamp IS NOT NULL
-		AND symbol ILIKE '%s%%'
+		AND symbol ILIKE %s
 		ORDER BY av.volume DESC`,
 			assetTable,
 			assetVolumeTable,
-			symbol,
+			args.bind(sqlLikePrefix(symbol)),
 		)
 	} else if symbol == "" {
 		query = fmt.Sprintf(`
@@ -211,11 +212,11 @@ func (rdb *RelDB) GetAssetsBySymbolName(symbol, name string) (assets []dia.Asset
 		ON av.asset_id=a.asset_id
 		WHERE av.volume>0
 		AND av.time_stamp IS NOT NULL
-		AND name ILIKE '%s%%'
+		AND name ILIKE %s
 		ORDER BY av.volume DESC`,
 			assetTable,
 			assetVolumeTable,
-			symbol,
+			args.bind(sqlLikePrefix(name)),
 		)
 	} else {
 		query = fmt.Sprintf(`
@@ -225,18 +226,18 @@ func (rdb *RelDB) GetAssetsBySymbolName(symbol, name string) (assets []dia.Asset
 		ON av.asset_id=a.asset_id 
 		WHERE av.volume>0
 		AND av.time_stamp IS NOT NULL
-		AND (symbol ILIKE '%s%%' OR name ILIKE '%s%%')
+		AND (symbol ILIKE %s OR name ILIKE %s)
 		ORDER BY av.volume DESC`,
 			assetTable,
 			assetVolumeTable,
-			name,
-			symbol,
+			args.bind(sqlLikePrefix(symbol)),
+			args.bind(sqlLikePrefix(name)),
 		)
 	}
 	if err != nil {
 		return
 	}
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, args...)
 
 	log.Infoln("GetAssetsBySymbolName query", query)
 	defer rows.Close()
@@ -268,13 +269,12 @@ func (rdb *RelDB) GetAssetsByAddress(address string) (assets []dia.Asset, err er
 	ON a.asset_id=av.asset_id
 	WHERE av.volume>0
 	AND av.time_stamp IS NOT NULL
-	AND address ILIKE '%s%%'
+	AND address ILIKE $1
 	ORDER BY av.volume DESC`,
 		assetTable,
 		assetVolumeTable,
-		address,
 	)
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, sqlLikePrefix(address))
 	if err != nil {
 		return
 	}
@@ -328,26 +328,27 @@ func (rdb *RelDB) GetFiatAssetBySymbol(symbol string) (asset dia.Asset, err erro
 func (rdb *RelDB) IdentifyAsset(asset dia.Asset) (assets []dia.Asset, err error) {
 	query := fmt.Sprintf("SELECT symbol,name,address,decimals,blockchain FROM %s WHERE ", assetTable)
 	var and string
+	var args sqlArgs
 	if asset.Symbol != "" {
-		query += fmt.Sprintf("symbol='%s'", asset.Symbol)
+		query += "symbol=" + args.bind(asset.Symbol)
 		and = " AND "
 	}
 	if asset.Name != "" {
-		query += fmt.Sprintf(and+"name='%s'", asset.Name)
+		query += and + "name=" + args.bind(asset.Name)
 		and = " AND "
 	}
 	if asset.Address != "" {
-		query += fmt.Sprintf(and+"address='%s'", common.HexToAddress(asset.Address).Hex())
+		query += and + "address=" + args.bind(common.HexToAddress(asset.Address).Hex())
 		and = " AND "
 	}
 	if asset.Decimals != 0 {
-		query += fmt.Sprintf(and+"decimals='%d'", asset.Decimals)
+		query += and + "decimals=" + args.bind(strconv.Itoa(int(asset.Decimals)))
 		and = " AND "
 	}
 	if asset.Blockchain != "" {
-		query += fmt.Sprintf(and+"blockchain='%s'", asset.Blockchain)
+		query += and + "blockchain=" + args.bind(asset.Blockchain)
 	}
-	rows, err := rdb.postgresClient.Query(context.Background(), query)
+	rows, err := rdb.postgresClient.Query(context.Background(), query, args...)
 	if err != nil {
 		return
 	}
@@ -465,8 +466,8 @@ func (rdb *RelDB) GetExchangeSymbols(exchange string, substring string) (symbols
 	var rows pgx.Rows
 	if exchange != "" {
 		if substring != "" {
-			query = fmt.Sprintf("SELECT symbol FROM %s WHERE exchange=$1 AND symbol ILIKE '%s%%'", exchangesymbolTable, substring)
-			rows, err = rdb.postgresClient.Query(context.Background(), query, exchange)
+			query = fmt.Sprintf("SELECT symbol FROM %s WHERE exchange=$1 AND symbol ILIKE $2", exchangesymbolTable)
+			rows, err = rdb.postgresClient.Query(context.Background(), query, exchange, sqlLikePrefix(substring))
 
 		} else {
 			query = fmt.Sprintf("SELECT symbol FROM %s WHERE exchange=$1", exchangesymbolTable)
@@ -474,9 +475,9 @@ func (rdb *RelDB) GetExchangeSymbols(exchange string, substring string) (symbols
 		}
 	} else {
 		if substring != "" {
-			query = fmt.Sprintf("SELECT symbol FROM %s WHERE symbol ILIKE '%s%%'", exchangesymbolTable, substring)
+			query = fmt.Sprintf("SELECT symbol FROM %s WHERE symbol ILIKE $1", exchangesymbolTable)
 			log.Info("query: ", query)
-			rows, err = rdb.postgresClient.Query(context.Background(), query)
+			rows, err = rdb.postgresClient.Query(context.Background(), query, sqlLikePrefix(substring))
 		} else {
 			query = fmt.Sprintf("SELECT symbol FROM %s", exchangesymbolTable)
 			rows, err = rdb.postgresClient.Query(context.Background(), query)
@@ -787,16 +788,14 @@ func (rdb *RelDB) SetAssetVolume24H(asset dia.Asset, volume float64, timestamp t
 
 	initialStr := fmt.Sprintf("INSERT INTO %s (asset_id,volume,time_stamp) VALUES ", assetVolumeTable)
 	substring := fmt.Sprintf(
-		"((SELECT asset_id FROM asset WHERE address='%s' AND blockchain='%s'),%f,to_timestamp(%v))",
-		asset.Address,
-		asset.Blockchain,
+		"((SELECT asset_id FROM asset WHERE address=$1 AND blockchain=$2),%f,to_timestamp(%v))",
 		volume,
 		timestamp.Unix(),
 	)
 	conflict := " ON CONFLICT (asset_id) DO UPDATE SET volume=EXCLUDED.volume,time_stamp=EXCLUDED.time_stamp"
 
 	query := initialStr + substring + conflict
-	_, err := rdb.postgresClient.Exec(context.Background(), query)
+	_, err := rdb.postgresClient.Exec(context.Background(), query, asset.Address, asset.Blockchain)
 	if err != nil {
 		return err
 	}
@@ -889,14 +888,15 @@ func (rdb *RelDB) GetAssetsWithVolByBlockchain(starttime time.Time, endtime time
 		starttime.Unix(),
 		endtime.Unix(),
 	)
+	var args sqlArgs
 	if blockchain != "" {
-		query += fmt.Sprintf(" AND asset.blockchain='%s')", blockchain)
+		query += " AND asset.blockchain=" + args.bind(blockchain) + ")"
 	} else {
 		query += (")")
 	}
 	query += " sub ORDER BY volume DESC"
 
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, args...)
 	if err != nil {
 		return
 	}
@@ -930,15 +930,15 @@ func (rdb *RelDB) GetSortedAssetSymbols(numAssets int64, skip int64, search stri
 	)
 
 	if numAssets == 0 {
-		queryString = "SELECT symbol,name,address,decimals,blockchain,volume FROM %s INNER JOIN %s ON (asset.asset_id = assetvolume.asset_id) WHERE symbol ILIKE '%s%%' ORDER BY assetvolume.volume DESC LIMIT 100"
-		query = fmt.Sprintf(queryString, assetTable, assetVolumeTable, search)
+		queryString = "SELECT symbol,name,address,decimals,blockchain,volume FROM %s INNER JOIN %s ON (asset.asset_id = assetvolume.asset_id) WHERE symbol ILIKE $1 ORDER BY assetvolume.volume DESC LIMIT 100"
+		query = fmt.Sprintf(queryString, assetTable, assetVolumeTable)
 	} else {
-		queryString = "SELECT DISTINCT ON (av.volume,av.asset_id)  a.symbol,a.name,a.address,a.decimals,a.blockchain,av.volume FROM %s  av INNER JOIN %s a ON av.asset_id=a.asset_id INNER JOIN %s es ON av.asset_id=es.asset_id INNER JOIN %s e ON es.exchange=e.name WHERE e.centralized=true  and a.symbol ILIKE '%s%%' ORDER BY av.volume DESC LIMIT %d OFFSET %d"
-		query = fmt.Sprintf(queryString, assetVolumeTable, assetTable, exchangesymbolTable, exchangeTable, search, numAssets, skip)
+		queryString = "SELECT DISTINCT ON (av.volume,av.asset_id)  a.symbol,a.name,a.address,a.decimals,a.blockchain,av.volume FROM %s  av INNER JOIN %s a ON av.asset_id=a.asset_id INNER JOIN %s es ON av.asset_id=es.asset_id INNER JOIN %s e ON es.exchange=e.name WHERE e.centralized=true  and a.symbol ILIKE $1 ORDER BY av.volume DESC LIMIT %d OFFSET %d"
+		query = fmt.Sprintf(queryString, assetVolumeTable, assetTable, exchangesymbolTable, exchangeTable, numAssets, skip)
 	}
 	log.Infoln("GetSortedAssetSymbols query", query)
 
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, sqlLikePrefix(search))
 	if err != nil {
 		return
 	}
@@ -976,6 +976,7 @@ func (rdb *RelDB) GetAssetsWithVOL(numAssets int64, skip int64, onlycex bool, bl
 		queryString string
 		query       string
 		rows        pgx.Rows
+		args        sqlArgs
 	)
 	if numAssets == 0 {
 		numAssets = 100
@@ -994,10 +995,10 @@ func (rdb *RelDB) GetAssetsWithVOL(numAssets int64, skip int64, onlycex bool, bl
 			queryString = `
 			SELECT symbol,name,address,decimals,blockchain,volume 
 			FROM %s INNER JOIN %s ON (asset.asset_id = assetvolume.asset_id) 
-			WHERE blockchain= '%s' 
+			WHERE blockchain= %s 
 			ORDER BY assetvolume.volume 
 			DESC LIMIT %d OFFSET %d`
-			query = fmt.Sprintf(queryString, assetTable, assetVolumeTable, blockchain, numAssets, skip)
+			query = fmt.Sprintf(queryString, assetTable, assetVolumeTable, args.bind(blockchain), numAssets, skip)
 		}
 
 	} else {
@@ -1020,17 +1021,17 @@ func (rdb *RelDB) GetAssetsWithVOL(numAssets int64, skip int64, onlycex bool, bl
 			INNER JOIN %s a  ON av.asset_id=a.asset_id 
 			INNER JOIN %s es ON av.asset_id=es.asset_id 
 			INNER JOIN %s e ON es.exchange=e.name 
-			WHERE e.centralized=true AND a.blockchain = '%s' 
+			WHERE e.centralized=true AND a.blockchain = %s 
 			ORDER BY av.volume 
 			DESC  LIMIT %d OFFSET %d`
-			query = fmt.Sprintf(queryString, assetVolumeTable, assetTable, exchangesymbolTable, exchangeTable, blockchain, numAssets, skip)
+			query = fmt.Sprintf(queryString, assetVolumeTable, assetTable, exchangesymbolTable, exchangeTable, args.bind(blockchain), numAssets, skip)
 		}
 
 	}
 
 	log.Infoln("GetAssetsWithVOL query", query)
 
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, args...)
 	if err != nil {
 		return
 	}
@@ -1068,19 +1069,19 @@ func (rdb *RelDB) GetAssetSource(asset dia.Asset, cex bool) (exchanges []string,
 		SELECT DISTINCT ON (es.exchange) es.exchange 
 		FROM %s es 
 		INNER JOIN %s a ON es.asset_id = a.asset_id 
-		WHERE a.blockchain='%s' AND a.address='%s'
-		`, exchangesymbolTable, assetTable, asset.Blockchain, asset.Address)
+		WHERE a.blockchain=$1 AND a.address=$2
+		`, exchangesymbolTable, assetTable)
 	} else {
 		query = fmt.Sprintf(`
 		SELECT  DISTINCT ON (p.exchange) p.exchange
 		FROM %s p 
 		INNER JOIN %s pa ON p.pool_id=pa.pool_id 
 		INNER JOIN %s a ON pa.asset_id=a.asset_id 
-		WHERE a.blockchain='%s' AND a.address='%s'
-		`, poolTable, poolassetTable, assetTable, asset.Blockchain, asset.Address)
+		WHERE a.blockchain=$1 AND a.address=$2
+		`, poolTable, poolassetTable, assetTable)
 	}
 
-	rows, err := rdb.postgresClient.Query(context.Background(), query)
+	rows, err := rdb.postgresClient.Query(context.Background(), query, asset.Blockchain, asset.Address)
 	if err != nil {
 		return
 	}
@@ -21,20 +21,24 @@ func (datastore *DB) SaveIndexEngineTimeInflux(tags map[string]string, fields ma
 	pt, err := clientInfluxdb.NewPoint(influxDbBenchmarkedIndexTableName, tags, fields, timestamp)
 	if err != nil {
 		log.Errorln("newPoint:", err)
-	} else {
-		datastore.addPoint(pt)
-		err := datastore.WriteBatchInflux()
-		if err != nil {
-			log.Errorln("newPoint:", err)
-		}
+		return err
+	}
+	err = datastore.addPoint(pt)
+	if err != nil {
+		return err
+	}
+	err = datastore.WriteBatchInflux()
+	if err != nil {
+		log.Errorln("newPoint:", err)
 	}
-	return err
+	return nil
 }
 
 func (datastore *DB) GetBenchmarkedIndexValuesInflux(symbol string, starttime time.Time, endtime time.Time) (BenchmarkedIndex, error) {
 	var retval BenchmarkedIndex
-	q := fmt.Sprintf("SELECT time,\"name\",value from %s WHERE time > %d and time < %d and \"name\" = '%s' ORDER BY time DESC", influxDbBenchmarkedIndexTableName, starttime.UnixNano(), endtime.UnixNano(), symbol)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT time,\"name\",value from %s WHERE time > %d and time < %d and \"name\" = %s ORDER BY time DESC", influxDbBenchmarkedIndexTableName, starttime.UnixNano(), endtime.UnixNano(), params.bind(symbol))
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return retval, err
 	}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	clientInfluxdb "github.com/influxdata/influxdb1-client/v2"
+)
+
+// SaveCandleInflux adds @candle to the influx batch. Saving a candle again with the same asset,
+// exchange, resolution and time overwrites it.
+func (datastore *DB) SaveCandleInflux(candle dia.Candle) error {
+	// The symbol is not a tag, so that candles with differing symbols of the same asset overwrite each other.
+	tags := map[string]string{
+		"resolution": candle.Resolution,
+		"address":    candle.Asset.Address,
+		"blockchain": candle.Asset.Blockchain,
+		"exchange":   candle.Exchange,
+	}
+	fields := map[string]interface{}{
+		"symbol":    candle.Asset.Symbol,
+		"open":      candle.Open,
+		"high":      candle.High,
+		"low":       candle.Low,
+		"close":     candle.Close,
+		"volume":    candle.Volume,
+		"volumeUSD": candle.VolumeUSD,
+		"numTrades": candle.NumTrades,
+	}
+	pt, err := clientInfluxdb.NewPoint(influxDbCandlesTable, tags, fields, candle.Time)
+	if err != nil {
+		log.Errorln("new candle influx:", err)
+	} else {
+		err = datastore.addPoint(pt)
+	}
+	return err
+}
+
+// GetCandles returns the candles of @asset on @exchange with @resolution beginning in [@starttime,@endtime)
+// in ascending order of time. An empty @exchange stands for all exchanges.
+func (datastore *DB) GetCandles(asset dia.Asset, exchange string, resolution string, starttime time.Time, endtime time.Time) ([]dia.Candle, error) {
+	candles := []dia.Candle{}
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT time,symbol,open,high,low,close,volume,volumeUSD,numTrades FROM %s"+
+		" WHERE address=%s AND blockchain=%s AND exchange=%s AND resolution=%s AND time>=%d AND time<%d ORDER BY ASC",
+		influxDbCandlesTable, params.bind(asset.Address), params.bind(asset.Blockchain), params.bind(exchange), params.bind(resolution), starttime.UnixNano(), endtime.UnixNano())
+
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
+	if err != nil {
+		return candles, err
+	}
+	if len(res) == 0 || len(res[0].Series) == 0 {
+		return candles, nil
+	}
+	for _, row := range res[0].Series[0].Values {
+		candle := dia.Candle{Asset: asset, Exchange: exchange, Resolution: resolution}
+		candle.Time, err = time.Parse(time.RFC3339, row[0].(string))
+		if err != nil {
+			return candles, err
+		}
+		if symbol, ok := row[1].(string); ok {
+			candle.Asset.Symbol = symbol
+		}
+		for i, field := range []*float64{&candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Volume, &candle.VolumeUSD} {
+			*field, err = row[2+i].(json.Number).Float64()
+			if err != nil {
+				return candles, err
+			}
+		}
+		numTrades, err := row[8].(json.Number).Int64()
+		if err != nil {
+			return candles, err
+		}
+		candle.NumTrades = int(numTrades)
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// GetLatestCandleTime returns the begin time of the latest candle with @resolution of any asset.
+// It returns the zero time if there is no such candle.
+func (datastore *DB) GetLatestCandleTime(resolution string) (time.Time, error) {
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT LAST(numTrades) FROM %s WHERE resolution=%s", influxDbCandlesTable, params.bind(resolution))
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(res) == 0 || len(res[0].Series) == 0 || len(res[0].Series[0].Values) == 0 {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, res[0].Series[0].Values[0][0].(string))
+}
@@ -4,35 +4,48 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia/helpers/db"
+	"github.com/diadata-org/diadata/pkg/utils"
 
 	"github.com/diadata-org/diadata/pkg/dia"
 	"github.com/go-redis/redis"
 	clientInfluxdb "github.com/influxdata/influxdb1-client/v2"
 )
 
+// Datastore is the storage of market data in influx and redis. It is composed of one interface per
+// concern, so that services can depend on the parts they use and backends and mocks can be provided
+// per concern.
 type Datastore interface {
-	SetInfluxClient(url string)
-	SetBatchFiatPriceInflux(fqs []*FiatQuotation) error
-	SetSingleFiatPriceRedis(fiatQuotation *FiatQuotation) error
+	BatchStore
+	TradeStore
+	VolumeStore
+	FilterStore
+	CandleStore
+	QuotationStore
+	SupplyStore
+	PoolHistoryStore
+	RateStore
+	ForeignQuotationStore
+	IndexStore
+	StockStore
+}
 
-	GetLatestSupply(string, *RelDB) (*dia.Supply, error)
-	GetSupplyCache(asset dia.Asset) (dia.Supply, error)
-	GetSupply(string, time.Time, time.Time, *RelDB) ([]dia.Supply, error)
-	SetSupply(supply *dia.Supply) error
-	GetSupplyInflux(dia.Asset, time.Time, time.Time) ([]dia.Supply, error)
-	SaveSynthSupplyInfluxToTable(*dia.SynthAssetSupply, string) error
-	SaveSynthSupplyInflux(*dia.SynthAssetSupply) error
-	GetSynthSupplyInflux(string, string, string, int, time.Time, time.Time) ([]dia.SynthAssetSupply, error)
-	GetSynthAssets(string, string) ([]string, error)
+var _ Datastore = (*DB)(nil)
 
-	SetDiaTotalSupply(totalSupply float64) error
-	GetDiaTotalSupply() (float64, error)
-	SetDiaCirculatingSupply(circulatingSupply float64) error
-	GetDiaCirculatingSupply() (float64, error)
+// BatchStore writes the batched influx points and redis commands of the other stores.
+type BatchStore interface {
+	SetInfluxClient(url string)
+	Flush() error
+	ExecuteRedisPipe() error
+	FlushRedisPipe() error
+	CopyInfluxMeasurements(dbOrigin string, dbDestination string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (int64, error)
+}
 
+// TradeStore stores trades and the pairs traded on exchanges.
+type TradeStore interface {
 	GetSymbols(exchange string) ([]string, error)
 	GetLastTradeTimeForExchange(asset dia.Asset, exchange string) (*time.Time, error)
 	SetLastTradeTimeForExchange(asset dia.Asset, exchange string, t time.Time) error
@@ -40,30 +53,21 @@ type Datastore interface {
 	SaveTradeInflux(t *dia.Trade) error
 	SaveTradeInfluxToTable(t *dia.Trade, table string) error
 	GetTradeInflux(dia.Asset, string, time.Time, time.Duration) (*dia.Trade, error)
-	SaveFilterInflux(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error
 	GetLastTrades(asset dia.Asset, exchange string, maxTrades int, fullAsset bool) ([]dia.Trade, error)
 	GetAllTrades(t time.Time, maxTrades int) ([]dia.Trade, error)
 	GetTradesByExchanges(asset dia.Asset, baseAssets []dia.Asset, exchange []string, startTime, endTime time.Time) ([]dia.Trade, error)
+	GetTradesByExchangesAndBaseAssets(asset dia.Asset, baseAssets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error)
 	GetTradesByExchangesFull(asset dia.Asset, baseAssets []dia.Asset, exchanges []string, returnBasetoken bool, startTime, endTime time.Time) ([]dia.Trade, error)
 	GetTradesByExchangesBatched(asset dia.Asset, baseAssets []dia.Asset, exchanges []string, startTimes, endTimes []time.Time) ([]dia.Trade, error)
 	GetTradesByExchangesBatchedFull(asset dia.Asset, baseAssets []dia.Asset, exchanges []string, returnBasetoken bool, startTimes, endTimes []time.Time) ([]dia.Trade, error)
 	GetActiveExchangesAndPairs(address string, blockchain string, starttime time.Time, endtime time.Time) (map[string][]dia.Pair, error)
 	GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) ([]dia.Trade, error)
-	CopyInfluxMeasurements(dbOrigin string, dbDestination string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (int64, error)
-
-	Flush() error
-	ExecuteRedisPipe() error
-	FlushRedisPipe() error
-	GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time) (*Points, error)
-	GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time) (*Points, error)
-	SetFilter(filterName string, asset dia.Asset, exchange string, value float64, t time.Time) error
-	GetLastPriceBefore(asset dia.Asset, filter string, exchange string, timestamp time.Time) (Price, error)
 	SetAvailablePairs(exchange string, pairs []dia.ExchangePair) error
 	GetAvailablePairs(exchange string) ([]dia.ExchangePair, error)
-	SetCurrencyChange(cc *Change) error
-	GetCurrencyChange() (*Change, error)
+}
 
-	// Volume methods
+// VolumeStore returns trading volumes and numbers of trades.
+type VolumeStore interface {
 	GetVolumeInflux(asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) (*float64, error)
 	Get24HoursAssetVolume(asset dia.Asset) (*float64, error)
 	Get24HoursExchangeVolume(exchange string) (*float64, error)
@@ -78,6 +82,35 @@ type Datastore interface {
 		quotetoken dia.Asset,
 		basetoken dia.Asset,
 	) ([]int, error)
+}
+
+// FilterStore stores filter points and the tradesBlocks they are computed from.
+type FilterStore interface {
+	SaveFilterInflux(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error
+	GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error)
+	GetFilter(filter string, topAsset dia.Asset, scale string, starttime time.Time, endtime time.Time) ([]dia.FilterPoint, error)
+	GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error)
+	SetFilter(filterName string, asset dia.Asset, exchange string, value float64, t time.Time) error
+	SetFilterQuality(filterName string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error
+	DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error
+	SetTradesBlockProcessed(tradesBlockHash string, revision int) error
+	IsTradesBlockProcessed(tradesBlockHash string, revision int) (bool, error)
+	GetLastPriceBefore(asset dia.Asset, filter string, exchange string, timestamp time.Time) (Price, error)
+}
+
+// CandleStore stores OHLCV candles.
+type CandleStore interface {
+	SaveCandleInflux(candle dia.Candle) error
+	GetCandles(asset dia.Asset, exchange string, resolution string, starttime time.Time, endtime time.Time) ([]dia.Candle, error)
+	GetLatestCandleTime(resolution string) (time.Time, error)
+}
+
+// QuotationStore stores USD quotations of assets and fiat currencies.
+type QuotationStore interface {
+	SetBatchFiatPriceInflux(fqs []*FiatQuotation) error
+	SetSingleFiatPriceRedis(fiatQuotation *FiatQuotation) error
+	SetCurrencyChange(cc *Change) error
+	GetCurrencyChange() (*Change, error)
 
 	// New Asset pricing methods: 23/02/2021
 	SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error
@@ -96,14 +129,36 @@ type Datastore interface {
 	GetTopAssetByVolume(symbol string, relDB *RelDB) (topAsset dia.Asset, err error)
 	GetAssetsWithVOLInflux(timeInit time.Time) ([]dia.Asset, error)
 
-	// DEX Pool  methods
-	SavePoolInflux(p dia.Pool) error
-	GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error)
-
 	// Market Measures
 	GetAssetsMarketCap(asset dia.Asset) (float64, error)
+}
+
+// SupplyStore stores supplies of assets and synthetic assets.
+type SupplyStore interface {
+	GetLatestSupply(string, *RelDB) (*dia.Supply, error)
+	GetSupplyCache(asset dia.Asset) (dia.Supply, error)
+	GetSupply(string, time.Time, time.Time, *RelDB) ([]dia.Supply, error)
+	SetSupply(supply *dia.Supply) error
+	GetSupplyInflux(dia.Asset, time.Time, time.Time) ([]dia.Supply, error)
+	SaveSynthSupplyInfluxToTable(*dia.SynthAssetSupply, string) error
+	SaveSynthSupplyInflux(*dia.SynthAssetSupply) error
+	GetSynthSupplyInflux(string, string, string, int, time.Time, time.Time) ([]dia.SynthAssetSupply, error)
+	GetSynthAssets(string, string) ([]string, error)
 
-	// Interest rates' methods
+	SetDiaTotalSupply(totalSupply float64) error
+	GetDiaTotalSupply() (float64, error)
+	SetDiaCirculatingSupply(circulatingSupply float64) error
+	GetDiaCirculatingSupply() (float64, error)
+}
+
+// PoolHistoryStore stores the liquidity of DEX pools over time.
+type PoolHistoryStore interface {
+	SavePoolInflux(p dia.Pool) error
+	GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error)
+}
+
+// RateStore stores interest rates and computes compounded rates from them.
+type RateStore interface {
 	SetInterestRate(ir *InterestRate) error
 	GetInterestRate(symbol, date string) (*InterestRate, error)
 	GetInterestRateRange(symbol, dateInit, dateFinal string) ([]*InterestRate, error)
@@ -113,8 +168,10 @@ type Datastore interface {
 	GetCompoundedAvg(symbol string, date time.Time, calDays, daysPerYear int, rounding int) (*InterestRate, error)
 	GetCompoundedAvgRange(symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) ([]*InterestRate, error)
 	GetCompoundedAvgDIARange(symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) ([]*InterestRate, error)
+}
 
-	// Foreign quotation methods
+// ForeignQuotationStore stores quotations which are not computed by DIA.
+type ForeignQuotationStore interface {
 	SaveForeignQuotationInflux(fq ForeignQuotation) error
 	GetForeignQuotationInflux(symbol, source string, timestamp time.Time) (ForeignQuotation, error)
 	GetForeignPriceYesterday(symbol, source string) (float64, error)
@@ -122,15 +179,16 @@ type Datastore interface {
 
 	SetVWAPFirefly(foreignName string, value float64, timestamp time.Time) error
 	GetVWAPFirefly(foreignName string, starttime time.Time, endtime time.Time) ([]float64, []time.Time, error)
+}
 
+// IndexStore stores the values of benchmarked indices.
+type IndexStore interface {
 	SaveIndexEngineTimeInflux(map[string]string, map[string]interface{}, time.Time) error
 	GetBenchmarkedIndexValuesInflux(string, time.Time, time.Time) (BenchmarkedIndex, error)
-	// Token methods
-	// SaveTokenDetailInflux(tk Token) error
-	// GetTokenDetailInflux(symbol, source string, timestamp time.Time) (Token, error)
-	// GetCurentTotalSupply(symbol, source string) (float64, error)
+}
 
-	// Stock methods
+// StockStore stores stock quotations.
+type StockStore interface {
 	SetStockQuotation(sq StockQuotation) error
 	GetStockQuotation(source string, symbol string, timeInit time.Time, timeFinal time.Time) ([]StockQuotation, error)
 	GetStockSymbols() (map[Stock]string, error)
@@ -147,12 +205,15 @@ type DB struct {
 	influxClient        clientInfluxdb.Client
 	influxBatchPoints   clientInfluxdb.BatchPoints
 	influxPointsInBatch int
+	// influxBatchLock makes adding points to and writing the influx batch safe for concurrent use.
+	influxBatchLock sync.Mutex
 }
 
 const (
 	influxDbName                      = "dia"
 	influxDbTradesTable               = "trades"
 	influxDbFiltersTable              = "filters"
+	influxDbCandlesTable              = "candles"
 	influxDbFiatQuotationsTable       = "fiat"
 	influxDbSupplyTable               = "supplies"
 	influxDbDEXPoolTable              = "DEXPools"
@@ -165,18 +226,31 @@ const (
 	influxDBDefaultURL = "http://influxdb:8086"
 )
 
+// errNoInflux is returned for time series stored in influx by datastores without influx client.
+var errNoInflux = errors.New("influx is not configured")
+
 // queryInfluxDB convenience function to query the database.
 func queryInfluxDB(clnt clientInfluxdb.Client, cmd string) (res []clientInfluxdb.Result, err error) {
 	res, err = queryInfluxDBName(clnt, influxDbName, cmd)
 	return
 }
 
+// queryInfluxDBParams queries the database with @cmd, whose placeholders are bound to @params.
+func queryInfluxDBParams(clnt clientInfluxdb.Client, cmd string, params influxParams) (res []clientInfluxdb.Result, err error) {
+	return queryInfluxDBNameParams(clnt, influxDbName, cmd, params)
+}
+
 // queryInfluxDBName is a wrapper for queryInfluxDB that allows for queries on the database with name @dbName.
 func queryInfluxDBName(clnt clientInfluxdb.Client, dbName string, cmd string) (res []clientInfluxdb.Result, err error) {
-	q := clientInfluxdb.Query{
-		Command:  cmd,
-		Database: dbName,
+	return queryInfluxDBNameParams(clnt, dbName, cmd, influxParams{})
+}
+
+// queryInfluxDBNameParams queries the database with name @dbName with @cmd, whose placeholders are bound to @params.
+func queryInfluxDBNameParams(clnt clientInfluxdb.Client, dbName string, cmd string, params influxParams) (res []clientInfluxdb.Result, err error) {
+	if clnt == nil {
+		return res, errNoInflux
 	}
+	q := clientInfluxdb.NewQueryWithParameters(cmd, dbName, "", params)
 	if response, err := clnt.Query(q); err == nil {
 		if response.Error() != nil {
 			return res, response.Error()
@@ -188,6 +262,22 @@ func queryInfluxDBName(clnt clientInfluxdb.Client, dbName string, cmd string) (r
 	return res, nil
 }
 
+// NewTimeSeriesDataStore returns the Datastore of the backend given in the environment variable
+// TIMESERIES_BACKEND. It is either influx (default) for influx 1.x, influxv2 for influx 2.x or postgres for
+// timescaledb in the postgres database of RelDB.
+func NewTimeSeriesDataStore() (Datastore, error) {
+	switch backend := utils.Getenv("TIMESERIES_BACKEND", "influx"); backend {
+	case "influx":
+		return NewDataStore()
+	case "influxv2":
+		return NewInfluxV2DataStore()
+	case "postgres":
+		return NewTimescaleDataStore()
+	default:
+		return nil, fmt.Errorf("unknown time series backend %s", backend)
+	}
+}
+
 func NewDataStore() (*DB, error) {
 	return NewDataStoreWithOptions(true, true)
 }
@@ -226,7 +316,7 @@ func NewDataStoreWithOptions(withRedis bool, withInflux bool) (*DB, error) {
 			log.Errorln("queryInfluxDB CREATE DATABASE", err)
 		}
 	}
-	return &DB{redisClient, redisPipe, influxClient, influxBatchPoints, 0}, nil
+	return &DB{redisClient: redisClient, redisPipe: redisPipe, influxClient: influxClient, influxBatchPoints: influxBatchPoints}, nil
 }
 
 // SetInfluxClient resets influx's client url to @url.
@@ -254,6 +344,16 @@ func (datastore *DB) Flush() error {
 }
 
 func (datastore *DB) WriteBatchInflux() (err error) {
+	datastore.influxBatchLock.Lock()
+	defer datastore.influxBatchLock.Unlock()
+	return datastore.writeBatchInflux()
+}
+
+// writeBatchInflux writes the influx batch. The caller must hold influxBatchLock.
+func (datastore *DB) writeBatchInflux() (err error) {
+	if datastore.influxClient == nil {
+		return errNoInflux
+	}
 	err = datastore.influxClient.Write(datastore.influxBatchPoints)
 	if err != nil {
 		log.Errorln("WriteBatchInflux", err)
@@ -264,16 +364,24 @@ func (datastore *DB) WriteBatchInflux() (err error) {
 	return
 }
 
-func (datastore *DB) addPoint(pt *clientInfluxdb.Point) {
+// addPoint adds @pt to the influx batch, which is written once it is full. It returns errNoInflux
+// if the datastore has no influx client.
+func (datastore *DB) addPoint(pt *clientInfluxdb.Point) error {
+	if datastore.influxBatchPoints == nil {
+		return errNoInflux
+	}
+	datastore.influxBatchLock.Lock()
+	defer datastore.influxBatchLock.Unlock()
 	datastore.influxBatchPoints.AddPoint(pt)
 	datastore.influxPointsInBatch++
 
 	if datastore.influxPointsInBatch >= influxMaxPointsInBatch {
-		err := datastore.WriteBatchInflux()
+		err := datastore.writeBatchInflux()
 		if err != nil {
 			log.Error("write influx batch: ", err)
 		}
 	}
+	return nil
 }
 
 func (datastore *DB) ExecuteRedisPipe() (err error) {
@@ -290,7 +398,7 @@ func (datastore *DB) FlushRedisPipe() error {
 // It takes into account all data ranging from @timeInit until @timeFinal.
 func (datastore *DB) CopyInfluxMeasurements(dbOrigin string, dbDestination string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (numCopiedRows int64, err error) {
 	queryString := "select * into %s..%s from %s..%s where time>%d and time<=%d group by *"
-	query := fmt.Sprintf(queryString, dbDestination, tableDestination, dbOrigin, tableOrigin, timeInit.UnixNano(), timeFinal.UnixNano())
+	query := fmt.Sprintf(queryString, influxIdentifier(dbDestination), influxIdentifier(tableDestination), influxIdentifier(dbOrigin), influxIdentifier(tableOrigin), timeInit.UnixNano(), timeFinal.UnixNano())
 	res, err := queryInfluxDB(datastore.influxClient, query)
 	if err != nil {
 		return
@@ -328,9 +436,10 @@ func (datastore *DB) SetVWAPFirefly(foreignName string, value float64, timestamp
 
 func (datastore *DB) GetVWAPFirefly(foreignName string, starttime time.Time, endtime time.Time) (values []float64, timestamps []time.Time, err error) {
 
-	influxQuery := "SELECT value FROM %s WHERE time > %d AND time <= %d AND foreignName = '%s' ORDER BY DESC"
-	q := fmt.Sprintf(influxQuery, influxDbVwapFireflyTable, starttime.UnixNano(), endtime.UnixNano(), foreignName)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	influxQuery := "SELECT value FROM %s WHERE time > %d AND time <= %d AND foreignName = %s ORDER BY DESC"
+	q := fmt.Sprintf(influxQuery, influxDbVwapFireflyTable, starttime.UnixNano(), endtime.UnixNano(), params.bind(foreignName))
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return
 	}
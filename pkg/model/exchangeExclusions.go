@@ -0,0 +1,63 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/jackc/pgx/v4"
+)
+
+// SetExchangeExclusion stores the exclusion of an exchange from the aggregated price of an asset in postgres.
+func (rdb *RelDB) SetExchangeExclusion(exclusion dia.ExchangeExclusion) error {
+	assetQuery := fmt.Sprintf("(SELECT asset_id FROM %s WHERE blockchain=$1 and address=$2)", assetTable)
+	query := fmt.Sprintf("INSERT INTO %s (asset_id,exchange,filter,filter_value,median,deviation,begin_time,end_time) VALUES(%s,$3,$4,$5,$6,$7,$8,$9);", exchangeExclusionTable, assetQuery)
+
+	_, err := rdb.postgresClient.Exec(context.Background(), query,
+		exclusion.Asset.Blockchain,
+		exclusion.Asset.Address,
+		exclusion.Exchange,
+		exclusion.Filter,
+		exclusion.Value,
+		exclusion.Median,
+		exclusion.Deviation,
+		exclusion.BeginTime,
+		exclusion.EndTime,
+	)
+	return err
+}
+
+// GetExchangeExclusions returns the exclusions of exchanges from the aggregated price of @asset
+// which began in the time-range @starttime - @endtime, in ascending order of begin time.
+func (rdb *RelDB) GetExchangeExclusions(asset dia.Asset, starttime time.Time, endtime time.Time) (exclusions []dia.ExchangeExclusion, err error) {
+	query := fmt.Sprintf("SELECT exchange,filter,filter_value,median,deviation,begin_time,end_time FROM %s WHERE asset_id=(SELECT asset_id FROM %s WHERE address=$1 AND blockchain=$2) AND begin_time>$3 AND begin_time<=$4 ORDER BY begin_time ASC",
+		exchangeExclusionTable,
+		assetTable,
+	)
+
+	var rows pgx.Rows
+	rows, err = rdb.postgresClient.Query(context.Background(), query, asset.Address, asset.Blockchain, starttime, endtime)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		exclusion := dia.ExchangeExclusion{Asset: asset}
+		err = rows.Scan(
+			&exclusion.Exchange,
+			&exclusion.Filter,
+			&exclusion.Value,
+			&exclusion.Median,
+			&exclusion.Deviation,
+			&exclusion.BeginTime,
+			&exclusion.EndTime,
+		)
+		if err != nil {
+			return
+		}
+		exclusions = append(exclusions, exclusion)
+	}
+	return
+}
@@ -60,13 +60,14 @@ func (datastore *DB) GetActiveExchangesAndPairs(address string, blockchain strin
 	SELECT exchange,pair,quotetokenaddress,quotetokenblockchain,basetokenaddress,basetokenblockchain,LAST(estimatedUSDPrice) 
 	FROM %s 
 	WHERE time>%d AND time<=%d 
-	AND quotetokenaddress='%s' AND quotetokenblockchain='%s'
+	AND quotetokenaddress=%s AND quotetokenblockchain=%s
 	AND verified='true'
 	GROUP BY "exchange","pair"
 	`
 
-	q := fmt.Sprintf(query, influxDbTradesTable, starttime.UnixNano(), endtime.UnixNano(), address, blockchain)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	q := fmt.Sprintf(query, influxDbTradesTable, starttime.UnixNano(), endtime.UnixNano(), params.bind(address), params.bind(blockchain))
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return exchangepairmap, err
 	}
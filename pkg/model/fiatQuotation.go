@@ -31,16 +31,7 @@ func checkInfluxIsAvailable(db *DB) error {
 
 func addMultiplePointsToBatch(db *DB, fiatQuotations []*FiatQuotation) {
 	for _, fq := range fiatQuotations {
-		tags := map[string]string{
-			"quote_currency": fq.QuoteCurrency,
-			"base_currency":  fq.BaseCurrency,
-			"source":         fq.Source,
-		}
-		fields := map[string]interface{}{
-			"price": fq.Price,
-		}
-
-		pt, err := clientInfluxdb.NewPoint(influxDbFiatQuotationsTable, tags, fields, fq.Time)
+		pt, err := fiatQuotationPoint(fq)
 		if err != nil {
 			log.Printf("Error: %v on NewPoint %v\n", err, fq.BaseCurrency)
 		}
@@ -49,6 +40,19 @@ func addMultiplePointsToBatch(db *DB, fiatQuotations []*FiatQuotation) {
 	}
 }
 
+// fiatQuotationPoint returns the influx point of @fq.
+func fiatQuotationPoint(fq *FiatQuotation) (*clientInfluxdb.Point, error) {
+	tags := map[string]string{
+		"quote_currency": fq.QuoteCurrency,
+		"base_currency":  fq.BaseCurrency,
+		"source":         fq.Source,
+	}
+	fields := map[string]interface{}{
+		"price": fq.Price,
+	}
+	return clientInfluxdb.NewPoint(influxDbFiatQuotationsTable, tags, fields, fq.Time)
+}
+
 func (datastore *DB) SetSingleFiatPriceRedis(fiatQuotation *FiatQuotation) error {
 	err := checkRedisIsAvailable(datastore)
 	if err != nil {
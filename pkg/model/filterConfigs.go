@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/jackc/pgx/v4"
+)
+
+// GetFilterConfigs returns all filter configurations of the filtersBlockService stored in postgres.
+func (rdb *RelDB) GetFilterConfigs() (configs []dia.FilterConfig, err error) {
+	query := fmt.Sprintf("SELECT exchange,blockchain,address,filter,window_size,outlier_detector,outlier_threshold FROM %s", filterConfigTable)
+	var rows pgx.Rows
+	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var config dia.FilterConfig
+		err = rows.Scan(
+			&config.Exchange,
+			&config.Blockchain,
+			&config.Address,
+			&config.Filter,
+			&config.Window,
+			&config.OutlierDetector,
+			&config.OutlierThreshold,
+		)
+		if err != nil {
+			return
+		}
+		configs = append(configs, config)
+	}
+	return
+}
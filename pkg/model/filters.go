@@ -26,15 +26,114 @@ func (datastore *DB) SetFilter(filter string, asset dia.Asset, exchange string,
 	return err
 }
 
-func (datastore *DB) GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time) (*Points, error) {
+// SetFilterQuality saves @quality of the point of filter @filterName at @t. In influx, it is written
+// to the same point as the filter's value, so it must be called with the time given to SetFilter.
+func (datastore *DB) SetFilterQuality(filter string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	pt, err := filterQualityPoint(filter, asset, exchange, quality, t)
+	if err != nil {
+		log.Errorln("new filter quality influx:", err)
+	} else {
+		err = datastore.addPoint(pt)
+	}
+	return err
+}
+
+// filterQualityPoint returns the influx point of the @quality of a point of @filter.
+func filterQualityPoint(filter string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) (*clientInfluxdb.Point, error) {
+	fields := map[string]interface{}{
+		"numTrades":             quality.NumTrades,
+		"numExchanges":          quality.NumExchanges,
+		"volumeUSD":             quality.VolumeUSD,
+		"stdDev":                quality.StdDev,
+		"iqr":                   quality.IQR,
+		"outlierFraction":       quality.OutlierFraction,
+		"secondsSinceLastTrade": quality.SecondsSinceLastTrade,
+	}
+	return clientInfluxdb.NewPoint(influxDbFiltersTable, filterTags(filter, asset, exchange), fields, t)
+}
+
+// filterTags returns the tags of the points of @filter of @asset on @exchange.
+func filterTags(filter string, asset dia.Asset, exchange string) map[string]string {
+	return map[string]string{
+		"filter":     filter,
+		"symbol":     asset.Symbol,
+		"address":    asset.Address,
+		"blockchain": asset.Blockchain,
+		"exchange":   exchange,
+	}
+}
+
+// filterQualityColumns are the columns of the quality of filter points, selected if requested.
+const filterQualityColumns = ",numTrades,numExchanges,volumeUSD,stdDev,iqr,outlierFraction,secondsSinceLastTrade"
+
+// DeleteFilterPoints removes all filter points of @asset on @exchange in [@starttime,@endtime] from influx
+// and the values of @filters in this range from redis. It is used when filters are recomputed for an amended tradesBlock.
+func (datastore *DB) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	params := influxParams{}
+	q := fmt.Sprintf("DELETE FROM %s WHERE address=%s AND blockchain=%s AND exchange=%s AND time>=%d AND time<=%d",
+		influxDbFiltersTable, params.bind(asset.Address), params.bind(asset.Blockchain), params.bind(exchange), starttime.UnixNano(), endtime.UnixNano())
+	_, err := queryInfluxDBParams(datastore.influxClient, q, params)
+	if err != nil {
+		return err
+	}
+	return datastore.deleteFilterZSETValues(filters, asset, exchange, starttime, endtime)
+}
+
+// deleteFilterZSETValues removes the values of @filters for @asset on @exchange in [@starttime,@endtime] from redis.
+// Values are removed immediately rather than through the pipe, so that values set afterwards are kept.
+func (datastore *DB) deleteFilterZSETValues(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	if datastore.redisClient == nil {
+		return nil
+	}
+	for _, filter := range filters {
+		key := getKeyFilterZSET(getKey(filter, asset, exchange))
+		err := datastore.redisClient.ZRemRangeByScore(key, strconv.FormatInt(starttime.Unix(), 10), strconv.FormatInt(endtime.Unix(), 10)).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TimeOutTradesBlockProcessed is the time a processed tradesBlock is remembered for deduplication.
+const TimeOutTradesBlockProcessed = 24 * time.Hour
+
+func getKeyTradesBlockProcessed(tradesBlockHash string, revision int) string {
+	return "dia_processed_tradesBlock_" + tradesBlockHash + "_" + strconv.Itoa(revision)
+}
 
-	exchangeQuery := "AND exchange='" + exchange + "' "
+// SetTradesBlockProcessed marks revision @revision of the tradesBlock with hash @tradesBlockHash as processed.
+// It must only be called once all filter points of the block are flushed.
+func (datastore *DB) SetTradesBlockProcessed(tradesBlockHash string, revision int) error {
+	return datastore.redisClient.Set(getKeyTradesBlockProcessed(tradesBlockHash, revision), time.Now().Unix(), TimeOutTradesBlockProcessed).Err()
+}
+
+// IsTradesBlockProcessed returns true if the filter points of revision @revision of the tradesBlock
+// with hash @tradesBlockHash were already written.
+func (datastore *DB) IsTradesBlockProcessed(tradesBlockHash string, revision int) (bool, error) {
+	n, err := datastore.redisClient.Exists(getKeyTradesBlockProcessed(tradesBlockHash, revision)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
 
-	q := fmt.Sprintf("SELECT time,address,blockchain,exchange,filter,symbol,value FROM %s"+
-		" WHERE filter='%s' %s AND address='%s' and blockchain='%s' AND time>%d and time<=%d ORDER BY DESC",
-		influxDbFiltersTable, filter, exchangeQuery, address, blockchain, starttime.UnixNano(), endtime.UnixNano())
+// GetFilterPointsAsset returns the points of @filter for an asset on @exchange, where an empty @exchange
+// stands for all exchanges. If @withQuality is true, the points' quality is returned as well.
+func (datastore *DB) GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	exchangeQuery := "AND exchange=" + params.bind(exchange) + " "
+	columns := "time,address,blockchain,exchange,filter,symbol,value"
+	if withQuality {
+		columns += filterQualityColumns
+	}
+
+	q := fmt.Sprintf("SELECT %s FROM %s"+
+		" WHERE filter=%s %s AND address=%s and blockchain=%s AND time>%d and time<=%d ORDER BY DESC",
+		columns, influxDbFiltersTable, params.bind(filter), exchangeQuery, params.bind(address), params.bind(blockchain), starttime.UnixNano(), endtime.UnixNano())
+
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetFilterPoints", err)
 	}
@@ -48,27 +147,21 @@ func (datastore *DB) GetFilterPointsAsset(filter string, exchange string, addres
 
 // GetFilterPoints returns filter points from either a specific exchange or all exchanges.
 // symbol is mapped to the underlying asset with biggest market cap.
-func (datastore *DB) GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time) (*Points, error) {
-	relDB, err := NewRelDataStore()
+// If @withQuality is true, the points' quality is returned as well. Downsampled points have no quality.
+func (datastore *DB) GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	topAsset, err := topAssetOfSymbol(symbol)
 	if err != nil {
-		log.Errorln("NewDataStore:", err)
-	}
-	// First get asset with @symbol with largest volume.
-	// topAsset, err := db.GetTopAssetByVolume(symbol, relDB)
-	sortedAssets, err := relDB.GetTopAssetByVolume(symbol)
-	if err != nil {
-		log.Error(err)
 		return &Points{}, err
 	}
-	if len(sortedAssets) == 0 {
-		return nil, errors.New("no traded assets found")
-	}
-	topAsset := sortedAssets[0]
 
-	exchangeQuery := "and exchange='" + exchange + "' "
+	params := influxParams{}
+	exchangeQuery := "and exchange=" + params.bind(exchange) + " "
 	table := ""
 	//	5m 30m 1h 4h 1d 1w
 	if scale != "" {
+		if !validInfluxDuration.MatchString(scale) {
+			return &Points{}, fmt.Errorf("invalid scale %s", scale)
+		}
 		if filter == "VOL120" {
 			table = "a_year.filters_sum_"
 		} else {
@@ -79,11 +172,16 @@ func (datastore *DB) GetFilterPoints(filter string, exchange string, symbol stri
 		table = influxDbFiltersTable
 	}
 
-	q := fmt.Sprintf("SELECT time,exchange,filter,symbol,value FROM %s"+
-		" WHERE filter='%s' %sand address='%s' and blockchain='%s' and time>%d and time<%d ORDER BY DESC",
-		table, filter, exchangeQuery, topAsset.Address, topAsset.Blockchain, starttime.UnixNano(), endtime.UnixNano())
+	columns := "time,exchange,filter,symbol,value"
+	if withQuality && scale == "" {
+		columns += filterQualityColumns
+	}
+
+	q := fmt.Sprintf("SELECT %s FROM %s"+
+		" WHERE filter=%s %sand address=%s and blockchain=%s and time>%d and time<%d ORDER BY DESC",
+		columns, table, params.bind(filter), exchangeQuery, params.bind(topAsset.Address), params.bind(topAsset.Blockchain), starttime.UnixNano(), endtime.UnixNano())
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetFilterPoints", err)
 	}
@@ -95,11 +193,51 @@ func (datastore *DB) GetFilterPoints(filter string, exchange string, symbol stri
 	}, err
 }
 
+// topAssetOfSymbol returns the asset with @symbol with the largest volume.
+func topAssetOfSymbol(symbol string) (dia.Asset, error) {
+	relDB, err := NewRelDataStore()
+	if err != nil {
+		log.Errorln("NewDataStore:", err)
+	}
+	sortedAssets, err := relDB.GetTopAssetByVolume(symbol)
+	if err != nil {
+		log.Error(err)
+		return dia.Asset{}, err
+	}
+	if len(sortedAssets) == 0 {
+		return dia.Asset{}, errors.New("no traded assets found")
+	}
+	return sortedAssets[0], nil
+}
+
+// dailyFilterPoints returns the last of @values at @times in ascending order for each day intersecting
+// (@starttime,@endtime) in descending order. Days without values take the value of the previous day,
+// as done by GROUP BY time(1d) fill(previous).
+func dailyFilterPoints(times []time.Time, values []float64, starttime time.Time, endtime time.Time) []dia.FilterPoint {
+	var allFilters []dia.FilterPoint
+	day := 24 * time.Hour
+	last := make(map[int64]float64)
+	for i := range times {
+		last[floorTime(times[i], day).UnixNano()] = values[i]
+	}
+	var value float64
+	for t := floorTime(starttime, day); t.Before(endtime); t = t.Add(day) {
+		if v, ok := last[t.UnixNano()]; ok {
+			value = v
+		}
+		allFilters = append([]dia.FilterPoint{{Time: t, Value: value}}, allFilters...)
+	}
+	return allFilters
+}
+
 func (datastore *DB) GetFilter(filter string, topAsset dia.Asset, scale string, starttime time.Time, endtime time.Time) ([]dia.FilterPoint, error) {
 	var allFilters []dia.FilterPoint
 	table := ""
 	//	5m 30m 1h 4h 1d 1w
 	if scale != "" {
+		if !validInfluxDuration.MatchString(scale) {
+			return allFilters, fmt.Errorf("invalid scale %s", scale)
+		}
 		if filter == "VOL120" {
 			table = "a_year.filters_sum_"
 		} else {
@@ -110,11 +248,13 @@ func (datastore *DB) GetFilter(filter string, topAsset dia.Asset, scale string,
 		table = influxDbFiltersTable
 	}
 
-	q := fmt.Sprintf("SELECT last(*) FROM %s"+
-		" WHERE filter='%s' and address='%s' and blockchain='%s' and time>%d and time<%d and allExchanges=true group by time(1d) fill(previous) ORDER BY DESC",
-		table, filter, topAsset.Address, topAsset.Blockchain, starttime.UnixNano(), endtime.UnixNano())
+	// Only the value is selected, as the columns of last(*) depend on the quality fields present.
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT last(value) FROM %s"+
+		" WHERE filter=%s and address=%s and blockchain=%s and time>%d and time<%d and allExchanges=true group by time(1d) fill(previous) ORDER BY DESC",
+		table, params.bind(filter), params.bind(topAsset.Address), params.bind(topAsset.Blockchain), starttime.UnixNano(), endtime.UnixNano())
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetFilterPoints", err)
 	}
@@ -138,10 +278,10 @@ func (datastore *DB) GetFilter(filter string, topAsset dia.Asset, scale string,
 			// if res[0].Series[0].Values[i][3] != nil {
 			// 	filterpoint.Asset.Symbol = res[0].Series[0].Values[i][3].(string)
 			// }
-			if res[0].Series[0].Values[i][2] != nil {
-				filterpoint.Value, err = res[0].Series[0].Values[i][2].(json.Number).Float64()
+			if res[0].Series[0].Values[i][1] != nil {
+				filterpoint.Value, err = res[0].Series[0].Values[i][1].(json.Number).Float64()
 			} else {
-				log.Errorln("res[0].Series[0].Values[i][2]", res[0].Series[0].Values[i][2])
+				log.Errorln("res[0].Series[0].Values[i][1]", res[0].Series[0].Values[i][1])
 			}
 			if err != nil {
 				return allFilters, err
@@ -177,27 +317,24 @@ func getKeyFilterSymbolAndExchangeZSET(filter string, asset dia.Asset, exchange
 
 // SaveFilterInflux stores a filter point in influx.
 func (datastore *DB) SaveFilterInflux(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
-	// Create a point and add to batch
-	tags := map[string]string{
-		"filter":     filter,
-		"symbol":     asset.Symbol,
-		"address":    asset.Address,
-		"blockchain": asset.Blockchain,
-		"exchange":   exchange,
-	}
-	fields := map[string]interface{}{
-		"value":        value,
-		"allExchanges": exchange == "",
-	}
-	pt, err := clientInfluxdb.NewPoint(influxDbFiltersTable, tags, fields, t)
+	pt, err := filterPoint(filter, asset, exchange, value, t)
 	if err != nil {
 		log.Errorln("new filter influx:", err)
 	} else {
-		datastore.addPoint(pt)
+		err = datastore.addPoint(pt)
 	}
 	return err
 }
 
+// filterPoint returns the influx point of @value of @filter.
+func filterPoint(filter string, asset dia.Asset, exchange string, value float64, t time.Time) (*clientInfluxdb.Point, error) {
+	fields := map[string]interface{}{
+		"value":        value,
+		"allExchanges": exchange == "",
+	}
+	return clientInfluxdb.NewPoint(influxDbFiltersTable, filterTags(filter, asset, exchange), fields, t)
+}
+
 func (datastore *DB) setZSETValue(key string, value float64, unixTime int64, maxWindow int64) error {
 	if datastore.redisClient == nil {
 		return nil
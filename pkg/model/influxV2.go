@@ -0,0 +1,561 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia/helpers/db"
+	influxModels "github.com/influxdata/influxdb1-client/models"
+	clientInfluxdb "github.com/influxdata/influxdb1-client/v2"
+)
+
+const (
+	influxDBV2DefaultURL = "http://influxdb2:8086"
+	influxV2Timeout      = 5 * time.Minute
+)
+
+// InfluxV2DB is a Datastore writing trades, filters, quotations, supplies and pools to a bucket of
+// influx 2.x and querying them with Flux, so that a deployment can run on influx 2.x and redis alone.
+// Redis is served by the embedded DB. The remaining time series, such as candles, rates and foreign
+// quotations, require influx 1.x. Without influx client, their writers return errNoInflux.
+type InfluxV2DB struct {
+	*DB
+	client *influxV2Client
+	batch  []*clientInfluxdb.Point
+	// batchLock makes adding points to and writing the batch safe for concurrent use.
+	batchLock sync.Mutex
+}
+
+var _ Datastore = (*InfluxV2DB)(nil)
+
+// NewInfluxV2DataStore returns a datastore writing to the influx 2.x bucket given by the environment.
+// Redis is connected to as done by NewDataStoreWithoutInflux.
+func NewInfluxV2DataStore() (*InfluxV2DB, error) {
+	datastore, err := NewDataStoreWithoutInflux()
+	if err != nil {
+		return nil, err
+	}
+	return &InfluxV2DB{
+		DB:     datastore,
+		client: newInfluxV2Client(db.GetInfluxV2Config(influxDBV2DefaultURL, influxDbName)),
+	}, nil
+}
+
+// SetInfluxClient resets the url of influx 2.x to @url.
+func (datastore *InfluxV2DB) SetInfluxClient(url string) {
+	datastore.client.url = strings.TrimSuffix(url, "/")
+}
+
+// Flush writes the batch of influx 2.x and the batch of the embedded DB.
+func (datastore *InfluxV2DB) Flush() error {
+	if err := datastore.WriteBatchInflux(); err != nil {
+		return err
+	}
+	return datastore.DB.Flush()
+}
+
+// WriteBatchInflux writes the batch of influx 2.x.
+func (datastore *InfluxV2DB) WriteBatchInflux() error {
+	datastore.batchLock.Lock()
+	defer datastore.batchLock.Unlock()
+	return datastore.writeBatch()
+}
+
+// writeBatch writes the batch. The caller must hold batchLock.
+func (datastore *InfluxV2DB) writeBatch() error {
+	if len(datastore.batch) == 0 {
+		return nil
+	}
+	err := datastore.client.write(datastore.client.bucket, datastore.batch)
+	if err != nil {
+		log.Errorln("WriteBatchInflux", err)
+		return err
+	}
+	datastore.batch = nil
+	return nil
+}
+
+// addPoint adds @pt to the batch, which is written once it is full.
+func (datastore *InfluxV2DB) addPoint(pt *clientInfluxdb.Point) {
+	datastore.batchLock.Lock()
+	defer datastore.batchLock.Unlock()
+	datastore.batch = append(datastore.batch, pt)
+	if len(datastore.batch) >= influxMaxPointsInBatch {
+		if err := datastore.writeBatch(); err != nil {
+			log.Error("write influx batch: ", err)
+		}
+	}
+}
+
+// CopyInfluxMeasurements copies the points of measurement @tableOrigin in bucket @dbOrigin in (@timeInit,@timeFinal]
+// into @tableDestination in bucket @dbDestination. It returns the number of copied points.
+func (datastore *InfluxV2DB) CopyInfluxMeasurements(dbOrigin string, dbDestination string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (int64, error) {
+	q := fluxQuery{
+		bucket:      dbOrigin,
+		measurement: tableOrigin,
+		start:       timeInit.Add(time.Nanosecond),
+		stop:        timeFinal.Add(time.Nanosecond),
+	}
+	// The points are counted by their timestamps after pivoting the written fields.
+	flux := q.from() +
+		"\n  |> set(key: \"_measurement\", value: " + fluxString(tableDestination) + ")" +
+		"\n  |> to(bucket: " + fluxString(dbDestination) + ", org: " + fluxString(datastore.client.org) + ")" +
+		"\n  |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")" +
+		"\n  |> group()" +
+		"\n  |> count(column: \"_time\")"
+	records, err := datastore.client.query(flux)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	numCopiedRows, _ := records[0].float("_time")
+	return int64(numCopiedRows), nil
+}
+
+// MigrateInfluxMeasurements copies the points of measurement @tableOrigin in database @dbOrigin of influx 1.x in
+// (@timeInit,@timeFinal] into @tableDestination in the bucket of influx 2.x. It selects the points as done by
+// DB.CopyInfluxMeasurements and keeps the types of their fields. It returns the number of copied points.
+func (datastore *InfluxV2DB) MigrateInfluxMeasurements(dbOrigin string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (numCopiedRows int64, err error) {
+	fieldTypes, err := influxFieldTypes(datastore.DB.influxClient, dbOrigin, tableOrigin)
+	if err != nil {
+		return
+	}
+	queryString := "select * from %s..%s where time>%d and time<=%d group by *"
+	query := fmt.Sprintf(queryString, influxIdentifier(dbOrigin), influxIdentifier(tableOrigin), timeInit.UnixNano(), timeFinal.UnixNano())
+	res, err := queryInfluxDBName(datastore.DB.influxClient, dbOrigin, query)
+	if err != nil || len(res) == 0 {
+		return
+	}
+
+	var points []*clientInfluxdb.Point
+	for _, series := range res[0].Series {
+		tags := make(map[string]string)
+		for key, value := range series.Tags {
+			if value != "" {
+				tags[key] = value
+			}
+		}
+		for _, values := range series.Values {
+			var t time.Time
+			t, err = time.Parse(time.RFC3339Nano, values[0].(string))
+			if err != nil {
+				return
+			}
+			fields := make(map[string]interface{})
+			for i := 1; i < len(series.Columns) && i < len(values); i++ {
+				var value interface{}
+				value, err = migratedFieldValue(fieldTypes[series.Columns[i]], values[i])
+				if err != nil {
+					return
+				}
+				if value != nil {
+					fields[series.Columns[i]] = value
+				}
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			var pt *clientInfluxdb.Point
+			pt, err = clientInfluxdb.NewPoint(tableDestination, tags, fields, t)
+			if err != nil {
+				return
+			}
+			points = append(points, pt)
+			if len(points) == influxMaxPointsInBatch {
+				if err = datastore.client.write(datastore.client.bucket, points); err != nil {
+					return
+				}
+				numCopiedRows += int64(len(points))
+				points = nil
+			}
+		}
+	}
+	if len(points) > 0 {
+		if err = datastore.client.write(datastore.client.bucket, points); err != nil {
+			return
+		}
+		numCopiedRows += int64(len(points))
+	}
+	return
+}
+
+// influxFieldTypes returns the types of the fields of measurement @table in database @dbName of influx 1.x.
+func influxFieldTypes(clnt clientInfluxdb.Client, dbName string, table string) (map[string]string, error) {
+	fieldTypes := make(map[string]string)
+	res, err := queryInfluxDBName(clnt, dbName, fmt.Sprintf("SHOW FIELD KEYS FROM %s", influxIdentifier(table)))
+	if err != nil {
+		return fieldTypes, err
+	}
+	if len(res) > 0 && len(res[0].Series) > 0 {
+		for _, row := range res[0].Series[0].Values {
+			fieldTypes[row[0].(string)] = row[1].(string)
+		}
+	}
+	return fieldTypes, nil
+}
+
+// migratedFieldValue converts @value as returned by an InfluxQL query to a field value of type @fieldType.
+func migratedFieldValue(fieldType string, value interface{}) (interface{}, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return value, nil
+	}
+	if fieldType == "integer" {
+		return number.Int64()
+	}
+	return number.Float64()
+}
+
+// influxV2Client is a client of the HTTP API of influx 2.x.
+type influxV2Client struct {
+	url        string
+	org        string
+	bucket     string
+	token      string
+	httpClient *http.Client
+}
+
+func newInfluxV2Client(config db.InfluxV2Config) *influxV2Client {
+	return &influxV2Client{
+		url:        strings.TrimSuffix(config.URL, "/"),
+		org:        config.Org,
+		bucket:     config.Bucket,
+		token:      config.Token,
+		httpClient: &http.Client{Timeout: influxV2Timeout},
+	}
+}
+
+// write writes @points to @bucket in line protocol.
+func (client *influxV2Client) write(bucket string, points []*clientInfluxdb.Point) error {
+	var body bytes.Buffer
+	for _, pt := range points {
+		body.WriteString(pt.String())
+		body.WriteByte('\n')
+	}
+	params := url.Values{"org": {client.org}, "bucket": {bucket}, "precision": {"ns"}}
+	return client.do("/api/v2/write", params, "text/plain; charset=utf-8", &body, nil)
+}
+
+// query runs the Flux script @flux and returns the records of all resulting tables.
+func (client *influxV2Client) query(flux string) ([]fluxRecord, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": flux,
+		"type":  "flux",
+		"dialect": map[string]interface{}{
+			"header":      true,
+			"delimiter":   ",",
+			"annotations": []string{"datatype", "default"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var records []fluxRecord
+	err = client.do("/api/v2/query", url.Values{"org": {client.org}}, "application/json", bytes.NewReader(body), func(r io.Reader) error {
+		records, err = parseFluxCSV(r)
+		return err
+	})
+	return records, err
+}
+
+// delete removes the points of the bucket in [@start,@stop] matching the delete predicate @predicate.
+func (client *influxV2Client) delete(start time.Time, stop time.Time, predicate string) error {
+	body, err := json.Marshal(map[string]string{
+		"start":     start.UTC().Format(time.RFC3339Nano),
+		"stop":      stop.UTC().Format(time.RFC3339Nano),
+		"predicate": predicate,
+	})
+	if err != nil {
+		return err
+	}
+	params := url.Values{"org": {client.org}, "bucket": {client.bucket}}
+	return client.do("/api/v2/delete", params, "application/json", bytes.NewReader(body), nil)
+}
+
+// do posts @body to @path and passes the response body to @read if given.
+func (client *influxV2Client) do(path string, params url.Values, contentType string, body io.Reader, read func(io.Reader) error) error {
+	req, err := http.NewRequest(http.MethodPost, client.url+path+"?"+params.Encode(), body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if client.token != "" {
+		req.Header.Set("Authorization", "Token "+client.token)
+	}
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			log.Error("close influx response: ", cerr)
+		}
+	}()
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("influx %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if read == nil {
+		return nil
+	}
+	return read(resp.Body)
+}
+
+// fluxRecord is a row of a table returned by a Flux query, mapping columns to their values.
+// Null values are nil.
+type fluxRecord map[string]interface{}
+
+func (r fluxRecord) time() time.Time {
+	t, _ := r["_time"].(time.Time)
+	return t
+}
+
+func (r fluxRecord) str(column string) string {
+	s, _ := r[column].(string)
+	return s
+}
+
+func (r fluxRecord) float(column string) (float64, bool) {
+	switch v := r[column].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// row returns the values of @columns as returned by influx 1.x queries. Missing values are nil.
+func (r fluxRecord) row(columns []string) []interface{} {
+	row := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if column == "time" {
+			column = "_time"
+		}
+		row[i] = influxValue(r[column])
+	}
+	return row
+}
+
+// influxValue returns @v as returned by influx 1.x queries, where times are strings in RFC3339 and
+// numbers are json.Number.
+func influxValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10))
+	case int32:
+		return json.Number(strconv.FormatInt(int64(v), 10))
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10))
+	}
+	return v
+}
+
+// fluxResult returns @records as result of an influx 1.x query of @columns on @measurement,
+// so that callers of both backends can parse it alike.
+func fluxResult(measurement string, columns []string, records []fluxRecord) []clientInfluxdb.Result {
+	result := clientInfluxdb.Result{}
+	if len(records) > 0 {
+		row := influxModels.Row{Name: measurement, Columns: columns}
+		for _, r := range records {
+			row.Values = append(row.Values, r.row(columns))
+		}
+		result.Series = []influxModels.Row{row}
+	}
+	return []clientInfluxdb.Result{result}
+}
+
+// parseFluxCSV parses the annotated CSV returned by Flux queries. Values are converted
+// according to the datatype annotations of their tables.
+func parseFluxCSV(r io.Reader) ([]fluxRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	var records []fluxRecord
+	var datatypes, defaults, header []string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case row[0] == "#datatype":
+			// Each table with a new schema starts with its annotations and header.
+			datatypes, defaults, header = row, nil, nil
+		case row[0] == "#default":
+			defaults = row
+		case strings.HasPrefix(row[0], "#"):
+		case header == nil:
+			header = row
+		default:
+			record := make(fluxRecord, len(header))
+			for i := 1; i < len(header) && i < len(row); i++ {
+				value := row[i]
+				if value == "" && i < len(defaults) {
+					value = defaults[i]
+				}
+				datatype := "string"
+				if i < len(datatypes) {
+					datatype = datatypes[i]
+				}
+				record[header[i]], err = fluxValue(datatype, value)
+				if err != nil {
+					return nil, err
+				}
+			}
+			// Errors during the execution of a query are returned as a table of their own.
+			if len(header) == 3 && header[1] == "error" && header[2] == "reference" {
+				return nil, errors.New("flux: " + record.str("error"))
+			}
+			records = append(records, record)
+		}
+	}
+}
+
+// fluxValue converts the CSV encoded @value of type @datatype. Empty values are null.
+func fluxValue(datatype string, value string) (interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	switch datatype {
+	case "double":
+		return strconv.ParseFloat(value, 64)
+	case "long":
+		return strconv.ParseInt(value, 10, 64)
+	case "unsignedLong":
+		return strconv.ParseUint(value, 10, 64)
+	case "boolean":
+		return value == "true", nil
+	case "dateTime:RFC3339", "dateTime:RFC3339Nano":
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
+
+var fluxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`)
+
+// fluxString returns @s as Flux string literal. Quotes, backslashes and interpolations are escaped,
+// so that values can't alter the query.
+func fluxString(s string) string {
+	return `"` + fluxStringEscaper.Replace(s) + `"`
+}
+
+// fluxTime returns @t as Flux time literal. Times before the unix epoch are mapped to the epoch.
+func fluxTime(t time.Time) string {
+	if t.Before(time.Unix(0, 0)) {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// fluxDuration returns @d as Flux duration literal.
+func fluxDuration(d time.Duration) string {
+	return strconv.FormatInt(int64(d), 10) + "ns"
+}
+
+// fluxEqual returns the predicate that column @column of a record equals @value. As empty tags are
+// not written, an empty @value also matches records without the column.
+func fluxEqual(column string, value string) string {
+	c := "r[" + fluxString(column) + "]"
+	if value == "" {
+		return "(not exists " + c + " or " + c + " == \"\")"
+	}
+	return c + " == " + fluxString(value)
+}
+
+// fluxAnd returns the conjunction of @predicates.
+func fluxAnd(predicates ...string) string {
+	return "(" + strings.Join(predicates, " and ") + ")"
+}
+
+// fluxOr returns the disjunction of @predicates.
+func fluxOr(predicates ...string) string {
+	return "(" + strings.Join(predicates, " or ") + ")"
+}
+
+// fluxTimeIn returns the predicate that the time of a record is in (@start,@end].
+func fluxTimeIn(start time.Time, end time.Time) string {
+	return "(r._time > " + fluxTime(start) + " and r._time <= " + fluxTime(end) + ")"
+}
+
+// fluxQuery selects points of a measurement as records holding the tags and all fields of a point,
+// as returned by InfluxQL.
+type fluxQuery struct {
+	bucket      string
+	measurement string
+	// start and stop are the begin and the exclusive end of the time range.
+	// A zero stop stands for now.
+	start time.Time
+	stop  time.Time
+	// fields restricts the queried fields if given.
+	fields []string
+	// where are predicates on tags applied before pivoting.
+	where []string
+	// having are predicates on fields applied after pivoting.
+	having []string
+	// last selects the latest point of each series. It requires a single field.
+	last  bool
+	desc  bool
+	limit int
+}
+
+// from returns the script selecting the points in the time range, before pivoting.
+func (q fluxQuery) from() string {
+	stop := "now()"
+	if !q.stop.IsZero() {
+		stop = fluxTime(q.stop)
+	}
+	predicates := append([]string{"r._measurement == " + fluxString(q.measurement)}, q.where...)
+	if len(q.fields) > 0 {
+		var fields []string
+		for _, field := range q.fields {
+			fields = append(fields, "r._field == "+fluxString(field))
+		}
+		predicates = append(predicates, fluxOr(fields...))
+	}
+	return "from(bucket: " + fluxString(q.bucket) + ")" +
+		"\n  |> range(start: " + fluxTime(q.start) + ", stop: " + stop + ")" +
+		"\n  |> filter(fn: (r) => " + strings.Join(predicates, " and ") + ")"
+}
+
+// String returns the script selecting the pivoted records in the order of time.
+func (q fluxQuery) String() string {
+	flux := q.from()
+	if q.last {
+		flux += "\n  |> last()"
+	}
+	flux += "\n  |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")" +
+		"\n  |> group()"
+	if len(q.having) > 0 {
+		flux += "\n  |> filter(fn: (r) => " + strings.Join(q.having, " and ") + ")"
+	}
+	flux += "\n  |> sort(columns: [\"_time\"], desc: " + strconv.FormatBool(q.desc) + ")"
+	if q.limit > 0 {
+		flux += "\n  |> limit(n: " + strconv.Itoa(q.limit) + ")"
+	}
+	return flux
+}
+
+// query runs @q on the bucket of the datastore.
+func (datastore *InfluxV2DB) query(q fluxQuery) ([]fluxRecord, error) {
+	q.bucket = datastore.client.bucket
+	return datastore.client.query(q.String())
+}
@@ -0,0 +1,294 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers"
+)
+
+// SetFilter adds a filter point to the batch and its value to the sorted set in redis.
+func (datastore *InfluxV2DB) SetFilter(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	err := datastore.SaveFilterInflux(filter, asset, exchange, value, t)
+	if err != nil {
+		return err
+	}
+	return datastore.setZSETValue(getKeyFilterZSET(getKey(filter, asset, exchange)), value, t.Unix(), BiggestWindow)
+}
+
+// SaveFilterInflux adds a filter point to the batch.
+func (datastore *InfluxV2DB) SaveFilterInflux(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	pt, err := filterPoint(filter, asset, exchange, value, t)
+	if err != nil {
+		log.Errorln("new filter influx:", err)
+		return err
+	}
+	datastore.addPoint(pt)
+	return nil
+}
+
+// SetFilterQuality adds @quality of the point of filter @filter at @t to the batch. It is written
+// to the same point as the filter's value, so it must be called with the time given to SetFilter.
+func (datastore *InfluxV2DB) SetFilterQuality(filter string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	pt, err := filterQualityPoint(filter, asset, exchange, quality, t)
+	if err != nil {
+		log.Errorln("new filter quality influx:", err)
+		return err
+	}
+	datastore.addPoint(pt)
+	return nil
+}
+
+var deleteStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// deleteEqual returns the delete predicate that tag @tag equals @value. As in InfluxQL, an empty
+// @value matches points without the tag.
+func deleteEqual(tag string, value string) string {
+	return tag + `="` + deleteStringEscaper.Replace(value) + `"`
+}
+
+// DeleteFilterPoints removes all filter points of @asset on @exchange in [@starttime,@endtime]
+// and the values of @filters in this range from redis.
+// It is used when filters are recomputed for an amended tradesBlock.
+func (datastore *InfluxV2DB) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	predicate := strings.Join([]string{
+		deleteEqual("_measurement", influxDbFiltersTable),
+		deleteEqual("address", asset.Address),
+		deleteEqual("blockchain", asset.Blockchain),
+		deleteEqual("exchange", exchange),
+	}, " AND ")
+	err := datastore.client.delete(starttime, endtime, predicate)
+	if err != nil {
+		return err
+	}
+	return datastore.deleteFilterZSETValues(filters, asset, exchange, starttime, endtime)
+}
+
+// GetFilterPointsAsset returns the points of @filter for an asset on @exchange in (@starttime,@endtime] in
+// descending order, where an empty @exchange stands for all exchanges.
+// If @withQuality is true, the points' quality is returned as well.
+func (datastore *InfluxV2DB) GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	records, err := datastore.query(fluxQuery{
+		measurement: influxDbFiltersTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime.Add(time.Nanosecond),
+		where: []string{
+			fluxEqual("filter", filter),
+			fluxEqual("exchange", exchange),
+			fluxEqual("address", address),
+			fluxEqual("blockchain", blockchain),
+		},
+		desc: true,
+	})
+	if err != nil {
+		log.Errorln("GetFilterPoints", err)
+		return &Points{}, err
+	}
+	columns := filterColumns("time,address,blockchain,exchange,filter,symbol,value", withQuality)
+	return &Points{DataPoints: fluxResult(influxDbFiltersTable, columns, records)}, nil
+}
+
+// downsampleFilter returns the script of @q downsampling the values of filter @filter to intervals of length
+// @interval aligned to the unix epoch. The points' time is the begin of their interval. As done by the
+// continuous queries of influx 1.x, the volume filter is summed up and all other filters are averaged.
+func downsampleFilter(q fluxQuery, filter string, interval time.Duration) string {
+	fn := "mean"
+	if filter == volumeKey {
+		fn = "sum"
+	}
+	q.start = floorTime(q.start, interval)
+	q.fields = []string{"value"}
+	return q.from() +
+		"\n  |> aggregateWindow(every: " + fluxDuration(interval) + ", fn: " + fn + ", timeSrc: \"_start\")" +
+		"\n  |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")" +
+		"\n  |> group()"
+}
+
+// GetFilterPoints returns the points of @filter in (@starttime,@endtime) in descending order from either a
+// specific exchange or all exchanges. @symbol is mapped to the asset with the largest volume. If @scale is given,
+// points are downsampled to intervals of length @scale. Downsampled points have no quality.
+func (datastore *InfluxV2DB) GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	topAsset, err := topAssetOfSymbol(symbol)
+	if err != nil {
+		return &Points{}, err
+	}
+	q := fluxQuery{
+		bucket:      datastore.client.bucket,
+		measurement: influxDbFiltersTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime,
+		where: []string{
+			fluxEqual("filter", filter),
+			fluxEqual("exchange", exchange),
+			fluxEqual("address", topAsset.Address),
+			fluxEqual("blockchain", topAsset.Blockchain),
+		},
+		desc: true,
+	}
+	table := influxDbFiltersTable
+	flux := q.String()
+	if scale != "" {
+		interval, err := parseInfluxDuration(scale)
+		if err != nil {
+			return &Points{}, err
+		}
+		table = "filters_mean_" + scale
+		if filter == volumeKey {
+			table = "filters_sum_" + scale
+		}
+		flux = downsampleFilter(q, filter, interval) +
+			"\n  |> filter(fn: (r) => r._time > " + fluxTime(starttime) + ")" +
+			"\n  |> sort(columns: [\"_time\"], desc: true)"
+		withQuality = false
+	}
+	records, err := datastore.client.query(flux)
+	if err != nil {
+		log.Errorln("GetFilterPoints", err)
+		return &Points{}, err
+	}
+	columns := filterColumns("time,exchange,filter,symbol,value", withQuality)
+	return &Points{DataPoints: fluxResult(table, columns, records)}, nil
+}
+
+// GetFilter returns the last point of @filter across all exchanges for each day in (@starttime,@endtime) in
+// descending order. Days without points take the value of the previous day. If @scale is given, points are
+// downsampled to intervals of length @scale first.
+func (datastore *InfluxV2DB) GetFilter(filter string, topAsset dia.Asset, scale string, starttime time.Time, endtime time.Time) ([]dia.FilterPoint, error) {
+	q := fluxQuery{
+		bucket:      datastore.client.bucket,
+		measurement: influxDbFiltersTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime,
+		fields:      []string{"value"},
+		where: []string{
+			fluxEqual("filter", filter),
+			fluxEqual("address", topAsset.Address),
+			fluxEqual("blockchain", topAsset.Blockchain),
+			fluxEqual("exchange", ""),
+		},
+	}
+	flux := q.String()
+	if scale != "" {
+		interval, err := parseInfluxDuration(scale)
+		if err != nil {
+			return nil, err
+		}
+		flux = downsampleFilter(q, filter, interval) +
+			"\n  |> filter(fn: (r) => r._time > " + fluxTime(starttime) + ")" +
+			"\n  |> sort(columns: [\"_time\"])"
+	}
+	records, err := datastore.client.query(flux)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no filter points in time range")
+	}
+	var times []time.Time
+	var values []float64
+	for _, r := range records {
+		value, _ := r.float("value")
+		times = append(times, r.time())
+		values = append(values, value)
+	}
+	return dailyFilterPoints(times, values, starttime, endtime), nil
+}
+
+// GetLastPriceBefore returns the first point of @filter on @exchange after @timestamp, as done by DB.
+func (datastore *InfluxV2DB) GetLastPriceBefore(asset dia.Asset, filter string, exchange string, timestamp time.Time) (Price, error) {
+	records, err := datastore.query(fluxQuery{
+		measurement: influxDbFiltersTable,
+		start:       timestamp.Add(time.Nanosecond),
+		fields:      []string{"value"},
+		where: []string{
+			fluxEqual("filter", filter),
+			fluxEqual("address", asset.Address),
+			fluxEqual("blockchain", asset.Blockchain),
+			fluxEqual("exchange", exchange),
+		},
+		limit: 1,
+	})
+	price := Price{Symbol: asset.Symbol, Name: helpers.NameForSymbol(asset.Symbol)}
+	if err != nil {
+		log.Errorln("GetLastFilterPointBefore", err)
+		return price, err
+	}
+	if len(records) > 0 {
+		price.Time = records[0].time()
+		price.Price, _ = records[0].float("value")
+	}
+	return price, nil
+}
+
+// volumeQuery returns the query of the volume filter's points of @asset on @exchange in (@starttime,@endtime].
+// All assets are queried if @asset is empty.
+func (datastore *InfluxV2DB) volumeQuery(asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) fluxQuery {
+	q := fluxQuery{
+		bucket:      datastore.client.bucket,
+		measurement: influxDbFiltersTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime.Add(time.Nanosecond),
+		fields:      []string{"value"},
+		where:       []string{fluxEqual("filter", volumeKey), fluxEqual("exchange", exchange)},
+	}
+	if asset != (dia.Asset{}) {
+		q.where = append(q.where, fluxEqual("address", asset.Address), fluxEqual("blockchain", asset.Blockchain))
+	}
+	return q
+}
+
+// GetVolumeInflux returns the volume of @asset on @exchange using the VOL120 filter in the given time-range.
+// Both, @asset and @exchange may be empty.
+// If @starttime,@endtime are empty, the last 24h are taken into account.
+func (datastore *InfluxV2DB) GetVolumeInflux(asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) (*float64, error) {
+	if endtime.IsZero() {
+		endtime = time.Now()
+		starttime = endtime.AddDate(0, 0, -1)
+	}
+	q := datastore.volumeQuery(asset, exchange, starttime, endtime)
+	records, err := datastore.client.query(q.from() + "\n  |> group()\n  |> sum()")
+	if err != nil {
+		log.Errorln("GetVolumeInflux ", err)
+		return nil, err
+	}
+	var volume float64
+	if len(records) > 0 {
+		volume, _ = records[0].float("_value")
+	}
+	return &volume, nil
+}
+
+// Get24HoursAssetVolume returns the 24h trading volume of @asset across exchanges.
+func (datastore *InfluxV2DB) Get24HoursAssetVolume(asset dia.Asset) (*float64, error) {
+	endtime := time.Now()
+	return datastore.GetVolumeInflux(asset, "", endtime.AddDate(0, 0, -1), endtime)
+}
+
+// Get24HoursExchangeVolume returns 24h trade volume on @exchange using the VOL120 filter.
+func (datastore *InfluxV2DB) Get24HoursExchangeVolume(exchange string) (*float64, error) {
+	endtime := time.Now()
+	return datastore.GetVolumeInflux(dia.Asset{}, exchange, endtime.AddDate(0, 0, -1), endtime)
+}
+
+// GetAssetsWithVOLInflux returns all assets with points of the volume filter across exchanges since @timeInit.
+func (datastore *InfluxV2DB) GetAssetsWithVOLInflux(timeInit time.Time) ([]dia.Asset, error) {
+	var quotedAssets []dia.Asset
+	q := datastore.volumeQuery(dia.Asset{}, "", timeInit, time.Now())
+	records, err := datastore.client.query(q.from() +
+		"\n  |> group(columns: [\"address\", \"blockchain\"])" +
+		"\n  |> first()" +
+		"\n  |> group()" +
+		"\n  |> sort(columns: [\"_time\"])")
+	if err != nil {
+		return quotedAssets, err
+	}
+	for _, r := range records {
+		quotedAssets = append(quotedAssets, dia.Asset{Address: r.str("address"), Blockchain: r.str("blockchain")})
+	}
+	if len(quotedAssets) == 0 {
+		return quotedAssets, errors.New("no recent asset with volume in influx")
+	}
+	return quotedAssets, nil
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// SetAssetPriceUSD stores the price of @asset in influx and the caching layer.
+func (datastore *InfluxV2DB) SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error {
+	return datastore.SetAssetQuotation(&AssetQuotation{
+		Asset:  asset,
+		Price:  price,
+		Source: dia.Diadata,
+		Time:   timestamp,
+	})
+}
+
+// GetAssetPriceUSDLatest returns the latest price of @asset.
+func (datastore *InfluxV2DB) GetAssetPriceUSDLatest(asset dia.Asset) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotationLatest(asset)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// GetAssetPriceUSD returns the latest USD price of @asset before @timestamp.
+func (datastore *InfluxV2DB) GetAssetPriceUSD(asset dia.Asset, timestamp time.Time) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotation(asset, timestamp)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// AddAssetQuotationsToBatch adds @quotations to the batch.
+func (datastore *InfluxV2DB) AddAssetQuotationsToBatch(quotations []*AssetQuotation) error {
+	for _, quotation := range quotations {
+		pt, err := assetQuotationPoint(quotation)
+		if err != nil {
+			log.Errorln("addAssetQuotationsToBatch:", err)
+			return err
+		}
+		datastore.addPoint(pt)
+	}
+	return nil
+}
+
+// SetAssetQuotation adds @quotation to the batch and writes it to the cache.
+func (datastore *InfluxV2DB) SetAssetQuotation(quotation *AssetQuotation) error {
+	pt, err := assetQuotationPoint(quotation)
+	if err != nil {
+		log.Errorln("SetAssetQuotation:", err)
+	} else {
+		datastore.addPoint(pt)
+	}
+	_, err = datastore.SetAssetQuotationCache(quotation, false)
+	return err
+}
+
+// GetAssetQuotationLatest returns the latest quotation of @asset from the cache or, if not cached, from influx.
+func (datastore *InfluxV2DB) GetAssetQuotationLatest(asset dia.Asset) (*AssetQuotation, error) {
+	quotation, err := datastore.GetAssetQuotationCache(asset)
+	if err == nil {
+		return quotation, nil
+	}
+	return datastore.GetAssetQuotation(asset, time.Now())
+}
+
+// assetQuotationsQuery returns the query of the quotations of @asset in [@start,@stop).
+func assetQuotationsQuery(asset dia.Asset, start time.Time, stop time.Time) fluxQuery {
+	return fluxQuery{
+		measurement: influxDBAssetQuotationsTable,
+		start:       start,
+		stop:        stop,
+		fields:      []string{"price"},
+		where:       []string{fluxEqual("address", asset.Address), fluxEqual("blockchain", asset.Blockchain)},
+		desc:        true,
+	}
+}
+
+// fluxAssetQuotation returns the quotation of @asset stored in @r.
+func fluxAssetQuotation(r fluxRecord, asset dia.Asset) AssetQuotation {
+	quotation := AssetQuotation{Asset: asset, Source: dia.Diadata, Time: r.time()}
+	quotation.Price, _ = r.float("price")
+	return quotation
+}
+
+// GetAssetQuotation returns the latest quotation of @asset until @timestamp.
+func (datastore *InfluxV2DB) GetAssetQuotation(asset dia.Asset, timestamp time.Time) (*AssetQuotation, error) {
+	q := assetQuotationsQuery(asset, time.Time{}, timestamp.Add(time.Nanosecond))
+	q.last = true
+	q.limit = 1
+	records, err := datastore.query(q)
+	if err != nil {
+		return &AssetQuotation{}, err
+	}
+	if len(records) == 0 {
+		return &AssetQuotation{}, errors.New("no assetQuotation in DB")
+	}
+	quotation := fluxAssetQuotation(records[0], asset)
+	return &quotation, nil
+}
+
+// GetAssetQuotations returns all quotations of @asset in (@starttime,@endtime] in descending order.
+func (datastore *InfluxV2DB) GetAssetQuotations(asset dia.Asset, starttime time.Time, endtime time.Time) ([]AssetQuotation, error) {
+	quotations := []AssetQuotation{}
+	records, err := datastore.query(assetQuotationsQuery(asset, starttime.Add(time.Nanosecond), endtime.Add(time.Nanosecond)))
+	if err != nil {
+		return quotations, err
+	}
+	if len(records) == 0 {
+		return quotations, errors.New("no assetQuotation in DB")
+	}
+	for _, r := range records {
+		quotations = append(quotations, fluxAssetQuotation(r, asset))
+	}
+	return quotations, nil
+}
+
+// GetSortedAssetQuotations returns quotations for all assets in @assets, sorted by 24h volume
+// in descending order.
+func (datastore *InfluxV2DB) GetSortedAssetQuotations(assets []dia.Asset) ([]AssetQuotation, error) {
+	return sortedAssetQuotations(datastore, assets)
+}
+
+// GetAssetsMarketCap returns the actual market cap of @asset.
+func (datastore *InfluxV2DB) GetAssetsMarketCap(asset dia.Asset) (float64, error) {
+	price, err := datastore.GetAssetPriceUSDLatest(asset)
+	if err != nil {
+		return 0, err
+	}
+	supply, err := datastore.GetSupplyCache(asset)
+	if err != nil {
+		return 0, err
+	}
+	return price * supply.CirculatingSupply, nil
+}
+
+// GetTopAssetByVolume returns the asset with highest volume among all assets with symbol @symbol.
+func (datastore *InfluxV2DB) GetTopAssetByVolume(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	return topAssetByVolume(datastore, symbol, relDB)
+}
+
+// GetTopAssetByMcap returns the asset with highest market cap among all assets with symbol @symbol.
+func (datastore *InfluxV2DB) GetTopAssetByMcap(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	return topAssetByMcap(datastore, symbol, relDB)
+}
+
+// SetBatchFiatPriceInflux writes @fiatQuotations to influx.
+func (datastore *InfluxV2DB) SetBatchFiatPriceInflux(fiatQuotations []*FiatQuotation) error {
+	for _, fq := range fiatQuotations {
+		pt, err := fiatQuotationPoint(fq)
+		if err != nil {
+			log.Printf("Error: %v on NewPoint %v\n", err, fq.BaseCurrency)
+			continue
+		}
+		datastore.addPoint(pt)
+	}
+	err := datastore.WriteBatchInflux()
+	if err != nil {
+		log.Printf("Error on WriteBatchInflux: %v\n", err)
+	}
+	return err
+}
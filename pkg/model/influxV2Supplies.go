@@ -0,0 +1,223 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// SaveSupplyInflux writes @supply to influx.
+func (datastore *InfluxV2DB) SaveSupplyInflux(supply *dia.Supply) error {
+	pt, err := supplyPoint(supply)
+	if err != nil {
+		log.Errorln("NewSupplyInflux:", err)
+		return err
+	}
+	datastore.addPoint(pt)
+	err = datastore.WriteBatchInflux()
+	if err != nil {
+		log.Errorln("SaveSupplyInflux", err)
+	}
+	return err
+}
+
+// GetSupplyInflux returns supply and circulating supply of @asset in (@starttime,@endtime) in descending order.
+// If no time range is given it returns the latest supply.
+func (datastore *InfluxV2DB) GetSupplyInflux(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.Supply, error) {
+	retval := []dia.Supply{}
+	q := fluxQuery{
+		measurement: influxDbSupplyTable,
+		where:       []string{fluxEqual("address", asset.Address), fluxEqual("blockchain", asset.Blockchain)},
+		desc:        true,
+	}
+	if starttime.IsZero() || endtime.IsZero() {
+		q.limit = 1
+	} else {
+		q.start = starttime.Add(time.Nanosecond)
+		q.stop = endtime
+	}
+	records, err := datastore.query(q)
+	if err != nil {
+		return retval, err
+	}
+	if len(records) == 0 {
+		return retval, errors.New("parsing supply value from database")
+	}
+	for _, r := range records {
+		currentSupply := dia.Supply{Asset: asset, Time: r.time(), Source: r.str("source")}
+		currentSupply.Supply, _ = r.float("supply")
+		currentSupply.CirculatingSupply, _ = r.float("circulatingsupply")
+		if name := r.str("name"); name != "" {
+			currentSupply.Asset.Name = name
+		}
+		if symbol := r.str("symbol"); symbol != "" {
+			currentSupply.Asset.Symbol = symbol
+		}
+		retval = append(retval, currentSupply)
+	}
+	return retval, nil
+}
+
+// GetLatestSupply returns the latest supply of the asset with @symbol and highest volume.
+func (datastore *InfluxV2DB) GetLatestSupply(symbol string, relDB *RelDB) (*dia.Supply, error) {
+	val, err := datastore.GetSupply(symbol, time.Time{}, time.Time{}, relDB)
+	if err != nil {
+		log.Error(err)
+		return &dia.Supply{}, err
+	}
+	return &val[0], err
+}
+
+// GetSupply returns the supplies of the asset with @symbol and highest volume in (@starttime,@endtime).
+func (datastore *InfluxV2DB) GetSupply(symbol string, starttime, endtime time.Time, relDB *RelDB) ([]dia.Supply, error) {
+	return supplyOfSymbol(datastore, symbol, starttime, endtime, relDB)
+}
+
+// SetSupply stores @supply in redis and influx.
+func (datastore *InfluxV2DB) SetSupply(supply *dia.Supply) error {
+	key := getKeySupply(supply.Asset)
+	err := datastore.redisClient.Set(key, supply, 0).Err()
+	if err != nil {
+		log.Errorf("Error: %v on SetSupply (redis) %v\n", err, supply.Asset.Symbol)
+	}
+	err = datastore.SaveSupplyInflux(supply)
+	if err != nil {
+		log.Errorf("Error: %v on SetSupply (influx) %v\n", err, supply.Asset.Symbol)
+	}
+	return err
+}
+
+// SaveSynthSupplyInflux adds a synth supply to the batch.
+func (datastore *InfluxV2DB) SaveSynthSupplyInflux(t *dia.SynthAssetSupply) error {
+	return datastore.SaveSynthSupplyInfluxToTable(t, influxDbSynthSupplyTable)
+}
+
+// SaveSynthSupplyInfluxToTable adds a synth supply of measurement @table to the batch.
+func (datastore *InfluxV2DB) SaveSynthSupplyInfluxToTable(t *dia.SynthAssetSupply, table string) error {
+	pt, err := synthSupplyPoint(t, table)
+	if err != nil {
+		log.Errorln("SaveSynthSupplyInfluxToTable:", err)
+		return err
+	}
+	datastore.addPoint(pt)
+	return nil
+}
+
+// GetSynthAssets returns the addresses of the synth assets of @protocol on @blockchain.
+func (datastore *InfluxV2DB) GetSynthAssets(blockchain, protocol string) (r []string, err error) {
+	predicate := fluxAnd(
+		"r._measurement == "+fluxString(influxDbSynthSupplyTable),
+		fluxEqual("blockchain", blockchain),
+		fluxEqual("protocol", protocol),
+	)
+	records, err := datastore.client.query("import \"influxdata/influxdb/schema\"\n" +
+		"schema.tagValues(bucket: " + fluxString(datastore.client.bucket) + ", tag: \"synthtokenaddress\"," +
+		" predicate: (r) => " + predicate + ", start: " + fluxTime(time.Time{}) + ")")
+	if err != nil {
+		log.Errorln("GetSynthAssets", err)
+		return r, err
+	}
+	for _, record := range records {
+		if value := record.str("_value"); value != "" {
+			r = append(r, value)
+		}
+	}
+	if len(r) == 0 {
+		return r, fmt.Errorf("Empty response for")
+	}
+	return r, nil
+}
+
+// GetSynthSupplyInflux returns the supplies of synth assets on @blockchain in (@starttime,@endtime] in descending order.
+// @protocol and @address are optional. Only the latest supply is returned if @limit is 1.
+func (datastore *InfluxV2DB) GetSynthSupplyInflux(blockchain, protocol, address string, limit int, starttime, endtime time.Time) ([]dia.SynthAssetSupply, error) {
+	var r []dia.SynthAssetSupply
+	q := fluxQuery{
+		measurement: influxDbSynthSupplyTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime.Add(time.Nanosecond),
+		where:       []string{fluxEqual("blockchain", blockchain)},
+		desc:        true,
+	}
+	if protocol != "" {
+		q.where = append(q.where, fluxEqual("protocol", protocol))
+	}
+	if address != "" && address != "0x0000000000000000000000000000000000000000" {
+		q.where = append(q.where, fluxOr(fluxEqual("underlyingtokenaddress", address), fluxEqual("synthtokenaddress", address)))
+	}
+	if limit == 1 {
+		q.limit = 1
+	}
+	records, err := datastore.query(q)
+	if err != nil {
+		log.Errorln("GetSynthSupplyInflux", err)
+		return r, err
+	}
+	if len(records) == 0 {
+		return r, fmt.Errorf("Empty response for")
+	}
+	for _, record := range records {
+		supply := dia.SynthAssetSupply{
+			Time:            record.time(),
+			Protocol:        record.str("protocol"),
+			Asset:           dia.Asset{Symbol: record.str("synthassetsymbol"), Blockchain: blockchain, Address: record.str("synthtokenaddress")},
+			AssetUnderlying: dia.Asset{Symbol: record.str("underlyingassetsymbol"), Blockchain: blockchain, Address: record.str("underlyingtokenaddress")},
+		}
+		blocknumber, _ := record.float("blocknumber")
+		supply.BlockNumber = uint64(blocknumber)
+		supply.ColleteralRatio, _ = record.float("collateralRatio")
+		supply.Supply, _ = record.float("supply")
+		supply.TotalDebt, _ = record.float("totaldebt")
+		supply.LockedUnderlying, _ = record.float("underlyinglocked")
+		r = append(r, supply)
+	}
+	return r, nil
+}
+
+// SavePoolInflux writes a DEX pool to influx.
+func (datastore *InfluxV2DB) SavePoolInflux(p dia.Pool) error {
+	pt, err := poolPoint(p)
+	if err != nil {
+		log.Errorln("NewTradeInflux:", err)
+		return err
+	}
+	datastore.addPoint(pt)
+	err = datastore.WriteBatchInflux()
+	if err != nil {
+		log.Errorln("Write influx batch: ", err)
+	}
+	return err
+}
+
+// GetPoolInflux returns all info/liquidities of pool with @poolAddress in the time-range [starttime, endtime).
+func (datastore *InfluxV2DB) GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error) {
+	pools := []dia.Pool{}
+	records, err := datastore.query(fluxQuery{
+		measurement: influxDbDEXPoolTable,
+		start:       starttime,
+		stop:        endtime,
+		where:       []string{fluxEqual("address", poolAddress)},
+		desc:        true,
+	})
+	if err != nil {
+		return pools, err
+	}
+	if len(records) == 0 {
+		return pools, errors.New("parsing pool from database")
+	}
+	for _, r := range records {
+		var pool dia.Pool
+		pool.Time = r.time()
+		pool.Exchange.Name = r.str("exchange")
+		pool.Blockchain.Name = r.str("blockchain")
+		if err := json.Unmarshal([]byte(r.str("volumes")), &pool.Assetvolumes); err != nil {
+			log.Error("unmarshal: ", err)
+		}
+		pool.Address = poolAddress
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
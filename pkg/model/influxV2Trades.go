@@ -0,0 +1,373 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// SaveTradeInflux adds a trade to the batch.
+func (datastore *InfluxV2DB) SaveTradeInflux(t *dia.Trade) error {
+	return datastore.SaveTradeInfluxToTable(t, influxDbTradesTable)
+}
+
+// SaveTradeInfluxToTable adds a trade of measurement @table to the batch.
+func (datastore *InfluxV2DB) SaveTradeInfluxToTable(t *dia.Trade, table string) error {
+	pt, err := tradePoint(t, table)
+	if err != nil {
+		log.Errorln("NewTradeInflux:", err)
+		return err
+	}
+	datastore.addPoint(pt)
+	return nil
+}
+
+// fluxTrade returns the trade stored in @r with the columns returned by parseTrade.
+func fluxTrade(r fluxRecord, fullBasetoken bool) dia.Trade {
+	trade := dia.Trade{
+		Symbol:         r.str("symbol"),
+		Pair:           r.str("pair"),
+		Time:           r.time(),
+		Source:         r.str("exchange"),
+		ForeignTradeID: r.str("foreignTradeID"),
+		VerifiedPair:   r.str("verified") == "true",
+	}
+	trade.EstimatedUSDPrice, _ = r.float("estimatedUSDPrice")
+	trade.Price, _ = r.float("price")
+	trade.Volume, _ = r.float("volume")
+	if fullBasetoken {
+		trade.BaseToken.Blockchain = r.str("basetokenblockchain")
+		trade.BaseToken.Address = r.str("basetokenaddress")
+	}
+	return trade
+}
+
+// fluxQuoteToken returns the predicate that a trade's quote token has @address and @blockchain.
+func fluxQuoteToken(address string, blockchain string) string {
+	return fluxAnd(fluxEqual("quotetokenaddress", address), fluxEqual("quotetokenblockchain", blockchain))
+}
+
+// GetTradeInflux returns the latest trade of @asset on @exchange in [@endtime-@window,@endtime).
+func (datastore *InfluxV2DB) GetTradeInflux(asset dia.Asset, exchange string, endtime time.Time, window time.Duration) (*dia.Trade, error) {
+	q := fluxQuery{
+		measurement: influxDbTradesTable,
+		start:       endtime.Add(-window),
+		stop:        endtime,
+		where:       []string{fluxQuoteToken(asset.Address, asset.Blockchain)},
+		desc:        true,
+		limit:       1,
+	}
+	if exchange != "" {
+		q.where = append(q.where, fluxEqual("exchange", exchange))
+	}
+	records, err := datastore.query(q)
+	if err != nil {
+		return &dia.Trade{}, err
+	}
+	if len(records) == 0 {
+		return &dia.Trade{}, errors.New("parsing trade from database")
+	}
+	trade := fluxTrade(records[0], false)
+	trade.VerifiedPair = false
+	return &trade, nil
+}
+
+// GetOldTradesFromInflux returns the trades of @table on @exchange in [@timeInit,@timeFinal) in ascending order.
+// If @exchange is empty, trades across all exchanges are returned.
+// If @verified is true, address and blockchain are also returned for both assets.
+func (datastore *InfluxV2DB) GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) ([]dia.Trade, error) {
+	allTrades := []dia.Trade{}
+	q := fluxQuery{measurement: table, start: timeInit, stop: timeFinal}
+	if exchange != "" {
+		q.where = append(q.where, fluxEqual("exchange", exchange))
+	}
+	records, err := datastore.query(q)
+	if err != nil {
+		return allTrades, err
+	}
+	if len(records) == 0 {
+		return allTrades, errors.New("no trades in time range")
+	}
+	for _, r := range records {
+		if r.str("symbol") == "" {
+			continue
+		}
+		trade := fluxTrade(r, verified)
+		if verified {
+			trade.QuoteToken.Address = r.str("quotetokenaddress")
+			trade.QuoteToken.Blockchain = r.str("quotetokenblockchain")
+		} else {
+			trade.VerifiedPair = false
+		}
+		allTrades = append(allTrades, trade)
+	}
+	return allTrades, nil
+}
+
+// GetTradesByExchanges returns the trades of @asset on @exchanges in [@startTime,@endTime].
+func (datastore *InfluxV2DB) GetTradesByExchanges(asset dia.Asset, baseassets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesFull(asset, baseassets, exchanges, false, startTime, endTime)
+}
+
+// GetTradesByExchangesAndBaseAssets returns the trades of @asset against @baseassets on @exchanges in [@startTime,@endTime].
+func (datastore *InfluxV2DB) GetTradesByExchangesAndBaseAssets(asset dia.Asset, baseassets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesFull(asset, baseassets, exchanges, false, startTime, endTime)
+}
+
+// tradesPredicates returns the predicates on the tags of trades of @asset against @baseassets on @exchanges.
+// Exchanges are matched exactly. All exchanges and base assets match if none are given.
+func tradesPredicates(asset dia.Asset, baseassets []dia.Asset, exchanges []string) []string {
+	predicates := []string{fluxQuoteToken(asset.Address, asset.Blockchain)}
+	if len(exchanges) > 0 {
+		var exchangePredicates []string
+		for _, exchange := range exchanges {
+			exchangePredicates = append(exchangePredicates, fluxEqual("exchange", exchange))
+		}
+		predicates = append(predicates, fluxOr(exchangePredicates...))
+	}
+	if len(baseassets) > 0 {
+		var basePredicates []string
+		for _, baseasset := range baseassets {
+			basePredicates = append(basePredicates, fluxAnd(fluxEqual("basetokenaddress", baseasset.Address), fluxEqual("basetokenblockchain", baseasset.Blockchain)))
+		}
+		predicates = append(predicates, fluxOr(basePredicates...))
+	}
+	return predicates
+}
+
+// GetTradesByExchangesFull returns the trades of @asset with positive USD price in [@startTime,@endTime] on
+// @exchanges. As in DB, @baseassets only restrict the trades if @exchanges are given.
+func (datastore *InfluxV2DB) GetTradesByExchangesFull(asset dia.Asset, baseassets []dia.Asset, exchanges []string, returnBasetoken bool, startTime, endTime time.Time) ([]dia.Trade, error) {
+	if len(exchanges) == 0 {
+		baseassets = nil
+	}
+	records, err := datastore.query(fluxQuery{
+		measurement: influxDbTradesTable,
+		start:       startTime,
+		stop:        endTime.Add(time.Nanosecond),
+		where:       tradesPredicates(asset, baseassets, exchanges),
+		having:      []string{"r.estimatedUSDPrice > 0.0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r []dia.Trade
+	for _, record := range records {
+		r = append(r, fluxTrade(record, returnBasetoken))
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("no trades found")
+	}
+	return r, nil
+}
+
+// GetTradesByExchangesBatched returns the trades of @quoteasset on @exchanges in the intervals (@startTimes[i],@endTimes[i]].
+func (datastore *InfluxV2DB) GetTradesByExchangesBatched(quoteasset dia.Asset, baseassets []dia.Asset, exchanges []string, startTimes, endTimes []time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesBatchedFull(quoteasset, baseassets, exchanges, false, startTimes, endTimes)
+}
+
+// GetTradesByExchangesBatchedFull returns the trades of @quoteasset with positive USD price on @exchanges in the
+// time ranges (startTimes[i],endTimes[i]] with one query. Trades are restricted to @baseassets if given.
+func (datastore *InfluxV2DB) GetTradesByExchangesBatchedFull(quoteasset dia.Asset, baseassets []dia.Asset, exchanges []string, returnBasetoken bool, startTimes, endTimes []time.Time) ([]dia.Trade, error) {
+	if len(startTimes) != len(endTimes) {
+		return []dia.Trade{}, errors.New("number of start times must equal number of end times.")
+	}
+	if len(startTimes) == 0 {
+		return nil, fmt.Errorf("no trades found")
+	}
+	start, end := startTimes[0], endTimes[0]
+	var intervals []string
+	for i := range startTimes {
+		if startTimes[i].Before(start) {
+			start = startTimes[i]
+		}
+		if endTimes[i].After(end) {
+			end = endTimes[i]
+		}
+		intervals = append(intervals, fluxTimeIn(startTimes[i], endTimes[i]))
+	}
+	records, err := datastore.query(fluxQuery{
+		measurement: influxDbTradesTable,
+		start:       start,
+		stop:        end.Add(time.Nanosecond),
+		where:       append(tradesPredicates(quoteasset, baseassets, exchanges), fluxOr(intervals...)),
+		having:      []string{"r.estimatedUSDPrice > 0.0"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r []dia.Trade
+	for _, record := range records {
+		r = append(r, fluxTrade(record, returnBasetoken))
+	}
+	return r, nil
+}
+
+// GetAllTrades returns at most @maxTrades trades with timestamp after the second of @t.
+func (datastore *InfluxV2DB) GetAllTrades(t time.Time, maxTrades int) ([]dia.Trade, error) {
+	records, err := datastore.query(fluxQuery{
+		measurement: influxDbTradesTable,
+		start:       time.Unix(t.Unix(), 1),
+		limit:       maxTrades,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var r []dia.Trade
+	for _, record := range records {
+		r = append(r, fluxTrade(record, false))
+	}
+	return r, nil
+}
+
+// GetLastTrades returns the last @maxTrades of @asset on @exchange in the last 30 days.
+// If exchange is empty string it returns trades from all exchanges.
+// If fullAsset=true, blockchain and address of the base token are returned as well.
+func (datastore *InfluxV2DB) GetLastTrades(asset dia.Asset, exchange string, maxTrades int, fullAsset bool) ([]dia.Trade, error) {
+	q := fluxQuery{
+		measurement: influxDbTradesTable,
+		start:       time.Now().AddDate(0, 0, -30),
+		where:       []string{fluxQuoteToken(asset.Address, asset.Blockchain)},
+		having:      []string{"r.estimatedUSDPrice > 0.0"},
+		desc:        true,
+		limit:       maxTrades,
+	}
+	if exchange != "" {
+		q.where = append(q.where, fluxEqual("exchange", exchange))
+	}
+	records, err := datastore.query(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("Empty response for %s on %s", asset.Symbol, exchange)
+	}
+	var r []dia.Trade
+	for _, record := range records {
+		trade := fluxTrade(record, fullAsset)
+		trade.QuoteToken = asset
+		r = append(r, trade)
+	}
+	return r, nil
+}
+
+// GetNumTradesExchange24H returns the number of trades on @exchange in the last 24 hours.
+func (datastore *InfluxV2DB) GetNumTradesExchange24H(exchange string) (int64, error) {
+	endtime := time.Now()
+	return datastore.GetNumTrades(exchange, "", "", endtime.AddDate(0, 0, -1), endtime)
+}
+
+// GetNumTrades returns the number of trades on @exchange for asset with @address and @blockchain in (@starttime,@endtime].
+// If @address and @blockchain are empty, it returns all trades on @exchange in the given time-range.
+func (datastore *InfluxV2DB) GetNumTrades(exchange string, address string, blockchain string, starttime time.Time, endtime time.Time) (int64, error) {
+	q := fluxQuery{
+		bucket:      datastore.client.bucket,
+		measurement: influxDbTradesTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime.Add(time.Nanosecond),
+		fields:      []string{"estimatedUSDPrice"},
+		where:       []string{fluxEqual("exchange", exchange)},
+	}
+	if address != "" && blockchain != "" {
+		q.where = append(q.where, fluxQuoteToken(address, blockchain))
+	}
+	records, err := datastore.client.query(q.from() + "\n  |> group()\n  |> count()")
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+	numTrades, _ := records[0].float("_value")
+	return int64(numTrades), nil
+}
+
+// GetNumTradesSeries returns the number of trades in the intervals of length @grouping aligned to the unix epoch
+// intersecting (@starttime,@endtime]. If pair is the empty string, trades are identified by address/blockchain.
+func (datastore *InfluxV2DB) GetNumTradesSeries(
+	exchange string,
+	pair string,
+	starttime time.Time,
+	endtime time.Time,
+	grouping string,
+	quotetoken dia.Asset,
+	basetoken dia.Asset,
+) (numTrades []int, err error) {
+	interval, err := parseInfluxDuration(grouping)
+	if err != nil {
+		return
+	}
+	if interval <= 0 {
+		return nil, errors.New("invalid grouping " + grouping)
+	}
+	q := fluxQuery{
+		bucket:      datastore.client.bucket,
+		measurement: influxDbTradesTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime.Add(time.Nanosecond),
+		fields:      []string{"price"},
+		where:       []string{fluxEqual("exchange", exchange)},
+	}
+	if pair != "" {
+		q.where = append(q.where, fluxEqual("pair", pair))
+	} else {
+		q.where = append(q.where,
+			fluxQuoteToken(quotetoken.Address, quotetoken.Blockchain),
+			fluxAnd(fluxEqual("basetokenaddress", basetoken.Address), fluxEqual("basetokenblockchain", basetoken.Blockchain)),
+		)
+	}
+	records, err := datastore.client.query(q.from() +
+		"\n  |> group()" +
+		"\n  |> aggregateWindow(every: " + fluxDuration(interval) + ", fn: count, timeSrc: \"_start\", createEmpty: true)")
+	if err != nil {
+		return
+	}
+	for _, r := range records {
+		count, _ := r.float("_value")
+		numTrades = append(numTrades, int(count))
+	}
+	return
+}
+
+// GetFirstTradeDate returns the time of the first trade in @table.
+func (datastore *InfluxV2DB) GetFirstTradeDate(table string) (time.Time, error) {
+	q := fluxQuery{bucket: datastore.client.bucket, measurement: table}
+	records, err := datastore.client.query(q.from() + "\n  |> first()\n  |> group()\n  |> min(column: \"_time\")")
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(records) == 0 {
+		return time.Time{}, errors.New("no trade found")
+	}
+	return records[0].time(), nil
+}
+
+// GetActiveExchangesAndPairs returns all exchanges the asset with @address and @blockchain was traded on
+// with verified pairs in (@starttime,@endtime] as keys of a map. The map's values are the underlying pairs.
+func (datastore *InfluxV2DB) GetActiveExchangesAndPairs(address string, blockchain string, starttime time.Time, endtime time.Time) (map[string][]dia.Pair, error) {
+	exchangepairmap := make(map[string][]dia.Pair)
+	q := fluxQuery{
+		bucket:      datastore.client.bucket,
+		measurement: influxDbTradesTable,
+		start:       starttime.Add(time.Nanosecond),
+		stop:        endtime.Add(time.Nanosecond),
+		fields:      []string{"estimatedUSDPrice"},
+		where:       []string{fluxQuoteToken(address, blockchain), fluxEqual("verified", "true")},
+	}
+	// The pair of each exchange and pair symbol is taken from the latest trade.
+	records, err := datastore.client.query(q.from() +
+		"\n  |> group(columns: [\"exchange\", \"pair\"])" +
+		"\n  |> last()" +
+		"\n  |> group()" +
+		"\n  |> sort(columns: [\"exchange\", \"pair\"])")
+	if err != nil {
+		return exchangepairmap, err
+	}
+	for _, r := range records {
+		pair := dia.Pair{
+			QuoteToken: dia.Asset{Address: r.str("quotetokenaddress"), Blockchain: r.str("quotetokenblockchain")},
+			BaseToken:  dia.Asset{Address: r.str("basetokenaddress"), Blockchain: r.str("basetokenblockchain")},
+		}
+		exchangepairmap[r.str("exchange")] = append(exchangepairmap[r.str("exchange")], pair)
+	}
+	return exchangepairmap, nil
+}
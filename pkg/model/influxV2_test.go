@@ -0,0 +1,165 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers/db"
+)
+
+// newTestInfluxV2DB returns a datastore whose influx 2.x API is served by @handler.
+func newTestInfluxV2DB(t *testing.T, handler http.HandlerFunc) *InfluxV2DB {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &InfluxV2DB{
+		DB:     &DB{},
+		client: newInfluxV2Client(db.InfluxV2Config{URL: server.URL, Org: "dia", Bucket: "dia", Token: "secret"}),
+	}
+}
+
+func TestParseFluxCSV(t *testing.T) {
+	body := "#datatype,string,long,dateTime:RFC3339,string,double\n" +
+		"#group,false,false,false,true,false\n" +
+		"#default,_result,,,,\n" +
+		",result,table,_time,exchange,price\n" +
+		",,0,2021-01-01T00:00:00Z,Binance,100.5\n" +
+		",,0,2021-01-01T00:00:01Z,,\n" +
+		"\n" +
+		"#datatype,string,long,long\n" +
+		"#group,false,false,false\n" +
+		"#default,_result,,\n" +
+		",result,table,_value\n" +
+		",,1,42\n"
+	records, err := parseFluxCSV(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if !records[0].time().Equal(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %v", records[0].time())
+	}
+	if price, ok := records[0].float("price"); !ok || price != 100.5 {
+		t.Errorf("unexpected price %v", records[0]["price"])
+	}
+	if records[0].str("exchange") != dia.BinanceExchange || records[0].str("result") != "_result" {
+		t.Errorf("unexpected record %v", records[0])
+	}
+	if _, ok := records[1].float("price"); ok {
+		t.Errorf("expected null price, got %v", records[1]["price"])
+	}
+	if value, ok := records[2]["_value"].(int64); !ok || value != 42 {
+		t.Errorf("unexpected value %v", records[2]["_value"])
+	}
+}
+
+func TestParseFluxCSVError(t *testing.T) {
+	body := "#datatype,string,string\n" +
+		"#group,true,true\n" +
+		"#default,,\n" +
+		",error,reference\n" +
+		",failed to execute query,897\n"
+	_, err := parseFluxCSV(strings.NewReader(body))
+	if err == nil || !strings.Contains(err.Error(), "failed to execute query") {
+		t.Errorf("expected query error, got %v", err)
+	}
+}
+
+func TestFluxStringEscapesHostileInput(t *testing.T) {
+	for input, expected := range map[string]string{
+		`Binance`:                 `"Binance"`,
+		`Binance" or true or "`:   `"Binance\" or true or \""`,
+		`\" or true //`:           `"\\\" or true //"`,
+		`${string(v: r._value)}`:  `"\${string(v: r._value)}"`,
+		"Binance\")\n|> drop()//": "\"Binance\\\")\n|> drop()//\"",
+	} {
+		if output := fluxString(input); output != expected {
+			t.Errorf("fluxString(%q) = %s, expected %s", input, output, expected)
+		}
+	}
+}
+
+func TestInfluxV2WriteBatch(t *testing.T) {
+	var request *http.Request
+	var body string
+	datastore := newTestInfluxV2DB(t, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		request, body = r, string(b)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	trade := testTrade(dia.BinanceExchange, 100, time.Unix(1600000000, 0))
+	if err := datastore.SaveTradeInflux(trade); err != nil {
+		t.Fatal(err)
+	}
+	if request != nil {
+		t.Fatal("expected trade to be batched")
+	}
+	if err := datastore.WriteBatchInflux(); err != nil {
+		t.Fatal(err)
+	}
+	if request == nil {
+		t.Fatal("expected batch to be written")
+	}
+	if request.URL.Path != "/api/v2/write" || request.URL.Query().Get("bucket") != "dia" || request.URL.Query().Get("org") != "dia" {
+		t.Errorf("unexpected request %s", request.URL)
+	}
+	if request.Header.Get("Authorization") != "Token secret" {
+		t.Errorf("unexpected authorization %q", request.Header.Get("Authorization"))
+	}
+	if !strings.HasPrefix(body, influxDbTradesTable+",") || !strings.Contains(body, "exchange="+dia.BinanceExchange) || !strings.HasSuffix(body, " 1600000000000000000\n") {
+		t.Errorf("unexpected line protocol %q", body)
+	}
+}
+
+func TestInfluxV2GetTradesByExchangesFull(t *testing.T) {
+	var flux string
+	datastore := newTestInfluxV2DB(t, func(w http.ResponseWriter, r *http.Request) {
+		var query struct {
+			Query string `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			t.Error(err)
+		}
+		flux = query.Query
+		_, _ = w.Write([]byte("#datatype,string,long,dateTime:RFC3339,string,string,string,string,double,double,double\n" +
+			"#group,false,false,false,false,false,false,false,false,false,false\n" +
+			"#default,_result,,,,,,,,,\n" +
+			",result,table,_time,exchange,pair,symbol,verified,estimatedUSDPrice,price,volume\n" +
+			",,0,2020-09-13T12:26:40Z,Binance,BTC-USDT,BTC,true,10000,10000,0.5\n"))
+	})
+	hostile := `Binance") or r._measurement != ("`
+	trades, err := datastore.GetTradesByExchangesFull(memoryBTC, nil, []string{hostile}, false, time.Unix(1600000000, 0), time.Unix(1600000060, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(flux, `r["exchange"] == "Binance\") or r._measurement != (\""`) {
+		t.Errorf("exchange not escaped in query %s", flux)
+	}
+	if len(trades) != 1 {
+		t.Fatalf("expected 1 trade, got %d", len(trades))
+	}
+	trade := trades[0]
+	if trade.Source != dia.BinanceExchange || trade.Pair != "BTC-USDT" || trade.Price != 10000 || trade.Volume != 0.5 || !trade.VerifiedPair {
+		t.Errorf("unexpected trade %+v", trade)
+	}
+	if !trade.Time.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("unexpected time %v", trade.Time)
+	}
+}
+
+func TestInfluxV2QueryError(t *testing.T) {
+	datastore := newTestInfluxV2DB(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"code":"unauthorized","message":"unauthorized access"}`, http.StatusUnauthorized)
+	})
+	_, err := datastore.GetNumTrades(dia.BinanceExchange, "", "", time.Unix(1600000000, 0), time.Unix(1600000060, 0))
+	if err == nil || !strings.Contains(err.Error(), "unauthorized access") {
+		t.Errorf("expected unauthorized error, got %v", err)
+	}
+}
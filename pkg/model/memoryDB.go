@@ -0,0 +1,399 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	influxModels "github.com/influxdata/influxdb1-client/models"
+	clientInfluxdb "github.com/influxdata/influxdb1-client/v2"
+)
+
+// MemoryDB is an in-memory implementation of Datastore for tests and local runs without redis and influx.
+// It keeps the semantics of DB: Points written to influx are visible once the batch is flushed, commands
+// sent through the redis pipe once the pipe is executed, and points with equal tags and time are merged.
+// Symbols are resolved among the assets written to the store, so that no RelDB is needed.
+type MemoryDB struct {
+	mu sync.RWMutex
+	// databases maps influx databases to their measurements.
+	databases map[string]map[string]memoryMeasurement
+	batch     []memoryWrite
+	cache     map[string]memoryCacheEntry
+	zsets     map[string][]redis.Z
+	sets      map[string]map[string]struct{}
+	pipe      []func()
+	assets    map[memoryAssetKey]memoryAsset
+}
+
+var _ Datastore = (*MemoryDB)(nil)
+
+// memoryPoint is a point of an influx measurement. Empty tags are not stored, as in influx.
+type memoryPoint struct {
+	tags   map[string]string
+	fields map[string]interface{}
+	time   time.Time
+}
+
+// memoryMeasurement maps the series and time of points to the points.
+type memoryMeasurement map[string]*memoryPoint
+
+type memoryWrite struct {
+	measurement string
+	point       memoryPoint
+}
+
+type memoryCacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+type memoryAssetKey struct {
+	blockchain string
+	address    string
+}
+
+// memoryAsset is an asset written to the store. Its symbol may be resolved to it.
+type memoryAsset struct {
+	symbol string
+	name   string
+}
+
+// NewMemoryDataStore returns an empty in-memory datastore.
+func NewMemoryDataStore() *MemoryDB {
+	return &MemoryDB{
+		databases: map[string]map[string]memoryMeasurement{influxDbName: {}},
+		cache:     make(map[string]memoryCacheEntry),
+		zsets:     make(map[string][]redis.Z),
+		sets:      make(map[string]map[string]struct{}),
+		assets:    make(map[memoryAssetKey]memoryAsset),
+	}
+}
+
+// SetInfluxClient is a no-op, as there is no influx client.
+func (datastore *MemoryDB) SetInfluxClient(url string) {}
+
+// Flush writes the batch of influx points.
+func (datastore *MemoryDB) Flush() error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	datastore.writeBatch()
+	return nil
+}
+
+// ExecuteRedisPipe executes the commands sent through the redis pipe.
+func (datastore *MemoryDB) ExecuteRedisPipe() error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	for _, command := range datastore.pipe {
+		command()
+	}
+	datastore.pipe = nil
+	return nil
+}
+
+// FlushRedisPipe discards the commands sent through the redis pipe.
+func (datastore *MemoryDB) FlushRedisPipe() error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	datastore.pipe = nil
+	return nil
+}
+
+// CopyInfluxMeasurements copies the points of measurement @tableOrigin in database @dbOrigin in (@timeInit,@timeFinal]
+// into @tableDestination in database @dbDestination. Only the default database is queried by the other methods.
+func (datastore *MemoryDB) CopyInfluxMeasurements(dbOrigin string, dbDestination string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (int64, error) {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	origin, ok := datastore.databases[dbOrigin]
+	if !ok {
+		return 0, errors.New("database not found: " + dbOrigin)
+	}
+	destination, ok := datastore.databases[dbDestination]
+	if !ok {
+		return 0, errors.New("database not found: " + dbDestination)
+	}
+	var numCopiedRows int64
+	for _, p := range selectMemoryPoints(origin[tableOrigin], func(p *memoryPoint) bool {
+		return p.time.After(timeInit) && !p.time.After(timeFinal)
+	}) {
+		if destination[tableDestination] == nil {
+			destination[tableDestination] = make(memoryMeasurement)
+		}
+		destination[tableDestination].write(*p)
+		numCopiedRows++
+	}
+	return numCopiedRows, nil
+}
+
+// addPoint adds a point to the influx batch, which is written once it is full.
+// The caller must hold the lock.
+func (datastore *MemoryDB) addPoint(measurement string, tags map[string]string, fields map[string]interface{}, t time.Time) {
+	p := memoryPoint{tags: make(map[string]string), fields: make(map[string]interface{}), time: t}
+	for key, value := range tags {
+		if value != "" {
+			p.tags[key] = value
+		}
+	}
+	for key, value := range fields {
+		switch v := value.(type) {
+		case int:
+			p.fields[key] = int64(v)
+		case uint64:
+			p.fields[key] = int64(v)
+		case float32:
+			p.fields[key] = float64(v)
+		default:
+			p.fields[key] = value
+		}
+	}
+	datastore.batch = append(datastore.batch, memoryWrite{measurement: measurement, point: p})
+	if len(datastore.batch) >= influxMaxPointsInBatch {
+		datastore.writeBatch()
+	}
+}
+
+// writeBatch writes the influx batch. The caller must hold the lock.
+func (datastore *MemoryDB) writeBatch() {
+	measurements := datastore.databases[influxDbName]
+	for _, w := range datastore.batch {
+		if measurements[w.measurement] == nil {
+			measurements[w.measurement] = make(memoryMeasurement)
+		}
+		measurements[w.measurement].write(w.point)
+	}
+	datastore.batch = nil
+}
+
+// selectPoints returns the points of @measurement for which @where is true in ascending order of time.
+// The caller must hold the lock.
+func (datastore *MemoryDB) selectPoints(measurement string, where func(p *memoryPoint) bool) []*memoryPoint {
+	return selectMemoryPoints(datastore.databases[influxDbName][measurement], where)
+}
+
+// deletePoints removes the points of @measurement for which @where is true. The caller must hold the lock.
+func (datastore *MemoryDB) deletePoints(measurement string, where func(p *memoryPoint) bool) {
+	m := datastore.databases[influxDbName][measurement]
+	for key, p := range m {
+		if where(p) {
+			delete(m, key)
+		}
+	}
+}
+
+// write adds @p to the measurement. The fields of a point with the same series and time are overwritten.
+func (m memoryMeasurement) write(p memoryPoint) {
+	key := seriesKey(p.tags) + " " + strconv.FormatInt(p.time.UnixNano(), 10)
+	stored, ok := m[key]
+	if !ok {
+		stored = &memoryPoint{tags: make(map[string]string), fields: make(map[string]interface{}), time: p.time}
+		for k, v := range p.tags {
+			stored.tags[k] = v
+		}
+		m[key] = stored
+	}
+	for k, v := range p.fields {
+		stored.fields[k] = v
+	}
+}
+
+func selectMemoryPoints(m memoryMeasurement, where func(p *memoryPoint) bool) []*memoryPoint {
+	var points []*memoryPoint
+	for _, p := range m {
+		if where(p) {
+			points = append(points, p)
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].time.Equal(points[j].time) {
+			return seriesKey(points[i].tags) < seriesKey(points[j].tags)
+		}
+		return points[i].time.Before(points[j].time)
+	})
+	return points
+}
+
+func seriesKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key + "=" + tags[key] + ",")
+	}
+	return sb.String()
+}
+
+// float returns the numeric field @name of the point.
+func (p *memoryPoint) float(name string) (float64, bool) {
+	switch v := p.fields[name].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// str returns the string field or the tag @name of the point. Fields take precedence over tags, as in influx.
+func (p *memoryPoint) str(name string) string {
+	if v, ok := p.fields[name].(string); ok {
+		return v
+	}
+	return p.tags[name]
+}
+
+// row returns the values of @columns of the point as returned by influx queries. Missing values are nil.
+func (p *memoryPoint) row(columns []string) []interface{} {
+	row := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if column == "time" {
+			row[i] = p.time.UTC().Format(time.RFC3339Nano)
+			continue
+		}
+		switch v := p.fields[column].(type) {
+		case float64:
+			row[i] = json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+		case int64:
+			row[i] = json.Number(strconv.FormatInt(v, 10))
+		case nil:
+			if tag, ok := p.tags[column]; ok {
+				row[i] = tag
+			}
+		default:
+			row[i] = v
+		}
+	}
+	return row
+}
+
+// memoryResult returns @points as result of an influx query of @columns on @measurement.
+// As in influx, there is no series if there are no points.
+func memoryResult(measurement string, columns []string, points []*memoryPoint) []clientInfluxdb.Result {
+	result := clientInfluxdb.Result{}
+	if len(points) > 0 {
+		row := influxModels.Row{Name: measurement, Columns: columns}
+		for _, p := range points {
+			row.Values = append(row.Values, p.row(columns))
+		}
+		result.Series = []influxModels.Row{row}
+	}
+	return []clientInfluxdb.Result{result}
+}
+
+// reversePoints reverses the order of @points in place and returns them.
+func reversePoints(points []*memoryPoint) []*memoryPoint {
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+	return points
+}
+
+// floorTime returns the begin of the interval of length @d containing @t, with intervals aligned
+// to the unix epoch as in influx' GROUP BY time().
+func floorTime(t time.Time, d time.Duration) time.Time {
+	ns := t.UnixNano()
+	mod := ns % int64(d)
+	if mod < 0 {
+		mod += int64(d)
+	}
+	return time.Unix(0, ns-mod)
+}
+
+// parseInfluxDuration parses a duration literal of InfluxQL such as 30s, 40m, 2h, 1d or 1w.
+func parseInfluxDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseInt(strings.TrimSuffix(s, suffix), 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// matchExchanges returns a function matching exchanges against the regular expression
+// built from @exchanges, as done in influx queries.
+func matchExchanges(exchanges []string) (func(exchange string) bool, error) {
+	if len(exchanges) == 0 {
+		return func(string) bool { return true }, nil
+	}
+	quoted := make([]string, len(exchanges))
+	for i, exchange := range exchanges {
+		quoted[i] = regexp.QuoteMeta(exchange)
+	}
+	re, err := regexp.Compile(strings.Join(quoted, "|"))
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString, nil
+}
+
+// setCache stores @value under @key in the cache. It never expires if @expiration is zero.
+// The caller must hold the lock.
+func (datastore *MemoryDB) setCache(key string, value interface{}, expiration time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	entry := memoryCacheEntry{value: b}
+	if expiration > 0 {
+		entry.expires = time.Now().Add(expiration)
+	}
+	datastore.cache[key] = entry
+	return nil
+}
+
+// getCache scans the value stored under @key into @value. As in redis, it returns redis.Nil if there is none.
+// The caller must hold the lock.
+func (datastore *MemoryDB) getCache(key string, value interface{}) error {
+	entry, ok := datastore.cache[key]
+	if !ok || (!entry.expires.IsZero() && time.Now().After(entry.expires)) {
+		return redis.Nil
+	}
+	return json.Unmarshal(entry.value, value)
+}
+
+// cacheKeys returns the unexpired keys matching the glob @pattern. The caller must hold the lock.
+func (datastore *MemoryDB) cacheKeys(pattern string) []string {
+	var keys []string
+	for key, entry := range datastore.cache {
+		if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pipeCommand sends @command through the redis pipe. The caller must hold the lock.
+func (datastore *MemoryDB) pipeCommand(command func()) {
+	datastore.pipe = append(datastore.pipe, command)
+}
+
+// addAsset remembers @asset, so that its symbol can be resolved. The caller must hold the lock.
+func (datastore *MemoryDB) addAsset(symbol string, name string, address string, blockchain string) {
+	if symbol == "" || (address == "" && blockchain == "") {
+		return
+	}
+	key := memoryAssetKey{blockchain: blockchain, address: address}
+	asset := datastore.assets[key]
+	asset.symbol = symbol
+	if name != "" {
+		asset.name = name
+	}
+	datastore.assets[key] = asset
+}
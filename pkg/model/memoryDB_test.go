@@ -0,0 +1,181 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/go-redis/redis"
+)
+
+var (
+	memoryBTC  = dia.Asset{Symbol: "BTC", Name: "Bitcoin", Address: "0x0000000000000000000000000000000000000000", Blockchain: dia.BITCOIN}
+	memoryUSDT = dia.Asset{Symbol: "USDT", Name: "Tether", Address: "0xdAC17F958D2ee523a2206206994597C13D831ec7", Blockchain: dia.ETHEREUM}
+)
+
+func testTrade(exchange string, price float64, t time.Time) *dia.Trade {
+	return &dia.Trade{
+		Symbol:            memoryBTC.Symbol,
+		Pair:              "BTC-USDT",
+		QuoteToken:        memoryBTC,
+		BaseToken:         memoryUSDT,
+		Price:             price,
+		Volume:            1,
+		Time:              t,
+		Source:            exchange,
+		EstimatedUSDPrice: price,
+		VerifiedPair:      true,
+	}
+}
+
+func TestMemoryDBTradesVisibleAfterFlush(t *testing.T) {
+	ds := NewMemoryDataStore()
+	start := time.Unix(1600000000, 0)
+	for i, exchange := range []string{dia.BinanceExchange, dia.KrakenExchange, dia.BinanceExchange} {
+		if err := ds.SaveTradeInflux(testTrade(exchange, float64(100+i), start.Add(time.Duration(i)*time.Second))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ds.GetTradesByExchanges(memoryBTC, nil, nil, start, start.Add(time.Minute)); err == nil {
+		t.Error("expected no trades before flush")
+	}
+	if err := ds.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	trades, err := ds.GetTradesByExchangesFull(memoryBTC, nil, []string{dia.BinanceExchange}, true, start, start.Add(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 || trades[0].Price != 100 || trades[1].Price != 102 {
+		t.Errorf("expected binance trades at 100 and 102, got %v", trades)
+	}
+	if trades[0].BaseToken.Address != memoryUSDT.Address || !trades[0].VerifiedPair {
+		t.Errorf("expected base token and verified flag to be stored, got %v", trades[0])
+	}
+
+	// The batched query is exclusive at the start of each interval.
+	trades, err = ds.GetTradesByExchangesBatched(memoryBTC, nil, nil, []time.Time{start}, []time.Time{start.Add(time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Errorf("expected 2 trades in (start,end], got %d", len(trades))
+	}
+}
+
+func TestMemoryDBFilterPointsWithQuality(t *testing.T) {
+	ds := NewMemoryDataStore()
+	timestamp := time.Unix(1600000000, 0)
+	if err := ds.SetFilter("MA120", memoryBTC, "", 42, timestamp); err != nil {
+		t.Fatal(err)
+	}
+	quality := dia.FilterPointQuality{NumTrades: 3, NumExchanges: 2, VolumeUSD: 1000}
+	if err := ds.SetFilterQuality("MA120", memoryBTC, "", quality, timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	points, err := ds.GetFilterPointsAsset("MA120", "", memoryBTC.Address, memoryBTC.Blockchain, timestamp.Add(-time.Minute), timestamp, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := points.DataPoints[0].Series
+	if len(series) != 1 || len(series[0].Values) != 1 {
+		t.Fatalf("expected one merged point, got %v", series)
+	}
+	row := make(map[string]interface{})
+	for i, column := range series[0].Columns {
+		row[column] = series[0].Values[0][i]
+	}
+	if row["value"] != json.Number("42") || row["numTrades"] != json.Number("3") || row["symbol"] != "BTC" {
+		t.Errorf("unexpected row %v", row)
+	}
+	if _, err := time.Parse(time.RFC3339, row["time"].(string)); err != nil {
+		t.Errorf("time not parsable as done by callers: %v", err)
+	}
+
+	// The end of the time range is inclusive and the start is exclusive.
+	points, err = ds.GetFilterPointsAsset("MA120", "", memoryBTC.Address, memoryBTC.Blockchain, timestamp, timestamp.Add(time.Minute), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points.DataPoints[0].Series) != 0 {
+		t.Errorf("expected no points after %v, got %v", timestamp, points.DataPoints[0].Series)
+	}
+}
+
+func TestMemoryDBDeleteFilterPoints(t *testing.T) {
+	ds := NewMemoryDataStore()
+	timestamp := time.Unix(1600000000, 0)
+	for _, filter := range []string{"MA120", "VOL120"} {
+		for _, ts := range []time.Time{timestamp, timestamp.Add(time.Minute)} {
+			if err := ds.SetFilter(filter, memoryBTC, "", 42, ts); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := ds.ExecuteRedisPipe(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.DeleteFilterPoints([]string{"MA120"}, memoryBTC, "", timestamp, timestamp.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	zset := ds.zsets[getKeyFilterZSET(getKey("MA120", memoryBTC, ""))]
+	if len(zset) != 1 || zset[0].Score != float64(timestamp.Add(time.Minute).Unix()) {
+		t.Errorf("expected only the value after the deleted range, got %v", zset)
+	}
+	if zset = ds.zsets[getKeyFilterZSET(getKey("VOL120", memoryBTC, ""))]; len(zset) != 2 {
+		t.Errorf("expected values of other filters to be kept, got %v", zset)
+	}
+}
+
+func TestMemoryDBRedisPipe(t *testing.T) {
+	ds := NewMemoryDataStore()
+	timestamp := time.Unix(1600000000, 0)
+	if err := ds.SetLastTradeTimeForExchange(memoryBTC, dia.BinanceExchange, timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetLastTradeTimeForExchange(memoryBTC, dia.BinanceExchange); !errors.Is(err, redis.Nil) {
+		t.Errorf("expected redis.Nil before executing the pipe, got %v", err)
+	}
+	if err := ds.ExecuteRedisPipe(); err != nil {
+		t.Fatal(err)
+	}
+	lastTime, err := ds.GetLastTradeTimeForExchange(memoryBTC, dia.BinanceExchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !lastTime.Equal(timestamp) {
+		t.Errorf("expected %v, got %v", timestamp, lastTime)
+	}
+}
+
+func TestMemoryDBAssetQuotation(t *testing.T) {
+	ds := NewMemoryDataStore()
+	timestamp := time.Now().Add(-time.Minute)
+	if err := ds.SetAssetPriceUSD(memoryBTC, 50000, timestamp); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.GetAssetQuotationCache(memoryBTC); !errors.Is(err, redis.Nil) {
+		t.Errorf("expected redis.Nil before executing the pipe, got %v", err)
+	}
+	price, err := ds.GetAssetPriceUSDLatest(memoryBTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 50000 {
+		t.Errorf("expected price 50000 from influx, got %v", price)
+	}
+	if _, err := ds.GetAssetQuotation(memoryBTC, timestamp.Add(-time.Second)); err == nil {
+		t.Error("expected no quotation before the first one")
+	}
+}
@@ -0,0 +1,458 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers"
+	"github.com/go-redis/redis"
+)
+
+// SetFilter adds a filter point to the influx batch and sends its value through the redis pipe.
+func (datastore *MemoryDB) SetFilter(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	err := datastore.SaveFilterInflux(filter, asset, exchange, value, t)
+	if err != nil {
+		return err
+	}
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	key := getKeyFilterZSET(getKey(filter, asset, exchange))
+	unixTime := t.Unix()
+	datastore.pipeCommand(func() {
+		datastore.setZSETValue(key, value, unixTime, BiggestWindow)
+	})
+	return nil
+}
+
+// SaveFilterInflux adds a filter point to the influx batch.
+func (datastore *MemoryDB) SaveFilterInflux(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	tags := map[string]string{
+		"filter":     filter,
+		"symbol":     asset.Symbol,
+		"address":    asset.Address,
+		"blockchain": asset.Blockchain,
+		"exchange":   exchange,
+	}
+	fields := map[string]interface{}{
+		"value":        value,
+		"allExchanges": exchange == "",
+	}
+	datastore.addPoint(influxDbFiltersTable, tags, fields, t)
+	datastore.addAsset(asset.Symbol, asset.Name, asset.Address, asset.Blockchain)
+	return nil
+}
+
+// SetFilterQuality adds @quality to the point of filter @filter at @t.
+func (datastore *MemoryDB) SetFilterQuality(filter string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	tags := map[string]string{
+		"filter":     filter,
+		"symbol":     asset.Symbol,
+		"address":    asset.Address,
+		"blockchain": asset.Blockchain,
+		"exchange":   exchange,
+	}
+	fields := map[string]interface{}{
+		"numTrades":             quality.NumTrades,
+		"numExchanges":          quality.NumExchanges,
+		"volumeUSD":             quality.VolumeUSD,
+		"stdDev":                quality.StdDev,
+		"iqr":                   quality.IQR,
+		"outlierFraction":       quality.OutlierFraction,
+		"secondsSinceLastTrade": quality.SecondsSinceLastTrade,
+	}
+	datastore.addPoint(influxDbFiltersTable, tags, fields, t)
+	return nil
+}
+
+// DeleteFilterPoints removes all written filter points of @asset on @exchange in [@starttime,@endtime]
+// and the values of @filters in this range from the sorted sets.
+func (datastore *MemoryDB) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	datastore.deletePoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		return p.str("address") == asset.Address && p.str("blockchain") == asset.Blockchain && p.str("exchange") == exchange &&
+			!p.time.Before(starttime) && !p.time.After(endtime)
+	})
+	for _, filter := range filters {
+		key := getKeyFilterZSET(getKey(filter, asset, exchange))
+		var zset []redis.Z
+		for _, z := range datastore.zsets[key] {
+			if z.Score < float64(starttime.Unix()) || z.Score > float64(endtime.Unix()) {
+				zset = append(zset, z)
+			}
+		}
+		datastore.zsets[key] = zset
+	}
+	return nil
+}
+
+// SetTradesBlockProcessed marks revision @revision of the tradesBlock with hash @tradesBlockHash as processed.
+func (datastore *MemoryDB) SetTradesBlockProcessed(tradesBlockHash string, revision int) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	return datastore.setCache(getKeyTradesBlockProcessed(tradesBlockHash, revision), time.Now().Unix(), TimeOutTradesBlockProcessed)
+}
+
+// IsTradesBlockProcessed returns true if revision @revision of the tradesBlock with hash @tradesBlockHash was processed.
+func (datastore *MemoryDB) IsTradesBlockProcessed(tradesBlockHash string, revision int) (bool, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	var processed int64
+	err := datastore.getCache(getKeyTradesBlockProcessed(tradesBlockHash, revision), &processed)
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// filterColumns returns the columns of filter points selected by DB.
+func filterColumns(columns string, withQuality bool) []string {
+	if withQuality {
+		columns += filterQualityColumns
+	}
+	return strings.Split(columns, ",")
+}
+
+// GetFilterPointsAsset returns the points of @filter for an asset on @exchange in (@starttime,@endtime] in
+// descending order, where an empty @exchange stands for all exchanges.
+func (datastore *MemoryDB) GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := datastore.selectPoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		return p.str("filter") == filter && p.str("exchange") == exchange && p.str("address") == address && p.str("blockchain") == blockchain &&
+			p.time.After(starttime) && !p.time.After(endtime)
+	})
+	columns := filterColumns("time,address,blockchain,exchange,filter,symbol,value", withQuality)
+	return &Points{DataPoints: memoryResult(influxDbFiltersTable, columns, reversePoints(points))}, nil
+}
+
+// GetFilterPoints returns the points of @filter in (@starttime,@endtime) in descending order from either a specific
+// exchange or all exchanges. @symbol is mapped to the asset with the largest volume. If @scale is given, points
+// are downsampled to intervals of length @scale as done by the continuous queries of influx.
+func (datastore *MemoryDB) GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	sortedAssets := datastore.topAssets(symbol)
+	if len(sortedAssets) == 0 {
+		return nil, errors.New("no traded assets found")
+	}
+	topAsset := sortedAssets[0]
+
+	points := datastore.selectPoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		return p.str("filter") == filter && p.str("exchange") == exchange && p.str("address") == topAsset.Address && p.str("blockchain") == topAsset.Blockchain
+	})
+	table := influxDbFiltersTable
+	if scale != "" {
+		interval, err := parseInfluxDuration(scale)
+		if err != nil {
+			return &Points{}, err
+		}
+		table = "filters_mean_" + scale
+		if filter == "VOL120" {
+			table = "filters_sum_" + scale
+		}
+		points = downsample(points, interval, filter == "VOL120")
+		withQuality = false
+	}
+	var selected []*memoryPoint
+	for _, p := range points {
+		if p.time.After(starttime) && p.time.Before(endtime) {
+			selected = append(selected, p)
+		}
+	}
+	columns := filterColumns("time,exchange,filter,symbol,value", withQuality)
+	return &Points{DataPoints: memoryResult(table, columns, reversePoints(selected))}, nil
+}
+
+// downsample returns the mean or, if @sum is true, the sum of the values of each series of @points in
+// intervals of length @interval in ascending order of time.
+func downsample(points []*memoryPoint, interval time.Duration, sum bool) []*memoryPoint {
+	type bucket struct {
+		point *memoryPoint
+		n     int
+	}
+	buckets := make(map[string]*bucket)
+	var downsampled []*memoryPoint
+	for _, p := range points {
+		value, ok := p.float("value")
+		if !ok {
+			continue
+		}
+		t := floorTime(p.time, interval)
+		key := seriesKey(p.tags) + " " + strconv.FormatInt(t.UnixNano(), 10)
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{point: &memoryPoint{tags: p.tags, fields: map[string]interface{}{"value": 0.0}, time: t}}
+			buckets[key] = b
+			downsampled = append(downsampled, b.point)
+		}
+		b.point.fields["value"] = b.point.fields["value"].(float64) + value
+		b.n++
+	}
+	if !sum {
+		for _, b := range buckets {
+			b.point.fields["value"] = b.point.fields["value"].(float64) / float64(b.n)
+		}
+	}
+	sort.SliceStable(downsampled, func(i, j int) bool { return downsampled[i].time.Before(downsampled[j].time) })
+	return downsampled
+}
+
+// GetFilter returns the last point of @filter across all exchanges for each day in (@starttime,@endtime) in
+// descending order. Days without points take the value of the previous day.
+func (datastore *MemoryDB) GetFilter(filter string, topAsset dia.Asset, scale string, starttime time.Time, endtime time.Time) ([]dia.FilterPoint, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := datastore.selectPoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		allExchanges, _ := p.fields["allExchanges"].(bool)
+		return p.str("filter") == filter && p.str("address") == topAsset.Address && p.str("blockchain") == topAsset.Blockchain &&
+			allExchanges && p.time.After(starttime) && p.time.Before(endtime)
+	})
+	if scale != "" {
+		interval, err := parseInfluxDuration(scale)
+		if err != nil {
+			return nil, err
+		}
+		points = downsample(points, interval, filter == "VOL120")
+	}
+	if len(points) == 0 {
+		return nil, errors.New("no filter points in time range")
+	}
+
+	var times []time.Time
+	var values []float64
+	for _, p := range points {
+		value, _ := p.float("value")
+		times = append(times, p.time)
+		values = append(values, value)
+	}
+	return dailyFilterPoints(times, values, starttime, endtime), nil
+}
+
+// GetLastPriceBefore returns the first point of @filter on @exchange after @timestamp, as done by DB.
+func (datastore *MemoryDB) GetLastPriceBefore(asset dia.Asset, filter string, exchange string, timestamp time.Time) (Price, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	now := time.Now()
+	points := datastore.selectPoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		_, ok := p.fields["value"]
+		return ok && p.str("filter") == filter && p.str("address") == asset.Address && p.str("blockchain") == asset.Blockchain &&
+			p.str("exchange") == exchange && p.time.Before(now) && p.time.After(timestamp)
+	})
+	price := Price{Symbol: asset.Symbol, Name: helpers.NameForSymbol(asset.Symbol)}
+	if len(points) > 0 {
+		price.Time = points[0].time
+		price.Price, _ = points[0].float("value")
+	}
+	return price, nil
+}
+
+// setZSETValue adds @value at @unixTime to the sorted set @key and purges values older than @maxWindow.
+// The caller must hold the lock.
+func (datastore *MemoryDB) setZSETValue(key string, value float64, unixTime int64, maxWindow int64) {
+	member := strconv.FormatFloat(value, 'f', -1, 64) + " " + strconv.FormatInt(unixTime, 10)
+	var zset []redis.Z
+	for _, z := range datastore.zsets[key] {
+		if z.Member != member && z.Score >= float64(unixTime-maxWindow) {
+			zset = append(zset, z)
+		}
+	}
+	zset = append(zset, redis.Z{Score: float64(unixTime), Member: member})
+	sort.Slice(zset, func(i, j int) bool {
+		if zset[i].Score == zset[j].Score {
+			return zset[i].Member.(string) < zset[j].Member.(string)
+		}
+		return zset[i].Score < zset[j].Score
+	})
+	datastore.zsets[key] = zset
+}
+
+// SaveCandleInflux adds @candle to the influx batch. Saving a candle again with the same asset,
+// exchange, resolution and time overwrites it.
+func (datastore *MemoryDB) SaveCandleInflux(candle dia.Candle) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	tags := map[string]string{
+		"resolution": candle.Resolution,
+		"address":    candle.Asset.Address,
+		"blockchain": candle.Asset.Blockchain,
+		"exchange":   candle.Exchange,
+	}
+	fields := map[string]interface{}{
+		"symbol":    candle.Asset.Symbol,
+		"open":      candle.Open,
+		"high":      candle.High,
+		"low":       candle.Low,
+		"close":     candle.Close,
+		"volume":    candle.Volume,
+		"volumeUSD": candle.VolumeUSD,
+		"numTrades": candle.NumTrades,
+	}
+	datastore.addPoint(influxDbCandlesTable, tags, fields, candle.Time)
+	return nil
+}
+
+// GetCandles returns the candles of @asset on @exchange with @resolution beginning in [@starttime,@endtime)
+// in ascending order of time. An empty @exchange stands for all exchanges.
+func (datastore *MemoryDB) GetCandles(asset dia.Asset, exchange string, resolution string, starttime time.Time, endtime time.Time) ([]dia.Candle, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	candles := []dia.Candle{}
+	for _, p := range datastore.selectPoints(influxDbCandlesTable, func(p *memoryPoint) bool {
+		return p.str("address") == asset.Address && p.str("blockchain") == asset.Blockchain && p.str("exchange") == exchange &&
+			p.str("resolution") == resolution && !p.time.Before(starttime) && p.time.Before(endtime)
+	}) {
+		candle := dia.Candle{Asset: asset, Exchange: exchange, Resolution: resolution, Time: p.time}
+		if symbol := p.str("symbol"); symbol != "" {
+			candle.Asset.Symbol = symbol
+		}
+		candle.Open, _ = p.float("open")
+		candle.High, _ = p.float("high")
+		candle.Low, _ = p.float("low")
+		candle.Close, _ = p.float("close")
+		candle.Volume, _ = p.float("volume")
+		candle.VolumeUSD, _ = p.float("volumeUSD")
+		numTrades, _ := p.float("numTrades")
+		candle.NumTrades = int(numTrades)
+		candles = append(candles, candle)
+	}
+	return candles, nil
+}
+
+// GetLatestCandleTime returns the begin time of the latest candle with @resolution of any asset.
+// It returns the zero time if there is no such candle.
+func (datastore *MemoryDB) GetLatestCandleTime(resolution string) (time.Time, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := datastore.selectPoints(influxDbCandlesTable, func(p *memoryPoint) bool {
+		_, ok := p.fields["numTrades"]
+		return ok && p.str("resolution") == resolution
+	})
+	if len(points) == 0 {
+		return time.Time{}, nil
+	}
+	return points[len(points)-1].time, nil
+}
+
+// volume returns the sum of the volume filter's points in (@starttime,@endtime], as done by GetVolumeInflux.
+// The caller must hold the lock.
+func (datastore *MemoryDB) volume(asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) float64 {
+	var volume float64
+	for _, p := range datastore.selectPoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		if p.str("filter") != volumeKey || p.str("exchange") != exchange || !p.time.After(starttime) || p.time.After(endtime) {
+			return false
+		}
+		return asset == (dia.Asset{}) || (p.str("address") == asset.Address && p.str("blockchain") == asset.Blockchain)
+	}) {
+		value, _ := p.float("value")
+		volume += value
+	}
+	return volume
+}
+
+// GetVolumeInflux returns the volume of @asset on @exchange using the VOL120 filter in the given time-range.
+// Both, @asset and @exchange may be empty.
+// If @starttime,@endtime are empty, the last 24h are taken into account.
+func (datastore *MemoryDB) GetVolumeInflux(asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) (*float64, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	if endtime.IsZero() {
+		endtime = time.Now()
+		starttime = endtime.AddDate(0, 0, -1)
+	}
+	volume := datastore.volume(asset, exchange, starttime, endtime)
+	return &volume, nil
+}
+
+// Get24HoursAssetVolume returns the 24h trading volume of @asset across exchanges.
+func (datastore *MemoryDB) Get24HoursAssetVolume(asset dia.Asset) (*float64, error) {
+	endtime := time.Now()
+	return datastore.GetVolumeInflux(asset, "", endtime.AddDate(0, 0, -1), endtime)
+}
+
+// Get24HoursExchangeVolume returns 24h trade volume on @exchange using the VOL120 filter.
+func (datastore *MemoryDB) Get24HoursExchangeVolume(exchange string) (*float64, error) {
+	endtime := time.Now()
+	return datastore.GetVolumeInflux(dia.Asset{}, exchange, endtime.AddDate(0, 0, -1), endtime)
+}
+
+// GetAssetsWithVOLInflux returns all assets with points of the volume filter across exchanges since @timeInit.
+func (datastore *MemoryDB) GetAssetsWithVOLInflux(timeInit time.Time) ([]dia.Asset, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	var quotedAssets []dia.Asset
+	now := time.Now()
+	uniqueMap := make(map[dia.Asset]struct{})
+	for _, p := range datastore.selectPoints(influxDbFiltersTable, func(p *memoryPoint) bool {
+		return p.str("filter") == "VOL120" && p.str("exchange") == "" && p.time.After(timeInit) && p.time.Before(now)
+	}) {
+		asset := dia.Asset{Address: p.str("address"), Blockchain: p.str("blockchain")}
+		if _, ok := uniqueMap[asset]; !ok {
+			quotedAssets = append(quotedAssets, asset)
+			uniqueMap[asset] = struct{}{}
+		}
+	}
+	if len(quotedAssets) == 0 {
+		return quotedAssets, errors.New("no recent asset with volume in influx")
+	}
+	return quotedAssets, nil
+}
+
+// topAssets returns the assets with @symbol in descending order of their 24h volume. The caller must hold the lock.
+func (datastore *MemoryDB) topAssets(symbol string) []dia.Asset {
+	var assets []dia.Asset
+	volumes := make(map[dia.Asset]float64)
+	endtime := time.Now()
+	for key, asset := range datastore.assets {
+		if asset.symbol != symbol {
+			continue
+		}
+		a := dia.Asset{Symbol: asset.symbol, Name: asset.name, Address: key.address, Blockchain: key.blockchain}
+		assets = append(assets, a)
+		volumes[a] = datastore.volume(a, "", endtime.AddDate(0, 0, -1), endtime)
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if volumes[assets[i]] != volumes[assets[j]] {
+			return volumes[assets[i]] > volumes[assets[j]]
+		}
+		return fmt.Sprint(assets[i].Blockchain, assets[i].Address) < fmt.Sprint(assets[j].Blockchain, assets[j].Address)
+	})
+	return assets
+}
+
+// SetVWAPFirefly writes a value of the Firefly VWAP of @foreignName.
+func (datastore *MemoryDB) SetVWAPFirefly(foreignName string, value float64, timestamp time.Time) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	datastore.addPoint(influxDbVwapFireflyTable, map[string]string{"foreignName": foreignName}, map[string]interface{}{"value": value}, timestamp)
+	datastore.writeBatch()
+	return nil
+}
+
+// GetVWAPFirefly returns the values of the Firefly VWAP of @foreignName in (@starttime,@endtime] in descending order.
+func (datastore *MemoryDB) GetVWAPFirefly(foreignName string, starttime time.Time, endtime time.Time) (values []float64, timestamps []time.Time, err error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := reversePoints(datastore.selectPoints(influxDbVwapFireflyTable, func(p *memoryPoint) bool {
+		return p.str("foreignName") == foreignName && p.time.After(starttime) && !p.time.After(endtime)
+	}))
+	if len(points) == 0 {
+		err = errors.New("no data available in given time range")
+		return
+	}
+	for _, p := range points {
+		value, _ := p.float("value")
+		values = append(values, value)
+		timestamps = append(timestamps, p.time)
+	}
+	return
+}
@@ -0,0 +1,471 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/go-redis/redis"
+)
+
+// SetAssetPriceUSD stores the price of @asset in influx and the cache.
+func (datastore *MemoryDB) SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error {
+	return datastore.SetAssetQuotation(&AssetQuotation{
+		Asset:  asset,
+		Price:  price,
+		Source: dia.Diadata,
+		Time:   timestamp,
+	})
+}
+
+// GetAssetPriceUSDLatest returns the latest price of @asset.
+func (datastore *MemoryDB) GetAssetPriceUSDLatest(asset dia.Asset) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotationLatest(asset)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// GetAssetPriceUSD returns the latest USD price of @asset before @timestamp.
+func (datastore *MemoryDB) GetAssetPriceUSD(asset dia.Asset, timestamp time.Time) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotation(asset, timestamp)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// addAssetQuotation adds @quotation to the influx batch. The caller must hold the lock.
+func (datastore *MemoryDB) addAssetQuotation(quotation *AssetQuotation) {
+	tags := map[string]string{
+		"symbol":     quotation.Asset.Symbol,
+		"name":       quotation.Asset.Name,
+		"address":    quotation.Asset.Address,
+		"blockchain": quotation.Asset.Blockchain,
+	}
+	datastore.addPoint(influxDBAssetQuotationsTable, tags, map[string]interface{}{"price": quotation.Price}, quotation.Time)
+	datastore.addAsset(quotation.Asset.Symbol, quotation.Asset.Name, quotation.Asset.Address, quotation.Asset.Blockchain)
+}
+
+// AddAssetQuotationsToBatch adds @quotations to the influx batch.
+func (datastore *MemoryDB) AddAssetQuotationsToBatch(quotations []*AssetQuotation) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	for _, quotation := range quotations {
+		datastore.addAssetQuotation(quotation)
+	}
+	return nil
+}
+
+// SetAssetQuotation adds @quotation to the influx batch and sends it to the cache through the redis pipe.
+func (datastore *MemoryDB) SetAssetQuotation(quotation *AssetQuotation) error {
+	datastore.mu.Lock()
+	datastore.addAssetQuotation(quotation)
+	datastore.mu.Unlock()
+	_, err := datastore.SetAssetQuotationCache(quotation, false)
+	return err
+}
+
+// GetAssetQuotationLatest returns the latest quotation of @asset from the cache or, if not cached, from influx.
+func (datastore *MemoryDB) GetAssetQuotationLatest(asset dia.Asset) (*AssetQuotation, error) {
+	quotation, err := datastore.GetAssetQuotationCache(asset)
+	if err == nil {
+		return quotation, nil
+	}
+	return datastore.GetAssetQuotation(asset, time.Now())
+}
+
+// memoryAssetQuotation returns the quotation of @asset stored in @p.
+func memoryAssetQuotation(p *memoryPoint, asset dia.Asset) AssetQuotation {
+	quotation := AssetQuotation{Asset: asset, Source: dia.Diadata, Time: p.time}
+	quotation.Price, _ = p.float("price")
+	return quotation
+}
+
+// GetAssetQuotation returns the latest quotation of @asset until @timestamp.
+func (datastore *MemoryDB) GetAssetQuotation(asset dia.Asset, timestamp time.Time) (*AssetQuotation, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := datastore.selectPoints(influxDBAssetQuotationsTable, func(p *memoryPoint) bool {
+		_, ok := p.fields["price"]
+		return ok && p.str("address") == asset.Address && p.str("blockchain") == asset.Blockchain && !p.time.After(timestamp)
+	})
+	if len(points) == 0 {
+		return &AssetQuotation{}, errors.New("no assetQuotation in DB")
+	}
+	quotation := memoryAssetQuotation(points[len(points)-1], asset)
+	return &quotation, nil
+}
+
+// GetAssetQuotations returns all quotations of @asset in (@starttime,@endtime] in descending order.
+func (datastore *MemoryDB) GetAssetQuotations(asset dia.Asset, starttime time.Time, endtime time.Time) ([]AssetQuotation, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	quotations := []AssetQuotation{}
+	points := reversePoints(datastore.selectPoints(influxDBAssetQuotationsTable, func(p *memoryPoint) bool {
+		_, ok := p.fields["price"]
+		return ok && p.str("address") == asset.Address && p.str("blockchain") == asset.Blockchain &&
+			p.time.After(starttime) && !p.time.After(endtime)
+	}))
+	if len(points) == 0 {
+		return quotations, errors.New("no assetQuotation in DB")
+	}
+	for _, p := range points {
+		quotations = append(quotations, memoryAssetQuotation(p, asset))
+	}
+	return quotations, nil
+}
+
+// SetAssetQuotationCache sends @quotation to the cache through the redis pipe.
+// If @check is true, it checks for a more recent quotation first.
+func (datastore *MemoryDB) SetAssetQuotationCache(quotation *AssetQuotation, check bool) (bool, error) {
+	if check {
+		cachestate, err := datastore.GetAssetQuotationCache(quotation.Asset)
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, err
+		}
+		if quotation.Time.Before(cachestate.Time) {
+			return false, nil
+		}
+	}
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	key := getKeyAssetQuotation(quotation.Asset.Blockchain, quotation.Asset.Address)
+	cached := *quotation
+	datastore.pipeCommand(func() {
+		if err := datastore.setCache(key, &cached, TimeOutAssetQuotation); err != nil {
+			log.Error("set asset quotation cache: ", err)
+		}
+	})
+	return true, nil
+}
+
+// GetAssetQuotationCache returns the latest quotation of @asset from the cache.
+func (datastore *MemoryDB) GetAssetQuotationCache(asset dia.Asset) (*AssetQuotation, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	quotation := &AssetQuotation{}
+	err := datastore.getCache(getKeyAssetQuotation(asset.Blockchain, asset.Address), quotation)
+	return quotation, err
+}
+
+// GetAssetPriceUSDCache returns the latest price of @asset from the cache.
+func (datastore *MemoryDB) GetAssetPriceUSDCache(asset dia.Asset) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotationCache(asset)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// GetSortedAssetQuotations returns the quotations of all @assets in descending order of their 24h volume.
+func (datastore *MemoryDB) GetSortedAssetQuotations(assets []dia.Asset) ([]AssetQuotation, error) {
+	var quotations []AssetQuotation
+	var volumes []float64
+	for _, asset := range assets {
+		quotation, err := datastore.GetAssetQuotationLatest(asset)
+		if err != nil {
+			continue
+		}
+		volume, err := datastore.Get24HoursAssetVolume(asset)
+		if err != nil {
+			continue
+		}
+		quotations = append(quotations, *quotation)
+		volumes = append(volumes, *volume)
+	}
+	if len(quotations) == 0 {
+		return quotations, errors.New("no quotations available")
+	}
+	indices := make([]int, len(quotations))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool { return volumes[indices[i]] > volumes[indices[j]] })
+	quotationsSorted := make([]AssetQuotation, 0, len(quotations))
+	for _, i := range indices {
+		quotationsSorted = append(quotationsSorted, quotations[i])
+	}
+	return quotationsSorted, nil
+}
+
+// GetAssetsMarketCap returns the actual market cap of @asset.
+func (datastore *MemoryDB) GetAssetsMarketCap(asset dia.Asset) (float64, error) {
+	price, err := datastore.GetAssetPriceUSDLatest(asset)
+	if err != nil {
+		return 0, err
+	}
+	supply, err := datastore.GetSupplyCache(asset)
+	if err != nil {
+		return 0, err
+	}
+	return price * supply.CirculatingSupply, nil
+}
+
+// symbolAssets returns the assets with @symbol written to the store. @relDB is not used.
+func (datastore *MemoryDB) symbolAssets(symbol string) []dia.Asset {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	return datastore.topAssets(symbol)
+}
+
+// GetTopAssetByVolume returns the asset with highest volume among all assets with symbol @symbol.
+// Assets are looked up among the assets written to the store instead of @relDB.
+func (datastore *MemoryDB) GetTopAssetByVolume(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	assets := datastore.symbolAssets(symbol)
+	if len(assets) == 0 {
+		err = errors.New("no matching asset")
+		return
+	}
+	var volume float64
+	for _, asset := range assets {
+		value, err := datastore.Get24HoursAssetVolume(asset)
+		if err != nil || value == nil {
+			continue
+		}
+		if *value > volume {
+			volume = *value
+			topAsset = asset
+		}
+	}
+	if volume == 0 {
+		err = errors.New("no quotation for symbol")
+	}
+	return
+}
+
+// GetTopAssetByMcap returns the asset with highest market cap among all assets with symbol @symbol.
+// Assets are looked up among the assets written to the store instead of @relDB.
+func (datastore *MemoryDB) GetTopAssetByMcap(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	assets := datastore.symbolAssets(symbol)
+	if len(assets) == 0 {
+		err = errors.New("no matching asset")
+		return
+	}
+	var mcap float64
+	for _, asset := range assets {
+		value, err := datastore.GetAssetsMarketCap(asset)
+		if err != nil {
+			continue
+		}
+		if value > mcap {
+			mcap = value
+			topAsset = asset
+		}
+	}
+	if mcap == 0 {
+		err = errors.New("no quotation for symbol")
+	}
+	return
+}
+
+// SetBatchFiatPriceInflux writes @fiatQuotations to influx.
+func (datastore *MemoryDB) SetBatchFiatPriceInflux(fiatQuotations []*FiatQuotation) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	for _, fq := range fiatQuotations {
+		tags := map[string]string{
+			"quote_currency": fq.QuoteCurrency,
+			"base_currency":  fq.BaseCurrency,
+			"source":         fq.Source,
+		}
+		datastore.addPoint(influxDbFiatQuotationsTable, tags, map[string]interface{}{"price": fq.Price}, fq.Time)
+	}
+	datastore.writeBatch()
+	return nil
+}
+
+// SetSingleFiatPriceRedis stores @fiatQuotation in the cache.
+func (datastore *MemoryDB) SetSingleFiatPriceRedis(fiatQuotation *FiatQuotation) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	return datastore.setCache(getKeyQuotation(fiatQuotation.QuoteCurrency), fiatQuotation, TimeOutRedis)
+}
+
+// SaveForeignQuotationInflux writes a quotation which is not from DIA to influx.
+func (datastore *MemoryDB) SaveForeignQuotationInflux(fq ForeignQuotation) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	fields := map[string]interface{}{
+		"price":              fq.Price,
+		"priceYesterday":     fq.PriceYesterday,
+		"source":             fq.Source,
+		"volumeYesterdayUSD": fq.VolumeYesterdayUSD,
+	}
+	datastore.addPoint(influxDbForeignQuotationTable, map[string]string{"symbol": fq.Symbol, "name": fq.Name}, fields, fq.Time)
+	datastore.writeBatch()
+	return nil
+}
+
+// GetForeignQuotationInflux returns the last quotation of @symbol from @source before @timestamp.
+// As in DB, an empty quotation is returned if there is none.
+func (datastore *MemoryDB) GetForeignQuotationInflux(symbol, source string, timestamp time.Time) (ForeignQuotation, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	retval := ForeignQuotation{}
+	points := datastore.selectPoints(influxDbForeignQuotationTable, func(p *memoryPoint) bool {
+		return p.str("source") == source && p.str("symbol") == symbol && p.time.Before(timestamp)
+	})
+	if len(points) == 0 {
+		return retval, nil
+	}
+	p := points[len(points)-1]
+	retval.Time = p.time
+	retval.Price, _ = p.float("price")
+	retval.PriceYesterday, _ = p.float("priceYesterday")
+	retval.VolumeYesterdayUSD, _ = p.float("volumeYesterdayUSD")
+	retval.Name = p.str("name")
+	retval.Source = source
+	retval.Symbol = symbol
+	return retval, nil
+}
+
+// GetForeignPriceYesterday returns the average price of @symbol on @source from yesterday.
+func (datastore *MemoryDB) GetForeignPriceYesterday(symbol, source string) (float64, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	now := time.Now()
+	secondsFromYesterday := now.Hour()*60*60 + now.Minute()*60 + now.Second()
+	timeFinal := time.Unix(now.Unix()-int64(secondsFromYesterday)-1, 0)
+	timeInit := timeFinal.Add(-24 * time.Hour)
+	var price float64
+	var numPrices int
+	for _, p := range datastore.selectPoints(influxDbForeignQuotationTable, func(p *memoryPoint) bool {
+		return p.str("source") == source && p.str("symbol") == symbol && p.time.After(timeInit) && p.time.Before(timeFinal)
+	}) {
+		if value, ok := p.float("price"); ok {
+			price += value
+			numPrices++
+		}
+	}
+	if numPrices == 0 {
+		return 0, errors.New("no data available from yesterday")
+	}
+	return price / float64(numPrices), nil
+}
+
+// GetForeignSymbolsInflux returns the symbols quoted by @source in the last 7 days.
+func (datastore *MemoryDB) GetForeignSymbolsInflux(source string) (symbols []string, err error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	set := make(map[string]struct{})
+	since := time.Now().AddDate(0, 0, -7)
+	for _, p := range datastore.selectPoints(influxDbForeignQuotationTable, func(p *memoryPoint) bool {
+		return p.str("source") == source && p.time.After(since)
+	}) {
+		if _, ok := set[p.str("symbol")]; !ok {
+			symbols = append(symbols, p.str("symbol"))
+			set[p.str("symbol")] = struct{}{}
+		}
+	}
+	return
+}
+
+// SetStockQuotation writes a stock quotation to influx.
+func (datastore *MemoryDB) SetStockQuotation(sq StockQuotation) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	fields := map[string]interface{}{
+		"priceAsk": sq.PriceAsk,
+		"priceBid": sq.PriceBid,
+		"sizeAsk":  sq.SizeAskLot,
+		"sizeBid":  sq.SizeBidLot,
+		"source":   sq.Source,
+	}
+	datastore.addPoint(influxDbStockQuotationsTable, map[string]string{"symbol": sq.Symbol, "name": sq.Name, "isin": sq.ISIN}, fields, sq.Time)
+	datastore.writeBatch()
+	return nil
+}
+
+// GetStockQuotation returns the quotations of @symbol from @source in (@timeInit,@timeFinal] in descending order.
+func (datastore *MemoryDB) GetStockQuotation(source string, symbol string, timeInit time.Time, timeFinal time.Time) ([]StockQuotation, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	stockQuotations := []StockQuotation{}
+	for _, p := range reversePoints(datastore.selectPoints(influxDbStockQuotationsTable, func(p *memoryPoint) bool {
+		return p.str("source") == source && p.str("symbol") == symbol && p.time.After(timeInit) && !p.time.After(timeFinal)
+	})) {
+		stockQuotation := StockQuotation{
+			Symbol: symbol,
+			Name:   p.str("name"),
+			ISIN:   p.str("isin"),
+			Source: p.str("source"),
+			Time:   p.time,
+		}
+		stockQuotation.PriceAsk, _ = p.float("priceAsk")
+		stockQuotation.PriceBid, _ = p.float("priceBid")
+		stockQuotation.SizeAskLot, _ = p.float("sizeAsk")
+		stockQuotation.SizeBidLot, _ = p.float("sizeBid")
+		stockQuotations = append(stockQuotations, stockQuotation)
+	}
+	return stockQuotations, nil
+}
+
+// GetStockSymbols returns the stocks quoted in the last 7 days mapped to their source.
+func (datastore *MemoryDB) GetStockSymbols() (map[Stock]string, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	allStocks := make(map[Stock]string)
+	set := make(map[string]struct{})
+	since := time.Now().AddDate(0, 0, -7)
+	for _, p := range datastore.selectPoints(influxDbStockQuotationsTable, func(p *memoryPoint) bool { return p.time.After(since) }) {
+		if _, ok := set[p.str("isin")+p.str("source")]; !ok {
+			allStocks[Stock{Symbol: p.str("symbol"), Name: p.str("name"), ISIN: p.str("isin")}] = p.str("source")
+			set[p.str("isin")+p.str("source")] = struct{}{}
+		}
+	}
+	return allStocks, nil
+}
+
+// SetCurrencyChange stores the currency change @cc.
+func (datastore *MemoryDB) SetCurrencyChange(cc *Change) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	return datastore.setCache("dia_currencyChange", cc, 0)
+}
+
+// GetCurrencyChange returns the stored currency change.
+func (datastore *MemoryDB) GetCurrencyChange() (*Change, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	value := &Change{}
+	if err := datastore.getCache("dia_currencyChange", value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// SaveIndexEngineTimeInflux writes a point of a benchmarked index to influx.
+func (datastore *MemoryDB) SaveIndexEngineTimeInflux(tags map[string]string, fields map[string]interface{}, timestamp time.Time) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	datastore.addPoint(influxDbBenchmarkedIndexTableName, tags, fields, timestamp)
+	datastore.writeBatch()
+	return nil
+}
+
+// GetBenchmarkedIndexValuesInflux returns the values of the index @symbol in (@starttime,@endtime) in descending order.
+func (datastore *MemoryDB) GetBenchmarkedIndexValuesInflux(symbol string, starttime time.Time, endtime time.Time) (BenchmarkedIndex, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	currentIndex := BenchmarkedIndex{}
+	for _, p := range reversePoints(datastore.selectPoints(influxDbBenchmarkedIndexTableName, func(p *memoryPoint) bool {
+		return p.str("name") == symbol && p.time.After(starttime) && p.time.Before(endtime)
+	})) {
+		currentIndex.Name = p.str("name")
+		value := p.str("value")
+		if value == "" {
+			if v, ok := p.fields["value"]; ok {
+				value = fmt.Sprint(v)
+			}
+		}
+		currentIndex.Values = append(currentIndex.Values, BenchmarkedIndexValue{CalculationTime: p.time, Value: value})
+	}
+	return currentIndex, nil
+}
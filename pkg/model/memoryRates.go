@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/utils"
+	"github.com/go-redis/redis"
+)
+
+// SetInterestRate stores @ir under the same key as DB and adds its symbol to the set of available rates.
+func (datastore *MemoryDB) SetInterestRate(ir *InterestRate) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	err := datastore.setCache(getKeyInterestRate(ir.Symbol, ir.EffectiveDate), ir, TimeOutRedis)
+	if err != nil {
+		return err
+	}
+	if datastore.sets[keyAllRates] == nil {
+		datastore.sets[keyAllRates] = make(map[string]struct{})
+	}
+	datastore.sets[keyAllRates][ir.Symbol] = struct{}{}
+	return nil
+}
+
+// GetInterestRate returns the interest rate value for the last time stamp before @date.
+// If @date is an empty string it returns the rate at the latest time stamp.
+func (datastore *MemoryDB) GetInterestRate(symbol, date string) (*InterestRate, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	if date == "" {
+		date = time.Now().Format("2006-01-02")
+	}
+	ir := &InterestRate{}
+	// As in DB, a missing key is looked up as empty key.
+	key, _ := datastore.matchKeyInterestRate(symbol, date)
+	return ir, datastore.getCache(key, ir)
+}
+
+// matchKeyInterestRate returns the latest key of @symbol on the last day before @date with an entry.
+// The caller must hold the lock.
+func (datastore *MemoryDB) matchKeyInterestRate(symbol, date string) (string, error) {
+	maxDays := 30
+	for count := 0; count < maxDays; count++ {
+		keys := datastore.cacheKeys("*" + symbol + "_" + date + "*")
+		if len(keys) > 0 {
+			return keys[len(keys)-1], nil
+		}
+		date = utils.GetYesterday(date, "2006-01-02")
+	}
+	return "", errors.New("No database entry found in the last " + strconv.Itoa(maxDays) + "days.")
+}
+
+// GetInterestRateRange returns the interest rate values of @symbol for the days from @dateInit to @dateFinal.
+func (datastore *MemoryDB) GetInterestRateRange(symbol, dateInit, dateFinal string) ([]*InterestRate, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	allValues := []*InterestRate{}
+	for auxDate := dateInit; dateFinal >= auxDate; auxDate = utils.GetTomorrow(auxDate, "2006-01-02") {
+		ir := &InterestRate{}
+		err := datastore.getCache("dia_quotation_"+symbol+"_"+auxDate+" 00:00:00 +0000 UTC", ir)
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return []*InterestRate{}, err
+		}
+		allValues = append(allValues, ir)
+	}
+	sort.Slice(allValues, func(i, j int) bool {
+		return (allValues[i].EffectiveDate).Before(allValues[j].EffectiveDate)
+	})
+	return allValues, nil
+}
+
+// GetRates returns the symbols of all rates written to the store.
+func (datastore *MemoryDB) GetRates() []string {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	allRates := []string{}
+	for symbol := range datastore.sets[keyAllRates] {
+		allRates = append(allRates, symbol)
+	}
+	sort.Strings(allRates)
+	return allRates
+}
+
+// GetRatesMeta returns a list of all available rate symbols along with their first
+// timestamp in the database.
+func (datastore *MemoryDB) GetRatesMeta() (RatesMeta []InterestRateMeta, err error) {
+	return ratesMeta(datastore)
+}
+
+// GetIssuer returns the issuing entity of the rate given by @symbol.
+func (datastore *MemoryDB) GetIssuer(symbol string) (string, error) {
+	newdate, err := datastore.GetFirstDate(symbol)
+	if err != nil {
+		return "", err
+	}
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	ir := &InterestRate{}
+	err = datastore.getCache(getKeyInterestRate(symbol, newdate), ir)
+	return ir.Source, err
+}
+
+// GetFirstDate returns the oldest date written in the database for the rate with symbol @symbol.
+func (datastore *MemoryDB) GetFirstDate(symbol string) (time.Time, error) {
+	allSyms := datastore.GetRates()
+	if !(utils.Contains(&allSyms, symbol)) {
+		return time.Time{}, errors.New("database error")
+	}
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	oldestKey, _ := utils.MinString(datastore.cacheKeys("dia_quotation_" + symbol + "_*"))
+	ir := &InterestRate{}
+	if err := datastore.getCache(oldestKey, ir); err != nil {
+		return time.Time{}, err
+	}
+	return ir.EffectiveDate, nil
+}
+
+// GetCompoundedIndex returns the compounded index over the maximal period of existence of @symbol.
+func (datastore *MemoryDB) GetCompoundedIndex(symbol string, date time.Time, daysPerYear int, rounding int) (*InterestRate, error) {
+	return compoundedIndex(datastore, symbol, date, daysPerYear, rounding)
+}
+
+// GetCompoundedIndexRange returns the compounded index of @symbol for all days from @dateInit to @dateFinal.
+func (datastore *MemoryDB) GetCompoundedIndexRange(symbol string, dateInit, dateFinal time.Time, daysPerYear int, rounding int) ([]*InterestRate, error) {
+	return compoundedIndexRange(datastore, symbol, dateInit, dateFinal, daysPerYear, rounding)
+}
+
+// GetCompoundedAvg returns the compounded average of the index @symbol over rolling @calDays calendar days.
+func (datastore *MemoryDB) GetCompoundedAvg(symbol string, date time.Time, calDays, daysPerYear int, rounding int) (*InterestRate, error) {
+	return compoundedAvg(datastore, symbol, date, calDays, daysPerYear, rounding)
+}
+
+// GetCompoundedAvgRange returns the compounded average of the index @symbol over rolling @calDays calendar days.
+func (datastore *MemoryDB) GetCompoundedAvgRange(symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) ([]*InterestRate, error) {
+	return compoundedAvgRange(datastore, symbol, dateInit, dateFinal, calDays, daysPerYear, rounding)
+}
+
+// GetCompoundedAvgDIARange returns the compounded average DIA index of @symbol over rolling @calDays calendar days.
+func (datastore *MemoryDB) GetCompoundedAvgDIARange(symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) ([]*InterestRate, error) {
+	return compoundedAvgDIARange(datastore, symbol, dateInit, dateFinal, calDays, daysPerYear, rounding)
+}
@@ -0,0 +1,267 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// SaveSupplyInflux writes @supply to influx.
+func (datastore *MemoryDB) SaveSupplyInflux(supply *dia.Supply) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	fields := map[string]interface{}{
+		"supply":            supply.Supply,
+		"circulatingsupply": supply.CirculatingSupply,
+		"source":            supply.Source,
+	}
+	tags := map[string]string{
+		"symbol":     supply.Asset.Symbol,
+		"name":       supply.Asset.Name,
+		"address":    supply.Asset.Address,
+		"blockchain": supply.Asset.Blockchain,
+	}
+	datastore.addPoint(influxDbSupplyTable, tags, fields, supply.Time)
+	datastore.addAsset(supply.Asset.Symbol, supply.Asset.Name, supply.Asset.Address, supply.Asset.Blockchain)
+	datastore.writeBatch()
+	return nil
+}
+
+// GetSupplyInflux returns supply and circulating supply of @asset in (@starttime,@endtime) in descending order.
+// If no time range is given it returns the latest supply.
+func (datastore *MemoryDB) GetSupplyInflux(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.Supply, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	retval := []dia.Supply{}
+	latest := starttime.IsZero() || endtime.IsZero()
+	now := time.Now()
+	points := reversePoints(datastore.selectPoints(influxDbSupplyTable, func(p *memoryPoint) bool {
+		if p.str("address") != asset.Address || p.str("blockchain") != asset.Blockchain {
+			return false
+		}
+		if latest {
+			return p.time.Before(now)
+		}
+		return p.time.After(starttime) && p.time.Before(endtime)
+	}))
+	if len(points) == 0 {
+		return retval, errors.New("parsing supply value from database")
+	}
+	if latest {
+		points = points[:1]
+	}
+	for _, p := range points {
+		currentSupply := dia.Supply{Asset: asset, Time: p.time, Source: p.str("source")}
+		currentSupply.Supply, _ = p.float("supply")
+		currentSupply.CirculatingSupply, _ = p.float("circulatingsupply")
+		if name := p.str("name"); name != "" {
+			currentSupply.Asset.Name = name
+		}
+		if symbol := p.str("symbol"); symbol != "" {
+			currentSupply.Asset.Symbol = symbol
+		}
+		retval = append(retval, currentSupply)
+	}
+	return retval, nil
+}
+
+// GetLatestSupply returns the latest supply of the asset with @symbol and highest volume.
+func (datastore *MemoryDB) GetLatestSupply(symbol string, relDB *RelDB) (*dia.Supply, error) {
+	val, err := datastore.GetSupply(symbol, time.Time{}, time.Time{}, relDB)
+	if err != nil {
+		return &dia.Supply{}, err
+	}
+	return &val[0], err
+}
+
+// GetSupply returns the supplies of the asset with @symbol and highest volume in (@starttime,@endtime).
+// Assets are looked up among the assets written to the store instead of @relDB.
+func (datastore *MemoryDB) GetSupply(symbol string, starttime, endtime time.Time, relDB *RelDB) ([]dia.Supply, error) {
+	topAsset := datastore.symbolAssets(symbol)
+	if len(topAsset) < 1 {
+		return []dia.Supply{}, errors.New("no traded assets found")
+	}
+	return datastore.GetSupplyInflux(topAsset[0], starttime, endtime)
+}
+
+// GetSupplyCache returns the cached supply of @asset.
+func (datastore *MemoryDB) GetSupplyCache(asset dia.Asset) (supply dia.Supply, err error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	err = datastore.getCache(getKeySupply(asset), &supply)
+	return
+}
+
+// SetSupply stores @supply in the cache and in influx.
+func (datastore *MemoryDB) SetSupply(supply *dia.Supply) error {
+	datastore.mu.Lock()
+	err := datastore.setCache(getKeySupply(supply.Asset), supply, 0)
+	datastore.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return datastore.SaveSupplyInflux(supply)
+}
+
+// SetDiaTotalSupply stores the total supply of DIA.
+func (datastore *MemoryDB) SetDiaTotalSupply(totalSupply float64) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	return datastore.setCache(getKeyDiaTotalSupply(), totalSupply, 0)
+}
+
+// GetDiaTotalSupply returns the total supply of DIA.
+func (datastore *MemoryDB) GetDiaTotalSupply() (totalSupply float64, err error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	err = datastore.getCache(getKeyDiaTotalSupply(), &totalSupply)
+	return
+}
+
+// SetDiaCirculatingSupply stores the circulating supply of DIA.
+func (datastore *MemoryDB) SetDiaCirculatingSupply(circulatingSupply float64) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	return datastore.setCache(getKeyDiaCirculatingSupply(), circulatingSupply, 0)
+}
+
+// GetDiaCirculatingSupply returns the circulating supply of DIA.
+func (datastore *MemoryDB) GetDiaCirculatingSupply() (circulatingSupply float64, err error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	err = datastore.getCache(getKeyDiaCirculatingSupply(), &circulatingSupply)
+	return
+}
+
+// SaveSynthSupplyInflux adds a synth supply to the influx batch.
+func (datastore *MemoryDB) SaveSynthSupplyInflux(t *dia.SynthAssetSupply) error {
+	return datastore.SaveSynthSupplyInfluxToTable(t, influxDbSynthSupplyTable)
+}
+
+// SaveSynthSupplyInfluxToTable adds a synth supply to the influx batch of @table.
+func (datastore *MemoryDB) SaveSynthSupplyInfluxToTable(t *dia.SynthAssetSupply, table string) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	tags := map[string]string{
+		"synthassetsymbol":       t.Asset.Symbol,
+		"underlyingassetsymbol":  t.AssetUnderlying.Symbol,
+		"synthtokenaddress":      t.Asset.Address,
+		"underlyingtokenaddress": t.AssetUnderlying.Address,
+		"blockchain":             t.Asset.Blockchain,
+		"protocol":               t.Protocol,
+	}
+	fields := map[string]interface{}{
+		"supply":           t.Supply,
+		"underlyinglocked": t.LockedUnderlying,
+		"collateralRatio":  t.ColleteralRatio,
+		"blocknumber":      int64(t.BlockNumber),
+		"totaldebt":        int64(t.TotalDebt),
+	}
+	datastore.addPoint(table, tags, fields, t.Time)
+	return nil
+}
+
+// GetSynthAssets returns the addresses of the synth assets of @protocol on @blockchain.
+func (datastore *MemoryDB) GetSynthAssets(blockchain, protocol string) (r []string, err error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	set := make(map[string]struct{})
+	for _, p := range datastore.selectPoints(influxDbSynthSupplyTable, func(p *memoryPoint) bool {
+		return p.tags["blockchain"] == blockchain && p.tags["protocol"] == protocol && p.tags["synthtokenaddress"] != ""
+	}) {
+		if _, ok := set[p.tags["synthtokenaddress"]]; !ok {
+			set[p.tags["synthtokenaddress"]] = struct{}{}
+			r = append(r, p.tags["synthtokenaddress"])
+		}
+	}
+	if len(r) == 0 {
+		return r, fmt.Errorf("Empty response for")
+	}
+	return r, nil
+}
+
+// GetSynthSupplyInflux returns the supplies of synth assets on @blockchain in (@starttime,@endtime] in descending order.
+// @protocol and @address are optional. Only the latest supply is returned if @limit is 1.
+func (datastore *MemoryDB) GetSynthSupplyInflux(blockchain, protocol, address string, limit int, starttime, endtime time.Time) ([]dia.SynthAssetSupply, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	var r []dia.SynthAssetSupply
+	points := reversePoints(datastore.selectPoints(influxDbSynthSupplyTable, func(p *memoryPoint) bool {
+		if p.tags["blockchain"] != blockchain || (protocol != "" && p.tags["protocol"] != protocol) {
+			return false
+		}
+		if address != "" && address != "0x0000000000000000000000000000000000000000" &&
+			p.tags["underlyingtokenaddress"] != address && p.tags["synthtokenaddress"] != address {
+			return false
+		}
+		return p.time.After(starttime) && !p.time.After(endtime)
+	}))
+	if len(points) == 0 {
+		return r, fmt.Errorf("Empty response for")
+	}
+	if limit == 1 {
+		points = points[:1]
+	}
+	for _, p := range points {
+		supply := dia.SynthAssetSupply{
+			Time:            p.time,
+			Protocol:        p.tags["protocol"],
+			Asset:           dia.Asset{Symbol: p.tags["synthassetsymbol"], Blockchain: blockchain, Address: p.tags["synthtokenaddress"]},
+			AssetUnderlying: dia.Asset{Symbol: p.tags["underlyingassetsymbol"], Blockchain: blockchain, Address: p.tags["underlyingtokenaddress"]},
+		}
+		blocknumber, _ := p.float("blocknumber")
+		supply.BlockNumber = uint64(blocknumber)
+		supply.ColleteralRatio, _ = p.float("collateralRatio")
+		supply.Supply, _ = p.float("supply")
+		supply.TotalDebt, _ = p.float("totaldebt")
+		supply.LockedUnderlying, _ = p.float("underlyinglocked")
+		r = append(r, supply)
+	}
+	return r, nil
+}
+
+// SavePoolInflux writes a DEX pool to influx.
+func (datastore *MemoryDB) SavePoolInflux(p dia.Pool) error {
+	assetvolumesEncoded, err := json.Marshal(p.Assetvolumes)
+	if err != nil {
+		return err
+	}
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	tags := map[string]string{
+		"exchange":   p.Exchange.Name,
+		"blockchain": p.Blockchain.Name,
+		"address":    p.Address,
+	}
+	datastore.addPoint(influxDbDEXPoolTable, tags, map[string]interface{}{"volumes": string(assetvolumesEncoded)}, p.Time)
+	datastore.writeBatch()
+	return nil
+}
+
+// GetPoolInflux returns all info/liquidities of pool with @poolAddress in the time-range [starttime, endtime).
+func (datastore *MemoryDB) GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	pools := []dia.Pool{}
+	points := reversePoints(datastore.selectPoints(influxDbDEXPoolTable, func(p *memoryPoint) bool {
+		return p.tags["address"] == poolAddress && !p.time.Before(starttime) && p.time.Before(endtime)
+	}))
+	if len(points) == 0 {
+		return pools, errors.New("parsing pool from database")
+	}
+	for _, p := range points {
+		var pool dia.Pool
+		pool.Time = p.time
+		pool.Exchange.Name = p.str("exchange")
+		pool.Blockchain.Name = p.str("blockchain")
+		if err := json.Unmarshal([]byte(p.str("volumes")), &pool.Assetvolumes); err != nil {
+			return pools, err
+		}
+		pool.Address = poolAddress
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
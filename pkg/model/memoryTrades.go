@@ -0,0 +1,440 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// SaveTradeInflux adds a trade to the influx batch.
+func (datastore *MemoryDB) SaveTradeInflux(t *dia.Trade) error {
+	return datastore.SaveTradeInfluxToTable(t, influxDbTradesTable)
+}
+
+// SaveTradeInfluxToTable adds a trade of @table to the influx batch.
+func (datastore *MemoryDB) SaveTradeInfluxToTable(t *dia.Trade, table string) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	tags := map[string]string{
+		"symbol":               t.Symbol,
+		"pair":                 t.Pair,
+		"exchange":             t.Source,
+		"verified":             strconv.FormatBool(t.VerifiedPair),
+		"quotetokenaddress":    t.QuoteToken.Address,
+		"basetokenaddress":     t.BaseToken.Address,
+		"quotetokenblockchain": t.QuoteToken.Blockchain,
+		"basetokenblockchain":  t.BaseToken.Blockchain,
+	}
+	fields := map[string]interface{}{
+		"price":             t.Price,
+		"volume":            t.Volume,
+		"estimatedUSDPrice": t.EstimatedUSDPrice,
+		"foreignTradeID":    t.ForeignTradeID,
+	}
+	datastore.addPoint(table, tags, fields, t.Time)
+	symbol := t.QuoteToken.Symbol
+	if symbol == "" {
+		symbol = t.Symbol
+	}
+	datastore.addAsset(symbol, t.QuoteToken.Name, t.QuoteToken.Address, t.QuoteToken.Blockchain)
+	return nil
+}
+
+// memoryTrade returns the trade stored in @p with the columns returned by parseTrade.
+func memoryTrade(p *memoryPoint, fullBasetoken bool) dia.Trade {
+	trade := dia.Trade{
+		Symbol:         p.str("symbol"),
+		Pair:           p.str("pair"),
+		Time:           p.time,
+		Source:         p.str("exchange"),
+		ForeignTradeID: p.str("foreignTradeID"),
+		VerifiedPair:   p.str("verified") == "true",
+	}
+	trade.EstimatedUSDPrice, _ = p.float("estimatedUSDPrice")
+	trade.Price, _ = p.float("price")
+	trade.Volume, _ = p.float("volume")
+	if fullBasetoken {
+		trade.BaseToken.Blockchain = p.str("basetokenblockchain")
+		trade.BaseToken.Address = p.str("basetokenaddress")
+	}
+	return trade
+}
+
+func isQuoteToken(p *memoryPoint, address string, blockchain string) bool {
+	return p.str("quotetokenaddress") == address && p.str("quotetokenblockchain") == blockchain
+}
+
+// GetTradeInflux returns the latest trade of @asset on @exchange in [@endtime-@window,@endtime).
+func (datastore *MemoryDB) GetTradeInflux(asset dia.Asset, exchange string, endtime time.Time, window time.Duration) (*dia.Trade, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	starttime := endtime.Add(-window)
+	points := datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+		return isQuoteToken(p, asset.Address, asset.Blockchain) &&
+			(exchange == "" || p.str("exchange") == exchange) &&
+			!p.time.Before(starttime) && p.time.Before(endtime)
+	})
+	if len(points) == 0 {
+		return &dia.Trade{}, errors.New("parsing trade from database")
+	}
+	trade := memoryTrade(points[len(points)-1], false)
+	trade.VerifiedPair = false
+	return &trade, nil
+}
+
+// GetOldTradesFromInflux returns the trades of @table on @exchange in [@timeInit,@timeFinal) in ascending order.
+// If @exchange is empty, trades across all exchanges are returned.
+// If @verified is true, address and blockchain are also returned for both assets.
+func (datastore *MemoryDB) GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) ([]dia.Trade, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	allTrades := []dia.Trade{}
+	points := datastore.selectPoints(table, func(p *memoryPoint) bool {
+		return (exchange == "" || p.str("exchange") == exchange) && !p.time.Before(timeInit) && p.time.Before(timeFinal)
+	})
+	if len(points) == 0 {
+		return allTrades, errors.New("no trades in time range")
+	}
+	for _, p := range points {
+		// Trades without symbol are skipped, as in DB.
+		if p.str("symbol") == "" {
+			continue
+		}
+		trade := memoryTrade(p, verified)
+		if verified {
+			trade.QuoteToken.Address = p.str("quotetokenaddress")
+			trade.QuoteToken.Blockchain = p.str("quotetokenblockchain")
+		} else {
+			trade.VerifiedPair = false
+		}
+		allTrades = append(allTrades, trade)
+	}
+	return allTrades, nil
+}
+
+// GetTradesByExchanges returns the trades of @asset on @exchanges in [@startTime,@endTime].
+func (datastore *MemoryDB) GetTradesByExchanges(asset dia.Asset, baseassets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesFull(asset, baseassets, exchanges, false, startTime, endTime)
+}
+
+// GetTradesByExchangesAndBaseAssets returns the trades of @asset against @baseassets on @exchanges in [@startTime,@endTime].
+func (datastore *MemoryDB) GetTradesByExchangesAndBaseAssets(asset dia.Asset, baseassets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesFull(asset, baseassets, exchanges, false, startTime, endTime)
+}
+
+// GetTradesByExchangesFull returns the trades of @asset with positive USD price in [@startTime,@endTime] on
+// @exchanges. As in DB, @baseassets only restrict the trades if @exchanges are given.
+func (datastore *MemoryDB) GetTradesByExchangesFull(asset dia.Asset, baseassets []dia.Asset, exchanges []string, returnBasetoken bool, startTime, endTime time.Time) ([]dia.Trade, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	matchExchange, err := matchExchanges(exchanges)
+	if err != nil {
+		return nil, err
+	}
+	if len(exchanges) == 0 {
+		baseassets = nil
+	}
+	var r []dia.Trade
+	for _, p := range datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+		return !p.time.Before(startTime) && !p.time.After(endTime)
+	}) {
+		if trade, ok := filterTrade(p, asset, baseassets, matchExchange, returnBasetoken); ok {
+			r = append(r, trade)
+		}
+	}
+	if len(r) == 0 {
+		return nil, fmt.Errorf("no trades found")
+	}
+	return r, nil
+}
+
+// GetTradesByExchangesBatched returns the trades of @quoteasset on @exchanges in the intervals (@startTimes[i],@endTimes[i]].
+func (datastore *MemoryDB) GetTradesByExchangesBatched(quoteasset dia.Asset, baseassets []dia.Asset, exchanges []string, startTimes, endTimes []time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesBatchedFull(quoteasset, baseassets, exchanges, false, startTimes, endTimes)
+}
+
+// GetTradesByExchangesBatchedFull returns the trades of @quoteasset with positive USD price on @exchanges in the
+// time ranges (startTimes[i],endTimes[i]]. Trades are restricted to @baseassets if given.
+func (datastore *MemoryDB) GetTradesByExchangesBatchedFull(quoteasset dia.Asset, baseassets []dia.Asset, exchanges []string, returnBasetoken bool, startTimes, endTimes []time.Time) ([]dia.Trade, error) {
+	if len(startTimes) != len(endTimes) {
+		return []dia.Trade{}, errors.New("number of start times must equal number of end times.")
+	}
+	if len(startTimes) == 0 {
+		return nil, fmt.Errorf("no trades found")
+	}
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	matchExchange, err := matchExchanges(exchanges)
+	if err != nil {
+		return nil, err
+	}
+	var r []dia.Trade
+	for i := range startTimes {
+		for _, p := range datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+			return p.time.After(startTimes[i]) && !p.time.After(endTimes[i])
+		}) {
+			if trade, ok := filterTrade(p, quoteasset, baseassets, matchExchange, returnBasetoken); ok {
+				r = append(r, trade)
+			}
+		}
+	}
+	return r, nil
+}
+
+// filterTrade returns the trade stored in @p if it is a trade of @asset against one of @baseassets on a matching
+// exchange with positive USD price. All base assets match if @baseassets is empty.
+func filterTrade(p *memoryPoint, asset dia.Asset, baseassets []dia.Asset, matchExchange func(string) bool, returnBasetoken bool) (dia.Trade, bool) {
+	if !isQuoteToken(p, asset.Address, asset.Blockchain) || !matchExchange(p.str("exchange")) {
+		return dia.Trade{}, false
+	}
+	if price, _ := p.float("estimatedUSDPrice"); price <= 0 {
+		return dia.Trade{}, false
+	}
+	if len(baseassets) > 0 {
+		var ok bool
+		for _, baseasset := range baseassets {
+			if p.str("basetokenaddress") == baseasset.Address && p.str("basetokenblockchain") == baseasset.Blockchain {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return dia.Trade{}, false
+		}
+	}
+	return memoryTrade(p, returnBasetoken), true
+}
+
+// GetAllTrades returns at most @maxTrades trades with timestamp after the second of @t.
+func (datastore *MemoryDB) GetAllTrades(t time.Time, maxTrades int) ([]dia.Trade, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	after := time.Unix(t.Unix(), 0)
+	var r []dia.Trade
+	for _, p := range datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool { return p.time.After(after) }) {
+		if maxTrades > 0 && len(r) == maxTrades {
+			break
+		}
+		r = append(r, memoryTrade(p, false))
+	}
+	return r, nil
+}
+
+// GetLastTrades returns the last @maxTrades of @asset on @exchange in the last 30 days.
+// If exchange is empty string it returns trades from all exchanges.
+// If fullAsset=true, blockchain and address of the base token are returned as well.
+func (datastore *MemoryDB) GetLastTrades(asset dia.Asset, exchange string, maxTrades int, fullAsset bool) ([]dia.Trade, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	now := time.Now()
+	points := reversePoints(datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+		price, _ := p.float("estimatedUSDPrice")
+		return p.time.Before(now) && p.time.After(now.AddDate(0, 0, -30)) &&
+			(exchange == "" || p.str("exchange") == exchange) &&
+			isQuoteToken(p, asset.Address, asset.Blockchain) && price > 0
+	}))
+	if len(points) == 0 {
+		return nil, fmt.Errorf("Empty response for %s on %s", asset.Symbol, exchange)
+	}
+	var r []dia.Trade
+	for _, p := range points {
+		if maxTrades > 0 && len(r) == maxTrades {
+			break
+		}
+		trade := memoryTrade(p, fullAsset)
+		trade.QuoteToken = asset
+		r = append(r, trade)
+	}
+	return r, nil
+}
+
+// GetNumTradesExchange24H returns the number of trades on @exchange in the last 24 hours.
+func (datastore *MemoryDB) GetNumTradesExchange24H(exchange string) (int64, error) {
+	endtime := time.Now()
+	return datastore.GetNumTrades(exchange, "", "", endtime.AddDate(0, 0, -1), endtime)
+}
+
+// GetNumTrades returns the number of trades on @exchange for asset with @address and @blockchain in (@starttime,@endtime].
+// If @address and @blockchain are empty, it returns all trades on @exchange in the given time-range.
+func (datastore *MemoryDB) GetNumTrades(exchange string, address string, blockchain string, starttime time.Time, endtime time.Time) (int64, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+		_, ok := p.fields["estimatedUSDPrice"]
+		return ok && p.str("exchange") == exchange &&
+			(address == "" || blockchain == "" || isQuoteToken(p, address, blockchain)) &&
+			p.time.After(starttime) && !p.time.After(endtime)
+	})
+	return int64(len(points)), nil
+}
+
+// GetNumTradesSeries returns the number of trades in the intervals of length @grouping aligned to the unix epoch
+// intersecting (@starttime,@endtime]. If pair is the empty string, trades are identified by address/blockchain.
+func (datastore *MemoryDB) GetNumTradesSeries(
+	exchange string,
+	pair string,
+	starttime time.Time,
+	endtime time.Time,
+	grouping string,
+	quotetoken dia.Asset,
+	basetoken dia.Asset,
+) (numTrades []int, err error) {
+	interval, err := parseInfluxDuration(grouping)
+	if err != nil {
+		return
+	}
+	if interval <= 0 {
+		return nil, errors.New("invalid grouping " + grouping)
+	}
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	points := datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+		if _, ok := p.fields["price"]; !ok || p.str("exchange") != exchange || !p.time.After(starttime) || p.time.After(endtime) {
+			return false
+		}
+		if pair != "" {
+			return p.str("pair") == pair
+		}
+		return isQuoteToken(p, quotetoken.Address, quotetoken.Blockchain) &&
+			p.str("basetokenaddress") == basetoken.Address && p.str("basetokenblockchain") == basetoken.Blockchain
+	})
+	if len(points) == 0 {
+		return
+	}
+	counts := make(map[int64]int)
+	for _, p := range points {
+		counts[floorTime(p.time, interval).UnixNano()]++
+	}
+	for t := floorTime(starttime, interval); !t.After(endtime); t = t.Add(interval) {
+		numTrades = append(numTrades, counts[t.UnixNano()])
+	}
+	return
+}
+
+// GetFirstTradeDate returns the time of the first trade in @table.
+func (datastore *MemoryDB) GetFirstTradeDate(table string) (time.Time, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	now := time.Now()
+	points := datastore.selectPoints(table, func(p *memoryPoint) bool { return p.time.Before(now) })
+	if len(points) == 0 {
+		return time.Time{}, errors.New("no trade found")
+	}
+	return points[0].time, nil
+}
+
+// GetActiveExchangesAndPairs returns all exchanges the asset with @address and @blockchain was traded on
+// with verified pairs in (@starttime,@endtime] as keys of a map. The map's values are the underlying pairs.
+func (datastore *MemoryDB) GetActiveExchangesAndPairs(address string, blockchain string, starttime time.Time, endtime time.Time) (map[string][]dia.Pair, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	exchangepairmap := make(map[string][]dia.Pair)
+
+	// The pair of each exchange and pair symbol is taken from the latest trade.
+	latest := make(map[[2]string]*memoryPoint)
+	var groups [][2]string
+	for _, p := range datastore.selectPoints(influxDbTradesTable, func(p *memoryPoint) bool {
+		_, ok := p.fields["estimatedUSDPrice"]
+		return ok && isQuoteToken(p, address, blockchain) && p.str("verified") == "true" &&
+			p.time.After(starttime) && !p.time.After(endtime)
+	}) {
+		group := [2]string{p.str("exchange"), p.str("pair")}
+		if _, ok := latest[group]; !ok {
+			groups = append(groups, group)
+		}
+		latest[group] = p
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i][0] == groups[j][0] {
+			return groups[i][1] < groups[j][1]
+		}
+		return groups[i][0] < groups[j][0]
+	})
+	for _, group := range groups {
+		p := latest[group]
+		pair := dia.Pair{
+			QuoteToken: dia.Asset{Address: p.str("quotetokenaddress"), Blockchain: p.str("quotetokenblockchain")},
+			BaseToken:  dia.Asset{Address: p.str("basetokenaddress"), Blockchain: p.str("basetokenblockchain")},
+		}
+		exchangepairmap[group[0]] = append(exchangepairmap[group[0]], pair)
+	}
+	return exchangepairmap, nil
+}
+
+// GetLastTradeTimeForExchange returns the time of the last trade of @asset on @exchange.
+func (datastore *MemoryDB) GetLastTradeTimeForExchange(asset dia.Asset, exchange string) (*time.Time, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	var unixTime int64
+	err := datastore.getCache(getKeyLastTradeTimeForExchange(asset, exchange), &unixTime)
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(unixTime, 0)
+	return &t, nil
+}
+
+// SetLastTradeTimeForExchange sends the time of the last trade of @asset on @exchange through the redis pipe.
+func (datastore *MemoryDB) SetLastTradeTimeForExchange(asset dia.Asset, exchange string, t time.Time) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	key := getKeyLastTradeTimeForExchange(asset, exchange)
+	unixTime := t.Unix()
+	datastore.pipeCommand(func() {
+		if err := datastore.setCache(key, unixTime, TimeOutRedis); err != nil {
+			log.Error("set last trade time: ", err)
+		}
+	})
+	return nil
+}
+
+// SetAvailablePairs stores the @pairs of @exchange.
+func (datastore *MemoryDB) SetAvailablePairs(exchange string, pairs []dia.ExchangePair) error {
+	datastore.mu.Lock()
+	defer datastore.mu.Unlock()
+	return datastore.setCache("dia_available_pairs_"+exchange, pairs, 0)
+}
+
+// GetAvailablePairs returns the pairs of @exchange.
+func (datastore *MemoryDB) GetAvailablePairs(exchange string) ([]dia.ExchangePair, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	var pairs []dia.ExchangePair
+	err := datastore.getCache("dia_available_pairs_"+exchange, &pairs)
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// GetSymbols returns the symbols parsed from the keys of the price filter's values, as done by DB.
+func (datastore *MemoryDB) GetSymbols(exchange string) ([]string, error) {
+	datastore.mu.RLock()
+	defer datastore.mu.RUnlock()
+	var result []string
+	key := "dia_" + dia.FilterKing + "_"
+	var keys []string
+	for zsetKey := range datastore.zsets {
+		if strings.HasPrefix(zsetKey, key) {
+			keys = append(keys, zsetKey)
+		}
+	}
+	sort.Strings(keys)
+	for _, value := range keys {
+		filteredKey := strings.Replace(strings.Replace(value, key, "", 1), "_ZSET", "", 1)
+		s := strings.Split(filteredKey, "_")
+		if exchange == "" {
+			if len(s) == 1 {
+				result = append(result, s[0])
+			}
+		} else if len(s) > 1 && s[1] == exchange {
+			result = append(result, s[0])
+		}
+	}
+	return result, nil
+}
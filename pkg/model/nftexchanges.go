@@ -117,13 +117,12 @@ func (rdb *RelDB) Get24HoursNFTExchangeTrades(exchange dia.NFTExchange) (int64,
 	FROM %s  
 	WHERE trade_time>now()- INTERVAL '1 days' 
 	AND trade_time<=now()
-	AND marketplace='%s'`,
+	AND marketplace=$1`,
 		NfttradeCurrTable,
-		exchange.Name,
 	)
 
 	var numTrades sql.NullInt64
-	err := rdb.postgresClient.QueryRow(context.Background(), query).Scan(&numTrades)
+	err := rdb.postgresClient.QueryRow(context.Background(), query, exchange.Name).Scan(&numTrades)
 	if numTrades.Valid {
 		return numTrades.Int64, nil
 	}
@@ -146,6 +145,7 @@ func (rdb *RelDB) Get24HoursNFTExchangeVolume(exchange dia.NFTExchange) (float64
 		paymentCurrencies = append(paymentCurrencies, dia.Asset{Blockchain: dia.BINANCESMARTCHAIN, Address: "0xbb4CdB9CBd36B01bD1cBaEBF2De08d9173bc095c"})
 	}
 
+	var args sqlArgs
 	query := fmt.Sprintf(`
 		SELECT SUM(price::numeric) 
 		FROM %s nt
@@ -153,16 +153,16 @@ func (rdb *RelDB) Get24HoursNFTExchangeVolume(exchange dia.NFTExchange) (float64
 		ON nt.currency_id=a.asset_id
 		WHERE trade_time>now()- INTERVAL '1 days' 
 		AND trade_time<=now()
-        AND marketplace='%s' `,
+        AND marketplace=%s `,
 		NfttradeCurrTable,
 		assetTable,
-		exchange.Name,
+		args.bind(exchange.Name),
 	)
 	for i, paymentCurrency := range paymentCurrencies {
 		if i == 0 {
 			query += " AND ("
 		}
-		query += fmt.Sprintf("(address='%s' and blockchain='%s')", paymentCurrency.Address, paymentCurrency.Blockchain)
+		query += fmt.Sprintf("(address=%s and blockchain=%s)", args.bind(paymentCurrency.Address), args.bind(paymentCurrency.Blockchain))
 		if i < len(paymentCurrencies)-1 {
 			query += " OR "
 		} else {
@@ -171,7 +171,7 @@ func (rdb *RelDB) Get24HoursNFTExchangeVolume(exchange dia.NFTExchange) (float64
 	}
 
 	var volume sql.NullFloat64
-	err := rdb.postgresClient.QueryRow(context.Background(), query).Scan(&volume)
+	err := rdb.postgresClient.QueryRow(context.Background(), query, args...).Scan(&volume)
 	if volume.Valid {
 		return volume.Float64 / 1e18, nil
 	}
@@ -183,13 +183,12 @@ func (rdb *RelDB) GetCollectionCountByExchange(exchange string) (int64, error) {
 	query := fmt.Sprintf(`
 		SELECT COUNT (DISTINCT nftclass_id) 
 		FROM %s  
-        WHERE marketplace='%s'`,
+        WHERE marketplace=$1`,
 		NfttradeCurrTable,
-		exchange,
 	)
 
 	var collections sql.NullInt64
-	err := rdb.postgresClient.QueryRow(context.Background(), query).Scan(&collections)
+	err := rdb.postgresClient.QueryRow(context.Background(), query, exchange).Scan(&collections)
 	if collections.Valid {
 		return collections.Int64, nil
 	}
@@ -252,8 +252,8 @@ func (rdb *RelDB) SetNFTTradeToTable(trade dia.NFTTrade, table string) error {
 
 // GetLastBlockNFTTtrade returns the last blocknumber that was scraped for trades in @nftclass.
 func (rdb *RelDB) GetLastBlockNFTTrade(nftclass dia.NFTClass) (blocknumber uint64, err error) {
-	query := fmt.Sprintf("SELECT block_number FROM %s WHERE nftclass_id=(SELECT nftclass_id FROM %s WHERE address='%s' AND blockchain='%s') ORDER BY block_number DESC LIMIT 1;", NfttradeCurrTable, nftclassTable, nftclass.Address, nftclass.Blockchain)
-	err = rdb.postgresClient.QueryRow(context.Background(), query).Scan(&blocknumber)
+	query := fmt.Sprintf("SELECT block_number FROM %s WHERE nftclass_id=(SELECT nftclass_id FROM %s WHERE address=$1 AND blockchain=$2) ORDER BY block_number DESC LIMIT 1;", NfttradeCurrTable, nftclassTable)
+	err = rdb.postgresClient.QueryRow(context.Background(), query, nftclass.Address, nftclass.Blockchain).Scan(&blocknumber)
 	if err != nil {
 		return
 	}
@@ -271,19 +271,17 @@ func (rdb *RelDB) GetNFTTradesCollection(address string, blockchain string, star
 		ON nt.nftclass_id=nc.nftclass_id 
 		INNER JOIN %s n
 		ON nt.nft_id=n.nft_id
-		WHERE nc.blockchain='%s' AND nc.address='%s'
+		WHERE nc.blockchain=$1 AND nc.address=$2
 		AND trade_time>to_timestamp(%v) AND trade_time<to_timestamp(%v) 
 		ORDER BY trade_time DESC`,
 		tradeVars,
 		NfttradeCurrTable,
 		nftclassTable,
 		nftTable,
-		blockchain,
-		address,
 		starttime.Unix(),
 		endtime.Unix(),
 	)
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, blockchain, address)
 	if err != nil {
 		return
 	}
@@ -349,14 +347,13 @@ func (rdb *RelDB) GetNFTTrades(address string, blockchain string, tokenID string
 	}
 	tradeVars := "price,price_usd,transfer_from,transfer_to,currency_id,bundle_sale,block_number,trade_time,tx_hash,marketplace"
 	query := fmt.Sprintf(
-		"SELECT %s FROM %s WHERE nft_id='%s' AND trade_time>to_timestamp(%v) AND trade_time<to_timestamp(%v) ORDER BY trade_time DESC",
+		"SELECT %s FROM %s WHERE nft_id=$1 AND trade_time>to_timestamp(%v) AND trade_time<to_timestamp(%v) ORDER BY trade_time DESC",
 		tradeVars,
 		NfttradeCurrTable,
-		nftID,
 		starttime.Unix(),
 		endtime.Unix(),
 	)
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, nftID)
 	if err != nil {
 		return
 	}
@@ -420,20 +417,21 @@ func (rdb *RelDB) GetNFTFloorLevel(
 	noBundles bool,
 ) (floor float64, err error) {
 
+	var args sqlArgs
 	query := fmt.Sprintf(`
 	SELECT min(tr.price::numeric)
 	FROM %s tr INNER JOIN %s n
 	ON tr.nftclass_id=n.nftclass_id
 	WHERE tr.trade_time<=to_timestamp(%d) AND tr.trade_time>to_timestamp(%d)
 	AND tr.price::numeric>%v
-	AND n.address='%s' AND n.blockchain='%s'`,
+	AND n.address=%s AND n.blockchain=%s`,
 		NfttradeCurrTable,
 		nftclassTable,
 		timestamp.Unix(),
 		timestamp.Add(-floorWindowSeconds).Unix(),
 		level,
-		nftclass.Address,
-		nftclass.Blockchain,
+		args.bind(nftclass.Address),
+		args.bind(nftclass.Blockchain),
 	)
 	// Only take into account selected currencies for payment.
 	if nftclass.Blockchain == dia.ETHEREUM || nftclass.Blockchain == dia.ASTAR {
@@ -441,10 +439,10 @@ func (rdb *RelDB) GetNFTFloorLevel(
 			if i == 0 {
 				query += " AND ("
 			}
-			query += fmt.Sprintf("  currency_id=(SELECT asset_id FROM %s WHERE blockchain='%s' AND address='%s') ",
+			query += fmt.Sprintf("  currency_id=(SELECT asset_id FROM %s WHERE blockchain=%s AND address=%s) ",
 				assetTable,
-				currency.Blockchain,
-				currency.Address,
+				args.bind(currency.Blockchain),
+				args.bind(currency.Address),
 			)
 			if i < len(currencies)-1 {
 				query += " OR "
@@ -458,7 +456,7 @@ func (rdb *RelDB) GetNFTFloorLevel(
 	}
 
 	var floorFloat sql.NullFloat64
-	err = rdb.postgresClient.QueryRow(context.Background(), query).Scan(&floorFloat)
+	err = rdb.postgresClient.QueryRow(context.Background(), query, args...).Scan(&floorFloat)
 	if err != nil {
 		return
 	}
@@ -555,6 +553,7 @@ func (rdb *RelDB) GetTopNFTsEth(numCollections int, offset int64, exchanges []st
 	var (
 		rows          pgx.Rows
 		exchangeQuery string
+		args          sqlArgs
 	)
 
 	query := fmt.Sprintf(`
@@ -579,10 +578,10 @@ func (rdb *RelDB) GetTopNFTsEth(numCollections int, offset int64, exchanges []st
 
 	for i, exchange := range exchanges {
 		if i == 0 {
-			exchangeQuery += fmt.Sprintf(" AND (marketplace='%s' ", exchange)
+			exchangeQuery += fmt.Sprintf(" AND (marketplace=%s ", args.bind(exchange))
 			continue
 		}
-		exchangeQuery += fmt.Sprintf(" OR marketplace='%s' ", exchange)
+		exchangeQuery += fmt.Sprintf(" OR marketplace=%s ", args.bind(exchange))
 	}
 	if len(exchanges) > 0 {
 		exchangeQuery += ") "
@@ -597,7 +596,7 @@ func (rdb *RelDB) GetTopNFTsEth(numCollections int, offset int64, exchanges []st
 		offset,
 	)
 
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, args...)
 	if err != nil {
 		return
 	}
@@ -633,6 +632,7 @@ func (rdb *RelDB) GetTopNFTsEth(numCollections int, offset int64, exchanges []st
 // GetNFTVolume returns the trade volume of a collection in the time-range (@starttime, @endtime].
 func (rdb *RelDB) GetNFTVolume(address, blockchain, exchange string, starttime time.Time, endtime time.Time) (float64, error) {
 	var query string
+	args := sqlArgs{address, blockchain}
 	if exchange == "" {
 		query = fmt.Sprintf(`
 	SELECT SUM(price::numeric) 
@@ -640,13 +640,11 @@ func (rdb *RelDB) GetNFTVolume(address, blockchain, exchange string, starttime t
 	ON nfttradecurrent.nftclass_id=nc.nftclass_id 
 	WHERE trade_time>to_timestamp(%v) 
 	AND trade_time<=to_timestamp(%v) 
-	AND nc.address='%s' AND nc.blockchain='%s'`,
+	AND nc.address=$1 AND nc.blockchain=$2`,
 			NfttradeCurrTable,
 			nftclassTable,
 			starttime.Unix(),
 			endtime.Unix(),
-			address,
-			blockchain,
 		)
 	} else {
 		query = fmt.Sprintf(`
@@ -655,20 +653,18 @@ func (rdb *RelDB) GetNFTVolume(address, blockchain, exchange string, starttime t
 		ON nfttradecurrent.nftclass_id=nc.nftclass_id 
 		WHERE trade_time>to_timestamp(%v) 
 		AND trade_time<=to_timestamp(%v) 
-		AND nc.address='%s' AND nc.blockchain='%s' AND marketplace='%s' `,
+		AND nc.address=$1 AND nc.blockchain=$2 AND marketplace=%s `,
 			NfttradeCurrTable,
 			nftclassTable,
 			starttime.Unix(),
 			endtime.Unix(),
-			address,
-			blockchain,
-			exchange,
+			args.bind(exchange),
 		)
 
 	}
 	// TO DO: address currency issue.
 	var volume sql.NullFloat64
-	err := rdb.postgresClient.QueryRow(context.Background(), query).Scan(&volume)
+	err := rdb.postgresClient.QueryRow(context.Background(), query, args...).Scan(&volume)
 	if volume.Valid {
 		return volume.Float64 / 1e18, nil
 	}
@@ -681,14 +677,12 @@ func (rdb *RelDB) GetNFTExchanges(address string, blockchain string) (exchanges
 	SELECT DISTINCT marketplace
 	FROM %s INNER JOIN %s nc 
 	ON nfttradecurrent.nftclass_id=nc.nftclass_id 
-	WHERE nc.address='%s' AND nc.blockchain='%s'`,
+	WHERE nc.address=$1 AND nc.blockchain=$2`,
 		NfttradeCurrTable,
 		nftclassTable,
-		address,
-		blockchain,
 	)
 
-	rows, err := rdb.postgresClient.Query(context.Background(), query)
+	rows, err := rdb.postgresClient.Query(context.Background(), query, address, blockchain)
 	if err != nil {
 		return
 	}
@@ -712,19 +706,18 @@ func (rdb *RelDB) GetNFTExchanges(address string, blockchain string) (exchanges
 // GetNumNFTTrades returns the number of trades recorded in [@starttime,@endtime] on the collection on @blockchain with @address.
 func (rdb *RelDB) GetNumNFTTrades(address, blockchain, exchange string, starttime time.Time, endtime time.Time) (int, error) {
 	var query string
+	args := sqlArgs{address, blockchain}
 	if exchange == "" {
 		query = fmt.Sprintf(`
 	SELECT count(*) 
 	FROM %s INNER JOIN %s nc 
 	ON nfttradecurrent.nftclass_id=nc.nftclass_id 
 	WHERE trade_time>to_timestamp(%v) AND trade_time<to_timestamp(%v) 
-	AND nc.address='%s' AND nc.blockchain='%s'`,
+	AND nc.address=$1 AND nc.blockchain=$2`,
 			NfttradeCurrTable,
 			nftclassTable,
 			starttime.Unix(),
 			endtime.Unix(),
-			address,
-			blockchain,
 		)
 	} else {
 		query = fmt.Sprintf(`
@@ -732,19 +725,17 @@ func (rdb *RelDB) GetNumNFTTrades(address, blockchain, exchange string, starttim
 		FROM %s INNER JOIN %s nc 
 		ON nfttradecurrent.nftclass_id=nc.nftclass_id 
 		WHERE trade_time>to_timestamp(%v) AND trade_time<to_timestamp(%v) 
-		AND nc.address='%s' AND nc.blockchain='%s' and marketplace='%s'`,
+		AND nc.address=$1 AND nc.blockchain=$2 and marketplace=%s`,
 			NfttradeCurrTable,
 			nftclassTable,
 			starttime.Unix(),
 			endtime.Unix(),
-			address,
-			blockchain,
-			exchange,
+			args.bind(exchange),
 		)
 
 	}
 	var numTrades sql.NullInt64
-	err := rdb.postgresClient.QueryRow(context.Background(), query).Scan(&numTrades)
+	err := rdb.postgresClient.QueryRow(context.Background(), query, args...).Scan(&numTrades)
 	if numTrades.Valid {
 		return int(numTrades.Int64), nil
 	}
@@ -756,8 +747,8 @@ func (rdb *RelDB) GetNFTOffers(address string, blockchain string, tokenID string
 	var rows pgx.Rows
 	nftID, err := rdb.GetNFTID(address, blockchain, tokenID)
 	tradeVars := "start_value,end_value,duration,from_address,auction_type,currency_symbol,currency_address,currency_decimals,blocknumber,offer_time,tx_hash,marketplace"
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id='%s' ORDER BY offer_time DESC", tradeVars, nftofferTable, nftID)
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id=$1 ORDER BY offer_time DESC", tradeVars, nftofferTable)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, nftID)
 	if err != nil {
 		return
 	}
@@ -817,8 +808,8 @@ func (rdb *RelDB) GetNFTBids(address string, blockchain string, tokenID string)
 	var rows pgx.Rows
 	nftID, err := rdb.GetNFTID(address, blockchain, tokenID)
 	tradeVars := "bid_value,from_address,currency_symbol,currency_address,currency_decimals,blocknumber,bid_time,tx_hash,marketplace"
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id='%s' ORDER BY bid_time DESC", tradeVars, nftbidTable, nftID)
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id=$1 ORDER BY bid_time DESC", tradeVars, nftbidTable)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, nftID)
 	if err != nil {
 		return
 	}
@@ -901,14 +892,14 @@ func (rdb *RelDB) GetLastNFTBid(address string, blockchain string, tokenID strin
 	nftBid.NFT.TokenID = tokenID
 
 	// First fetch biggest blocknumber<=@blockNumber for given nft.
-	subquery := fmt.Sprintf("SELECT blocknumber FROM %s WHERE nft_id='%s' AND blocknumber<=%d ORDER BY blocknumber DESC LIMIT 1", nftbidTable, nftID, blockNumber)
+	subquery := fmt.Sprintf("SELECT blocknumber FROM %s WHERE nft_id=$1 AND blocknumber<=%d ORDER BY blocknumber DESC LIMIT 1", nftbidTable, blockNumber)
 	// Next, restrict to largest blockPosition in this block.
 	returnVars := "bid_value,from_address,currency_symbol,currency_address,currency_decimals,blocknumber,blockposition,bid_time,tx_hash,marketplace"
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id='%s' AND blocknumber=(%s) ORDER BY blockposition DESC LIMIT 1", returnVars, nftbidTable, nftID, subquery)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id=$1 AND blocknumber=(%s) ORDER BY blockposition DESC LIMIT 1", returnVars, nftbidTable, subquery)
 	var txHash sql.NullString
 	var bidTime sql.NullTime
 	var value string
-	err = rdb.postgresClient.QueryRow(context.Background(), query).Scan(
+	err = rdb.postgresClient.QueryRow(context.Background(), query, nftID).Scan(
 		&value,
 		&nftBid.FromAddress,
 		&nftBid.CurrencySymbol,
@@ -940,9 +931,9 @@ func (rdb *RelDB) GetLastNFTBid(address string, blockchain string, tokenID strin
 
 // GetLastBlockNFTBid returns the last blocknumber that was scraped for bids in @nftclass.
 func (rdb *RelDB) GetLastBlockNFTBid(nftclass dia.NFTClass) (blocknumber uint64, err error) {
-	query := fmt.Sprintf("SELECT b.blocknumber FROM %s b INNER JOIN %s n ON b.nft_id=n.nft_id INNER JOIN %s c ON(n.nftclass_id=c.nftclass_id AND c.address='%s' and c.blockchain='%s') ORDER BY b.blocknumber DESC LIMIT 1;", nftbidTable, nftTable, nftclassTable, nftclass.Address, nftclass.Blockchain)
+	query := fmt.Sprintf("SELECT b.blocknumber FROM %s b INNER JOIN %s n ON b.nft_id=n.nft_id INNER JOIN %s c ON(n.nftclass_id=c.nftclass_id AND c.address=$1 and c.blockchain=$2) ORDER BY b.blocknumber DESC LIMIT 1;", nftbidTable, nftTable, nftclassTable)
 	log.Info("query: ", query)
-	err = rdb.postgresClient.QueryRow(context.Background(), query).Scan(&blocknumber)
+	err = rdb.postgresClient.QueryRow(context.Background(), query, nftclass.Address, nftclass.Blockchain).Scan(&blocknumber)
 	if err != nil {
 		return
 	}
@@ -951,8 +942,8 @@ func (rdb *RelDB) GetLastBlockNFTBid(nftclass dia.NFTClass) (blocknumber uint64,
 
 // GetLastBlockNFTOffer returns the last blocknumber that was scraped for offers in @nftclass.
 func (rdb *RelDB) GetLastBlockNFTOffer(nftclass dia.NFTClass) (blocknumber uint64, err error) {
-	query := fmt.Sprintf("SELECT b.blocknumber FROM %s b INNER JOIN %s n ON b.nft_id=n.nft_id INNER JOIN %s c ON(n.nftclass_id=c.nftclass_id AND c.address='%s' and c.blockchain='%s') ORDER BY b.blocknumber DESC LIMIT 1;", nftofferTable, nftTable, nftclassTable, nftclass.Address, nftclass.Blockchain)
-	err = rdb.postgresClient.QueryRow(context.Background(), query).Scan(&blocknumber)
+	query := fmt.Sprintf("SELECT b.blocknumber FROM %s b INNER JOIN %s n ON b.nft_id=n.nft_id INNER JOIN %s c ON(n.nftclass_id=c.nftclass_id AND c.address=$1 and c.blockchain=$2) ORDER BY b.blocknumber DESC LIMIT 1;", nftofferTable, nftTable, nftclassTable)
+	err = rdb.postgresClient.QueryRow(context.Background(), query, nftclass.Address, nftclass.Blockchain).Scan(&blocknumber)
 	if err != nil {
 		return
 	}
@@ -1004,15 +995,15 @@ func (rdb *RelDB) GetLastNFTOffer(address string, blockchain string, tokenID str
 	offer.NFT.TokenID = tokenID
 
 	// First fetch biggest blocknumber<=@blockNumber for given nft.
-	subquery := fmt.Sprintf("SELECT blocknumber FROM %s WHERE nft_id='%s' AND blocknumber<=%d ORDER BY blocknumber DESC LIMIT 1", nftofferTable, nftID, blockNumber)
+	subquery := fmt.Sprintf("SELECT blocknumber FROM %s WHERE nft_id=$1 AND blocknumber<=%d ORDER BY blocknumber DESC LIMIT 1", nftofferTable, blockNumber)
 	// Next, restrict to largest blockPosition in this block.
 	returnVars := "start_value,end_value,duration,from_address,auction_type,currency_symbol,currency_address,currency_decimals,blocknumber,blockposition,offer_time,tx_hash,marketplace"
-	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id='%s' AND blocknumber=(%s) ORDER BY blockposition DESC LIMIT 1", returnVars, nftofferTable, nftID, subquery)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE nft_id=$1 AND blocknumber=(%s) ORDER BY blockposition DESC LIMIT 1", returnVars, nftofferTable, subquery)
 	var txHash sql.NullString
 	var offerTime sql.NullTime
 	var startValue string
 	var endValue string
-	err = rdb.postgresClient.QueryRow(context.Background(), query).Scan(
+	err = rdb.postgresClient.QueryRow(context.Background(), query, nftID).Scan(
 		&startValue,
 		&endValue,
 		&offer.Duration,
@@ -1062,7 +1053,7 @@ func (rdb *RelDB) GetNFTClassesByNameSymbol(searchstring string) (collections []
 	FROM %s nc 
 	INNER JOIN %s nt 
 	ON nc.nftclass_id=nt.nftclass_id
-	WHERE (symbol ILIKE '%s%%'  or name ILIKE '%s%%')
+	WHERE (symbol ILIKE $1  or name ILIKE $1)
 	AND nc.blockchain='%s'
 	AND (
 		currency_id=(SELECT currency_id FROM asset WHERE address='%s' AND blockchain='%s') 
@@ -1072,8 +1063,6 @@ func (rdb *RelDB) GetNFTClassesByNameSymbol(searchstring string) (collections []
 	ORDER BY SUM(nt.price::numeric) DESC`,
 		nftclassTable,
 		NfttradeCurrTable,
-		searchstring,
-		searchstring,
 		dia.ETHEREUM,
 		"0x0000000000000000000000000000000000000000",
 		dia.ETHEREUM,
@@ -1081,7 +1070,7 @@ func (rdb *RelDB) GetNFTClassesByNameSymbol(searchstring string) (collections []
 		dia.ETHEREUM,
 	)
 
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, sqlLikePrefix(searchstring))
 	if err != nil {
 		return
 	}
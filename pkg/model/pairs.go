@@ -78,21 +78,21 @@ func (rdb *RelDB) SetExchangePair(exchange string, pair dia.ExchangePair, cache
 		log.Error(err)
 	}
 	if basetokenID != "" {
-		query = fmt.Sprintf("UPDATE %s SET id_basetoken='%s' WHERE foreignname='%s' AND exchange='%s'", exchangepairTable, basetokenID, pair.ForeignName, exchange)
-		_, err = rdb.postgresClient.Exec(context.Background(), query)
+		query = fmt.Sprintf("UPDATE %s SET id_basetoken=$1 WHERE foreignname=$2 AND exchange=$3", exchangepairTable)
+		_, err = rdb.postgresClient.Exec(context.Background(), query, basetokenID, pair.ForeignName, exchange)
 		if err != nil {
 			return err
 		}
 	}
 	if quotetokenID != "" {
-		query = fmt.Sprintf("UPDATE %s SET id_quotetoken='%s' WHERE foreignname='%s' AND exchange='%s'", exchangepairTable, quotetokenID, pair.ForeignName, exchange)
-		_, err = rdb.postgresClient.Exec(context.Background(), query)
+		query = fmt.Sprintf("UPDATE %s SET id_quotetoken=$1 WHERE foreignname=$2 AND exchange=$3", exchangepairTable)
+		_, err = rdb.postgresClient.Exec(context.Background(), query, quotetokenID, pair.ForeignName, exchange)
 		if err != nil {
 			return err
 		}
 	}
-	query = fmt.Sprintf("UPDATE %s SET verified='%v' WHERE foreignname='%s' AND exchange='%s'", exchangepairTable, pair.Verified, pair.ForeignName, exchange)
-	_, err = rdb.postgresClient.Exec(context.Background(), query)
+	query = fmt.Sprintf("UPDATE %s SET verified=$1 WHERE foreignname=$2 AND exchange=$3", exchangepairTable)
+	_, err = rdb.postgresClient.Exec(context.Background(), query, pair.Verified, pair.ForeignName, exchange)
 	if err != nil {
 		return err
 	}
@@ -142,17 +142,17 @@ func (rdb *RelDB) GetPairsForExchange(exchange dia.Exchange, filterVerified bool
 		ON e.id_quotetoken=a.asset_id 
 		INNER JOIN %s b 
 		ON e.id_basetoken=b.asset_id 
-		WHERE e.exchange='%s'`,
+		WHERE e.exchange=$1`,
 		exchangepairTable,
 		assetTable,
 		assetTable,
-		exchange.Name,
 	)
+	args := sqlArgs{exchange.Name}
 	if filterVerified {
-		query += fmt.Sprintf(" AND e.verified='%v'", verified)
+		query += " AND e.verified=" + args.bind(verified)
 	}
 
-	rows, err := rdb.postgresClient.Query(context.Background(), query)
+	rows, err := rdb.postgresClient.Query(context.Background(), query, args...)
 	if err != nil {
 		return pairs, err
 	}
@@ -207,20 +207,17 @@ func (rdb *RelDB) GetPairsForAsset(asset dia.Asset, filterVerified bool, verifie
 		ON e.id_quotetoken=a.asset_id 
 		INNER JOIN %s b 
 		ON e.id_basetoken=b.asset_id 
-		WHERE ((a.address='%s' and a.blockchain='%s') OR (b.address='%s' and b.blockchain='%s'))`,
+		WHERE ((a.address=$1 and a.blockchain=$2) OR (b.address=$1 and b.blockchain=$2))`,
 		exchangepairTable,
 		assetTable,
 		assetTable,
-		asset.Address,
-		asset.Blockchain,
-		asset.Address,
-		asset.Blockchain,
 	)
+	args := sqlArgs{asset.Address, asset.Blockchain}
 	if filterVerified {
-		query += fmt.Sprintf(" AND e.verified='%v'", verified)
+		query += " AND e.verified=" + args.bind(verified)
 	}
 
-	rows, err := rdb.postgresClient.Query(context.Background(), query)
+	rows, err := rdb.postgresClient.Query(context.Background(), query, args...)
 	if err != nil {
 		return pairs, err
 	}
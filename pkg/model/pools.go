@@ -16,13 +16,27 @@ import (
 
 // SavePoolInflux stores a DEX pool in influx.
 func (datastore *DB) SavePoolInflux(p dia.Pool) error {
+	pt, err := poolPoint(p)
+	if err != nil {
+		log.Errorln("NewTradeInflux:", err)
+	} else {
+		datastore.addPoint(pt)
+	}
+
+	err = datastore.WriteBatchInflux()
+	if err != nil {
+		log.Errorln("Write influx batch: ", err)
+	}
+
+	return err
+}
 
+// poolPoint returns the influx point of pool @p.
+func poolPoint(p dia.Pool) (*clientInfluxdb.Point, error) {
 	assetvolumesEncoded, err := json.Marshal(p.Assetvolumes)
 	if err != nil {
 		log.Error("marshal volumes: ", err)
 	}
-
-	// Create a point and add to batch
 	tags := map[string]string{
 		"exchange":   p.Exchange.Name,
 		"blockchain": p.Blockchain.Name,
@@ -31,30 +45,18 @@ func (datastore *DB) SavePoolInflux(p dia.Pool) error {
 	fields := map[string]interface{}{
 		"volumes": string(assetvolumesEncoded),
 	}
-
-	pt, err := clientInfluxdb.NewPoint(influxDbDEXPoolTable, tags, fields, p.Time)
-	if err != nil {
-		log.Errorln("NewTradeInflux:", err)
-	} else {
-		datastore.addPoint(pt)
-	}
-
-	err = datastore.WriteBatchInflux()
-	if err != nil {
-		log.Errorln("Write influx batch: ", err)
-	}
-
-	return err
+	return clientInfluxdb.NewPoint(influxDbDEXPoolTable, tags, fields, p.Time)
 }
 
 // GetPoolInflux returns all info/liquidities of pool with @poolAddress in the time-range [starttime, endtime).
 func (datastore *DB) GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error) {
 
 	pools := []dia.Pool{}
-	queryString := "SELECT \"exchange\",\"blockchain\",volumes FROM %s WHERE address='%s' AND time >= %d AND time < %d ORDER BY DESC"
-	q := fmt.Sprintf(queryString, influxDbDEXPoolTable, poolAddress, starttime.UnixNano(), endtime.UnixNano())
+	params := influxParams{}
+	queryString := "SELECT \"exchange\",\"blockchain\",volumes FROM %s WHERE address=%s AND time >= %d AND time < %d ORDER BY DESC"
+	q := fmt.Sprintf(queryString, influxDbDEXPoolTable, params.bind(poolAddress), starttime.UnixNano(), endtime.UnixNano())
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return pools, err
 	}
@@ -150,16 +152,14 @@ func (rdb *RelDB) GetPoolByAddress(blockchain string, address string) (pool dia.
 		ON p.pool_id=pa.pool_id 
 		INNER JOIN %s a
 		ON pa.asset_id=a.asset_id 
-		WHERE p.blockchain='%s'
-		AND p.address='%s'`,
+		WHERE p.blockchain=$1
+		AND p.address=$2`,
 		poolassetTable,
 		poolTable,
 		assetTable,
-		blockchain,
-		address,
 	)
 
-	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, blockchain, address)
 	if err != nil {
 		return
 	}
@@ -219,3 +219,39 @@ func (rdb *RelDB) GetAllPoolAddrsExchange(exchange string) (addresses []string,
 	}
 	return
 }
+
+// GetPoolAddrsByAssetPair returns the addresses of all pools on @exchange which contain @asset0 and @asset1.
+func (rdb *RelDB) GetPoolAddrsByAssetPair(exchange string, asset0 dia.Asset, asset1 dia.Asset) (addresses []string, err error) {
+	var rows pgx.Rows
+	query := fmt.Sprintf(`
+		SELECT p.address
+		FROM %s p
+		INNER JOIN %s pa0 ON pa0.pool_id=p.pool_id
+		INNER JOIN %s a0 ON a0.asset_id=pa0.asset_id
+		INNER JOIN %s pa1 ON pa1.pool_id=p.pool_id
+		INNER JOIN %s a1 ON a1.asset_id=pa1.asset_id
+		WHERE p.exchange=$1
+		AND a0.blockchain=$2 AND a0.address=$3
+		AND a1.blockchain=$4 AND a1.address=$5`,
+		poolTable,
+		poolassetTable,
+		assetTable,
+		poolassetTable,
+		assetTable,
+	)
+	rows, err = rdb.postgresClient.Query(context.Background(), query, exchange, asset0.Blockchain, asset0.Address, asset1.Blockchain, asset1.Address)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var poolAddr string
+		err = rows.Scan(&poolAddr)
+		if err != nil {
+			return
+		}
+		addresses = append(addresses, poolAddr)
+	}
+	return
+}
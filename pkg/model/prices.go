@@ -60,15 +60,16 @@ func (datastore *DB) GetTradePrice30d(asset dia.Asset, exchange string) (*dia.Tr
 }
 
 func (datastore *DB) GetLastPriceBefore(asset dia.Asset, filter string, exchange string, timestamp time.Time) (Price, error) {
-	exchangeQuery := "exchange='" + exchange + "'"
+	params := influxParams{}
+	exchangeQuery := "exchange=" + params.bind(exchange)
 	table := influxDbFiltersTable
 	// q := fmt.Sprintf("SELECT LAST(value) FROM %s WHERE filter='%s' AND symbol='%s' AND %s AND time < %d",
 	// 	table, filter, symbol, exchangeQuery, timestamp.UnixNano())
 
-	q := fmt.Sprintf("SELECT value FROM %s WHERE filter='%s' AND address='%s' AND blockchain='%s' AND %s AND time<now() AND time > %d ORDER BY ASC LIMIT 1",
-		table, filter, asset.Address, asset.Blockchain, exchangeQuery, timestamp.UnixNano())
+	q := fmt.Sprintf("SELECT value FROM %s WHERE filter=%s AND address=%s AND blockchain=%s AND %s AND time<now() AND time > %d ORDER BY ASC LIMIT 1",
+		table, params.bind(filter), params.bind(asset.Address), params.bind(asset.Blockchain), exchangeQuery, timestamp.UnixNano())
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetLastFilterPointBefore", err)
 	}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Queries are built from constant statements and placeholders. Values such as addresses, symbols and exchanges,
+// which may come from users, are bound to the placeholders and never become part of the statement.
+
+// sqlArgs are the arguments of a postgres query bound to its placeholders $1, $2, ...
+type sqlArgs []interface{}
+
+// bind appends @arg to the arguments and returns its placeholder.
+func (args *sqlArgs) bind(arg interface{}) string {
+	*args = append(*args, arg)
+	return "$" + strconv.Itoa(len(*args))
+}
+
+// influxParams are the parameters of an InfluxQL query bound to its placeholders $p1, $p2, ...
+type influxParams map[string]interface{}
+
+// bind adds @arg to the parameters and returns its placeholder. Strings are bound as string literals,
+// so that they can be compared to tags and fields.
+func (params influxParams) bind(arg interface{}) string {
+	name := "p" + strconv.Itoa(len(params)+1)
+	params[name] = arg
+	return "$" + name
+}
+
+// influxIdentifier returns @name as double quoted InfluxQL identifier, such as a measurement given by configuration.
+func influxIdentifier(name string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+}
+
+// influxRegex returns a regular expression literal matching strings which contain any of @values.
+// Regular expressions can not be bound, so that the values are escaped instead.
+func influxRegex(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(value), "/", `\/`)
+	}
+	return "/" + strings.Join(quoted, "|") + "/"
+}
+
+// validInfluxDuration matches InfluxQL duration literals such as 5m or 1d.
+var validInfluxDuration = regexp.MustCompile(`^[0-9]+(ns|u|µ|ms|s|m|h|d|w)$`)
+
+// sqlLikePrefix returns the pattern for LIKE and ILIKE matching strings beginning with @prefix. Wildcards in
+// @prefix are matched literally.
+func sqlLikePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
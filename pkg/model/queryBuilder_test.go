@@ -0,0 +1,107 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	clientInfluxdb "github.com/influxdata/influxdb1-client/v2"
+)
+
+// queryRecorder is an influx client recording the queries it receives.
+type queryRecorder struct {
+	clientInfluxdb.Client
+	queries []clientInfluxdb.Query
+}
+
+func (client *queryRecorder) Query(q clientInfluxdb.Query) (*clientInfluxdb.Response, error) {
+	client.queries = append(client.queries, q)
+	return &clientInfluxdb.Response{}, nil
+}
+
+const hostileInput = `0x0' OR 1=1 --"/`
+
+// checkBound fails if @hostileInput is part of the command of @q instead of its parameters.
+func checkBound(t *testing.T, q clientInfluxdb.Query) {
+	if strings.Contains(q.Command, "OR 1=1") {
+		t.Errorf("argument in query: %s", q.Command)
+	}
+	for _, value := range q.Parameters {
+		if value == hostileInput {
+			return
+		}
+	}
+	t.Errorf("argument not bound: %v", q.Parameters)
+}
+
+func TestGetTradesByExchangesFullBound(t *testing.T) {
+	client := &queryRecorder{}
+	datastore := &DB{influxClient: client}
+	asset := dia.Asset{Address: hostileInput, Blockchain: dia.ETHEREUM}
+	baseassets := []dia.Asset{{Address: hostileInput, Blockchain: dia.ETHEREUM}}
+	_, err := datastore.GetTradesByExchangesFull(asset, baseassets, []string{"Binance"}, false, time.Unix(0, 0), time.Now())
+	if err == nil {
+		t.Error("expected no trades")
+	}
+	if len(client.queries) != 1 {
+		t.Fatalf("got %d queries, want 1", len(client.queries))
+	}
+	checkBound(t, client.queries[0])
+}
+
+func TestGetFilterPointsAssetBound(t *testing.T) {
+	client := &queryRecorder{}
+	datastore := &DB{influxClient: client}
+	_, err := datastore.GetFilterPointsAsset("MA120", hostileInput, hostileInput, dia.ETHEREUM, time.Unix(0, 0), time.Now(), false)
+	if err != nil {
+		t.Error(err)
+	}
+	if len(client.queries) != 1 {
+		t.Fatalf("got %d queries, want 1", len(client.queries))
+	}
+	checkBound(t, client.queries[0])
+}
+
+func TestGetNumTradesSeriesGrouping(t *testing.T) {
+	datastore := &DB{influxClient: &queryRecorder{}}
+	_, err := datastore.GetNumTradesSeries("Binance", "BTC-USDT", time.Unix(0, 0), time.Now(), "1d') --", dia.Asset{}, dia.Asset{})
+	if err == nil {
+		t.Error("invalid grouping was accepted")
+	}
+}
+
+func TestInfluxRegex(t *testing.T) {
+	literal := influxRegex([]string{"Binance", "Crypto.com", `a/ OR exchange=~/.*`})
+	if literal != `/Binance|Crypto\.com|a\/ OR exchange=~\/\.\*/` {
+		t.Errorf("influxRegex = %s", literal)
+	}
+	re := regexp.MustCompile(strings.ReplaceAll(strings.Trim(literal, "/"), `\/`, "/"))
+	if !re.MatchString("Crypto.com") || re.MatchString("CryptoXcom") || re.MatchString("Kraken") {
+		t.Errorf("influxRegex %s matches wrong exchanges", literal)
+	}
+}
+
+func TestInfluxIdentifier(t *testing.T) {
+	if got := influxIdentifier(`trades" WHERE 1=1 --`); got != `"trades\" WHERE 1=1 --"` {
+		t.Errorf("influxIdentifier = %s", got)
+	}
+}
+
+func TestSQLLikePrefix(t *testing.T) {
+	if got := sqlLikePrefix(`E_T%\`); got != `E\_T\%\\%` {
+		t.Errorf("sqlLikePrefix = %s", got)
+	}
+}
+
+func TestSQLArgs(t *testing.T) {
+	var args sqlArgs
+	if args.bind("Ethereum") != "$1" || args.bind(hostileInput) != "$2" || len(args) != 2 {
+		t.Errorf("unexpected args %v", args)
+	}
+	params := influxParams{}
+	if params.bind("Ethereum") != "$p1" || params.bind(hostileInput) != "$p2" || params["p2"] != hostileInput {
+		t.Errorf("unexpected params %v", params)
+	}
+}
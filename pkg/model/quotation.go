@@ -77,28 +77,20 @@ func (datastore *DB) GetAssetPriceUSD(asset dia.Asset, timestamp time.Time) (pri
 // quotations to an influx batch.
 func (datastore *DB) AddAssetQuotationsToBatch(quotations []*AssetQuotation) error {
 	for _, quotation := range quotations {
-		tags := map[string]string{
-			"symbol":     quotation.Asset.Symbol,
-			"name":       quotation.Asset.Name,
-			"address":    quotation.Asset.Address,
-			"blockchain": quotation.Asset.Blockchain,
-		}
-		fields := map[string]interface{}{
-			"price": quotation.Price,
-		}
-		pt, err := clientInfluxdb.NewPoint(influxDBAssetQuotationsTable, tags, fields, quotation.Time)
+		pt, err := assetQuotationPoint(quotation)
 		if err != nil {
 			log.Errorln("addAssetQuotationsToBatch:", err)
 			return err
 		}
-		datastore.addPoint(pt)
+		if err = datastore.addPoint(pt); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// SetAssetQuotation stores the full quotation of @asset into influx and cache.
-func (datastore *DB) SetAssetQuotation(quotation *AssetQuotation) error {
-	// Write to influx
+// assetQuotationPoint returns the influx point of @quotation.
+func assetQuotationPoint(quotation *AssetQuotation) (*clientInfluxdb.Point, error) {
 	tags := map[string]string{
 		"symbol":     quotation.Asset.Symbol,
 		"name":       quotation.Asset.Name,
@@ -108,17 +100,25 @@ func (datastore *DB) SetAssetQuotation(quotation *AssetQuotation) error {
 	fields := map[string]interface{}{
 		"price": quotation.Price,
 	}
+	return clientInfluxdb.NewPoint(influxDBAssetQuotationsTable, tags, fields, quotation.Time)
+}
 
-	pt, err := clientInfluxdb.NewPoint(influxDBAssetQuotationsTable, tags, fields, quotation.Time)
+// SetAssetQuotation stores the full quotation of @asset into influx and cache.
+func (datastore *DB) SetAssetQuotation(quotation *AssetQuotation) error {
+	// Write to influx
+	pt, err := assetQuotationPoint(quotation)
 	if err != nil {
 		log.Errorln("SetAssetQuotation:", err)
 	} else {
-		datastore.addPoint(pt)
+		err = datastore.addPoint(pt)
 	}
 
 	// Write latest point to redis cache
 	// log.Printf("write to cache: %s", quotation.Asset.Symbol)
-	_, err = datastore.SetAssetQuotationCache(quotation, false)
+	_, cacheErr := datastore.SetAssetQuotationCache(quotation, false)
+	if cacheErr != nil {
+		return cacheErr
+	}
 	return err
 
 }
@@ -143,8 +143,9 @@ func (datastore *DB) GetAssetQuotationLatest(asset dia.Asset) (*AssetQuotation,
 func (datastore *DB) GetAssetQuotation(asset dia.Asset, timestamp time.Time) (*AssetQuotation, error) {
 
 	quotation := AssetQuotation{}
-	q := fmt.Sprintf("SELECT price FROM %s WHERE address='%s' AND blockchain='%s' AND time<=%d ORDER BY DESC LIMIT 1", influxDBAssetQuotationsTable, asset.Address, asset.Blockchain, timestamp.UnixNano())
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	params := influxParams{}
+	q := fmt.Sprintf("SELECT price FROM %s WHERE address=%s AND blockchain=%s AND time<=%d ORDER BY DESC LIMIT 1", influxDBAssetQuotationsTable, params.bind(asset.Address), params.bind(asset.Blockchain), timestamp.UnixNano())
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return &quotation, err
 	}
@@ -175,16 +176,17 @@ func (datastore *DB) GetAssetQuotation(asset dia.Asset, timestamp time.Time) (*A
 func (datastore *DB) GetAssetQuotations(asset dia.Asset, starttime time.Time, endtime time.Time) ([]AssetQuotation, error) {
 
 	quotations := []AssetQuotation{}
+	params := influxParams{}
 	q := fmt.Sprintf(
-		"SELECT price FROM %s WHERE address='%s' AND blockchain='%s' AND time>%d AND time<=%d ORDER BY DESC",
+		"SELECT price FROM %s WHERE address=%s AND blockchain=%s AND time>%d AND time<=%d ORDER BY DESC",
 		influxDBAssetQuotationsTable,
-		asset.Address,
-		asset.Blockchain,
+		params.bind(asset.Address),
+		params.bind(asset.Blockchain),
 		starttime.UnixNano(),
 		endtime.UnixNano(),
 	)
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return quotations, err
 	}
@@ -261,6 +263,15 @@ func (datastore *DB) GetAssetPriceUSDCache(asset dia.Asset) (price float64, err
 // GetSortedQuotations returns quotations for all assets in @assets, sorted by 24h volume
 // in descending order.
 func (datastore *DB) GetSortedAssetQuotations(assets []dia.Asset) ([]AssetQuotation, error) {
+	return sortedAssetQuotations(datastore, assets)
+}
+
+// sortedAssetQuotations returns the latest quotations of @assets in @datastore, sorted by 24h volume
+// in descending order.
+func sortedAssetQuotations(datastore interface {
+	QuotationStore
+	VolumeStore
+}, assets []dia.Asset) ([]AssetQuotation, error) {
 	var quotations []AssetQuotation
 	var volumes []float64
 	for _, asset := range assets {
@@ -313,6 +324,11 @@ func (datastore *DB) GetAssetsMarketCap(asset dia.Asset) (float64, error) {
 // GetTopAssetByVolume returns the asset with highest volume among all assets with symbol @symbol.
 // This method allows us to use all API endpoints called on a symbol.
 func (datastore *DB) GetTopAssetByVolume(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	return topAssetByVolume(datastore, symbol, relDB)
+}
+
+// topAssetByVolume returns the asset with highest volume in @datastore among all assets with symbol @symbol.
+func topAssetByVolume(datastore VolumeStore, symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
 	assets, err := relDB.GetAssets(symbol)
 	if err != nil {
 		return
@@ -347,6 +363,11 @@ func (datastore *DB) GetTopAssetByVolume(symbol string, relDB *RelDB) (topAsset
 
 // GetTopAssetByMcap returns the asset with highest market cap among all assets with symbol @symbol.
 func (datastore *DB) GetTopAssetByMcap(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	return topAssetByMcap(datastore, symbol, relDB)
+}
+
+// topAssetByMcap returns the asset with highest market cap in @datastore among all assets with symbol @symbol.
+func topAssetByMcap(datastore QuotationStore, symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
 	assets, err := relDB.GetAssets(symbol)
 	if err != nil {
 		return
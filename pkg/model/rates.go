@@ -40,6 +40,15 @@ func (e *InterestRate) UnmarshalBinary(data []byte) error {
 	return nil
 }
 
+// interestRateSource is the storage of interest rates the compounded rates are computed from.
+type interestRateSource interface {
+	GetRates() []string
+	GetInterestRate(symbol, date string) (*InterestRate, error)
+	GetInterestRateRange(symbol, dateInit, dateFinal string) ([]*InterestRate, error)
+	GetFirstDate(symbol string) (time.Time, error)
+	GetIssuer(symbol string) (string, error)
+}
+
 type InterestRateMeta struct {
 	Symbol    string
 	FirstDate time.Time
@@ -154,6 +163,11 @@ func (datastore *DB) GetRates() []string {
 // GetRatesMeta returns a list of all available rate symbols along with their first
 // timestamp in the database.
 func (datastore *DB) GetRatesMeta() (RatesMeta []InterestRateMeta, err error) {
+	return ratesMeta(datastore)
+}
+
+// ratesMeta computes GetRatesMeta for the rates in @datastore.
+func ratesMeta(datastore interestRateSource) (RatesMeta []InterestRateMeta, err error) {
 	allRates := datastore.GetRates()
 	for _, symbol := range allRates {
 		// Get first publication date
@@ -237,6 +251,11 @@ func (datastore *DB) GetFirstDate(symbol string) (time.Time, error) {
 // GetCompoundedRate returns the compounded rate for the period @dateInit to @date. It computes the rate for all
 // days for which an entry is present in the database. All other days are assumed to be holidays (or weekends).
 func (datastore *DB) GetCompoundedRate(symbol string, dateInit, date time.Time, daysPerYear int, rounding int) (*InterestRate, error) {
+	return compoundedRate(datastore, symbol, dateInit, date, daysPerYear, rounding)
+}
+
+// compoundedRate computes GetCompoundedRate for the rates in @datastore.
+func compoundedRate(datastore interestRateSource, symbol string, dateInit, date time.Time, daysPerYear int, rounding int) (*InterestRate, error) {
 
 	// Get first publication date for the rate with @symbol in order to check feasibility of dateInit
 	firstPublication, err := datastore.GetFirstDate(symbol)
@@ -307,16 +326,26 @@ func (datastore *DB) GetCompoundedRate(symbol string, dateInit, date time.Time,
 
 // GetCompoundedIndex returns the compounded index over the maximal period of existence of @symbol
 func (datastore *DB) GetCompoundedIndex(symbol string, date time.Time, daysPerYear int, rounding int) (*InterestRate, error) {
+	return compoundedIndex(datastore, symbol, date, daysPerYear, rounding)
+}
+
+// compoundedIndex computes GetCompoundedIndex for the rates in @datastore.
+func compoundedIndex(datastore interestRateSource, symbol string, date time.Time, daysPerYear int, rounding int) (*InterestRate, error) {
 	// Get initial date for the rate with @symbol
 	dateInit, err := datastore.GetFirstDate(symbol)
 	if err != nil {
 		return &InterestRate{}, err
 	}
-	return datastore.GetCompoundedRate(symbol, dateInit, date, daysPerYear, rounding)
+	return compoundedRate(datastore, symbol, dateInit, date, daysPerYear, rounding)
 }
 
 // GetCompoundedIndexRange returns the compounded average of the index @symbol over rolling @calDays calendar days.
 func (datastore *DB) GetCompoundedIndexRange(symbol string, dateInit, dateFinal time.Time, daysPerYear int, rounding int) (values []*InterestRate, err error) {
+	return compoundedIndexRange(datastore, symbol, dateInit, dateFinal, daysPerYear, rounding)
+}
+
+// compoundedIndexRange computes GetCompoundedIndexRange for the rates in @datastore.
+func compoundedIndexRange(datastore interestRateSource, symbol string, dateInit, dateFinal time.Time, daysPerYear int, rounding int) (values []*InterestRate, err error) {
 
 	// Get first publication date for the rate with @symbol in order to check feasibility of dateInit
 	firstPublication, err := datastore.GetFirstDate(symbol)
@@ -360,7 +389,7 @@ func (datastore *DB) GetCompoundedIndexRange(symbol string, dateInit, dateFinal
 	}
 
 	// Initialize return values
-	compRate, err := datastore.GetCompoundedRate(symbol, firstPublication, dateInit, daysPerYear, 0)
+	compRate, err := compoundedRate(datastore, symbol, firstPublication, dateInit, daysPerYear, 0)
 	if err != nil {
 		return
 	}
@@ -396,10 +425,15 @@ func (datastore *DB) GetCompoundedIndexRange(symbol string, dateInit, dateFinal
 
 // GetCompoundedAvg returns the compounded average of the index @symbol over rolling @calDays calendar days.
 func (datastore *DB) GetCompoundedAvg(symbol string, date time.Time, calDays, daysPerYear int, rounding int) (*InterestRate, error) {
+	return compoundedAvg(datastore, symbol, date, calDays, daysPerYear, rounding)
+}
+
+// compoundedAvg computes GetCompoundedAvg for the rates in @datastore.
+func compoundedAvg(datastore interestRateSource, symbol string, date time.Time, calDays, daysPerYear int, rounding int) (*InterestRate, error) {
 
 	dateInit := date.AddDate(0, 0, -calDays)
 
-	index, err := datastore.GetCompoundedRate(symbol, dateInit, date, daysPerYear, rounding)
+	index, err := compoundedRate(datastore, symbol, dateInit, date, daysPerYear, rounding)
 	if err != nil {
 		return &InterestRate{}, err
 	}
@@ -460,6 +494,11 @@ func WeightedRates(intRates []*InterestRate, dateInit, dateFinal time.Time, holi
 
 // GetCompoundedAvgRange returns the compounded average of the index @symbol over rolling @calDays calendar days.
 func (datastore *DB) GetCompoundedAvgRange(symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) (values []*InterestRate, err error) {
+	return compoundedAvgRange(datastore, symbol, dateInit, dateFinal, calDays, daysPerYear, rounding)
+}
+
+// compoundedAvgRange computes GetCompoundedAvgRange for the rates in @datastore.
+func compoundedAvgRange(datastore interestRateSource, symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) (values []*InterestRate, err error) {
 
 	dateStart := dateInit.AddDate(0, 0, -calDays)
 
@@ -602,6 +641,11 @@ func StraightRates(intRates []*InterestRate) map[time.Time]float64 {
 
 // GetCompoundedAvgDIARange returns the compounded average DIA index of @symbol over rolling @calDays calendar days.
 func (datastore *DB) GetCompoundedAvgDIARange(symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) (values []*InterestRate, err error) {
+	return compoundedAvgDIARange(datastore, symbol, dateInit, dateFinal, calDays, daysPerYear, rounding)
+}
+
+// compoundedAvgDIARange computes GetCompoundedAvgDIARange for the rates in @datastore.
+func compoundedAvgDIARange(datastore interestRateSource, symbol string, dateInit, dateFinal time.Time, calDays, daysPerYear int, rounding int) (values []*InterestRate, err error) {
 
 	dateStart := dateInit.AddDate(0, 0, -calDays)
 
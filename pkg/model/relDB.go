@@ -2,7 +2,6 @@ package models
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -13,10 +12,28 @@ import (
 	"github.com/go-redis/redis"
 )
 
-// RelDatastore is a (persistent) relational database with an additional redis caching layer
+// RelDatastore is a (persistent) relational database with an additional redis caching layer.
+// It is composed of one interface per concern, so that services can depend on the parts they
+// use and backends and mocks can be provided per concern.
 type RelDatastore interface {
+	AssetStore
+	FilterConfigStore
+	SanityRuleStore
+	ExchangeStore
+	PoolStore
+	BlockchainStore
+	NFTStore
+	ScraperStore
+	KeyStore
 
-	// --- Assets methods ---
+	// General methods
+	GetKeys(table string) ([]string, error)
+}
+
+var _ RelDatastore = (*RelDB)(nil)
+
+// AssetStore stores assets, their volumes and the groups of assets mapped onto each other.
+type AssetStore interface {
 	// --------- Persistent ---------
 	SetAsset(asset dia.Asset) error
 	GetAsset(address, blockchain string) (dia.Asset, error)
@@ -39,7 +56,33 @@ type RelDatastore interface {
 	GetAggVolumesByPair(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.PairVolumesList, error)
 	SetTradesDistribution(tradesDist dia.TradesDistribution) error
 	GetTradesDistribution(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.TradesDistribution, error)
+	GetAssetMap(assetID string) (string, error)
+	GetAssetByGroupID(groupID string) ([]dia.Asset, error)
+	InsertAssetMap(groupID string, assetID string) error
+	InsertNewAssetMap(assetID string) error
+
+	// ------ Caching ------
+	SetAssetCache(asset dia.Asset) error
+	GetAssetCache(assetID string) (dia.Asset, error)
+	CountCache() (uint32, error)
+}
 
+// FilterConfigStore stores the configurations of filters and the exchanges excluded from aggregated prices.
+type FilterConfigStore interface {
+	GetFilterConfigs() ([]dia.FilterConfig, error)
+	SetExchangeExclusion(exclusion dia.ExchangeExclusion) error
+	GetExchangeExclusions(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.ExchangeExclusion, error)
+}
+
+// SanityRuleStore stores the sanity rules for trades and the trades rejected by them.
+type SanityRuleStore interface {
+	GetSanityRules() ([]dia.SanityRule, error)
+	SetTradeRejection(rejection dia.TradeRejection) error
+	GetTradeRejections(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.TradeRejection, error)
+}
+
+// ExchangeStore stores exchanges and the pairs and symbols traded on them.
+type ExchangeStore interface {
 	// --------------- asset methods for exchanges ---------------
 	SetExchangePair(exchange string, pair dia.ExchangePair, cache bool) error
 	GetExchangePair(exchange string, foreignname string) (exchangepair dia.ExchangePair, err error)
@@ -59,25 +102,34 @@ type RelDatastore interface {
 	GetAllExchanges() ([]dia.Exchange, error)
 	GetExchangeNames() ([]string, error)
 
-	// ----------------- pool methods -------------------
+	// ------ Caching ------
+	SetExchangePairCache(exchange string, pair dia.ExchangePair) error
+	GetExchangePairCache(exchange string, foreignName string) (dia.ExchangePair, error)
+}
+
+// PoolStore stores DEX pools and their assets.
+type PoolStore interface {
 	SetPool(pool dia.Pool) error
 	GetPoolByAddress(blockchain string, address string) (pool dia.Pool, err error)
 	GetAllPoolAddrsExchange(exchange string) ([]string, error)
+	GetPoolAddrsByAssetPair(exchange string, asset0 dia.Asset, asset1 dia.Asset) ([]string, error)
+}
 
-	// ----------------- blockchain methods -------------------
+// BlockchainStore stores blockchains and data of their blocks.
+type BlockchainStore interface {
 	SetBlockchain(blockchain dia.BlockChain) error
 	GetBlockchain(name string) (dia.BlockChain, error)
 	GetAllAssetsBlockchains() ([]string, error)
 	GetAllBlockchains(fullAsset bool) ([]dia.BlockChain, error)
 
-	// ------ Caching ------
-	SetAssetCache(asset dia.Asset) error
-	GetAssetCache(assetID string) (dia.Asset, error)
-	SetExchangePairCache(exchange string, pair dia.ExchangePair) error
-	GetExchangePairCache(exchange string, foreignName string) (dia.ExchangePair, error)
-	CountCache() (uint32, error)
+	// Blockchain data
+	SetBlockData(dia.BlockData) error
+	GetBlockData(blockchain string, blocknumber int64) (dia.BlockData, error)
+	GetLastBlockBlockscraper(blockchain string) (int64, error)
+}
 
-	// ---------------- NFT methods -------------------
+// NFTStore stores NFT classes, NFTs, their trades, bids and offers and NFT exchanges.
+type NFTStore interface {
 	// NFT class methods
 	SetNFTClass(nftClass dia.NFTClass) error
 	GetAllNFTClasses(blockchain string) ([]dia.NFTClass, error)
@@ -124,30 +176,25 @@ type RelDatastore interface {
 	GetNumNFTTrades(address string, blockchain string, exchange string, starttime time.Time, endtime time.Time) (int, error)
 	GetNFTVolume(address string, blockchain string, exchange string, starttime time.Time, endtime time.Time) (float64, error)
 
-	// General methods
-	GetKeys(table string) ([]string, error)
-
-	// Scraper config and state
-	GetScraperState(ctx context.Context, scraperName string, state ScraperState) error
-	SetScraperState(ctx context.Context, scraperName string, state ScraperState) error
-	GetScraperConfig(ctx context.Context, scraperName string, config ScraperConfig) error
-	SetScraperConfig(ctx context.Context, scraperName string, config ScraperConfig) error
-
-	// Blockchain data
-	SetBlockData(dia.BlockData) error
-	GetBlockData(blockchain string, blocknumber int64) (dia.BlockData, error)
-	GetLastBlockBlockscraper(blockchain string) (int64, error)
-
-	//NFT exchange methods
-
+	// NFT exchange methods
 	GetAllNFTExchanges() (exchanges []dia.NFTExchange, err error)
 	GetNFTExchange(name string) (exchange dia.Exchange, err error)
 	SetNFTExchange(exchange dia.NFTExchange) (err error)
 	GetCollectionCountByExchange(exchange string) (int64, error)
 	Get24HoursNFTExchangeVolume(exchange dia.NFTExchange) (float64, error)
 	Get24HoursNFTExchangeTrades(exchange dia.NFTExchange) (int64, error)
+}
+
+// ScraperStore stores the configuration and state of scrapers.
+type ScraperStore interface {
+	GetScraperState(ctx context.Context, scraperName string, state ScraperState) error
+	SetScraperState(ctx context.Context, scraperName string, state ScraperState) error
+	GetScraperConfig(ctx context.Context, scraperName string, config ScraperConfig) error
+	SetScraperConfig(ctx context.Context, scraperName string, config ScraperConfig) error
+}
 
-	//Oracle builder
+// KeyStore stores the key pairs of the oracle builder.
+type KeyStore interface {
 	SetKeyPair(publickey string, privatekey string) error
 	GetKeyPairID(publickey string) string
 }
@@ -168,6 +215,10 @@ const (
 	assetVolumeTable        = "assetvolume"
 	aggregatedVolumeTable   = "aggregatedvolume"
 	tradesDistributionTable = "tradesdistribution"
+	sanityRuleTable         = "sanityrule"
+	filterConfigTable       = "filterconfig"
+	tradeRejectionTable     = "traderejection"
+	exchangeExclusionTable  = "exchangeexclusion"
 
 	// cache keys
 	keyAssetCache        = "dia_asset_"
@@ -231,8 +282,8 @@ func NewRelDataStoreWithOptions(withPostgres bool, withRedis bool) (*RelDB, erro
 
 // GetKeys returns a slice of strings holding the names of the keys of @table in postgres
 func (rdb *RelDB) GetKeys(table string) (keys []string, err error) {
-	query := fmt.Sprintf("SELECT column_name from information_schema.columns WHERE table_name='%s'", table)
-	rows, err := rdb.postgresClient.Query(context.Background(), query)
+	query := "SELECT column_name from information_schema.columns WHERE table_name=$1"
+	rows, err := rdb.postgresClient.Query(context.Background(), query, table)
 	if err != nil {
 		return
 	}
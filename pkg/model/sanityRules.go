@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/jackc/pgx/v4"
+)
+
+// GetSanityRules returns all sanity rules for trades stored in postgres.
+func (rdb *RelDB) GetSanityRules() (rules []dia.SanityRule, err error) {
+	query := fmt.Sprintf("SELECT exchange,blockchain,address,symbol,min_volume,max_deviation,peg,peg_tolerance,max_price_jump FROM %s", sanityRuleTable)
+	var rows pgx.Rows
+	rows, err = rdb.postgresClient.Query(context.Background(), query)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rule dia.SanityRule
+		err = rows.Scan(
+			&rule.Exchange,
+			&rule.Blockchain,
+			&rule.Address,
+			&rule.Symbol,
+			&rule.MinVolume,
+			&rule.MaxDeviation,
+			&rule.Peg,
+			&rule.PegTolerance,
+			&rule.MaxPriceJump,
+		)
+		if err != nil {
+			return
+		}
+		rules = append(rules, rule)
+	}
+	return
+}
+
+// SetTradeRejection stores the number of trades rejected by the sanity rules in postgres.
+func (rdb *RelDB) SetTradeRejection(rejection dia.TradeRejection) error {
+	assetQuery := fmt.Sprintf("(SELECT asset_id FROM %s WHERE blockchain=$1 and address=$2)", assetTable)
+	query := fmt.Sprintf("INSERT INTO %s (asset_id,exchange,reason,num_trades,time_range_seconds,compute_time) VALUES(%s,$3,$4,$5,$6,$7);", tradeRejectionTable, assetQuery)
+
+	_, err := rdb.postgresClient.Exec(context.Background(), query,
+		rejection.Asset.Blockchain,
+		rejection.Asset.Address,
+		rejection.Exchange,
+		rejection.Reason,
+		rejection.Count,
+		rejection.TimeRangeSeconds,
+		rejection.Timestamp,
+	)
+	return err
+}
+
+// GetTradeRejections returns the number of rejected trades of @asset in the time-range @starttime - @endtime,
+// summed up by exchange and reason.
+func (rdb *RelDB) GetTradeRejections(asset dia.Asset, starttime time.Time, endtime time.Time) (rejections []dia.TradeRejection, err error) {
+	query := fmt.Sprintf("SELECT exchange,reason,SUM(num_trades) FROM %s WHERE asset_id=(SELECT asset_id FROM %s WHERE address=$1 AND blockchain=$2) AND compute_time>$3 AND compute_time<=$4 GROUP BY exchange,reason ORDER BY SUM(num_trades) DESC",
+		tradeRejectionTable,
+		assetTable,
+	)
+
+	var rows pgx.Rows
+	rows, err = rdb.postgresClient.Query(context.Background(), query, asset.Address, asset.Blockchain, starttime, endtime)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		rejection := dia.TradeRejection{
+			Asset:            asset,
+			TimeRangeSeconds: int64(endtime.Sub(starttime).Seconds()),
+			Timestamp:        endtime,
+		}
+		err = rows.Scan(
+			&rejection.Exchange,
+			&rejection.Reason,
+			&rejection.Count,
+		)
+		if err != nil {
+			return
+		}
+		rejections = append(rejections, rejection)
+	}
+	return
+}
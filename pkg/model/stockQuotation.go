@@ -43,9 +43,10 @@ func (db *DB) GetStockQuotation(source string, symbol string, timeInit time.Time
 	unixtimeInit := timeInit.UnixNano()
 	unixtimeFinal := timeFinal.UnixNano()
 
-	query := "SELECT priceAsk,priceBid,sizeAsk,sizeBid,source,\"isin\",\"name\" FROM %s WHERE source='%s' and \"symbol\"='%s' and time>%d and time<=%d order by time desc"
-	q := fmt.Sprintf(query, influxDbStockQuotationsTable, source, symbol, unixtimeInit, unixtimeFinal)
-	res, err := queryInfluxDB(db.influxClient, q)
+	params := influxParams{}
+	query := "SELECT priceAsk,priceBid,sizeAsk,sizeBid,source,\"isin\",\"name\" FROM %s WHERE source=%s and \"symbol\"=%s and time>%d and time<=%d order by time desc"
+	q := fmt.Sprintf(query, influxDbStockQuotationsTable, params.bind(source), params.bind(symbol), unixtimeInit, unixtimeFinal)
+	res, err := queryInfluxDBParams(db.influxClient, q, params)
 	if err != nil {
 		fmt.Println("Error querying influx")
 		return stockQuotations, err
@@ -26,18 +26,7 @@ func getKeyDiaCirculatingSupply() string {
 }
 
 func (datastore *DB) SaveSupplyInflux(supply *dia.Supply) error {
-	fields := map[string]interface{}{
-		"supply":            supply.Supply,
-		"circulatingsupply": supply.CirculatingSupply,
-		"source":            supply.Source,
-	}
-	tags := map[string]string{
-		"symbol":     supply.Asset.Symbol,
-		"name":       supply.Asset.Name,
-		"address":    supply.Asset.Address,
-		"blockchain": supply.Asset.Blockchain,
-	}
-	pt, err := clientInfluxdb.NewPoint(influxDbSupplyTable, tags, fields, supply.Time)
+	pt, err := supplyPoint(supply)
 	if err != nil {
 		log.Errorln("NewSupplyInflux:", err)
 	} else {
@@ -52,19 +41,36 @@ func (datastore *DB) SaveSupplyInflux(supply *dia.Supply) error {
 	return err
 }
 
+// supplyPoint returns the influx point of @supply.
+func supplyPoint(supply *dia.Supply) (*clientInfluxdb.Point, error) {
+	fields := map[string]interface{}{
+		"supply":            supply.Supply,
+		"circulatingsupply": supply.CirculatingSupply,
+		"source":            supply.Source,
+	}
+	tags := map[string]string{
+		"symbol":     supply.Asset.Symbol,
+		"name":       supply.Asset.Name,
+		"address":    supply.Asset.Address,
+		"blockchain": supply.Asset.Blockchain,
+	}
+	return clientInfluxdb.NewPoint(influxDbSupplyTable, tags, fields, supply.Time)
+}
+
 // GetSupplyInflux returns supply and circulating supply of @asset. Needs asset.Address and asset.Blockchain.
 // If no time range is given it returns the latest supply.
 func (datastore *DB) GetSupplyInflux(asset dia.Asset, starttime time.Time, endtime time.Time) ([]dia.Supply, error) {
 	retval := []dia.Supply{}
 	var q string
+	params := influxParams{}
 	if starttime.IsZero() || endtime.IsZero() {
-		queryString := "SELECT supply,circulatingsupply,source,\"name\",\"symbol\" FROM %s WHERE \"address\" = '%s' AND \"blockchain\"='%s' AND time<now() ORDER BY DESC LIMIT 1"
-		q = fmt.Sprintf(queryString, influxDbSupplyTable, asset.Address, asset.Blockchain)
+		queryString := "SELECT supply,circulatingsupply,source,\"name\",\"symbol\" FROM %s WHERE \"address\" = %s AND \"blockchain\"=%s AND time<now() ORDER BY DESC LIMIT 1"
+		q = fmt.Sprintf(queryString, influxDbSupplyTable, params.bind(asset.Address), params.bind(asset.Blockchain))
 	} else {
-		queryString := "SELECT supply,circulatingsupply,source,\"name\",\"symbol\" FROM %s WHERE time > %d AND time < %d AND \"address\" = '%s' AND \"blockchain\"='%s' ORDER BY DESC"
-		q = fmt.Sprintf(queryString, influxDbSupplyTable, starttime.UnixNano(), endtime.UnixNano(), asset.Address, asset.Blockchain)
+		queryString := "SELECT supply,circulatingsupply,source,\"name\",\"symbol\" FROM %s WHERE time > %d AND time < %d AND \"address\" = %s AND \"blockchain\"=%s ORDER BY DESC"
+		q = fmt.Sprintf(queryString, influxDbSupplyTable, starttime.UnixNano(), endtime.UnixNano(), params.bind(asset.Address), params.bind(asset.Blockchain))
 	}
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return retval, err
 	}
@@ -121,6 +127,11 @@ func (datastore *DB) GetLatestSupply(symbol string, relDB *RelDB) (*dia.Supply,
 }
 
 func (datastore *DB) GetSupply(symbol string, starttime, endtime time.Time, relDB *RelDB) ([]dia.Supply, error) {
+	return supplyOfSymbol(datastore, symbol, starttime, endtime, relDB)
+}
+
+// supplyOfSymbol returns the supplies in @datastore of the asset with @symbol and largest volume.
+func supplyOfSymbol(datastore SupplyStore, symbol string, starttime, endtime time.Time, relDB *RelDB) ([]dia.Supply, error) {
 
 	// First get asset with @symbol with largest market cap.
 	topAsset, err := relDB.GetTopAssetByVolume(symbol)
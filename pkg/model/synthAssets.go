@@ -18,8 +18,18 @@ func (datastore *DB) SaveSynthSupplyInflux(t *dia.SynthAssetSupply) error {
 // SaveSynthSupplyInfluxToTable stores a synth supply in influx into @table.
 // Flushed when more than maxPoints in batch.
 func (datastore *DB) SaveSynthSupplyInfluxToTable(t *dia.SynthAssetSupply, table string) error {
+	pt, err := synthSupplyPoint(t, table)
+	if err != nil {
+		log.Errorln("SaveSynthSupplyInfluxToTable:", err)
+	} else {
+		err = datastore.addPoint(pt)
+	}
+
+	return err
+}
 
-	// Create a point and add to batch
+// synthSupplyPoint returns the influx point of synth supply @t in @table.
+func synthSupplyPoint(t *dia.SynthAssetSupply, table string) (*clientInfluxdb.Point, error) {
 	tags := map[string]string{
 		"synthassetsymbol":       t.Asset.Symbol,
 		"underlyingassetsymbol":  t.AssetUnderlying.Symbol,
@@ -35,15 +45,7 @@ func (datastore *DB) SaveSynthSupplyInfluxToTable(t *dia.SynthAssetSupply, table
 		"blocknumber":      int64(t.BlockNumber),
 		"totaldebt":        int64(t.TotalDebt),
 	}
-
-	pt, err := clientInfluxdb.NewPoint(table, tags, fields, t.Time)
-	if err != nil {
-		log.Errorln("SaveSynthSupplyInfluxToTable:", err)
-	} else {
-		datastore.addPoint(pt)
-	}
-
-	return err
+	return clientInfluxdb.NewPoint(table, tags, fields, t.Time)
 }
 
 func (datastore *DB) GetSynthLastSupplyInflux(blockchain, protocol, address string) {
@@ -55,13 +57,14 @@ func (datastore *DB) GetSynthAssets(blockchain, protocol string) (r []string, er
 	queryString := ` 
 	SHOW TAG VALUES FROM %s 
 	WITH KEY="synthtokenaddress" 
-	WHERE blockchain='%s'
-	and protocol='%s'`
+	WHERE blockchain=%s
+	and protocol=%s`
 
-	q := fmt.Sprintf(queryString, influxDbSynthSupplyTable, blockchain, protocol)
+	params := influxParams{}
+	q := fmt.Sprintf(queryString, influxDbSynthSupplyTable, params.bind(blockchain), params.bind(protocol))
 
 	log.Info("query: ", q)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetSynthAssets", err)
 		return r, err
@@ -88,6 +91,7 @@ func (datastore *DB) GetSynthAssets(blockchain, protocol string) (r []string, er
 // GetSynthSupplyInflux
 func (datastore *DB) GetSynthSupplyInflux(blockchain, protocol, address string, limit int, starttime, endtime time.Time) ([]dia.SynthAssetSupply, error) {
 	var r []dia.SynthAssetSupply
+	params := influxParams{}
 
 	queryString := ` 
 	SELECT  
@@ -95,15 +99,15 @@ func (datastore *DB) GetSynthSupplyInflux(blockchain, protocol, address string,
 	protocol, supply,   synthassetsymbol, synthtokenaddress,
 	totaldebt, underlyingassetsymbol, underlyinglocked, underlyingtokenaddress  
 	FROM %s 
-	WHERE blockchain='%s'
+	WHERE blockchain=` + params.bind(blockchain) + `
 	`
 
 	if protocol != "" {
-		queryString = queryString + `AND protocol='` + protocol + `'`
+		queryString = queryString + `AND protocol=` + params.bind(protocol)
 	}
 	if address != "" && address != "0x0000000000000000000000000000000000000000" {
-		queryString = queryString + `AND underlyingtokenaddress='` + address + `'`
-		queryString = queryString + ` OR synthtokenaddress='` + address + `'`
+		queryString = queryString + `AND underlyingtokenaddress=` + params.bind(address)
+		queryString = queryString + ` OR synthtokenaddress=` + params.bind(address)
 
 	}
 
@@ -115,10 +119,10 @@ func (datastore *DB) GetSynthSupplyInflux(blockchain, protocol, address string,
 		queryString = queryString + " LIMIT 1"
 	}
 	queryString = queryString + " ;"
-	q := fmt.Sprintf(queryString, influxDbSynthSupplyTable, blockchain, starttime.UnixNano(), endtime.UnixNano())
+	q := fmt.Sprintf(queryString, influxDbSynthSupplyTable, starttime.UnixNano(), endtime.UnixNano())
 
 	log.Info("query: ", q)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetSynthSupplyInflux", err)
 		return r, err
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia/helpers/db"
+	influxModels "github.com/influxdata/influxdb1-client/models"
+	clientInfluxdb "github.com/influxdata/influxdb1-client/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	// postgres tables of the time series, see deployments/config/pgtimescale.sql.
+	timescaleFiltersTable         = "filters"
+	timescaleVolumesTable         = "volumes_hourly"
+	timescaleAssetQuotationsTable = "assetquotations"
+	timescaleDEXPoolTable         = "dexpools"
+)
+
+// TimescaleDB is a Datastore storing trades, filters, asset quotations and pools in hypertables of postgres with
+// the timescaledb extension, so that a deployment can run on postgres and redis alone. Redis is served by the
+// embedded DB. The remaining time series, such as supplies, candles and rates, require influx. Without influx
+// client, their writers return errNoInflux instead of dropping points.
+type TimescaleDB struct {
+	*DB
+	postgresClient *pgxpool.Pool
+	batch          *pgx.Batch
+	// batchLock makes queueing statements to and sending the batch safe for concurrent use.
+	batchLock sync.Mutex
+}
+
+var _ Datastore = (*TimescaleDB)(nil)
+
+// NewTimescaleDataStore returns a datastore writing time series to the postgres database of RelDB
+// and using redis as done by NewDataStore.
+func NewTimescaleDataStore() (*TimescaleDB, error) {
+	datastore, err := NewDataStoreWithoutInflux()
+	if err != nil {
+		return nil, err
+	}
+	postgresClient := db.PostgresDatabase()
+	if postgresClient == nil {
+		return nil, errors.New("no connection to postgres")
+	}
+	return &TimescaleDB{DB: datastore, postgresClient: postgresClient, batch: &pgx.Batch{}}, nil
+}
+
+// timescaleTable returns the postgres table of influx measurement @measurement as quoted identifier.
+// Unquoted identifiers are folded to lower case by postgres, so that measurements map to lower case tables.
+func timescaleTable(measurement string) string {
+	return pgx.Identifier{strings.ToLower(measurement)}.Sanitize()
+}
+
+// Flush sends the batch of postgres and writes the batch of the embedded DB.
+func (datastore *TimescaleDB) Flush() error {
+	if err := datastore.WriteBatch(); err != nil {
+		return err
+	}
+	return datastore.DB.Flush()
+}
+
+// WriteBatch sends the queued statements to postgres.
+func (datastore *TimescaleDB) WriteBatch() error {
+	datastore.batchLock.Lock()
+	defer datastore.batchLock.Unlock()
+	return datastore.writeBatch()
+}
+
+// writeBatch sends the batch. The statements of a batch are executed in one implicit transaction, so that the
+// batch is kept if it fails. The caller must hold batchLock.
+func (datastore *TimescaleDB) writeBatch() error {
+	if datastore.batch.Len() == 0 {
+		return nil
+	}
+	results := datastore.postgresClient.SendBatch(context.Background(), datastore.batch)
+	for i := 0; i < datastore.batch.Len(); i++ {
+		if _, err := results.Exec(); err != nil {
+			log.Errorln("WriteBatch", err)
+			if cerr := results.Close(); cerr != nil {
+				log.Error("close batch results: ", cerr)
+			}
+			return err
+		}
+	}
+	if err := results.Close(); err != nil {
+		return err
+	}
+	datastore.batch = &pgx.Batch{}
+	return nil
+}
+
+// queue adds @query with arguments @args to the batch, which is sent once it is full.
+func (datastore *TimescaleDB) queue(query string, args ...interface{}) {
+	datastore.batchLock.Lock()
+	defer datastore.batchLock.Unlock()
+	datastore.batch.Queue(query, args...)
+	if datastore.batch.Len() >= influxMaxPointsInBatch {
+		if err := datastore.writeBatch(); err != nil {
+			log.Error("write postgres batch: ", err)
+		}
+	}
+}
+
+// CopyInfluxMeasurements copies the rows of table @tableOrigin in schema @dbOrigin in (@timeInit,@timeFinal] into
+// @tableDestination in schema @dbDestination. Empty schemas stand for the search path. It returns the number of
+// copied rows.
+func (datastore *TimescaleDB) CopyInfluxMeasurements(dbOrigin string, dbDestination string, tableOrigin string, tableDestination string, timeInit time.Time, timeFinal time.Time) (int64, error) {
+	query := fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE time>$1 AND time<=$2",
+		timescaleSchemaTable(dbDestination, tableDestination),
+		timescaleSchemaTable(dbOrigin, tableOrigin),
+	)
+	tag, err := datastore.postgresClient.Exec(context.Background(), query, timeInit, timeFinal)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// timescaleSchemaTable returns the table of @measurement in @schema as quoted identifier.
+func timescaleSchemaTable(schema string, measurement string) string {
+	if schema == "" {
+		return timescaleTable(measurement)
+	}
+	return pgx.Identifier{strings.ToLower(schema), strings.ToLower(measurement)}.Sanitize()
+}
+
+// timescaleResult returns @rows as result of an influx 1.x query of @columns on @measurement, so that callers of
+// all backends can parse it alike. The rows' values must be in the order of @columns.
+func timescaleResult(measurement string, columns []string, rows pgx.Rows) ([]clientInfluxdb.Result, error) {
+	result := clientInfluxdb.Result{}
+	var values [][]interface{}
+	for rows.Next() {
+		row, err := rows.Values()
+		if err != nil {
+			return nil, err
+		}
+		for i := range row {
+			row[i] = influxValue(row[i])
+		}
+		values = append(values, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(values) > 0 {
+		result.Series = append(result.Series, influxModels.Row{Name: measurement, Columns: columns, Values: values})
+	}
+	return []clientInfluxdb.Result{result}, nil
+}
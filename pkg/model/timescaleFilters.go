@@ -0,0 +1,326 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/diadata-org/diadata/pkg/dia/helpers"
+)
+
+// timescaleFilterQualityColumns maps the quality fields of filter points in influx to their columns in postgres.
+var timescaleFilterQualityColumns = map[string]string{
+	"numTrades":             "num_trades",
+	"numExchanges":          "num_exchanges",
+	"volumeUSD":             "volume_usd",
+	"stdDev":                "std_dev",
+	"iqr":                   "iqr",
+	"outlierFraction":       "outlier_fraction",
+	"secondsSinceLastTrade": "seconds_since_last_trade",
+}
+
+// timescaleFilterSelect returns the select list of the filter points' @columns as named by influx.
+func timescaleFilterSelect(columns []string) string {
+	selected := make([]string, len(columns))
+	for i, column := range columns {
+		selected[i] = column
+		if c, ok := timescaleFilterQualityColumns[column]; ok {
+			selected[i] = c
+		}
+	}
+	return strings.Join(selected, ",")
+}
+
+// SetFilter adds a filter point to the batch and its value to the sorted set in redis.
+func (datastore *TimescaleDB) SetFilter(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	err := datastore.SaveFilterInflux(filter, asset, exchange, value, t)
+	if err != nil {
+		return err
+	}
+	return datastore.setZSETValue(getKeyFilterZSET(getKey(filter, asset, exchange)), value, t.Unix(), BiggestWindow)
+}
+
+// SaveFilterInflux adds a filter point to the batch. As in influx, the value of an existing point is overwritten.
+func (datastore *TimescaleDB) SaveFilterInflux(filter string, asset dia.Asset, exchange string, value float64, t time.Time) error {
+	query := fmt.Sprintf("INSERT INTO %s (time,filter,symbol,address,blockchain,exchange,value) VALUES ($1,$2,$3,$4,$5,$6,$7)"+
+		" ON CONFLICT (filter,address,blockchain,exchange,time) DO UPDATE SET symbol=EXCLUDED.symbol,value=EXCLUDED.value",
+		timescaleFiltersTable)
+	datastore.queue(query, t, filter, asset.Symbol, asset.Address, asset.Blockchain, exchange, value)
+	return nil
+}
+
+// SetFilterQuality adds @quality of the point of filter @filter at @t to the batch. It is written
+// to the same row as the filter's value, so it must be called with the time given to SetFilter.
+func (datastore *TimescaleDB) SetFilterQuality(filter string, asset dia.Asset, exchange string, quality dia.FilterPointQuality, t time.Time) error {
+	query := fmt.Sprintf("INSERT INTO %s (time,filter,symbol,address,blockchain,exchange,"+
+		"num_trades,num_exchanges,volume_usd,std_dev,iqr,outlier_fraction,seconds_since_last_trade)"+
+		" VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)"+
+		" ON CONFLICT (filter,address,blockchain,exchange,time) DO UPDATE SET num_trades=EXCLUDED.num_trades,"+
+		"num_exchanges=EXCLUDED.num_exchanges,volume_usd=EXCLUDED.volume_usd,std_dev=EXCLUDED.std_dev,iqr=EXCLUDED.iqr,"+
+		"outlier_fraction=EXCLUDED.outlier_fraction,seconds_since_last_trade=EXCLUDED.seconds_since_last_trade",
+		timescaleFiltersTable)
+	datastore.queue(query,
+		t,
+		filter,
+		asset.Symbol,
+		asset.Address,
+		asset.Blockchain,
+		exchange,
+		quality.NumTrades,
+		quality.NumExchanges,
+		quality.VolumeUSD,
+		quality.StdDev,
+		quality.IQR,
+		quality.OutlierFraction,
+		quality.SecondsSinceLastTrade,
+	)
+	return nil
+}
+
+// DeleteFilterPoints removes all filter points of @asset on @exchange in [@starttime,@endtime]
+// and the values of @filters in this range from redis.
+// It is used when filters are recomputed for an amended tradesBlock.
+func (datastore *TimescaleDB) DeleteFilterPoints(filters []string, asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE address=$1 AND blockchain=$2 AND exchange=$3 AND time>=$4 AND time<=$5", timescaleFiltersTable)
+	_, err := datastore.postgresClient.Exec(context.Background(), query, asset.Address, asset.Blockchain, exchange, starttime, endtime)
+	if err != nil {
+		return err
+	}
+	return datastore.deleteFilterZSETValues(filters, asset, exchange, starttime, endtime)
+}
+
+// GetFilterPointsAsset returns the points of @filter for an asset on @exchange in (@starttime,@endtime] in
+// descending order, where an empty @exchange stands for all exchanges.
+// If @withQuality is true, the points' quality is returned as well.
+func (datastore *TimescaleDB) GetFilterPointsAsset(filter string, exchange string, address string, blockchain string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	columns := filterColumns("time,address,blockchain,exchange,filter,symbol,value", withQuality)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE filter=$1 AND exchange=$2 AND address=$3 AND blockchain=$4 AND time>$5 AND time<=$6 ORDER BY time DESC",
+		timescaleFilterSelect(columns), timescaleFiltersTable)
+	rows, err := datastore.postgresClient.Query(context.Background(), query, filter, exchange, address, blockchain, starttime, endtime)
+	if err != nil {
+		log.Errorln("GetFilterPoints", err)
+		return &Points{}, err
+	}
+	defer rows.Close()
+	res, err := timescaleResult(influxDbFiltersTable, columns, rows)
+	if err != nil {
+		return &Points{}, err
+	}
+	return &Points{DataPoints: res}, nil
+}
+
+// downsampledFilterQuery returns the query of the values of filter @filter in (@starttime,@endtime) downsampled to
+// intervals of length @interval aligned to the unix epoch, in the order @order of time. The points' time is the
+// begin of their interval. As done by the continuous queries of influx 1.x, the volume filter is summed up and
+// all other filters are averaged. @columns are grouped by and selected before the value.
+func downsampledFilterQuery(args *sqlArgs, columns string, conditions []string, filter string, interval time.Duration, starttime time.Time, endtime time.Time, order string) string {
+	fn := "avg"
+	if filter == volumeKey {
+		fn = "sum"
+	}
+	seconds := args.bind(interval.Seconds())
+	bucket := "to_timestamp(floor(extract(epoch FROM time)/" + seconds + ")*" + seconds + ")"
+	conditions = append(conditions,
+		"time>="+args.bind(floorTime(starttime, interval)),
+		"time<"+args.bind(endtime),
+	)
+	return fmt.Sprintf("SELECT %s AS bucket,%s,%s(value) AS value FROM %s WHERE %s GROUP BY bucket,%s HAVING %s>%s ORDER BY bucket %s",
+		bucket, columns, fn, timescaleFiltersTable, strings.Join(conditions, " AND "), columns, bucket, args.bind(starttime), order)
+}
+
+// GetFilterPoints returns the points of @filter in (@starttime,@endtime) in descending order from either a
+// specific exchange or all exchanges. @symbol is mapped to the asset with the largest volume. If @scale is given,
+// points are downsampled to intervals of length @scale. Downsampled points have no quality.
+func (datastore *TimescaleDB) GetFilterPoints(filter string, exchange string, symbol string, scale string, starttime time.Time, endtime time.Time, withQuality bool) (*Points, error) {
+	topAsset, err := topAssetOfSymbol(symbol)
+	if err != nil {
+		return &Points{}, err
+	}
+	var args sqlArgs
+	conditions := []string{
+		"filter=" + args.bind(filter),
+		"exchange=" + args.bind(exchange),
+		"address=" + args.bind(topAsset.Address),
+		"blockchain=" + args.bind(topAsset.Blockchain),
+	}
+	table := influxDbFiltersTable
+	var columns []string
+	var query string
+	if scale != "" {
+		interval, err := parseInfluxDuration(scale)
+		if err != nil {
+			return &Points{}, err
+		}
+		table = "filters_mean_" + scale
+		if filter == volumeKey {
+			table = "filters_sum_" + scale
+		}
+		columns = filterColumns("time,exchange,filter,symbol,value", false)
+		query = downsampledFilterQuery(&args, "exchange,filter,symbol", conditions, filter, interval, starttime, endtime, "DESC")
+	} else {
+		columns = filterColumns("time,exchange,filter,symbol,value", withQuality)
+		conditions = append(conditions, "time>"+args.bind(starttime), "time<"+args.bind(endtime))
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time DESC",
+			timescaleFilterSelect(columns), timescaleFiltersTable, strings.Join(conditions, " AND "))
+	}
+	rows, err := datastore.postgresClient.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Errorln("GetFilterPoints", err)
+		return &Points{}, err
+	}
+	defer rows.Close()
+	res, err := timescaleResult(table, columns, rows)
+	if err != nil {
+		return &Points{}, err
+	}
+	return &Points{DataPoints: res}, nil
+}
+
+// GetFilter returns the last point of @filter across all exchanges for each day in (@starttime,@endtime) in
+// descending order. Days without points take the value of the previous day. If @scale is given, points are
+// downsampled to intervals of length @scale first.
+func (datastore *TimescaleDB) GetFilter(filter string, topAsset dia.Asset, scale string, starttime time.Time, endtime time.Time) ([]dia.FilterPoint, error) {
+	var args sqlArgs
+	conditions := []string{
+		"filter=" + args.bind(filter),
+		"address=" + args.bind(topAsset.Address),
+		"blockchain=" + args.bind(topAsset.Blockchain),
+		"exchange=''",
+		"value IS NOT NULL",
+	}
+	var query string
+	if scale != "" {
+		interval, err := parseInfluxDuration(scale)
+		if err != nil {
+			return nil, err
+		}
+		query = "SELECT bucket,value FROM (" + downsampledFilterQuery(&args, "filter", conditions, filter, interval, starttime, endtime, "ASC") + ") AS downsampled"
+	} else {
+		conditions = append(conditions, "time>"+args.bind(starttime), "time<"+args.bind(endtime))
+		query = fmt.Sprintf("SELECT time,value FROM %s WHERE %s ORDER BY time ASC", timescaleFiltersTable, strings.Join(conditions, " AND "))
+	}
+	rows, err := datastore.postgresClient.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var times []time.Time
+	var values []float64
+	for rows.Next() {
+		var t time.Time
+		var value float64
+		if err := rows.Scan(&t, &value); err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, errors.New("no filter points in time range")
+	}
+	return dailyFilterPoints(times, values, starttime, endtime), nil
+}
+
+// GetLastPriceBefore returns the first point of @filter on @exchange after @timestamp, as done by DB.
+func (datastore *TimescaleDB) GetLastPriceBefore(asset dia.Asset, filter string, exchange string, timestamp time.Time) (Price, error) {
+	price := Price{Symbol: asset.Symbol, Name: helpers.NameForSymbol(asset.Symbol)}
+	query := fmt.Sprintf("SELECT time,value FROM %s WHERE filter=$1 AND address=$2 AND blockchain=$3 AND exchange=$4 AND time>$5"+
+		" AND value IS NOT NULL ORDER BY time ASC LIMIT 1", timescaleFiltersTable)
+	rows, err := datastore.postgresClient.Query(context.Background(), query, filter, asset.Address, asset.Blockchain, exchange, timestamp)
+	if err != nil {
+		log.Errorln("GetLastFilterPointBefore", err)
+		return price, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err := rows.Scan(&price.Time, &price.Price); err != nil {
+			return price, err
+		}
+		price.Time = price.Time.UTC()
+	}
+	return price, rows.Err()
+}
+
+// GetVolumeInflux returns the volume of @asset on @exchange using the VOL120 filter in (@starttime,@endtime].
+// Both, @asset and @exchange may be empty. If @starttime,@endtime are empty, the last 24h are taken into account.
+// Full hours are summed up from the continuous aggregate of hourly volumes, the remainder from the filter points.
+func (datastore *TimescaleDB) GetVolumeInflux(asset dia.Asset, exchange string, starttime time.Time, endtime time.Time) (*float64, error) {
+	if endtime.IsZero() {
+		endtime = time.Now()
+		starttime = endtime.AddDate(0, 0, -1)
+	}
+	var args sqlArgs
+	conditions := []string{"exchange=" + args.bind(exchange)}
+	if asset != (dia.Asset{}) {
+		conditions = append(conditions, "address="+args.bind(asset.Address), "blockchain="+args.bind(asset.Blockchain))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	hourStart := floorTime(starttime, time.Hour).Add(time.Hour)
+	hourEnd := floorTime(endtime, time.Hour)
+	var query string
+	if hourStart.After(hourEnd) {
+		query = fmt.Sprintf("SELECT COALESCE(sum(value),0) FROM %s WHERE filter='%s' AND %s AND time>%s AND time<=%s",
+			timescaleFiltersTable, volumeKey, where, args.bind(starttime), args.bind(endtime))
+	} else {
+		start, hStart, hEnd, end := args.bind(starttime), args.bind(hourStart), args.bind(hourEnd), args.bind(endtime)
+		query = fmt.Sprintf("SELECT COALESCE(sum(volume),0) FROM ("+
+			"SELECT volume FROM %s WHERE %s AND bucket>=%s AND bucket<%s"+
+			" UNION ALL "+
+			"SELECT value FROM %s WHERE filter='%s' AND %s AND ((time>%s AND time<%s) OR (time>=%s AND time<=%s))"+
+			") AS volumes",
+			timescaleVolumesTable, where, hStart, hEnd,
+			timescaleFiltersTable, volumeKey, where, start, hStart, hEnd, end)
+	}
+	var volume float64
+	err := datastore.postgresClient.QueryRow(context.Background(), query, args...).Scan(&volume)
+	if err != nil {
+		log.Errorln("GetVolumeInflux ", err)
+		return nil, err
+	}
+	return &volume, nil
+}
+
+// Get24HoursAssetVolume returns the 24h trading volume of @asset across exchanges.
+func (datastore *TimescaleDB) Get24HoursAssetVolume(asset dia.Asset) (*float64, error) {
+	endtime := time.Now()
+	return datastore.GetVolumeInflux(asset, "", endtime.AddDate(0, 0, -1), endtime)
+}
+
+// Get24HoursExchangeVolume returns 24h trade volume on @exchange using the VOL120 filter.
+func (datastore *TimescaleDB) Get24HoursExchangeVolume(exchange string) (*float64, error) {
+	endtime := time.Now()
+	return datastore.GetVolumeInflux(dia.Asset{}, exchange, endtime.AddDate(0, 0, -1), endtime)
+}
+
+// GetAssetsWithVOLInflux returns all assets with points of the volume filter across exchanges since @timeInit.
+func (datastore *TimescaleDB) GetAssetsWithVOLInflux(timeInit time.Time) ([]dia.Asset, error) {
+	var quotedAssets []dia.Asset
+	query := fmt.Sprintf("SELECT address,blockchain FROM %s WHERE filter='%s' AND exchange='' AND time>$1"+
+		" GROUP BY address,blockchain ORDER BY min(time) ASC", timescaleFiltersTable, volumeKey)
+	rows, err := datastore.postgresClient.Query(context.Background(), query, timeInit)
+	if err != nil {
+		return quotedAssets, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var asset dia.Asset
+		if err := rows.Scan(&asset.Address, &asset.Blockchain); err != nil {
+			return quotedAssets, err
+		}
+		quotedAssets = append(quotedAssets, asset)
+	}
+	if err := rows.Err(); err != nil {
+		return quotedAssets, err
+	}
+	if len(quotedAssets) == 0 {
+		return quotedAssets, errors.New("no recent asset with volume in influx")
+	}
+	return quotedAssets, nil
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+// SetAssetPriceUSD stores the price of @asset in postgres and the caching layer.
+func (datastore *TimescaleDB) SetAssetPriceUSD(asset dia.Asset, price float64, timestamp time.Time) error {
+	return datastore.SetAssetQuotation(&AssetQuotation{
+		Asset:  asset,
+		Price:  price,
+		Source: dia.Diadata,
+		Time:   timestamp,
+	})
+}
+
+// GetAssetPriceUSDLatest returns the latest price of @asset.
+func (datastore *TimescaleDB) GetAssetPriceUSDLatest(asset dia.Asset) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotationLatest(asset)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// GetAssetPriceUSD returns the latest USD price of @asset before @timestamp.
+func (datastore *TimescaleDB) GetAssetPriceUSD(asset dia.Asset, timestamp time.Time) (price float64, err error) {
+	quotation, err := datastore.GetAssetQuotation(asset, timestamp)
+	if err != nil {
+		return
+	}
+	price = quotation.Price
+	return
+}
+
+// queueAssetQuotation adds @quotation to the batch. As in influx, the price of an existing quotation is overwritten.
+func (datastore *TimescaleDB) queueAssetQuotation(quotation *AssetQuotation) {
+	query := fmt.Sprintf("INSERT INTO %s (time,symbol,name,address,blockchain,price) VALUES ($1,$2,$3,$4,$5,$6)"+
+		" ON CONFLICT (address,blockchain,time) DO UPDATE SET symbol=EXCLUDED.symbol,name=EXCLUDED.name,price=EXCLUDED.price",
+		timescaleAssetQuotationsTable)
+	datastore.queue(query,
+		quotation.Time,
+		quotation.Asset.Symbol,
+		quotation.Asset.Name,
+		quotation.Asset.Address,
+		quotation.Asset.Blockchain,
+		quotation.Price,
+	)
+}
+
+// AddAssetQuotationsToBatch adds @quotations to the batch.
+func (datastore *TimescaleDB) AddAssetQuotationsToBatch(quotations []*AssetQuotation) error {
+	for _, quotation := range quotations {
+		datastore.queueAssetQuotation(quotation)
+	}
+	return nil
+}
+
+// SetAssetQuotation adds @quotation to the batch and writes it to the cache.
+func (datastore *TimescaleDB) SetAssetQuotation(quotation *AssetQuotation) error {
+	datastore.queueAssetQuotation(quotation)
+	_, err := datastore.SetAssetQuotationCache(quotation, false)
+	return err
+}
+
+// GetAssetQuotationLatest returns the latest quotation of @asset from the cache or, if not cached, from postgres.
+func (datastore *TimescaleDB) GetAssetQuotationLatest(asset dia.Asset) (*AssetQuotation, error) {
+	quotation, err := datastore.GetAssetQuotationCache(asset)
+	if err == nil {
+		return quotation, nil
+	}
+	return datastore.GetAssetQuotation(asset, time.Now())
+}
+
+// queryAssetQuotations returns the quotations of @asset selected by @query with arguments @args.
+func (datastore *TimescaleDB) queryAssetQuotations(asset dia.Asset, query string, args ...interface{}) ([]AssetQuotation, error) {
+	rows, err := datastore.postgresClient.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var quotations []AssetQuotation
+	for rows.Next() {
+		quotation := AssetQuotation{Asset: asset, Source: dia.Diadata}
+		if err := rows.Scan(&quotation.Time, &quotation.Price); err != nil {
+			return nil, err
+		}
+		quotation.Time = quotation.Time.UTC()
+		quotations = append(quotations, quotation)
+	}
+	return quotations, rows.Err()
+}
+
+// GetAssetQuotation returns the latest quotation of @asset until @timestamp.
+func (datastore *TimescaleDB) GetAssetQuotation(asset dia.Asset, timestamp time.Time) (*AssetQuotation, error) {
+	query := fmt.Sprintf("SELECT time,price FROM %s WHERE address=$1 AND blockchain=$2 AND time<=$3 ORDER BY time DESC LIMIT 1",
+		timescaleAssetQuotationsTable)
+	quotations, err := datastore.queryAssetQuotations(asset, query, asset.Address, asset.Blockchain, timestamp)
+	if err != nil {
+		return &AssetQuotation{}, err
+	}
+	if len(quotations) == 0 {
+		return &AssetQuotation{}, errors.New("no assetQuotation in DB")
+	}
+	return &quotations[0], nil
+}
+
+// GetAssetQuotations returns all quotations of @asset in (@starttime,@endtime] in descending order.
+func (datastore *TimescaleDB) GetAssetQuotations(asset dia.Asset, starttime time.Time, endtime time.Time) ([]AssetQuotation, error) {
+	query := fmt.Sprintf("SELECT time,price FROM %s WHERE address=$1 AND blockchain=$2 AND time>$3 AND time<=$4 ORDER BY time DESC",
+		timescaleAssetQuotationsTable)
+	quotations, err := datastore.queryAssetQuotations(asset, query, asset.Address, asset.Blockchain, starttime, endtime)
+	if err != nil {
+		return []AssetQuotation{}, err
+	}
+	if len(quotations) == 0 {
+		return []AssetQuotation{}, errors.New("no assetQuotation in DB")
+	}
+	return quotations, nil
+}
+
+// GetSortedAssetQuotations returns quotations for all assets in @assets, sorted by 24h volume
+// in descending order.
+func (datastore *TimescaleDB) GetSortedAssetQuotations(assets []dia.Asset) ([]AssetQuotation, error) {
+	return sortedAssetQuotations(datastore, assets)
+}
+
+// GetAssetsMarketCap returns the actual market cap of @asset.
+func (datastore *TimescaleDB) GetAssetsMarketCap(asset dia.Asset) (float64, error) {
+	price, err := datastore.GetAssetPriceUSDLatest(asset)
+	if err != nil {
+		return 0, err
+	}
+	supply, err := datastore.GetSupplyCache(asset)
+	if err != nil {
+		return 0, err
+	}
+	return price * supply.CirculatingSupply, nil
+}
+
+// GetTopAssetByVolume returns the asset with highest volume among all assets with symbol @symbol.
+func (datastore *TimescaleDB) GetTopAssetByVolume(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	return topAssetByVolume(datastore, symbol, relDB)
+}
+
+// GetTopAssetByMcap returns the asset with highest market cap among all assets with symbol @symbol.
+func (datastore *TimescaleDB) GetTopAssetByMcap(symbol string, relDB *RelDB) (topAsset dia.Asset, err error) {
+	return topAssetByMcap(datastore, symbol, relDB)
+}
+
+// SavePoolInflux writes a DEX pool to postgres.
+func (datastore *TimescaleDB) SavePoolInflux(p dia.Pool) error {
+	assetvolumesEncoded, err := json.Marshal(p.Assetvolumes)
+	if err != nil {
+		log.Error("marshal volumes: ", err)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (time,exchange,blockchain,address,volumes) VALUES ($1,$2,$3,$4,$5)", timescaleDEXPoolTable)
+	_, err = datastore.postgresClient.Exec(context.Background(), query, p.Time, p.Exchange.Name, p.Blockchain.Name, p.Address, string(assetvolumesEncoded))
+	if err != nil {
+		log.Errorln("SavePoolInflux: ", err)
+	}
+	return err
+}
+
+// GetPoolInflux returns all info/liquidities of pool with @poolAddress in the time-range [starttime, endtime).
+func (datastore *TimescaleDB) GetPoolInflux(poolAddress string, starttime time.Time, endtime time.Time) ([]dia.Pool, error) {
+	pools := []dia.Pool{}
+	query := fmt.Sprintf("SELECT time,exchange,blockchain,volumes::text FROM %s WHERE address=$1 AND time>=$2 AND time<$3 ORDER BY time DESC",
+		timescaleDEXPoolTable)
+	rows, err := datastore.postgresClient.Query(context.Background(), query, poolAddress, starttime, endtime)
+	if err != nil {
+		return pools, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pool dia.Pool
+		var volumes string
+		if err := rows.Scan(&pool.Time, &pool.Exchange.Name, &pool.Blockchain.Name, &volumes); err != nil {
+			return pools, err
+		}
+		pool.Time = pool.Time.UTC()
+		if err := json.Unmarshal([]byte(volumes), &pool.Assetvolumes); err != nil {
+			log.Error("unmarshal: ", err)
+		}
+		pool.Address = poolAddress
+		pools = append(pools, pool)
+	}
+	if err := rows.Err(); err != nil {
+		return pools, err
+	}
+	if len(pools) == 0 {
+		return pools, errors.New("parsing pool from database")
+	}
+	return pools, nil
+}
@@ -0,0 +1,403 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+	"github.com/jackc/pgx/v4"
+)
+
+// timescaleTradeColumns are the columns of trades in the order scanned by scanTimescaleTrade.
+const timescaleTradeColumns = "time,symbol,pair,exchange,verified,foreign_trade_id,price,volume,estimated_usd_price," +
+	"quotetoken_address,quotetoken_blockchain,basetoken_address,basetoken_blockchain"
+
+// scanTimescaleTrade scans the trade in the current row of @rows. As done by DB, blockchain and address of
+// the quote and base token are only returned if @fullQuotetoken and @fullBasetoken are true respectively.
+func scanTimescaleTrade(rows pgx.Rows, fullQuotetoken bool, fullBasetoken bool) (dia.Trade, error) {
+	var trade dia.Trade
+	var quotetoken, basetoken dia.Asset
+	err := rows.Scan(
+		&trade.Time,
+		&trade.Symbol,
+		&trade.Pair,
+		&trade.Source,
+		&trade.VerifiedPair,
+		&trade.ForeignTradeID,
+		&trade.Price,
+		&trade.Volume,
+		&trade.EstimatedUSDPrice,
+		&quotetoken.Address,
+		&quotetoken.Blockchain,
+		&basetoken.Address,
+		&basetoken.Blockchain,
+	)
+	trade.Time = trade.Time.UTC()
+	if fullQuotetoken {
+		trade.QuoteToken = quotetoken
+	}
+	if fullBasetoken {
+		trade.BaseToken = basetoken
+	}
+	return trade, err
+}
+
+// queryTimescaleTrades returns the trades selected by @query with arguments @args.
+func (datastore *TimescaleDB) queryTimescaleTrades(fullQuotetoken bool, fullBasetoken bool, query string, args ...interface{}) ([]dia.Trade, error) {
+	rows, err := datastore.postgresClient.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var trades []dia.Trade
+	for rows.Next() {
+		trade, err := scanTimescaleTrade(rows, fullQuotetoken, fullBasetoken)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, rows.Err()
+}
+
+// SaveTradeInflux adds @t to the batch of the trades table.
+func (datastore *TimescaleDB) SaveTradeInflux(t *dia.Trade) error {
+	return datastore.SaveTradeInfluxToTable(t, influxDbTradesTable)
+}
+
+// SaveTradeInfluxToTable adds @t to the batch of @table. The table must have the columns of the trades table.
+func (datastore *TimescaleDB) SaveTradeInfluxToTable(t *dia.Trade, table string) error {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)", timescaleTable(table), timescaleTradeColumns)
+	datastore.queue(query,
+		t.Time,
+		t.Symbol,
+		t.Pair,
+		t.Source,
+		t.VerifiedPair,
+		t.ForeignTradeID,
+		t.Price,
+		t.Volume,
+		t.EstimatedUSDPrice,
+		t.QuoteToken.Address,
+		t.QuoteToken.Blockchain,
+		t.BaseToken.Address,
+		t.BaseToken.Blockchain,
+	)
+	return nil
+}
+
+// quoteTokenCondition returns the condition that a trade's quote token is @asset.
+func quoteTokenCondition(args *sqlArgs, asset dia.Asset) string {
+	return "quotetoken_address=" + args.bind(asset.Address) + " AND quotetoken_blockchain=" + args.bind(asset.Blockchain)
+}
+
+// GetTradeInflux returns the latest trade of @asset on @exchange in [@endtime-@window,@endtime).
+func (datastore *TimescaleDB) GetTradeInflux(asset dia.Asset, exchange string, endtime time.Time, window time.Duration) (*dia.Trade, error) {
+	var args sqlArgs
+	conditions := []string{
+		quoteTokenCondition(&args, asset),
+		"time>=" + args.bind(endtime.Add(-window)),
+		"time<" + args.bind(endtime),
+	}
+	if exchange != "" {
+		conditions = append(conditions, "exchange="+args.bind(exchange))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time DESC LIMIT 1",
+		timescaleTradeColumns, timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "))
+	trades, err := datastore.queryTimescaleTrades(false, false, query, args...)
+	if err != nil {
+		return &dia.Trade{}, err
+	}
+	if len(trades) == 0 {
+		return &dia.Trade{}, errors.New("parsing trade from database")
+	}
+	trade := trades[0]
+	trade.VerifiedPair = false
+	return &trade, nil
+}
+
+// GetOldTradesFromInflux returns the trades of @table on @exchange in [@timeInit,@timeFinal) in ascending order.
+// If @exchange is empty, trades across all exchanges are returned.
+// If @verified is true, address and blockchain are also returned for both assets.
+func (datastore *TimescaleDB) GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) ([]dia.Trade, error) {
+	var args sqlArgs
+	conditions := []string{"time>=" + args.bind(timeInit), "time<" + args.bind(timeFinal)}
+	if exchange != "" {
+		conditions = append(conditions, "exchange="+args.bind(exchange))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time ASC",
+		timescaleTradeColumns, timescaleTable(table), strings.Join(conditions, " AND "))
+	trades, err := datastore.queryTimescaleTrades(verified, verified, query, args...)
+	if err != nil {
+		return []dia.Trade{}, err
+	}
+	if len(trades) == 0 {
+		return []dia.Trade{}, errors.New("no trades in time range")
+	}
+	allTrades := []dia.Trade{}
+	for _, trade := range trades {
+		if trade.Symbol == "" {
+			continue
+		}
+		if !verified {
+			trade.VerifiedPair = false
+		}
+		allTrades = append(allTrades, trade)
+	}
+	return allTrades, nil
+}
+
+// GetTradesByExchanges returns the trades of @asset on @exchanges in [@startTime,@endTime].
+func (datastore *TimescaleDB) GetTradesByExchanges(asset dia.Asset, baseassets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesFull(asset, baseassets, exchanges, false, startTime, endTime)
+}
+
+// GetTradesByExchangesAndBaseAssets returns the trades of @asset against @baseassets on @exchanges in [@startTime,@endTime].
+func (datastore *TimescaleDB) GetTradesByExchangesAndBaseAssets(asset dia.Asset, baseassets []dia.Asset, exchanges []string, startTime, endTime time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesFull(asset, baseassets, exchanges, false, startTime, endTime)
+}
+
+// tradesConditions returns the conditions on trades of @asset with positive USD price against @baseassets on
+// @exchanges. Exchanges are matched exactly. All exchanges and base assets match if none are given.
+func tradesConditions(args *sqlArgs, asset dia.Asset, baseassets []dia.Asset, exchanges []string) []string {
+	conditions := []string{quoteTokenCondition(args, asset), "estimated_usd_price>0"}
+	if len(exchanges) > 0 {
+		conditions = append(conditions, "exchange=ANY("+args.bind(exchanges)+")")
+	}
+	if len(baseassets) > 0 {
+		var baseConditions []string
+		for _, baseasset := range baseassets {
+			baseConditions = append(baseConditions, "(basetoken_address="+args.bind(baseasset.Address)+" AND basetoken_blockchain="+args.bind(baseasset.Blockchain)+")")
+		}
+		conditions = append(conditions, "("+strings.Join(baseConditions, " OR ")+")")
+	}
+	return conditions
+}
+
+// GetTradesByExchangesFull returns the trades of @asset with positive USD price in [@startTime,@endTime] on
+// @exchanges. As in DB, @baseassets only restrict the trades if @exchanges are given.
+func (datastore *TimescaleDB) GetTradesByExchangesFull(asset dia.Asset, baseassets []dia.Asset, exchanges []string, returnBasetoken bool, startTime, endTime time.Time) ([]dia.Trade, error) {
+	if len(exchanges) == 0 {
+		baseassets = nil
+	}
+	var args sqlArgs
+	conditions := append(tradesConditions(&args, asset, baseassets, exchanges),
+		"time>="+args.bind(startTime),
+		"time<="+args.bind(endTime),
+	)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time ASC",
+		timescaleTradeColumns, timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "))
+	trades, err := datastore.queryTimescaleTrades(false, returnBasetoken, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(trades) == 0 {
+		return nil, fmt.Errorf("no trades found")
+	}
+	return trades, nil
+}
+
+// GetTradesByExchangesBatched returns the trades of @quoteasset on @exchanges in the intervals (@startTimes[i],@endTimes[i]].
+func (datastore *TimescaleDB) GetTradesByExchangesBatched(quoteasset dia.Asset, baseassets []dia.Asset, exchanges []string, startTimes, endTimes []time.Time) ([]dia.Trade, error) {
+	return datastore.GetTradesByExchangesBatchedFull(quoteasset, baseassets, exchanges, false, startTimes, endTimes)
+}
+
+// GetTradesByExchangesBatchedFull returns the trades of @quoteasset with positive USD price on @exchanges in the
+// time ranges (startTimes[i],endTimes[i]] with one query. Trades are restricted to @baseassets if given.
+func (datastore *TimescaleDB) GetTradesByExchangesBatchedFull(quoteasset dia.Asset, baseassets []dia.Asset, exchanges []string, returnBasetoken bool, startTimes, endTimes []time.Time) ([]dia.Trade, error) {
+	if len(startTimes) != len(endTimes) {
+		return []dia.Trade{}, errors.New("number of start times must equal number of end times.")
+	}
+	if len(startTimes) == 0 {
+		return nil, fmt.Errorf("no trades found")
+	}
+	var args sqlArgs
+	conditions := tradesConditions(&args, quoteasset, baseassets, exchanges)
+	var intervals []string
+	for i := range startTimes {
+		intervals = append(intervals, "(time>"+args.bind(startTimes[i])+" AND time<="+args.bind(endTimes[i])+")")
+	}
+	conditions = append(conditions, "("+strings.Join(intervals, " OR ")+")")
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time ASC",
+		timescaleTradeColumns, timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "))
+	trades, err := datastore.queryTimescaleTrades(false, returnBasetoken, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetAllTrades returns at most @maxTrades trades with timestamp after the second of @t.
+func (datastore *TimescaleDB) GetAllTrades(t time.Time, maxTrades int) ([]dia.Trade, error) {
+	var args sqlArgs
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE time>%s ORDER BY time ASC",
+		timescaleTradeColumns, timescaleTable(influxDbTradesTable), args.bind(time.Unix(t.Unix(), 0)))
+	if maxTrades > 0 {
+		query += " LIMIT " + args.bind(maxTrades)
+	}
+	trades, err := datastore.queryTimescaleTrades(false, false, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetLastTrades returns the last @maxTrades of @asset on @exchange in the last 30 days.
+// If exchange is empty string it returns trades from all exchanges.
+// If fullAsset=true, blockchain and address of the base token are returned as well.
+func (datastore *TimescaleDB) GetLastTrades(asset dia.Asset, exchange string, maxTrades int, fullAsset bool) ([]dia.Trade, error) {
+	var args sqlArgs
+	conditions := []string{
+		quoteTokenCondition(&args, asset),
+		"estimated_usd_price>0",
+		"time>" + args.bind(time.Now().AddDate(0, 0, -30)),
+	}
+	if exchange != "" {
+		conditions = append(conditions, "exchange="+args.bind(exchange))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY time DESC LIMIT %s",
+		timescaleTradeColumns, timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "), args.bind(maxTrades))
+	trades, err := datastore.queryTimescaleTrades(false, fullAsset, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(trades) == 0 {
+		return nil, fmt.Errorf("Empty response for %s on %s", asset.Symbol, exchange)
+	}
+	for i := range trades {
+		trades[i].QuoteToken = asset
+	}
+	return trades, nil
+}
+
+// GetNumTradesExchange24H returns the number of trades on @exchange in the last 24 hours.
+func (datastore *TimescaleDB) GetNumTradesExchange24H(exchange string) (int64, error) {
+	endtime := time.Now()
+	return datastore.GetNumTrades(exchange, "", "", endtime.AddDate(0, 0, -1), endtime)
+}
+
+// GetNumTrades returns the number of trades on @exchange for asset with @address and @blockchain in (@starttime,@endtime].
+// If @address and @blockchain are empty, it returns all trades on @exchange in the given time-range.
+func (datastore *TimescaleDB) GetNumTrades(exchange string, address string, blockchain string, starttime time.Time, endtime time.Time) (numTrades int64, err error) {
+	var args sqlArgs
+	conditions := []string{
+		"exchange=" + args.bind(exchange),
+		"time>" + args.bind(starttime),
+		"time<=" + args.bind(endtime),
+	}
+	if address != "" && blockchain != "" {
+		conditions = append(conditions, quoteTokenCondition(&args, dia.Asset{Address: address, Blockchain: blockchain}))
+	}
+	query := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "))
+	err = datastore.postgresClient.QueryRow(context.Background(), query, args...).Scan(&numTrades)
+	return
+}
+
+// GetNumTradesSeries returns the number of trades in the intervals of length @grouping aligned to the unix epoch
+// intersecting (@starttime,@endtime]. If pair is the empty string, trades are identified by address/blockchain.
+func (datastore *TimescaleDB) GetNumTradesSeries(
+	exchange string,
+	pair string,
+	starttime time.Time,
+	endtime time.Time,
+	grouping string,
+	quotetoken dia.Asset,
+	basetoken dia.Asset,
+) (numTrades []int, err error) {
+	interval, err := parseInfluxDuration(grouping)
+	if err != nil {
+		return
+	}
+	if interval < time.Second {
+		return nil, errors.New("invalid grouping " + grouping)
+	}
+	var args sqlArgs
+	seconds := args.bind(interval.Seconds())
+	conditions := []string{
+		"exchange=" + args.bind(exchange),
+		"time>" + args.bind(starttime),
+		"time<=" + args.bind(endtime),
+	}
+	if pair != "" {
+		conditions = append(conditions, "pair="+args.bind(pair))
+	} else {
+		conditions = append(conditions,
+			quoteTokenCondition(&args, quotetoken),
+			"basetoken_address="+args.bind(basetoken.Address),
+			"basetoken_blockchain="+args.bind(basetoken.Blockchain),
+		)
+	}
+	query := fmt.Sprintf("SELECT floor(extract(epoch FROM time)/%s)::bigint AS bucket,count(*) FROM %s WHERE %s GROUP BY bucket",
+		seconds, timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "))
+	rows, err := datastore.postgresClient.Query(context.Background(), query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var bucket int64
+		var count int
+		if err = rows.Scan(&bucket, &count); err != nil {
+			return
+		}
+		counts[bucket] = count
+	}
+	if err = rows.Err(); err != nil {
+		return
+	}
+	// Empty intervals are filled with 0, as done by GROUP BY time().
+	first := int64(math.Floor(float64(starttime.Unix()) / interval.Seconds()))
+	last := int64(math.Floor(float64(endtime.Unix()) / interval.Seconds()))
+	for bucket := first; bucket <= last; bucket++ {
+		numTrades = append(numTrades, counts[bucket])
+	}
+	return
+}
+
+// GetFirstTradeDate returns the time of the first trade in @table.
+func (datastore *TimescaleDB) GetFirstTradeDate(table string) (time.Time, error) {
+	var t time.Time
+	query := fmt.Sprintf("SELECT time FROM %s ORDER BY time ASC LIMIT 1", timescaleTable(table))
+	err := datastore.postgresClient.QueryRow(context.Background(), query).Scan(&t)
+	if err == pgx.ErrNoRows {
+		return time.Time{}, errors.New("no trade found")
+	}
+	return t.UTC(), err
+}
+
+// GetActiveExchangesAndPairs returns all exchanges the asset with @address and @blockchain was traded on
+// with verified pairs in (@starttime,@endtime] as keys of a map. The map's values are the underlying pairs.
+func (datastore *TimescaleDB) GetActiveExchangesAndPairs(address string, blockchain string, starttime time.Time, endtime time.Time) (map[string][]dia.Pair, error) {
+	exchangepairmap := make(map[string][]dia.Pair)
+	var args sqlArgs
+	conditions := []string{
+		quoteTokenCondition(&args, dia.Asset{Address: address, Blockchain: blockchain}),
+		"verified",
+		"time>" + args.bind(starttime),
+		"time<=" + args.bind(endtime),
+	}
+	// The pair of each exchange and pair symbol is taken from the latest trade.
+	query := fmt.Sprintf("SELECT DISTINCT ON (exchange,pair) exchange,quotetoken_address,quotetoken_blockchain,basetoken_address,basetoken_blockchain"+
+		" FROM %s WHERE %s ORDER BY exchange,pair,time DESC",
+		timescaleTable(influxDbTradesTable), strings.Join(conditions, " AND "))
+	rows, err := datastore.postgresClient.Query(context.Background(), query, args...)
+	if err != nil {
+		return exchangepairmap, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var exchange string
+		var pair dia.Pair
+		err = rows.Scan(&exchange, &pair.QuoteToken.Address, &pair.QuoteToken.Blockchain, &pair.BaseToken.Address, &pair.BaseToken.Blockchain)
+		if err != nil {
+			return exchangepairmap, err
+		}
+		exchangepairmap[exchange] = append(exchangepairmap[exchange], pair)
+	}
+	return exchangepairmap, rows.Err()
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diadata-org/diadata/pkg/dia"
+)
+
+func TestTimescaleTable(t *testing.T) {
+	cases := map[string]string{
+		"trades":                     `"trades"`,
+		"tradesTmp":                  `"tradestmp"`,
+		`trades"; DROP TABLE trades`: `"trades""; drop table trades"`,
+	}
+	for measurement, table := range cases {
+		if got := timescaleTable(measurement); got != table {
+			t.Errorf("timescaleTable(%q) = %s, want %s", measurement, got, table)
+		}
+	}
+	if got := timescaleSchemaTable("public", "trades"); got != `"public"."trades"` {
+		t.Errorf("timescaleSchemaTable = %s", got)
+	}
+}
+
+func TestTradesConditions(t *testing.T) {
+	var args sqlArgs
+	asset := dia.Asset{Address: "0x0", Blockchain: dia.ETHEREUM}
+	baseassets := []dia.Asset{{Address: "' OR 1=1 --", Blockchain: dia.ETHEREUM}}
+	conditions := tradesConditions(&args, asset, baseassets, []string{"Binance"})
+	where := strings.Join(conditions, " AND ")
+	if strings.Contains(where, "OR 1=1") {
+		t.Errorf("argument in query: %s", where)
+	}
+	if !strings.Contains(where, "exchange=ANY($3)") || !strings.Contains(where, "basetoken_address=$4 AND basetoken_blockchain=$5") {
+		t.Errorf("unexpected placeholders: %s", where)
+	}
+	want := sqlArgs{"0x0", dia.ETHEREUM, []string{"Binance"}, "' OR 1=1 --", dia.ETHEREUM}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+}
+
+func TestTimescaleFilterSelect(t *testing.T) {
+	got := timescaleFilterSelect(filterColumns("time,value", true))
+	if !strings.HasPrefix(got, "time,value,") || !strings.Contains(got, "num_trades") || strings.Contains(got, "numTrades") {
+		t.Errorf("timescaleFilterSelect = %s", got)
+	}
+}
+
+func TestInfluxValue(t *testing.T) {
+	timestamp := time.Date(2021, 1, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600))
+	if got := influxValue(timestamp); got != "2021-01-01T00:00:00Z" {
+		t.Errorf("influxValue(time) = %v", got)
+	}
+	if got := influxValue(1.5); got != interface{}(json.Number("1.5")) {
+		t.Errorf("influxValue(float) = %v", got)
+	}
+	if got := influxValue(int64(3)); got != interface{}(json.Number("3")) {
+		t.Errorf("influxValue(int) = %v", got)
+	}
+	if got := influxValue("Binance"); got != "Binance" {
+		t.Errorf("influxValue(string) = %v", got)
+	}
+}
+
+func TestTimescaleWithoutInflux(t *testing.T) {
+	datastore := &TimescaleDB{DB: &DB{}}
+	if err := datastore.SaveCandleInflux(dia.Candle{Resolution: "1m", Time: time.Now()}); err != errNoInflux {
+		t.Errorf("SaveCandleInflux: got %v, want %v", err, errNoInflux)
+	}
+	if err := datastore.SaveSynthSupplyInflux(&dia.SynthAssetSupply{Time: time.Now()}); err != errNoInflux {
+		t.Errorf("SaveSynthSupplyInflux: got %v, want %v", err, errNoInflux)
+	}
+	if err := datastore.SaveSupplyInflux(&dia.Supply{Time: time.Now()}); err != errNoInflux {
+		t.Errorf("SaveSupplyInflux: got %v, want %v", err, errNoInflux)
+	}
+}
+
+// TestTimeSeriesDataStoreWithoutInflux checks that the postgres and influx 2.x backends start
+// without connecting to influx 1.x. The postgres backend is skipped if postgres is not reachable.
+func TestTimeSeriesDataStoreWithoutInflux(t *testing.T) {
+	t.Setenv("INFLUXURL", "")
+	os.Unsetenv("INFLUXURL")
+	if os.Getenv("USE_ENV") != "true" {
+		// Connect to postgres given by the environment rather than by the secrets file.
+		t.Setenv("USE_ENV", "true")
+	}
+	for _, backend := range []string{"influxv2", "postgres"} {
+		t.Setenv("TIMESERIES_BACKEND", backend)
+		datastore, err := NewTimeSeriesDataStore()
+		if backend == "postgres" && err != nil {
+			t.Logf("skip postgres backend: %v", err)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", backend, err)
+		}
+		var embedded *DB
+		switch ds := datastore.(type) {
+		case *InfluxV2DB:
+			embedded = ds.DB
+		case *TimescaleDB:
+			embedded = ds.DB
+		default:
+			t.Fatalf("%s: unexpected datastore %T", backend, datastore)
+		}
+		if embedded.influxClient != nil {
+			t.Errorf("%s: datastore connects to influx 1.x", backend)
+		}
+	}
+}
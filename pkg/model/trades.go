@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/diadata-org/diadata/pkg/dia"
@@ -21,8 +20,18 @@ func (datastore *DB) SaveTradeInflux(t *dia.Trade) error {
 // SaveTradeInfluxToTable stores a trade in influx into @table.
 // Flushed when more than maxPoints in batch.
 func (datastore *DB) SaveTradeInfluxToTable(t *dia.Trade, table string) error {
+	pt, err := tradePoint(t, table)
+	if err != nil {
+		log.Errorln("NewTradeInflux:", err)
+	} else {
+		err = datastore.addPoint(pt)
+	}
+
+	return err
+}
 
-	// Create a point and add to batch
+// tradePoint returns the influx point of trade @t in @table.
+func tradePoint(t *dia.Trade, table string) (*clientInfluxdb.Point, error) {
 	tags := map[string]string{
 		"symbol":               t.Symbol,
 		"pair":                 t.Pair,
@@ -39,15 +48,7 @@ func (datastore *DB) SaveTradeInfluxToTable(t *dia.Trade, table string) error {
 		"estimatedUSDPrice": t.EstimatedUSDPrice,
 		"foreignTradeID":    t.ForeignTradeID,
 	}
-
-	pt, err := clientInfluxdb.NewPoint(table, tags, fields, t.Time)
-	if err != nil {
-		log.Errorln("NewTradeInflux:", err)
-	} else {
-		datastore.addPoint(pt)
-	}
-
-	return err
+	return clientInfluxdb.NewPoint(table, tags, fields, t.Time)
 }
 
 // GetTradeInflux returns the latest trade of @asset on @exchange before @timestamp in the time-range [endtime-window, endtime].
@@ -55,16 +56,17 @@ func (datastore *DB) GetTradeInflux(asset dia.Asset, exchange string, endtime ti
 	starttime := endtime.Add(-window)
 	retval := dia.Trade{}
 	var q string
+	params := influxParams{}
 	if exchange != "" {
-		queryString := "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume FROM %s WHERE quotetokenaddress='%s' AND quotetokenblockchain='%s' AND exchange='%s' AND time >= %d AND time < %d ORDER BY DESC LIMIT 1"
-		q = fmt.Sprintf(queryString, influxDbTradesTable, asset.Address, asset.Blockchain, exchange, starttime.UnixNano(), endtime.UnixNano())
+		queryString := "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume FROM %s WHERE quotetokenaddress=%s AND quotetokenblockchain=%s AND exchange=%s AND time >= %d AND time < %d ORDER BY DESC LIMIT 1"
+		q = fmt.Sprintf(queryString, influxDbTradesTable, params.bind(asset.Address), params.bind(asset.Blockchain), params.bind(exchange), starttime.UnixNano(), endtime.UnixNano())
 	} else {
-		queryString := "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume FROM %s WHERE quotetokenaddress='%s' AND quotetokenblockchain='%s' AND time >= %d AND time < %d ORDER BY DESC LIMIT 1"
-		q = fmt.Sprintf(queryString, influxDbTradesTable, asset.Address, asset.Blockchain, starttime.UnixNano(), endtime.UnixNano())
+		queryString := "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume FROM %s WHERE quotetokenaddress=%s AND quotetokenblockchain=%s AND time >= %d AND time < %d ORDER BY DESC LIMIT 1"
+		q = fmt.Sprintf(queryString, influxDbTradesTable, params.bind(asset.Address), params.bind(asset.Blockchain), starttime.UnixNano(), endtime.UnixNano())
 	}
 
 	/// TODO
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		return &retval, err
 	}
@@ -104,6 +106,7 @@ func (datastore *DB) GetTradeInflux(asset dia.Asset, exchange string, endtime ti
 func (datastore *DB) GetOldTradesFromInflux(table string, exchange string, verified bool, timeInit, timeFinal time.Time) ([]dia.Trade, error) {
 	allTrades := []dia.Trade{}
 	var queryString, query, addQueryString string
+	params := influxParams{}
 	if verified {
 		addQueryString = ",\"quotetokenaddress\",\"basetokenaddress\",\"quotetokenblockchain\",\"basetokenblockchain\",\"verified\""
 	}
@@ -111,14 +114,14 @@ func (datastore *DB) GetOldTradesFromInflux(table string, exchange string, verif
 		queryString = "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume" +
 			addQueryString +
 			" FROM %s WHERE time>=%d and time<%d order by asc"
-		query = fmt.Sprintf(queryString, table, timeInit.UnixNano(), timeFinal.UnixNano())
+		query = fmt.Sprintf(queryString, influxIdentifier(table), timeInit.UnixNano(), timeFinal.UnixNano())
 	} else {
 		queryString = "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume" +
 			addQueryString +
-			" FROM %s WHERE exchange='%s' and time>=%d and time<%d order by asc"
-		query = fmt.Sprintf(queryString, table, exchange, timeInit.UnixNano(), timeFinal.UnixNano())
+			" FROM %s WHERE exchange=%s and time>=%d and time<%d order by asc"
+		query = fmt.Sprintf(queryString, influxIdentifier(table), params.bind(exchange), timeInit.UnixNano(), timeFinal.UnixNano())
 	}
-	res, err := queryInfluxDB(datastore.influxClient, query)
+	res, err := queryInfluxDBParams(datastore.influxClient, query, params)
 	if err != nil {
 		log.Error("influx query: ", err)
 		return allTrades, err
@@ -289,26 +292,24 @@ func (datastore *DB) GetTradesByExchangesFull(asset dia.Asset, baseassets []dia.
 	var r []dia.Trade
 	subQuery := ""
 	subQueryBase := ""
+	params := influxParams{}
 	if len(exchanges) > 0 {
-		for _, exchange := range exchanges {
-			subQuery = subQuery + fmt.Sprintf("%s|", exchange)
-		}
-		subQuery = "AND exchange =~ /" + strings.TrimRight(subQuery, "|") + "/"
+		subQuery = "AND exchange =~ " + influxRegex(exchanges)
 
 		if len(baseassets) > 0 {
 			for i, baseasset := range baseassets {
 				if i == 0 {
-					subQueryBase = subQueryBase + fmt.Sprintf(` AND ((basetokenaddress='%s' AND basetokenblockchain='%s')`, baseasset.Address, baseasset.Blockchain)
+					subQueryBase = subQueryBase + fmt.Sprintf(` AND ((basetokenaddress=%s AND basetokenblockchain=%s)`, params.bind(baseasset.Address), params.bind(baseasset.Blockchain))
 
 				} else {
-					subQueryBase = subQueryBase + fmt.Sprintf(` OR (basetokenaddress='%s' AND basetokenblockchain='%s')`, baseasset.Address, baseasset.Blockchain)
+					subQueryBase = subQueryBase + fmt.Sprintf(` OR (basetokenaddress=%s AND basetokenblockchain=%s)`, params.bind(baseasset.Address), params.bind(baseasset.Blockchain))
 				}
 			}
 			subQueryBase = subQueryBase + ") "
 		}
 	}
-	query := fmt.Sprintf("SELECT time,estimatedUSDPrice,exchange,foreignTradeID,pair,price,symbol,volume,verified,basetokenblockchain,basetokenaddress FROM %s WHERE (quotetokenaddress='%s' and quotetokenblockchain='%s') %s %s AND estimatedUSDPrice > 0 AND time >= %d AND time <= %d ", influxDbTradesTable, asset.Address, asset.Blockchain, subQuery, subQueryBase, startTime.UnixNano(), endTime.UnixNano())
-	res, err := queryInfluxDB(datastore.influxClient, query)
+	query := fmt.Sprintf("SELECT time,estimatedUSDPrice,exchange,foreignTradeID,pair,price,symbol,volume,verified,basetokenblockchain,basetokenaddress FROM %s WHERE (quotetokenaddress=%s and quotetokenblockchain=%s) %s %s AND estimatedUSDPrice > 0 AND time >= %d AND time <= %d ", influxDbTradesTable, params.bind(asset.Address), params.bind(asset.Blockchain), subQuery, subQueryBase, startTime.UnixNano(), endTime.UnixNano())
+	res, err := queryInfluxDBParams(datastore.influxClient, query, params)
 	if err != nil {
 		return r, err
 	}
@@ -342,23 +343,21 @@ func (datastore *DB) GetTradesByExchangesBatchedFull(quoteasset dia.Asset, basea
 		return []dia.Trade{}, errors.New("number of start times must equal number of end times.")
 	}
 	var query string
+	params := influxParams{}
 	for i := range startTimes {
 		subQuery := ""
 		subQueryBase := ""
 		if len(exchanges) > 0 {
-			for _, exchange := range exchanges {
-				subQuery = subQuery + fmt.Sprintf("%s|", exchange)
-			}
-			subQuery = "and exchange =~ /" + strings.TrimRight(subQuery, "|") + "/"
+			subQuery = "and exchange =~ " + influxRegex(exchanges)
 		}
 
 		if len(baseassets) > 0 {
 			for i, baseasset := range baseassets {
 				if i == 0 {
-					subQueryBase = subQueryBase + fmt.Sprintf(` and ((basetokenaddress='%s' and basetokenblockchain='%s')`, baseasset.Address, baseasset.Blockchain)
+					subQueryBase = subQueryBase + fmt.Sprintf(` and ((basetokenaddress=%s and basetokenblockchain=%s)`, params.bind(baseasset.Address), params.bind(baseasset.Blockchain))
 
 				} else {
-					subQueryBase = subQueryBase + fmt.Sprintf(` or (basetokenaddress='%s' and basetokenblockchain='%s')`, baseasset.Address, baseasset.Blockchain)
+					subQueryBase = subQueryBase + fmt.Sprintf(` or (basetokenaddress=%s and basetokenblockchain=%s)`, params.bind(baseasset.Address), params.bind(baseasset.Blockchain))
 				}
 
 			}
@@ -366,10 +365,10 @@ func (datastore *DB) GetTradesByExchangesBatchedFull(quoteasset dia.Asset, basea
 
 		}
 		log.Errorln("subQueryBase", subQueryBase)
-		query = query + fmt.Sprintf("SELECT time,estimatedUSDPrice,exchange,foreignTradeID,pair,price,symbol,volume,verified,basetokenblockchain,basetokenaddress FROM %s WHERE (quotetokenaddress='%s' AND quotetokenblockchain='%s') %s %s AND estimatedUSDPrice > 0 AND time > %d AND time <= %d ;", influxDbTradesTable, quoteasset.Address, quoteasset.Blockchain, subQuery, subQueryBase, startTimes[i].UnixNano(), endTimes[i].UnixNano())
+		query = query + fmt.Sprintf("SELECT time,estimatedUSDPrice,exchange,foreignTradeID,pair,price,symbol,volume,verified,basetokenblockchain,basetokenaddress FROM %s WHERE (quotetokenaddress=%s AND quotetokenblockchain=%s) %s %s AND estimatedUSDPrice > 0 AND time > %d AND time <= %d ;", influxDbTradesTable, params.bind(quoteasset.Address), params.bind(quoteasset.Blockchain), subQuery, subQueryBase, startTimes[i].UnixNano(), endTimes[i].UnixNano())
 	}
 	log.Errorln("query", query)
-	res, err := queryInfluxDB(datastore.influxClient, query)
+	res, err := queryInfluxDBParams(datastore.influxClient, query, params)
 	if err != nil {
 		return r, err
 	}
@@ -427,19 +426,20 @@ func (datastore *DB) GetLastTrades(asset dia.Asset, exchange string, maxTrades i
 	var r []dia.Trade
 	var queryString string
 	var q string
+	params := influxParams{}
 	if exchange == "" {
 		queryString = "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume,\"verified\"," +
 			"\"basetokenblockchain\",\"basetokenaddress\"" +
-			" FROM %s WHERE time<now() AND time>now()-30d AND quotetokenaddress='%s' AND quotetokenblockchain='%s' AND estimatedUSDPrice>0 ORDER BY DESC LIMIT %d"
-		q = fmt.Sprintf(queryString, influxDbTradesTable, asset.Address, asset.Blockchain, maxTrades)
+			" FROM %s WHERE time<now() AND time>now()-30d AND quotetokenaddress=%s AND quotetokenblockchain=%s AND estimatedUSDPrice>0 ORDER BY DESC LIMIT %d"
+		q = fmt.Sprintf(queryString, influxDbTradesTable, params.bind(asset.Address), params.bind(asset.Blockchain), maxTrades)
 	} else {
 		queryString = "SELECT estimatedUSDPrice,\"exchange\",foreignTradeID,\"pair\",price,\"symbol\",volume,\"verified\"," +
 			"\"basetokenblockchain\",\"basetokenaddress\"" +
-			" FROM %s WHERE time<now() AND time>now()-30d AND exchange='%s' AND quotetokenaddress='%s' AND quotetokenblockchain='%s' AND estimatedUSDPrice>0 ORDER BY DESC LIMIT %d"
-		q = fmt.Sprintf(queryString, influxDbTradesTable, exchange, asset.Address, asset.Blockchain, maxTrades)
+			" FROM %s WHERE time<now() AND time>now()-30d AND exchange=%s AND quotetokenaddress=%s AND quotetokenblockchain=%s AND estimatedUSDPrice>0 ORDER BY DESC LIMIT %d"
+		q = fmt.Sprintf(queryString, influxDbTradesTable, params.bind(exchange), params.bind(asset.Address), params.bind(asset.Blockchain), maxTrades)
 	}
 	log.Info("query: ", q)
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetLastTrades", err)
 		return r, err
@@ -471,27 +471,28 @@ func (datastore *DB) GetNumTradesExchange24H(exchange string) (numTrades int64,
 // If @address and @blockchain are empty, it returns all trades on @exchange in the given-time range.
 func (datastore *DB) GetNumTrades(exchange string, address string, blockchain string, starttime time.Time, endtime time.Time) (numTrades int64, err error) {
 	var q string
+	params := influxParams{}
 
 	if address != "" && blockchain != "" {
 		queryString := `
 	SELECT COUNT(*) 
 	FROM %s 
-	WHERE exchange='%s' 
-	AND quotetokenaddress='%s' AND quotetokenblockchain='%s' 
+	WHERE exchange=%s 
+	AND quotetokenaddress=%s AND quotetokenblockchain=%s 
 	AND time > %d AND time<= %d
 	`
-		q = fmt.Sprintf(queryString, influxDbTradesTable, exchange, address, blockchain, starttime.UnixNano(), endtime.UnixNano())
+		q = fmt.Sprintf(queryString, influxDbTradesTable, params.bind(exchange), params.bind(address), params.bind(blockchain), starttime.UnixNano(), endtime.UnixNano())
 	} else {
 		queryString := `
 	SELECT COUNT(*) 
 	FROM %s 
-	WHERE exchange='%s' 
+	WHERE exchange=%s 
 	AND time > %d AND time<= %d
 	`
-		q = fmt.Sprintf(queryString, influxDbTradesTable, exchange, starttime.UnixNano(), endtime.UnixNano())
+		q = fmt.Sprintf(queryString, influxDbTradesTable, params.bind(exchange), starttime.UnixNano(), endtime.UnixNano())
 	}
 
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetNumTrades ", err)
 		return
@@ -522,39 +523,43 @@ func (datastore *DB) GetNumTradesSeries(
 	quotetoken dia.Asset,
 	basetoken dia.Asset,
 ) (numTrades []int, err error) {
+	if !validInfluxDuration.MatchString(grouping) {
+		return numTrades, fmt.Errorf("invalid grouping %s", grouping)
+	}
 	var query string
+	params := influxParams{}
 	if pair != "" {
-		queryString := "SELECT COUNT(price) FROM %s WHERE exchange='%s' AND pair='%s' AND time<=%d AND time>%d GROUP BY time('%s') ORDER BY ASC"
+		queryString := "SELECT COUNT(price) FROM %s WHERE exchange=%s AND pair=%s AND time<=%d AND time>%d GROUP BY time('%s') ORDER BY ASC"
 		query = fmt.Sprintf(
 			queryString,
 			influxDbTradesTable,
-			exchange,
-			pair,
+			params.bind(exchange),
+			params.bind(pair),
 			endtime.UnixNano(),
 			starttime.UnixNano(),
 			grouping,
 		)
 	} else {
 		queryString := `SELECT COUNT(price) FROM %s 
-		WHERE exchange='%s' 
-		AND quotetokenaddress='%s' AND quotetokenblockchain='%s' 
-		AND basetokenaddress='%s' AND basetokenblockchain='%s' 
+		WHERE exchange=%s 
+		AND quotetokenaddress=%s AND quotetokenblockchain=%s 
+		AND basetokenaddress=%s AND basetokenblockchain=%s 
 		AND time<=%d AND time>%d 
 		GROUP BY time('%s') ORDER BY ASC`
 		query = fmt.Sprintf(
 			queryString,
 			influxDbTradesTable,
-			exchange,
-			quotetoken.Address,
-			quotetoken.Blockchain,
-			basetoken.Address,
-			basetoken.Blockchain,
+			params.bind(exchange),
+			params.bind(quotetoken.Address),
+			params.bind(quotetoken.Blockchain),
+			params.bind(basetoken.Address),
+			params.bind(basetoken.Blockchain),
 			endtime.UnixNano(),
 			starttime.UnixNano(),
 			grouping,
 		)
 	}
-	res, err := queryInfluxDB(datastore.influxClient, query)
+	res, err := queryInfluxDBParams(datastore.influxClient, query, params)
 	if err != nil {
 		return
 	}
@@ -576,7 +581,7 @@ func (datastore *DB) GetNumTradesSeries(
 func (datastore *DB) GetFirstTradeDate(table string) (time.Time, error) {
 	var query string
 	queryString := "SELECT \"exchange\",price FROM %s  where time<now() order by asc limit 1"
-	query = fmt.Sprintf(queryString, table)
+	query = fmt.Sprintf(queryString, influxIdentifier(table))
 
 	res, err := queryInfluxDB(datastore.influxClient, query)
 	if err != nil {
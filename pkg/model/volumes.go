@@ -32,40 +32,41 @@ func (datastore *DB) GetVolumeInflux(asset dia.Asset, exchange string, starttime
 	}
 
 	var q string
+	params := influxParams{}
 
 	if asset == (dia.Asset{}) {
 		queryString := `
 		SELECT SUM(value) 
 		FROM %s 
-		WHERE exchange='%s' 
+		WHERE exchange=%s 
 		AND filter='%s' 
 		AND time > %d AND time<= %d
 		`
-		q = fmt.Sprintf(queryString, influxDbFiltersTable, exchange, volumeKey, starttime.UnixNano(), endtime.UnixNano())
+		q = fmt.Sprintf(queryString, influxDbFiltersTable, params.bind(exchange), volumeKey, starttime.UnixNano(), endtime.UnixNano())
 	} else if exchange == "" {
 		queryString := `
 		SELECT SUM(value) 
 		FROM %s 
-		WHERE address='%s' AND blockchain='%s' 
+		WHERE address=%s AND blockchain=%s 
 		AND exchange=''
 		AND filter='%s' 
 		AND time > %d AND time<= %d
 		`
-		q = fmt.Sprintf(queryString, influxDbFiltersTable, asset.Address, asset.Blockchain, volumeKey, starttime.UnixNano(), endtime.UnixNano())
+		q = fmt.Sprintf(queryString, influxDbFiltersTable, params.bind(asset.Address), params.bind(asset.Blockchain), volumeKey, starttime.UnixNano(), endtime.UnixNano())
 	} else {
 		queryString := `
 		SELECT SUM(value) 
 		FROM %s 
-		WHERE address='%s' AND blockchain='%s' 
-		AND exchange='%s' 
+		WHERE address=%s AND blockchain=%s 
+		AND exchange=%s 
 		AND filter='%s' 
 		AND time > %d AND time<= %d
 		`
-		q = fmt.Sprintf(queryString, influxDbFiltersTable, asset.Address, asset.Blockchain, exchange, volumeKey, starttime.UnixNano(), endtime.UnixNano())
+		q = fmt.Sprintf(queryString, influxDbFiltersTable, params.bind(asset.Address), params.bind(asset.Blockchain), params.bind(exchange), volumeKey, starttime.UnixNano(), endtime.UnixNano())
 	}
 
 	var errorString string
-	res, err := queryInfluxDB(datastore.influxClient, q)
+	res, err := queryInfluxDBParams(datastore.influxClient, q, params)
 	if err != nil {
 		log.Errorln("GetVolumeInflux ", err)
 		return nil, err
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"regexp"
+)
+
+var (
+	validBlockchain = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+	validAddress    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+	validExchange   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 ._-]{0,63}$`)
+)
+
+// ValidBlockchain returns true if @blockchain is a well-formed blockchain name such as Ethereum or BinanceSmartChain.
+func ValidBlockchain(blockchain string) bool {
+	return validBlockchain.MatchString(blockchain)
+}
+
+// ValidAddress returns true if @address is a well-formed asset or contract address. Besides hex addresses,
+// this covers addresses on non-EVM chains such as A.0b2a3299cc857e29.TopShot on Flow.
+func ValidAddress(address string) bool {
+	return validAddress.MatchString(address)
+}
+
+// ValidExchange returns true if @exchange is a well-formed exchange name such as Crypto.com or UniswapV3-polygon.
+func ValidExchange(exchange string) bool {
+	return validExchange.MatchString(exchange)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+var hostileInputs = []string{
+	"",
+	"' OR 1=1 --",
+	"Ethereum'; DROP TABLE asset;--",
+	`0x0" OR "1"="1`,
+	"Binance/ OR exchange=~/.*",
+	"0x0\nSELECT",
+	`0x0\`,
+	"$1",
+	"-Ethereum",
+	strings.Repeat("a", 129),
+}
+
+func TestValidIdentifiers(t *testing.T) {
+	tables := []struct {
+		valid  func(string) bool
+		inputs []string
+	}{
+		{ValidBlockchain, []string{"Ethereum", "BinanceSmartChain", "Fiat", "Polygon"}},
+		{ValidAddress, []string{"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "0x0000000000000000000000000000000000000000", "A.0b2a3299cc857e29.TopShot", "840"}},
+		{ValidExchange, []string{"Binance", "Crypto.com", "UniswapV3-polygon", "TofuNFT-BinanceSmartChain"}},
+	}
+	for _, table := range tables {
+		for _, input := range table.inputs {
+			if !table.valid(input) {
+				t.Errorf("valid identifier %s was rejected", input)
+			}
+		}
+		for _, input := range hostileInputs {
+			if table.valid(input) {
+				t.Errorf("hostile identifier %q was accepted", input)
+			}
+		}
+	}
+}